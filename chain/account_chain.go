@@ -4,7 +4,7 @@ import (
 	"errors"
 	"math/big"
 
-	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/vitelabs/go-vite/chain_db/database"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/ledger"
 	"github.com/vitelabs/go-vite/monitor"
@@ -26,11 +26,20 @@ func (c *chain) completeBlock(block *ledger.AccountBlock, account *ledger.Accoun
 }
 
 func (c *chain) InsertAccountBlocks(vmAccountBlocks []*vm_context.VmAccountBlock) error {
-	batch := new(leveldb.Batch)
+	batch := c.chainDb.NewBatch()
 	monitor.LogEventNum("chain", "insert", len(vmAccountBlocks))
 	trieSaveCallback := make([]func(), 0)
 	var account *ledger.Account
 
+	// The saved tries may skip the nodes existing in db, so they mustn't be pruned until the states are marked
+	c.pruneLock.RLock()
+	pruneLocked := true
+	defer func() {
+		if pruneLocked {
+			c.pruneLock.RUnlock()
+		}
+	}()
+
 	// Write vmContext
 	var addBlockHashList []types.Hash
 	for _, vmAccountBlock := range vmAccountBlocks {
@@ -154,9 +163,15 @@ func (c *chain) InsertAccountBlocks(vmAccountBlocks []*vm_context.VmAccountBlock
 	}
 
 	// Write db
-	if err := c.chainDb.Commit(batch); err != nil {
-		c.log.Crit("c.chainDb.Commit(batch) failed, error is "+err.Error(), "method", "InsertAccountBlocks")
-		return err
+	commitErr := c.chainDb.Commit(batch)
+	if commitErr == nil {
+		c.markInserted(nil, vmAccountBlocks)
+	}
+	c.pruneLock.RUnlock()
+	pruneLocked = false
+	if commitErr != nil {
+		c.log.Crit("c.chainDb.Commit(batch) failed, error is "+commitErr.Error(), "method", "InsertAccountBlocks")
+		return commitErr
 	}
 
 	// Set stateTriePool
@@ -373,7 +388,7 @@ func (c *chain) GetAccountBlockByHeight(addr *types.Address, height uint64) (*le
 
 	block, err := c.chainDb.Ac.GetBlockByHeight(account.AccountId, height)
 	if err != nil {
-		if err == leveldb.ErrNotFound {
+		if err == database.ErrNotFound {
 			return nil, nil
 		}
 
@@ -393,7 +408,7 @@ func (c *chain) GetAccountBlockByHeight(addr *types.Address, height uint64) (*le
 func (c *chain) GetAccountBlockByHash(blockHash *types.Hash) (*ledger.AccountBlock, error) {
 	block, err := c.chainDb.Ac.GetBlock(blockHash)
 	if err != nil {
-		if err == leveldb.ErrNotFound {
+		if err == database.ErrNotFound {
 			return nil, nil
 		}
 
@@ -576,7 +591,7 @@ func (c *chain) DeleteAccountBlocks(addr *types.Address, toHeight uint64) (map[t
 		return nil, getErr
 	}
 
-	batch := new(leveldb.Batch)
+	batch := c.chainDb.NewBatch()
 	deleteAccountBlocks, deleteAccountBlocksErr := c.chainDb.Ac.Delete(batch, deleteMap)
 	if len(deleteAccountBlocks) <= 0 {
 		return nil, nil
@@ -78,7 +78,8 @@ func BenchmarkChain_InsertAccountBlocks(b *testing.B) {
 	chainInstance := NewChain(&config.Config{
 		DataDir: common.HomeDir(),
 		//Chain: &config.Chain{
-		//	KafkaProducers: []*config.KafkaProducer{{
+		//	EventSinks: []*config.EventSink{{
+		//		Type:       "kafka",
 		//		Topic:      "test",
 		//		BrokerList: []string{"abc", "def"},
 		//	}},
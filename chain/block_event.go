@@ -1,6 +1,24 @@
 package chain
 
-import "github.com/vitelabs/go-vite/common/types"
+import (
+	"github.com/vitelabs/go-vite/chain_db/access"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+)
+
+// BlockEvent is an add or delete event of the block event log. The blocks of add events are resolved if they
+// still exist, the blocks of delete events have been removed from the ledger, so only their hashes are available.
+type BlockEvent struct {
+	EventId       uint64
+	EventType     byte
+	BlockHashList []types.Hash
+
+	AccountBlocks  []*ledger.AccountBlock
+	SnapshotBlocks []*ledger.SnapshotBlock
+}
+
+// MaxEventsCount is the max count of events returned by GetEvents at a time
+const MaxEventsCount = uint64(1000)
 
 func (c *chain) GetLatestBlockEventId() (uint64, error) {
 	return c.ChainDb().Be.LatestEventId()
@@ -9,3 +27,53 @@ func (c *chain) GetLatestBlockEventId() (uint64, error) {
 func (c *chain) GetEvent(eventId uint64) (byte, []types.Hash, error) {
 	return c.ChainDb().Be.GetEvent(eventId)
 }
+
+func (c *chain) GetEvents(fromEventId uint64, count uint64) ([]*BlockEvent, error) {
+	if count > MaxEventsCount {
+		count = MaxEventsCount
+	}
+
+	eventIdList, eventTypeList, blockHashLists, err := c.ChainDb().Be.GetEvents(fromEventId, count)
+	if err != nil {
+		c.log.Error("GetEvents failed, error is "+err.Error(), "method", "GetEvents")
+		return nil, err
+	}
+
+	events := make([]*BlockEvent, 0, len(eventIdList))
+	for index, eventId := range eventIdList {
+		event := &BlockEvent{
+			EventId:       eventId,
+			EventType:     eventTypeList[index],
+			BlockHashList: blockHashLists[index],
+		}
+
+		switch event.EventType {
+		case access.AddAccountBlocksEvent:
+			for _, blockHash := range event.BlockHashList {
+				block, err := c.GetAccountBlockByHash(&blockHash)
+				if err != nil {
+					c.log.Error("GetAccountBlockByHash failed, error is "+err.Error(), "method", "GetEvents")
+					return nil, err
+				}
+				if block != nil {
+					event.AccountBlocks = append(event.AccountBlocks, block)
+				}
+			}
+		case access.AddSnapshotBlocksEvent:
+			for _, blockHash := range event.BlockHashList {
+				block, err := c.GetSnapshotBlockByHash(&blockHash)
+				if err != nil {
+					c.log.Error("GetSnapshotBlockByHash failed, error is "+err.Error(), "method", "GetEvents")
+					return nil, err
+				}
+				if block != nil {
+					event.SnapshotBlocks = append(event.SnapshotBlocks, block)
+				}
+			}
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
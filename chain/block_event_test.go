@@ -21,3 +21,17 @@ func TestChain_GetEvent(t *testing.T) {
 func TestDifficulty(t *testing.T) {
 	new(big.Int).SetString("", 10)
 }
+
+func TestChain_GetEvents(t *testing.T) {
+	chainInstance := getChainInstance()
+	events, err := chainInstance.GetEvents(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, event := range events {
+		if index > 0 && event.EventId <= events[index-1].EventId {
+			t.Fatalf("event id is not ascending, %d <= %d", event.EventId, events[index-1].EventId)
+		}
+		fmt.Printf("%d %v: %+v\n", event.EventId, event.EventType, event.BlockHashList)
+	}
+}
@@ -18,6 +18,9 @@ import (
 )
 
 type chain struct {
+	// accessed atomically, keep it 64-bit aligned
+	lastPruneHeight uint64
+
 	log        log15.Logger
 	blackBlock *blackBlock
 
@@ -29,6 +32,12 @@ type chain struct {
 
 	createAccountLock sync.Mutex
 
+	// pruneLock is held for reading from saving tries to marking the committed states, for writing by PruneState
+	// while deleting a batch of nodes and setting pruner
+	pruneLock sync.RWMutex
+	pruner    *trie.Pruner
+	pruning   int32
+
 	needSnapshotCache *NeedSnapshotCache
 
 	genesisSnapshotBlock *ledger.SnapshotBlock
@@ -38,9 +47,13 @@ type chain struct {
 
 	em *eventManager
 
-	cfg         *config.Chain
-	globalCfg   *config.Config
-	kafkaSender *sender.KafkaSender
+	indexes          []*blockIndex
+	indexTermination chan struct{}
+	indexWg          sync.WaitGroup
+
+	cfg       *config.Chain
+	globalCfg *config.Config
+	sender    *sender.Sender
 }
 
 func NewChain(cfg *config.Config) Chain {
@@ -74,30 +87,50 @@ func (c *chain) Init() {
 	c.em = newEventManager()
 
 	// chainDb
-	chainDb := chain_db.NewChainDb(filepath.Join(c.dataDir, "ledger"))
+	var chainDb *chain_db.ChainDb
+	if c.cfg.LedgerInMemory {
+		chainDb = chain_db.NewMemChainDb()
+	} else {
+		chainDb = chain_db.NewChainDb(filepath.Join(c.dataDir, "ledger"), c.cfg.WipeCorruptedLedger)
+	}
 	if chainDb == nil {
 		c.log.Crit("NewChain failed, db init failed", "method", "Init")
 	}
 	c.chainDb = chainDb
 
+	// tx index
+	if c.cfg.OpenTxIndex {
+		c.registerIndex(c.newTxIndex())
+	}
+
+	// log index
+	if c.cfg.OpenLogIndex {
+		c.registerIndex(c.newLogIndex())
+	}
+
+	// state prune
+	if c.cfg.StatePruneHeight > 0 {
+		c.registerStatePrune()
+	}
+
 	// compressor
-	compressor := compress.NewCompressor(c, c.dataDir)
+	compressor := compress.NewCompressor(c, c.dataDir, c.cfg.LedgerFileCodec)
 	c.compressor = compressor
 
-	// kafka sender
-	if len(c.cfg.KafkaProducers) > 0 {
-		var newKafkaErr error
-		c.kafkaSender, newKafkaErr = sender.NewKafkaSender(c, filepath.Join(c.dataDir, "ledger_mq"))
-		if newKafkaErr != nil {
-			c.log.Crit("NewKafkaSender failed, error is " + newKafkaErr.Error())
+	// event sender
+	if len(c.cfg.EventSinks) > 0 {
+		var newSenderErr error
+		c.sender, newSenderErr = sender.NewSender(c, filepath.Join(c.dataDir, "ledger_mq"))
+		if newSenderErr != nil {
+			c.log.Crit("NewSender failed, error is " + newSenderErr.Error())
 		}
 	}
 	// Finish initialize
 	c.log.Info("Chain module initialized")
 }
 
-func (c *chain) KafkaSender() *sender.KafkaSender {
-	return c.kafkaSender
+func (c *chain) Sender() *sender.Sender {
+	return c.sender
 }
 func (c *chain) checkAndInitData() {
 	sb := c.genesisSnapshotBlock
@@ -116,10 +149,32 @@ func (c *chain) checkAndInitData() {
 			c.log.Error("GetSnapshotBlockByHeight(2) failed, error is "+err.Error(), "method", "CheckAndInitDb")
 		}
 
+		// the ledger of other genesis or the broken ledger is wiped only if required
+		if latestBlock, _ := c.chainDb.Sc.GetLatestBlock(); latestBlock != nil && !c.cfg.WipeCorruptedLedger {
+			c.log.Crit("Genesis blocks of the ledger don't match, restart with wipecorrupted to wipe it and sync again", "method", "CheckAndInitDb")
+		}
+
 		c.clearData()
 		c.initData()
 		return
 	}
+
+	// the ledger may be inconsistent after a crash even if the db isn't corrupted, it's repaired if required
+	if !c.chainDb.Recovered() && !c.cfg.RepairLedger {
+		return
+	}
+	if c.chainDb.Recovered() {
+		c.log.Warn("Ledger db has been recovered", "method", "CheckAndInitDb")
+	}
+	if err := c.repair(); err != nil {
+		if !c.cfg.WipeCorruptedLedger {
+			c.log.Crit("Repair ledger failed, error is "+err.Error(), "method", "CheckAndInitDb")
+		}
+
+		c.log.Warn("Wipe the ledger which can't be repaired", "method", "CheckAndInitDb")
+		c.clearData()
+		c.initData()
+	}
 }
 
 func (c *chain) clearData() {
@@ -181,9 +236,6 @@ func (c *chain) initData() {
 	if err != nil {
 		c.log.Crit("WriteSnapshotBlock failed, error is "+err.Error(), "method", "initData")
 	}
-
-	// rebuild cache
-	c.needSnapshotCache.Rebuild()
 }
 
 func (c *chain) Compressor() *compress.Compressor {
@@ -198,6 +250,13 @@ func (c *chain) Start() {
 	// Start compress in the background
 	c.log.Info("Start chain module")
 
+	// trieNodePool
+	c.trieNodePool = trie.NewTrieNodePool()
+
+	// check, the blocks are deleted with an empty needSnapshotCache, which is built after the ledger is consistent
+	c.needSnapshotCache = NewNeedSnapshotContent(c, nil)
+	c.checkAndInitData()
+
 	// needSnapshotCache
 	unconfirmedSubLedger, getSubLedgerErr := c.getUnConfirmedSubLedger()
 	if getSubLedgerErr != nil {
@@ -205,12 +264,6 @@ func (c *chain) Start() {
 	}
 	c.needSnapshotCache = NewNeedSnapshotContent(c, unconfirmedSubLedger)
 
-	// check
-	c.checkAndInitData()
-
-	// trieNodePool
-	c.trieNodePool = trie.NewTrieNodePool()
-
 	// latestSnapshotBlock
 	var getLatestBlockErr error
 	c.latestSnapshotBlock, getLatestBlockErr = c.chainDb.Sc.GetLatestBlock()
@@ -218,15 +271,23 @@ func (c *chain) Start() {
 		c.log.Crit("GetLatestBlock failed, error is "+getLatestBlockErr.Error(), "method", "Start")
 	}
 
+	// rebuild the tx index and the log index in the background
+	c.startIndexes()
+
 	// start compressor
 	c.compressor.Start()
 
-	// start kafka sender
-	if c.kafkaSender != nil {
-		for _, producer := range c.cfg.KafkaProducers {
-			startErr := c.kafkaSender.Start(producer.BrokerList, producer.Topic)
+	// start event sender
+	if c.sender != nil {
+		for _, sinkCfg := range c.cfg.EventSinks {
+			sink, newSinkErr := sender.NewSink(sinkCfg)
+			if newSinkErr != nil {
+				c.log.Crit("NewSink failed, error is " + newSinkErr.Error())
+			}
+
+			startErr := c.sender.Start(sink)
 			if startErr != nil {
-				c.log.Crit("Start kafka sender failed, error is " + startErr.Error())
+				c.log.Crit("Start sender failed, error is " + startErr.Error())
 			}
 		}
 	}
@@ -238,12 +299,15 @@ func (c *chain) Stop() {
 	// Stop compress
 	c.log.Info("Stop chain module")
 
+	// stop rebuilding indexes
+	c.stopIndexes()
+
 	// stop compressor
 	c.compressor.Stop()
 
-	// stop kafka sender
-	if c.kafkaSender != nil {
-		c.kafkaSender.StopAll()
+	// stop event sender
+	if c.sender != nil {
+		c.sender.StopAll()
 	}
 
 	c.log.Info("Chain module stopped")
@@ -267,8 +331,8 @@ func (c *chain) Destroy() {
 	// needSnapshotCache
 	c.needSnapshotCache = nil
 
-	// kafka sender
-	c.kafkaSender = nil
+	// event sender
+	c.sender = nil
 
 	c.log.Info("Chain module destroyed")
 }
@@ -0,0 +1,22 @@
+package chain
+
+import (
+	"github.com/vitelabs/go-vite/common/types"
+)
+
+func (c *chain) SaveContractAbi(addr *types.Address, abiJson []byte) error {
+	if err := c.chainDb.ContractAbi.WriteAbi(addr, abiJson); err != nil {
+		c.log.Error("WriteAbi failed, error is "+err.Error(), "method", "SaveContractAbi")
+		return err
+	}
+	return nil
+}
+
+func (c *chain) GetContractAbi(addr *types.Address) ([]byte, error) {
+	abiJson, err := c.chainDb.ContractAbi.GetAbi(addr)
+	if err != nil {
+		c.log.Error("GetAbi failed, error is "+err.Error(), "method", "GetContractAbi")
+		return nil, err
+	}
+	return abiJson, nil
+}
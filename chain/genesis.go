@@ -7,6 +7,7 @@ import (
 
 	"github.com/vitelabs/go-vite/vm/contracts/abi"
 
+	"github.com/vitelabs/go-vite/checkdb"
 	"github.com/vitelabs/go-vite/common/helper"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/ledger"
@@ -49,6 +50,20 @@ func initGenesis(config *GenesisConfig) {
 	SecondSnapshotBlock = secondSnapshotBlock()
 }
 
+// LedgerGenesis returns the genesis blocks which the ledger is checked against, they are loaded by NewChain.
+func LedgerGenesis() *checkdb.Genesis {
+	return &checkdb.Genesis{
+		SnapshotBlock:       &GenesisSnapshotBlock,
+		SecondSnapshotBlock: &SecondSnapshotBlock,
+		AccountBlocks: []*ledger.AccountBlock{
+			&GenesisMintageBlock,
+			&GenesisMintageSendBlock,
+			&GenesisConsensusGroupBlock,
+			&GenesisRegisterBlock,
+		},
+	}
+}
+
 var genesisTrieNodePool = trie.NewTrieNodePool()
 var genesisTimestamp = time.Unix(1541650394, 0)
 
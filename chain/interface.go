@@ -4,9 +4,10 @@ import (
 	"math/big"
 	"time"
 
-	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/vitelabs/go-vite/chain/sender"
 	"github.com/vitelabs/go-vite/chain_db"
+	"github.com/vitelabs/go-vite/chain_db/access"
+	"github.com/vitelabs/go-vite/chain_db/database"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/compress"
 	"github.com/vitelabs/go-vite/ledger"
@@ -14,9 +15,9 @@ import (
 	"github.com/vitelabs/go-vite/vm_context"
 )
 
-type InsertProcessorFunc func(batch *leveldb.Batch, blocks []*vm_context.VmAccountBlock) error
+type InsertProcessorFunc func(batch database.Batch, blocks []*vm_context.VmAccountBlock) error
 type InsertProcessorFuncSuccess func(blocks []*vm_context.VmAccountBlock)
-type DeleteProcessorFunc func(batch *leveldb.Batch, subLedger map[types.Address][]*ledger.AccountBlock) error
+type DeleteProcessorFunc func(batch database.Batch, subLedger map[types.Address][]*ledger.AccountBlock) error
 type DeleteProcessorFuncSuccess func(subLedger map[types.Address][]*ledger.AccountBlock)
 
 type InsertSnapshotBlocksSuccess func([]*ledger.SnapshotBlock)
@@ -70,7 +71,7 @@ type Chain interface {
 	GetContractGid(addr *types.Address) (*types.Gid, error)
 	GetRegisterList(snapshotHash types.Hash, gid types.Gid) ([]*types.Registration, error)
 	GetVoteMap(snapshotHash types.Hash, gid types.Gid) ([]*types.VoteInfo, error)
-	KafkaSender() *sender.KafkaSender
+	Sender() *sender.Sender
 
 	// Pledge amount
 	GetPledgeAmount(snapshotHash types.Hash, beneficial types.Address) (*big.Int, error)
@@ -89,6 +90,11 @@ type Chain interface {
 	GetSubLedgerByHash(startBlockHash *types.Hash, count uint64, forward bool) ([]*ledger.CompressedFileMeta, [][2]uint64, error)
 	GetConfirmSubLedger(fromHeight uint64, toHeight uint64) ([]*ledger.SnapshotBlock, map[types.Address][]*ledger.AccountBlock, error)
 	GetVmLogList(logListHash *types.Hash) (ledger.VmLogList, error)
+	SaveContractAbi(addr *types.Address, abiJson []byte) error
+	GetContractAbi(addr *types.Address) ([]byte, error)
+	GetLogs(addr *types.Address, startHeight uint64, endHeight uint64, topics [][]types.Hash, maxCount int) ([]*VmLogItem, error)
+	GetLogsByTopics(topics [][]types.Hash, fromHeight uint64, toHeight uint64, maxCount int) ([]*VmLogItem, error)
+	GetTransfers(addr *types.Address, direction byte, filter *access.TxIndexFilter, cursor []byte, count uint64) ([]*ledger.AccountBlock, []byte, error)
 	UnRegister(listenerId uint64)
 	RegisterInsertAccountBlocks(processor InsertProcessorFunc) uint64
 	RegisterInsertAccountBlocksSuccess(processor InsertProcessorFuncSuccess) uint64
@@ -99,11 +105,14 @@ type Chain interface {
 	GetConfirmSubLedgerBySnapshotBlocks(snapshotBlocks []*ledger.SnapshotBlock) (map[types.Address][]*ledger.AccountBlock, error)
 
 	GetStateTrie(stateHash *types.Hash) *trie.Trie
+	GetLazyStateTrie(stateHash *types.Hash) *trie.LazyTrie
 	NewStateTrie() *trie.Trie
+	PruneState(keepHeight uint64) error
 
 	// Be
 	GetLatestBlockEventId() (uint64, error)
 	GetEvent(eventId uint64) (byte, []types.Hash, error)
+	GetEvents(fromEventId uint64, count uint64) ([]*BlockEvent, error)
 
 	// onroad
 	IsSuccessReceived(addr *types.Address, hash *types.Hash) bool
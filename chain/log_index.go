@@ -0,0 +1,269 @@
+package chain
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/vitelabs/go-vite/chain_db/access"
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+)
+
+var (
+	ErrLogIndexNotOpen = errors.New("log index is not open")
+	ErrLogTopicsEmpty  = errors.New("topics are empty")
+	ErrTooManyLogs     = errors.New("too many logs")
+)
+
+// maximum count of the blocks read from the log index by a query
+const maxLogIndexItemsScanned = 100000
+
+// VmLogItem is a vm log together with the position it was emitted at.
+type VmLogItem struct {
+	Log *ledger.VmLog
+
+	AccountAddress types.Address
+	AccountHeight  uint64
+	BlockHash      types.Hash
+	LogIndex       uint64
+
+	// the height of the snapshot block confirming the block, 0 if unconfirmed, only set by GetLogsByTopics
+	ConfirmHeight uint64
+}
+
+func (c *chain) newLogIndex() *blockIndex {
+	return &blockIndex{
+		name:     "log",
+		id:       database.DBKP_LOG_INDEX,
+		needLogs: true,
+		writeBlock: func(batch database.Batch, block *ledger.AccountBlock, logList ledger.VmLogList) error {
+			snapshotHeight, err := c.chainDb.Sc.GetSnapshotBlockHeight(&block.SnapshotHash)
+			if err != nil {
+				return err
+			}
+			c.chainDb.LogIndex.WriteBlock(batch, block, snapshotHeight, logList)
+			return nil
+		},
+		// the referred snapshot block is deleted in the same batch at most, so its height can still be read
+		deleteBlock: func(batch database.Batch, block *ledger.AccountBlock, logList ledger.VmLogList) error {
+			snapshotHeight, err := c.chainDb.Sc.GetSnapshotBlockHeight(&block.SnapshotHash)
+			if err != nil {
+				return err
+			}
+			c.chainDb.LogIndex.DeleteBlock(batch, block, snapshotHeight, logList)
+			return nil
+		},
+	}
+}
+
+// GetLogs returns the vm logs emitted by the blocks of addr from startHeight to endHeight inclusively, endHeight
+// 0 means up to the latest block. topics[i] lists the accepted values of the i-th topic, an empty list accepts
+// any value, logs with fewer topics than len(topics) never match. ErrTooManyLogs is returned if more than
+// maxCount logs match or more than maxLogIndexItemsScanned blocks are read, together with the logs of the blocks
+// before the one exceeding the limit.
+func (c *chain) GetLogs(addr *types.Address, startHeight uint64, endHeight uint64, topics [][]types.Hash, maxCount int) ([]*VmLogItem, error) {
+	if !c.cfg.OpenLogIndex {
+		return nil, ErrLogIndexNotOpen
+	}
+
+	var logs []*VmLogItem
+	var matchErr error
+	scanned := 0
+	err := c.chainDb.LogIndex.IterateItems(addr, startHeight, endHeight, func(item *access.LogIndexItem) bool {
+		if scanned++; scanned > maxLogIndexItemsScanned {
+			matchErr = ErrTooManyLogs
+			return false
+		}
+		complete := len(logs)
+		if logs, matchErr = c.matchLogs(logs, item, topics, maxCount); matchErr != nil {
+			logs = logs[:complete]
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		c.log.Error("IterateItems failed, error is "+err.Error(), "method", "GetLogs")
+		return nil, err
+	}
+	return logs, matchErr
+}
+
+// topicLogItem is a block found by the topic index and the snapshot height confirming it, 0 if unconfirmed
+type topicLogItem struct {
+	*access.LogIndexItem
+	confirmHeight uint64
+}
+
+// GetLogsByTopics returns the vm logs matched by topics, like GetLogs, of all addresses. At least one list of
+// topics must be non-empty, the blocks are looked up by the topic index with the first one. Only the blocks
+// confirmed by the snapshot blocks from fromHeight to toHeight are searched, toHeight 0 means up to the latest
+// blocks, including the unconfirmed ones. The logs are ordered by the snapshot height confirming them, the
+// unconfirmed ones last, then by address and height. ErrTooManyLogs is returned if more than maxCount logs
+// match, together with the logs confirmed below the snapshot height exceeding the limit, or if more than
+// maxLogIndexItemsScanned blocks are found by the index.
+func (c *chain) GetLogsByTopics(topics [][]types.Hash, fromHeight uint64, toHeight uint64, maxCount int) ([]*VmLogItem, error) {
+	if !c.cfg.OpenLogIndex {
+		return nil, ErrLogIndexNotOpen
+	}
+
+	var candidates []types.Hash
+	for _, list := range topics {
+		if len(list) > 0 {
+			candidates = list
+			break
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, ErrLogTopicsEmpty
+	}
+
+	// A block is confirmed by a snapshot block not lower than the one it refers to, and not higher than
+	// types.AccountLimitSnapshotHeight above it, so the blocks referring to the other snapshot blocks are skipped.
+	fromSnapshotHeight := uint64(0)
+	if fromHeight > types.AccountLimitSnapshotHeight {
+		fromSnapshotHeight = fromHeight - types.AccountLimitSnapshotHeight
+	}
+
+	var items []*topicLogItem
+	var iterErr error
+	scanned := 0
+	visited := make(map[types.Hash]struct{})
+	for _, topic := range candidates {
+		err := c.chainDb.LogIndex.IterateTopicItems(&topic, fromSnapshotHeight, toHeight, func(item *access.LogIndexItem) bool {
+			if scanned++; scanned > maxLogIndexItemsScanned {
+				iterErr = ErrTooManyLogs
+				return false
+			}
+			// a block emitting several candidates is matched once
+			if _, ok := visited[item.BlockHash]; ok {
+				return true
+			}
+			visited[item.BlockHash] = struct{}{}
+
+			var confirmHeight uint64
+			var confirmed bool
+			if confirmHeight, confirmed, iterErr = c.confirmHeightIn(&item.BlockHash, fromHeight, toHeight); iterErr != nil {
+				return false
+			}
+			if confirmed {
+				items = append(items, &topicLogItem{LogIndexItem: item, confirmHeight: confirmHeight})
+			}
+			return true
+		})
+		if err != nil {
+			c.log.Error("IterateTopicItems failed, error is "+err.Error(), "method", "GetLogsByTopics")
+			return nil, err
+		}
+		if iterErr != nil {
+			return nil, iterErr
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].confirmHeight != items[j].confirmHeight {
+			return items[j].confirmHeight == 0 || (items[i].confirmHeight != 0 && items[i].confirmHeight < items[j].confirmHeight)
+		}
+		if cmp := bytes.Compare(items[i].AccountAddress.Bytes(), items[j].AccountAddress.Bytes()); cmp != 0 {
+			return cmp < 0
+		}
+		return items[i].Height < items[j].Height
+	})
+
+	var logs []*VmLogItem
+	complete := 0
+	for i, item := range items {
+		if i > 0 && item.confirmHeight != items[i-1].confirmHeight {
+			complete = len(logs)
+		}
+		var err error
+		start := len(logs)
+		if logs, err = c.matchLogs(logs, item.LogIndexItem, topics, maxCount); err != nil {
+			if err == ErrTooManyLogs {
+				return logs[:complete], err
+			}
+			return nil, err
+		}
+		for _, log := range logs[start:] {
+			log.ConfirmHeight = item.confirmHeight
+		}
+	}
+	return logs, nil
+}
+
+// confirmHeightIn returns the height of the snapshot block confirming the block, 0 if unconfirmed, and whether
+// it is from fromHeight to toHeight, toHeight 0 also accepts the unconfirmed blocks. The deleted block isn't in
+// any range.
+func (c *chain) confirmHeightIn(blockHash *types.Hash, fromHeight uint64, toHeight uint64) (uint64, bool, error) {
+	// GetConfirmHeight requires the block exists
+	if meta, err := c.chainDb.Ac.GetBlockMeta(blockHash); err != nil || meta == nil {
+		return 0, false, err
+	}
+	confirmHeight, err := c.chainDb.Ac.GetConfirmHeight(blockHash)
+	if err != nil {
+		c.log.Error("GetConfirmHeight failed, error is "+err.Error(), "method", "confirmHeightIn")
+		return 0, false, err
+	}
+	if confirmHeight == 0 {
+		return 0, toHeight == 0, nil
+	}
+	return confirmHeight, confirmHeight >= fromHeight && (toHeight == 0 || confirmHeight <= toHeight), nil
+}
+
+// matchLogs appends the logs of the indexed block matched by topics to logs. The block is skipped if it has been
+// deleted since the index was read.
+func (c *chain) matchLogs(logs []*VmLogItem, item *access.LogIndexItem, topics [][]types.Hash, maxCount int) ([]*VmLogItem, error) {
+	meta, err := c.chainDb.Ac.GetBlockMeta(&item.BlockHash)
+	if err != nil {
+		c.log.Error("GetBlockMeta failed, error is "+err.Error(), "method", "matchLogs")
+		return logs, err
+	}
+	if meta == nil {
+		return logs, nil
+	}
+
+	logList, err := c.GetVmLogList(&item.LogHash)
+	if err != nil {
+		return logs, err
+	}
+	for index, log := range logList {
+		if !MatchTopics(log, topics) {
+			continue
+		}
+		if len(logs) >= maxCount {
+			return logs, ErrTooManyLogs
+		}
+		logs = append(logs, &VmLogItem{
+			Log:            log,
+			AccountAddress: item.AccountAddress,
+			AccountHeight:  item.Height,
+			BlockHash:      item.BlockHash,
+			LogIndex:       uint64(index),
+		})
+	}
+	return logs, nil
+}
+
+// MatchTopics reports whether log is accepted by topics, see GetLogs.
+func MatchTopics(log *ledger.VmLog, topics [][]types.Hash) bool {
+	if len(log.Topics) < len(topics) {
+		return false
+	}
+	for i, candidates := range topics {
+		if len(candidates) == 0 {
+			continue
+		}
+		matched := false
+		for _, topic := range candidates {
+			if log.Topics[i] == topic {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,182 @@
+package chain
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/config"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/vm_context"
+)
+
+// insertLogBlock inserts a send block of addr which emits a log of each topics
+func insertLogBlock(t *testing.T, c *chain, addr types.Address, topics ...[]types.Hash) *ledger.AccountBlock {
+	vmContext, err := vm_context.NewVmContext(c, nil, nil, &addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	block := &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeSendCall,
+		AccountAddress: addr,
+		ToAddress:      addr,
+		TokenId:        ledger.ViteTokenId,
+		Amount:         big.NewInt(0),
+		Fee:            big.NewInt(0),
+		Height:         1,
+		SnapshotHash:   c.GetLatestSnapshotBlock().Hash,
+		Timestamp:      &now,
+	}
+	if latestBlock, _ := c.GetLatestAccountBlock(&addr); latestBlock != nil {
+		block.Height = latestBlock.Height + 1
+		block.PrevHash = latestBlock.Hash
+	}
+
+	for i, logTopics := range topics {
+		// the log lists of the blocks are different
+		vmContext.AddLog(&ledger.VmLog{Topics: logTopics, Data: append(addr.Bytes(), byte(block.Height), byte(i))})
+	}
+	if len(topics) > 0 {
+		block.LogHash = vmContext.GetLogListHash()
+	}
+	if stateHash := vmContext.GetStorageHash(); stateHash != nil {
+		block.StateHash = *stateHash
+	}
+	block.Hash = block.ComputeHash()
+
+	if err := c.InsertAccountBlocks([]*vm_context.VmAccountBlock{{AccountBlock: block, VmContext: vmContext}}); err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+func insertSnapshotBlock(t *testing.T, c *chain) *ledger.SnapshotBlock {
+	latestBlock := c.GetLatestSnapshotBlock()
+	now := time.Now()
+	snapshotBlock := &ledger.SnapshotBlock{
+		Height:          latestBlock.Height + 1,
+		PrevHash:        latestBlock.Hash,
+		Timestamp:       &now,
+		SnapshotContent: c.GetNeedSnapshotContent(),
+	}
+
+	stateTrie, err := c.GenStateTrie(latestBlock.StateHash, snapshotBlock.SnapshotContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshotBlock.StateTrie = stateTrie
+	snapshotBlock.StateHash = *stateTrie.Hash()
+	snapshotBlock.Hash = snapshotBlock.ComputeHash()
+
+	if err := c.InsertSnapshotBlock(snapshotBlock); err != nil {
+		t.Fatal(err)
+	}
+	return snapshotBlock
+}
+
+func TestChain_GetLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log_index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := NewChain(&config.Config{DataDir: dir, Chain: &config.Chain{LedgerInMemory: true, OpenLogIndex: true}}).(*chain)
+	c.Init()
+	c.Start()
+	defer func() {
+		c.Stop()
+		c.Destroy()
+	}()
+
+	topic1 := types.DataHash([]byte("topic1"))
+	topic2 := types.DataHash([]byte("topic2"))
+	addr1, _, _ := types.CreateAddress()
+	addr2, _, _ := types.CreateAddress()
+
+	blockA := insertLogBlock(t, c, addr1, []types.Hash{topic1, topic2})
+	blockB := insertLogBlock(t, c, addr1, []types.Hash{topic1}, []types.Hash{topic1})
+	insertLogBlock(t, c, addr2, []types.Hash{topic2})
+	insertLogBlock(t, c, addr1)
+	snapshotBlock := insertSnapshotBlock(t, c)
+	blockD := insertLogBlock(t, c, addr1, []types.Hash{topic1})
+
+	checkLogs := func(logs []*VmLogItem, err error, blocks ...*ledger.AccountBlock) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(logs) != len(blocks) {
+			t.Fatalf("should get %d logs, got %d", len(blocks), len(logs))
+		}
+		for i, log := range logs {
+			if log.BlockHash != blocks[i].Hash || log.AccountHeight != blocks[i].Height || log.AccountAddress != blocks[i].AccountAddress {
+				t.Fatalf("log %d mismatch", i)
+			}
+		}
+	}
+
+	logs, err := c.GetLogs(&addr1, 1, 0, nil, 10)
+	checkLogs(logs, err, blockA, blockB, blockB, blockD)
+	if logs[2].LogIndex != 1 {
+		t.Fatal("log index should be the position in the block")
+	}
+
+	logs, err = c.GetLogs(&addr1, 2, 0, [][]types.Hash{{topic1}}, 10)
+	checkLogs(logs, err, blockB, blockB, blockD)
+
+	logs, err = c.GetLogs(&addr1, 1, 0, [][]types.Hash{{}, {topic2}}, 10)
+	checkLogs(logs, err, blockA)
+
+	// the logs of the blocks before the one exceeding the limit are returned
+	logs, err = c.GetLogs(&addr1, 1, 0, nil, 3)
+	if err != ErrTooManyLogs {
+		t.Fatalf("should get ErrTooManyLogs, got %v", err)
+	}
+	checkLogs(logs, nil, blockA, blockB, blockB)
+
+	// by topics
+	logs, err = c.GetLogsByTopics([][]types.Hash{{topic1}}, 0, 0, 10)
+	checkLogs(logs, err, blockA, blockB, blockB, blockD)
+	if logs[0].ConfirmHeight != snapshotBlock.Height || logs[3].ConfirmHeight != 0 {
+		t.Fatal("the confirm heights of the logs mismatch")
+	}
+
+	logs, err = c.GetLogsByTopics([][]types.Hash{{topic1}}, snapshotBlock.Height, snapshotBlock.Height, 10)
+	checkLogs(logs, err, blockA, blockB, blockB)
+
+	logs, err = c.GetLogsByTopics([][]types.Hash{{topic1}}, snapshotBlock.Height+1, 0, 10)
+	checkLogs(logs, err, blockD)
+
+	logs, err = c.GetLogsByTopics([][]types.Hash{{}, {topic2}}, 0, 0, 10)
+	checkLogs(logs, err, blockA)
+
+	// the logs confirmed below the snapshot height exceeding the limit are returned
+	logs, err = c.GetLogsByTopics([][]types.Hash{{topic1}}, 0, 0, 3)
+	if err != ErrTooManyLogs {
+		t.Fatalf("should get ErrTooManyLogs, got %v", err)
+	}
+	checkLogs(logs, nil, blockA, blockB, blockB)
+	logs, err = c.GetLogsByTopics([][]types.Hash{{topic1}}, 0, 0, 2)
+	if err != ErrTooManyLogs {
+		t.Fatalf("should get ErrTooManyLogs, got %v", err)
+	}
+	checkLogs(logs, nil)
+	if _, err := c.GetLogsByTopics([][]types.Hash{{}}, 0, 0, 10); err != ErrLogTopicsEmpty {
+		t.Fatalf("should get ErrLogTopicsEmpty, got %v", err)
+	}
+
+	// the deleted blocks are removed from both indexes
+	if _, err := c.DeleteAccountBlocks(&addr1, blockD.Height); err != nil {
+		t.Fatal(err)
+	}
+	logs, err = c.GetLogs(&addr1, 1, 0, nil, 10)
+	checkLogs(logs, err, blockA, blockB, blockB)
+	logs, err = c.GetLogsByTopics([][]types.Hash{{topic1}}, 0, 0, 10)
+	checkLogs(logs, err, blockA, blockB, blockB)
+}
@@ -0,0 +1,184 @@
+package chain
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/trie"
+	"github.com/vitelabs/go-vite/vm_context"
+)
+
+const (
+	// MinStatePruneHeight keeps the states read by consensus and by rollbacks.
+	MinStatePruneHeight = uint64(1000)
+
+	pruneSubLedgerPageSize = uint64(100)
+)
+
+var ErrStatePruneHeightTooSmall = fmt.Errorf("state prune height should not be less than %d", MinStatePruneHeight)
+
+// registerStatePrune prunes the state in the background each time StatePruneHeight snapshot blocks have been
+// inserted since the last prune.
+func (c *chain) registerStatePrune() {
+	if c.cfg.StatePruneHeight < MinStatePruneHeight {
+		c.log.Error(ErrStatePruneHeightTooSmall.Error(), "method", "registerStatePrune")
+		return
+	}
+
+	c.RegisterInsertSnapshotBlocksSuccess(func(blocks []*ledger.SnapshotBlock) {
+		latestHeight := blocks[len(blocks)-1].Height
+		if latestHeight < atomic.LoadUint64(&c.lastPruneHeight)+c.cfg.StatePruneHeight {
+			return
+		}
+		if !atomic.CompareAndSwapInt32(&c.pruning, 0, 1) {
+			return
+		}
+
+		go func() {
+			defer atomic.StoreInt32(&c.pruning, 0)
+			if err := c.PruneState(c.cfg.StatePruneHeight); err != nil {
+				c.log.Error("PruneState failed, error is "+err.Error(), "method", "registerStatePrune")
+			}
+		}()
+	})
+}
+
+// PruneState deletes the trie nodes and ref values which are neither reachable from the states of the latest
+// keepHeight snapshot blocks nor from the states of the account blocks confirmed by them or unconfirmed. The
+// reachable nodes are marked in a temporary db, the insertion of blocks is only paused while a batch of nodes is
+// deleted. Rollbacks deeper than keepHeight snapshot blocks aren't possible anymore.
+func (c *chain) PruneState(keepHeight uint64) error {
+	if keepHeight < MinStatePruneHeight {
+		return ErrStatePruneHeightTooSmall
+	}
+
+	markDb, closeMarkDb, err := c.newPruneMarkDb()
+	if err != nil {
+		c.log.Error("newPruneMarkDb failed, error is "+err.Error(), "method", "PruneState")
+		return err
+	}
+	defer closeMarkDb()
+
+	pruner := trie.NewPruner(c.chainDb.Db(), markDb)
+
+	// the blocks inserted from now on mark their states, see markInserted
+	c.pruneLock.Lock()
+	c.pruner = pruner
+	c.pruneLock.Unlock()
+	defer func() {
+		c.pruneLock.Lock()
+		c.pruner = nil
+		c.pruneLock.Unlock()
+	}()
+
+	startTime := time.Now()
+	latestHeight := c.GetLatestSnapshotBlock().Height
+	startHeight := uint64(1)
+	if latestHeight > keepHeight {
+		startHeight = latestHeight - keepHeight + 1
+	}
+	c.log.Info("Prune state", "startHeight", startHeight, "latestHeight", latestHeight)
+
+	for fromHeight := startHeight; fromHeight <= latestHeight; fromHeight += pruneSubLedgerPageSize {
+		toHeight := fromHeight + pruneSubLedgerPageSize - 1
+		if toHeight > latestHeight {
+			toHeight = latestHeight
+		}
+		snapshotBlocks, subLedger, err := c.GetConfirmSubLedger(fromHeight, toHeight)
+		if err != nil {
+			c.log.Error("GetConfirmSubLedger failed, error is "+err.Error(), "method", "PruneState")
+			return err
+		}
+
+		for _, snapshotBlock := range snapshotBlocks {
+			pruner.Mark(&snapshotBlock.StateHash, markAccountStates(pruner))
+		}
+		for _, blocks := range subLedger {
+			for _, block := range blocks {
+				pruner.Mark(&block.StateHash, nil)
+			}
+		}
+	}
+
+	unconfirmedSubLedger, err := c.getUnConfirmedSubLedger()
+	if err != nil {
+		c.log.Error("getUnConfirmedSubLedger failed, error is "+err.Error(), "method", "PruneState")
+		return err
+	}
+	for _, blocks := range unconfirmedSubLedger {
+		for _, block := range blocks {
+			pruner.Mark(&block.StateHash, nil)
+		}
+	}
+
+	markedNodes, markedRefValues := pruner.MarkedCount()
+	if markedNodes == 0 {
+		return errors.New("no trie node is reachable, the ledger may be broken")
+	}
+
+	// the cached nodes may have been deleted, a cached node is treated as saved by Trie.Save
+	deletedNodes, deletedRefValues, err := pruner.Sweep(&c.pruneLock, c.trieNodePool.Clear)
+	if err != nil {
+		c.log.Error("Sweep failed, error is "+err.Error(), "method", "PruneState")
+		return err
+	}
+
+	atomic.StoreUint64(&c.lastPruneHeight, latestHeight)
+	markedNodes, markedRefValues = pruner.MarkedCount()
+	c.log.Info("State pruned", "keptNodes", markedNodes, "keptRefValues", markedRefValues,
+		"deletedNodes", deletedNodes, "deletedRefValues", deletedRefValues, "elapsed", time.Since(startTime))
+	return nil
+}
+
+// markAccountStates returns the leafFunc marking the account states referred by a snapshot state trie.
+func markAccountStates(pruner *trie.Pruner) func(value []byte) {
+	return func(value []byte) {
+		if stateHash, err := types.BytesToHash(value); err == nil {
+			pruner.Mark(&stateHash, nil)
+		}
+	}
+}
+
+// markInserted marks the states of the committed blocks while pruning, it must be called with pruneLock held for
+// reading, so the nodes skipped by the saved tries aren't deleted before they are marked.
+func (c *chain) markInserted(snapshotBlock *ledger.SnapshotBlock, accountBlocks []*vm_context.VmAccountBlock) {
+	if c.pruner == nil {
+		return
+	}
+	if snapshotBlock != nil {
+		c.pruner.Mark(&snapshotBlock.StateHash, markAccountStates(c.pruner))
+	}
+	for _, vmAccountBlock := range accountBlocks {
+		c.pruner.Mark(&vmAccountBlock.AccountBlock.StateHash, nil)
+	}
+}
+
+// newPruneMarkDb returns an empty db for the marks of PruneState, and the func closing and removing it.
+func (c *chain) newPruneMarkDb() (database.KeyValueStore, func(), error) {
+	if c.cfg.LedgerInMemory {
+		markDb := database.NewMemStore()
+		return markDb, func() { markDb.Close() }, nil
+	}
+
+	markDir := filepath.Join(c.dataDir, "prune_marks")
+	// the marks left by an interrupted prune
+	if err := os.RemoveAll(markDir); err != nil {
+		return nil, nil, err
+	}
+	db, err := database.NewLevelDb(markDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	markDb := database.NewLevelDbStore(db)
+	return markDb, func() {
+		markDb.Close()
+		os.RemoveAll(markDir)
+	}, nil
+}
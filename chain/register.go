@@ -1,7 +1,7 @@
 package chain
 
 import (
-	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/vitelabs/go-vite/chain_db/database"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/ledger"
 	"github.com/vitelabs/go-vite/vm_context"
@@ -49,6 +49,8 @@ type dsbssListener struct {
 	processor  DeleteSnapshotBlocksSuccess
 }
 
+// eventManager holds the listeners of chain events. listeners can be registered and unregistered at any time,
+// so triggers iterate a copy of the listeners taken under lock.
 type eventManager struct {
 	iabsEventListener  []iabsListener
 	iabssEventListener []iabssListener
@@ -70,8 +72,12 @@ func newEventManager() *eventManager {
 	}
 }
 
-func (em *eventManager) triggerInsertAccountBlocks(batch *leveldb.Batch, blocks []*vm_context.VmAccountBlock) error {
-	for _, listener := range em.iabsEventListener {
+func (em *eventManager) triggerInsertAccountBlocks(batch database.Batch, blocks []*vm_context.VmAccountBlock) error {
+	em.lock.Lock()
+	listeners := append([]iabsListener(nil), em.iabsEventListener...)
+	em.lock.Unlock()
+
+	for _, listener := range listeners {
 		if err := listener.processor(batch, blocks); err != nil {
 			return err
 		}
@@ -79,13 +85,21 @@ func (em *eventManager) triggerInsertAccountBlocks(batch *leveldb.Batch, blocks
 	return nil
 }
 func (em *eventManager) triggerInsertAccountBlocksSuccess(blocks []*vm_context.VmAccountBlock) {
-	for _, listener := range em.iabssEventListener {
+	em.lock.Lock()
+	listeners := append([]iabssListener(nil), em.iabssEventListener...)
+	em.lock.Unlock()
+
+	for _, listener := range listeners {
 		listener.processor(blocks)
 	}
 }
 
-func (em *eventManager) triggerDeleteAccountBlocks(batch *leveldb.Batch, subLedger map[types.Address][]*ledger.AccountBlock) error {
-	for _, listener := range em.dabsEventListener {
+func (em *eventManager) triggerDeleteAccountBlocks(batch database.Batch, subLedger map[types.Address][]*ledger.AccountBlock) error {
+	em.lock.Lock()
+	listeners := append([]dabsListener(nil), em.dabsEventListener...)
+	em.lock.Unlock()
+
+	for _, listener := range listeners {
 		if err := listener.processor(batch, subLedger); err != nil {
 			return err
 		}
@@ -94,19 +108,31 @@ func (em *eventManager) triggerDeleteAccountBlocks(batch *leveldb.Batch, subLedg
 }
 
 func (em *eventManager) triggerDeleteAccountBlocksSuccess(subLedger map[types.Address][]*ledger.AccountBlock) {
-	for _, listener := range em.dabssEventListener {
+	em.lock.Lock()
+	listeners := append([]dabssListener(nil), em.dabssEventListener...)
+	em.lock.Unlock()
+
+	for _, listener := range listeners {
 		listener.processor(subLedger)
 	}
 }
 
 func (em *eventManager) triggerInsertSnapshotBlocksSuccess(snapshotBlocks []*ledger.SnapshotBlock) {
-	for _, listener := range em.isbssEventListener {
+	em.lock.Lock()
+	listeners := append([]isbssListener(nil), em.isbssEventListener...)
+	em.lock.Unlock()
+
+	for _, listener := range listeners {
 		listener.processor(snapshotBlocks)
 	}
 }
 
 func (em *eventManager) triggerDeleteSnapshotBlocksSuccess(snapshotBlocks []*ledger.SnapshotBlock) {
-	for _, listener := range em.dsbssEventListener {
+	em.lock.Lock()
+	listeners := append([]dsbssListener(nil), em.dsbssEventListener...)
+	em.lock.Unlock()
+
+	for _, listener := range listeners {
 		listener.processor(snapshotBlocks)
 	}
 }
@@ -149,7 +175,8 @@ func (em *eventManager) register(actionId uint8, processor interface{}) uint64 {
 		})
 	}
 
-	return 0
+	em.maxListenerId = nextListenerId
+	return nextListenerId
 }
 
 func (em *eventManager) unRegister(listenerId uint64) {
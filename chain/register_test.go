@@ -0,0 +1,52 @@
+package chain
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/vitelabs/go-vite/ledger"
+)
+
+func TestEventManager_unRegister(t *testing.T) {
+	em := newEventManager()
+
+	var calls []int
+	var firstId uint64
+	firstId = em.register(InsertSnapshotBlocksSuccessEvent, InsertSnapshotBlocksSuccess(func([]*ledger.SnapshotBlock) {
+		calls = append(calls, 1)
+		// unregistered while triggered, the other listeners are still called
+		em.unRegister(firstId)
+	}))
+	em.register(InsertSnapshotBlocksSuccessEvent, InsertSnapshotBlocksSuccess(func([]*ledger.SnapshotBlock) {
+		calls = append(calls, 2)
+	}))
+
+	em.triggerInsertSnapshotBlocksSuccess(nil)
+	if len(calls) != 2 || calls[0] != 1 || calls[1] != 2 {
+		t.Fatalf("both listeners should be called, got %v", calls)
+	}
+
+	calls = nil
+	em.triggerInsertSnapshotBlocksSuccess(nil)
+	if len(calls) != 1 || calls[0] != 2 {
+		t.Fatalf("only the second listener should be called, got %v", calls)
+	}
+
+	// listeners are registered and unregistered while triggered
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			id := em.register(DeleteSnapshotBlocksSuccessEvent, DeleteSnapshotBlocksSuccess(func([]*ledger.SnapshotBlock) {}))
+			em.unRegister(id)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			em.triggerDeleteSnapshotBlocksSuccess(nil)
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,131 @@
+package chain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vitelabs/go-vite/checkdb"
+	"github.com/vitelabs/go-vite/common/types"
+)
+
+var ErrLedgerNotRepairable = errors.New("ledger can't be repaired, restart with wipecorrupted to wipe it and sync again")
+
+// repairTail is the number of the latest snapshot blocks checked by repair, the ledger below them is assumed to be
+// consistent.
+const repairTail = 3600
+
+// repair checks the tail of the ledger at start up. It truncates the ledger back to the highest consistent snapshot
+// block, and deletes the inconsistent account blocks which aren't confirmed. The tail is checked again after every
+// truncation, until it's consistent.
+func (c *chain) repair() error {
+	for {
+		latestSnapshotBlock, err := c.chainDb.Sc.GetLatestBlock()
+		if err != nil {
+			return err
+		}
+		if latestSnapshotBlock == nil {
+			return ErrLedgerNotRepairable
+		}
+		c.latestSnapshotBlock = latestSnapshotBlock
+
+		fromHeight := SecondSnapshotBlock.Height + 1
+		if latestSnapshotBlock.Height > fromHeight+repairTail {
+			fromHeight = latestSnapshotBlock.Height - repairTail
+		}
+
+		c.log.Info(fmt.Sprintf("Check the ledger from snapshot height %d to %d", fromHeight, latestSnapshotBlock.Height), "method", "repair")
+		report := checkdb.CheckTail(c.chainDb, LedgerGenesis(), fromHeight, checkdb.DefaultMaxIssues)
+
+		// the lowest inconsistent snapshot height and the lowest inconsistent height of each account
+		truncateHeight := latestSnapshotBlock.Height + 1
+		brokenHeights := make(map[types.Address]uint64)
+		for _, issue := range report.Issues {
+			c.log.Error(fmt.Sprintf("Ledger is inconsistent, %s: %s", issue.Kind, issue.Message), "method", "repair",
+				"snapshotHeight", issue.SnapshotHeight, "address", issue.Address, "height", issue.Height)
+
+			switch {
+			case issue.SnapshotHeight > 0:
+				if issue.SnapshotHeight < truncateHeight {
+					truncateHeight = issue.SnapshotHeight
+				}
+			case issue.Address != nil && issue.Height > 0:
+				confirmHeight, err := c.repairConfirmHeight(issue.Address, issue.Height)
+				if err != nil {
+					c.log.Error("repairConfirmHeight failed, error is "+err.Error(), "method", "repair")
+					return ErrLedgerNotRepairable
+				}
+				if confirmHeight > 0 {
+					if confirmHeight < truncateHeight {
+						truncateHeight = confirmHeight
+					}
+				} else if height, ok := brokenHeights[*issue.Address]; !ok || issue.Height < height {
+					brokenHeights[*issue.Address] = issue.Height
+				}
+			default:
+				// the issues of the db and the account index aren't repaired by truncating the ledger
+				return ErrLedgerNotRepairable
+			}
+		}
+
+		if truncateHeight <= latestSnapshotBlock.Height {
+			// the state of the latest snapshot block is read by the next insert
+			if truncateHeight <= SecondSnapshotBlock.Height {
+				return ErrLedgerNotRepairable
+			}
+
+			c.log.Warn(fmt.Sprintf("Truncate the ledger to snapshot height %d", truncateHeight-1), "method", "repair")
+			if _, _, err := c.DeleteSnapshotBlocksToHeight(truncateHeight); err != nil {
+				c.log.Error("DeleteSnapshotBlocksToHeight failed, error is "+err.Error(), "method", "repair")
+				return ErrLedgerNotRepairable
+			}
+			continue
+		}
+
+		if len(brokenHeights) <= 0 {
+			c.log.Info("Ledger is checked", "method", "repair")
+			return nil
+		}
+
+		for addr, height := range brokenHeights {
+			c.log.Warn(fmt.Sprintf("Delete the unconfirmed blocks of %s from height %d", addr, height), "method", "repair")
+			deleted, err := c.DeleteAccountBlocks(&addr, height)
+			if err != nil {
+				c.log.Error("DeleteAccountBlocks failed, error is "+err.Error(), "method", "repair")
+				return ErrLedgerNotRepairable
+			}
+			if len(deleted) <= 0 {
+				return ErrLedgerNotRepairable
+			}
+		}
+	}
+}
+
+// repairConfirmHeight returns the height of the snapshot block confirming the account block of height, 0 if it isn't
+// confirmed. The metas of the blocks may be missing.
+func (c *chain) repairConfirmHeight(addr *types.Address, height uint64) (uint64, error) {
+	account, err := c.chainDb.Account.GetAccountByAddress(addr)
+	if err != nil {
+		return 0, err
+	}
+	if account == nil {
+		return 0, fmt.Errorf("account %s is missing", addr)
+	}
+
+	for ; ; height++ {
+		hash, err := c.chainDb.Ac.GetHashByHeight(account.AccountId, height)
+		if err != nil {
+			return 0, err
+		}
+		if hash == nil {
+			return 0, nil
+		}
+
+		meta, err := c.chainDb.Ac.GetBlockMeta(hash)
+		if err != nil {
+			return 0, err
+		}
+		if meta != nil && meta.SnapshotHeight > 0 {
+			return meta.SnapshotHeight, nil
+		}
+	}
+}
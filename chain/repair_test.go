@@ -0,0 +1,166 @@
+package chain
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/config"
+	"github.com/vitelabs/go-vite/crypto/ed25519"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/vm_context"
+)
+
+// insertSignedBlock inserts a signed send block of the account of key, which sets a storage.
+func insertSignedBlock(t *testing.T, c *chain, key ed25519.PrivateKey) *ledger.AccountBlock {
+	addr := types.PubkeyToAddress(key.PubByte())
+	var prevHash *types.Hash
+	now := time.Now()
+	block := &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeSendCall,
+		AccountAddress: addr,
+		ToAddress:      addr,
+		TokenId:        ledger.ViteTokenId,
+		Amount:         big.NewInt(0),
+		Fee:            big.NewInt(0),
+		Height:         1,
+		SnapshotHash:   c.GetLatestSnapshotBlock().Hash,
+		Timestamp:      &now,
+	}
+	if latestBlock, _ := c.GetLatestAccountBlock(&addr); latestBlock != nil {
+		block.Height = latestBlock.Height + 1
+		block.PrevHash = latestBlock.Hash
+		prevHash = &latestBlock.Hash
+	}
+
+	vmContext, err := vm_context.NewVmContext(c, nil, prevHash, &addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vmContext.SetStorage([]byte{byte(block.Height)}, []byte{byte(block.Height)})
+	block.StateHash = *vmContext.GetStorageHash()
+	block.Hash = block.ComputeHash()
+	block.PublicKey = key.PubByte()
+	block.Signature = ed25519.Sign(key, block.Hash.Bytes())
+
+	if err := c.InsertAccountBlocks([]*vm_context.VmAccountBlock{{AccountBlock: block, VmContext: vmContext}}); err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+// insertSignedSnapshotBlock inserts a snapshot block signed by key.
+func insertSignedSnapshotBlock(t *testing.T, c *chain, key ed25519.PrivateKey) *ledger.SnapshotBlock {
+	latestBlock := c.GetLatestSnapshotBlock()
+	now := time.Now()
+	snapshotBlock := &ledger.SnapshotBlock{
+		Height:          latestBlock.Height + 1,
+		PrevHash:        latestBlock.Hash,
+		Timestamp:       &now,
+		SnapshotContent: c.GetNeedSnapshotContent(),
+	}
+
+	stateTrie, err := c.GenStateTrie(latestBlock.StateHash, snapshotBlock.SnapshotContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshotBlock.StateTrie = stateTrie
+	snapshotBlock.StateHash = *stateTrie.Hash()
+	snapshotBlock.Hash = snapshotBlock.ComputeHash()
+	snapshotBlock.PublicKey = key.PubByte()
+	snapshotBlock.Signature = ed25519.Sign(key, snapshotBlock.Hash.Bytes())
+
+	if err := c.InsertSnapshotBlock(snapshotBlock); err != nil {
+		t.Fatal(err)
+	}
+	return snapshotBlock
+}
+
+func TestChain_repair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := NewChain(&config.Config{DataDir: dir, Chain: &config.Chain{LedgerInMemory: true}}).(*chain)
+	c.Init()
+	c.Start()
+	defer func() {
+		c.Stop()
+		c.Destroy()
+	}()
+
+	// every snapshot block confirms a block of a new account, so the state roots are different
+	addr1, key1, _ := types.CreateAddress()
+	addr2, key2, _ := types.CreateAddress()
+	addr3, key3, _ := types.CreateAddress()
+	_, producerKey, _ := types.CreateAddress()
+	insertSignedBlock(t, c, key1)
+	insertSignedSnapshotBlock(t, c, producerKey)
+	blockB := insertSignedBlock(t, c, key2)
+	insertSignedSnapshotBlock(t, c, producerKey)
+	insertSignedBlock(t, c, key3)
+	snapshotBlock := insertSignedSnapshotBlock(t, c, producerKey)
+	unconfirmedBlock := insertSignedBlock(t, c, key1)
+
+	checkLatest := func(snapshotHeight uint64, addr types.Address, accountHeight uint64) {
+		t.Helper()
+		if err := c.repair(); err != nil {
+			t.Fatal(err)
+		}
+		if latestBlock := c.GetLatestSnapshotBlock(); latestBlock.Height != snapshotHeight {
+			t.Fatalf("latest snapshot height should be %d, got %d", snapshotHeight, latestBlock.Height)
+		}
+		latestBlock, err := c.GetLatestAccountBlock(&addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if latestBlock == nil && accountHeight > 0 || latestBlock != nil && latestBlock.Height != accountHeight {
+			t.Fatalf("latest height of %s should be %d", addr, accountHeight)
+		}
+	}
+
+	// consistent
+	checkLatest(snapshotBlock.Height, addr1, unconfirmedBlock.Height)
+
+	// the inconsistent unconfirmed block is deleted
+	batch := c.chainDb.NewBatch()
+	c.chainDb.Ac.DeleteBlockMeta(batch, &unconfirmedBlock.Hash)
+	if err := c.chainDb.Commit(batch); err != nil {
+		t.Fatal(err)
+	}
+	checkLatest(snapshotBlock.Height, addr1, unconfirmedBlock.Height-1)
+
+	// the snapshot block of which the state is missing is deleted
+	rootKey, _ := database.EncodeKey(database.DBKP_TRIE_NODE, snapshotBlock.StateHash.Bytes())
+	if err := c.chainDb.Db().Delete(rootKey); err != nil {
+		t.Fatal(err)
+	}
+	c.trieNodePool.Clear()
+	// the blocks confirmed by the deleted snapshot blocks become unconfirmed
+	checkLatest(snapshotBlock.Height-1, addr3, 1)
+
+	// the snapshot block confirming an inconsistent block is deleted
+	batch = c.chainDb.NewBatch()
+	c.chainDb.Ac.DeleteBeSnapshot(batch, &blockB.Hash)
+	if err := c.chainDb.Commit(batch); err != nil {
+		t.Fatal(err)
+	}
+	checkLatest(snapshotBlock.Height-2, addr2, 1)
+
+	// the genesis blocks can't be repaired
+	for _, stateHash := range []types.Hash{c.GetLatestSnapshotBlock().StateHash, SecondSnapshotBlock.StateHash} {
+		rootKey, _ := database.EncodeKey(database.DBKP_TRIE_NODE, stateHash.Bytes())
+		if err := c.chainDb.Db().Delete(rootKey); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.repair(); err != ErrLedgerNotRepairable {
+		t.Fatalf("should get ErrLedgerNotRepairable, got %v", err)
+	}
+}
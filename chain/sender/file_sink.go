@@ -0,0 +1,277 @@
+package sender
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/vitelabs/go-vite/config"
+)
+
+const defaultMaxFileSize = 64 * 1024 * 1024
+
+// FileSink appends messages as newline-delimited json to files in dir. A new file, named after the id of its
+// first event, is started when the current one grows beyond maxFileSize. Every batch is synced to disk before
+// Send returns.
+//
+// The last line written is the cursor of the sink. Open restores it from the newest file, dropping a line
+// half written before a crash, and goes on appending to that file. Send skips the messages not after it, so the batches the producer sends
+// again after a restart are not duplicated in the files. Rewind moves the cursor back by truncating the files, so
+// the messages the producer sends again after a rewind replace the old ones.
+type FileSink struct {
+	dir         string
+	maxFileSize int64
+
+	file        *os.File
+	fileSize    int64
+	lastEventId uint64
+}
+
+func NewFileSink(dir string, maxFileSize int64) (*FileSink, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxFileSize <= 0 {
+		maxFileSize = defaultMaxFileSize
+	}
+	return &FileSink{
+		dir:         absDir,
+		maxFileSize: maxFileSize,
+	}, nil
+}
+
+func (sink *FileSink) Id() string {
+	return config.SinkTypeFile + "|" + sink.dir
+}
+
+func (sink *FileSink) Type() string {
+	return config.SinkTypeFile
+}
+
+func (sink *FileSink) Dir() string {
+	return sink.dir
+}
+
+// LastEventId returns the id of the last event written
+func (sink *FileSink) LastEventId() uint64 {
+	return sink.lastEventId
+}
+
+func (sink *FileSink) Open() error {
+	if err := os.MkdirAll(sink.dir, 0755); err != nil {
+		return err
+	}
+	return sink.restoreCursor()
+}
+
+func (sink *FileSink) restoreCursor() error {
+	fileNames, err := filepath.Glob(filepath.Join(sink.dir, "events_*.jsonl"))
+	if err != nil {
+		return err
+	}
+	// the names are zero padded, so they are sorted by the first event id
+	sort.Strings(fileNames)
+
+	for i := len(fileNames) - 1; i >= 0; i-- {
+		lastEventId, err := lastEventIdOfFile(fileNames[i])
+		if err != nil {
+			return err
+		}
+		if lastEventId > 0 {
+			sink.lastEventId = lastEventId
+			// go on appending to the newest file
+			return sink.openFile(fileNames[len(fileNames)-1])
+		}
+	}
+	return nil
+}
+
+// lastEventIdOfFile reads the event id of the last complete line, a trailing incomplete line is truncated
+func lastEventIdOfFile(fileName string) (uint64, error) {
+	file, err := os.OpenFile(fileName, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var lastLine []byte
+	var offset int64
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				if err := file.Truncate(offset); err != nil {
+					return 0, err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		offset += int64(len(line))
+		lastLine = line
+	}
+
+	if lastLine == nil {
+		return 0, nil
+	}
+
+	msg := &Message{}
+	if err := json.Unmarshal(lastLine, msg); err != nil {
+		return 0, fmt.Errorf("last line of %s is broken: %v", fileName, err)
+	}
+	return msg.EventId, nil
+}
+
+// Rewind drops the messages after hasSend from the files, the files starting after hasSend are removed
+func (sink *FileSink) Rewind(hasSend uint64) error {
+	wasOpen := sink.file != nil
+	if err := sink.Close(); err != nil {
+		return err
+	}
+
+	fileNames, err := filepath.Glob(filepath.Join(sink.dir, "events_*.jsonl"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(fileNames)
+
+	for i := len(fileNames) - 1; i >= 0; i-- {
+		var firstEventId uint64
+		if _, err := fmt.Sscanf(filepath.Base(fileNames[i]), "events_%d.jsonl", &firstEventId); err != nil {
+			return fmt.Errorf("unexpected file name %s: %v", fileNames[i], err)
+		}
+
+		if firstEventId > hasSend {
+			if err := os.Remove(fileNames[i]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// the files before it have only the messages before it
+		if err := truncateFileAfter(fileNames[i], hasSend); err != nil {
+			return err
+		}
+		break
+	}
+
+	sink.lastEventId = 0
+	if wasOpen {
+		return sink.restoreCursor()
+	}
+	return nil
+}
+
+// truncateFileAfter drops the lines of which the event id is larger than eventId
+func truncateFileAfter(fileName string, eventId uint64) error {
+	file, err := os.OpenFile(fileName, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var offset int64
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		msg := &Message{}
+		if err := json.Unmarshal(line, msg); err != nil {
+			return fmt.Errorf("line at %d of %s is broken: %v", offset, fileName, err)
+		}
+		if msg.EventId > eventId {
+			break
+		}
+		offset += int64(len(line))
+	}
+
+	return file.Truncate(offset)
+}
+
+func (sink *FileSink) Send(msgList []*Message) error {
+	// skip the messages written before
+	for len(msgList) > 0 && msgList[0].EventId <= sink.lastEventId {
+		msgList = msgList[1:]
+	}
+	if len(msgList) <= 0 {
+		return nil
+	}
+
+	if sink.file == nil || sink.fileSize >= sink.maxFileSize {
+		if err := sink.rotate(msgList[0].EventId); err != nil {
+			return err
+		}
+	}
+
+	var buf []byte
+	for _, msg := range msgList {
+		line, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+
+	if _, err := sink.file.Write(buf); err != nil {
+		// drop the part written, so the batch sent again starts at a new line
+		sink.file.Truncate(sink.fileSize)
+		return err
+	}
+	sink.fileSize += int64(len(buf))
+	if err := sink.file.Sync(); err != nil {
+		return err
+	}
+
+	sink.lastEventId = msgList[len(msgList)-1].EventId
+	return nil
+}
+
+func (sink *FileSink) rotate(firstEventId uint64) error {
+	if err := sink.Close(); err != nil {
+		return err
+	}
+
+	return sink.openFile(filepath.Join(sink.dir, fmt.Sprintf("events_%020d.jsonl", firstEventId)))
+}
+
+func (sink *FileSink) openFile(fileName string) error {
+	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	sink.file = file
+	sink.fileSize = stat.Size()
+	return nil
+}
+
+func (sink *FileSink) Close() error {
+	if sink.file == nil {
+		return nil
+	}
+	err := sink.file.Close()
+	sink.file = nil
+	return err
+}
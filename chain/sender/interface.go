@@ -12,3 +12,22 @@ type Chain interface {
 	GetConfirmSubLedgerBySnapshotBlocks(snapshotBlocks []*ledger.SnapshotBlock) (map[types.Address][]*ledger.AccountBlock, error)
 	vm_context.Chain
 }
+
+// Sink is a delivery target of the block event log. The producer calls Send with messages in ascending event id
+// order and only advances its hasSend record after Send returns nil, so a sink has to return an error unless
+// every message is delivered. Messages may be delivered more than once after a failure or a restart.
+type Sink interface {
+	// Id identifies the target, the delivery progress is persisted under it.
+	Id() string
+	Type() string
+
+	Open() error
+	Send(msgList []*Message) error
+	Close() error
+}
+
+// Rewinder is implemented by the sinks which skip the messages delivered before. The producer calls Rewind when
+// its hasSend is set back, so the messages after hasSend are delivered again.
+type Rewinder interface {
+	Rewind(hasSend uint64) error
+}
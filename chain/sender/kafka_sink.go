@@ -0,0 +1,108 @@
+package sender
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/vitelabs/go-vite/config"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+type KafkaSink struct {
+	brokerList []string
+	topic      string
+
+	kafkaProducer sarama.AsyncProducer
+	sendWg        sync.WaitGroup
+
+	log log15.Logger
+}
+
+func NewKafkaSink(brokerList []string, topic string) *KafkaSink {
+	return &KafkaSink{
+		brokerList: brokerList,
+		topic:      topic,
+		log:        log15.New("module", "sender/kafka_sink"),
+	}
+}
+
+func kafkaSinkId(brokerList []string, topic string) string {
+	sortedBrokerList := make([]string, len(brokerList))
+	copy(sortedBrokerList, brokerList)
+	sort.Strings(sortedBrokerList)
+
+	return config.SinkTypeKafka + "|" + strings.Join(sortedBrokerList, ",") + "|" + topic
+}
+
+func (sink *KafkaSink) Id() string {
+	return kafkaSinkId(sink.brokerList, sink.topic)
+}
+
+func (sink *KafkaSink) Type() string {
+	return config.SinkTypeKafka
+}
+
+func (sink *KafkaSink) BrokerList() []string {
+	return sink.brokerList
+}
+
+func (sink *KafkaSink) Topic() string {
+	return sink.topic
+}
+
+func (sink *KafkaSink) Open() error {
+	config := sarama.NewConfig()
+	config.Producer.Return.Successes = true
+	config.Producer.Return.Errors = true
+
+	kafkaProducer, err := sarama.NewAsyncProducer(sink.brokerList, config)
+	if err != nil {
+		return err
+	}
+
+	sink.kafkaProducer = kafkaProducer
+	return nil
+}
+
+func (sink *KafkaSink) Send(msgList []*Message) (err error) {
+	for i := 0; i < len(msgList); i++ {
+		buf, jsonErr := json.Marshal(msgList[i])
+		if jsonErr != nil {
+			return jsonErr
+		}
+		sMsg := &sarama.ProducerMessage{Topic: sink.topic, Value: sarama.StringEncoder(buf)}
+
+		sink.sendWg.Add(1)
+		// Simple implementation, may be fix
+		go func() {
+			defer sink.sendWg.Done()
+			sink.kafkaProducer.Input() <- sMsg
+			select {
+			// success
+			case <-sink.kafkaProducer.Successes():
+				break
+
+			// error
+			case sendError := <-sink.kafkaProducer.Errors():
+				sink.log.Error("kafka send failed, error is "+sendError.Error(), "method", "Send")
+				err = sendError
+			}
+		}()
+	}
+	sink.sendWg.Wait()
+	return
+}
+
+func (sink *KafkaSink) Close() error {
+	if sink.kafkaProducer == nil {
+		return nil
+	}
+	if err := sink.kafkaProducer.Close(); err != nil {
+		return err
+	}
+	sink.kafkaProducer = nil
+	return nil
+}
@@ -0,0 +1,241 @@
+package sender
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vitelabs/go-vite/config"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+const (
+	defaultNatsPort = "4222"
+	natsTimeout     = 30 * time.Second
+)
+
+// NatsSink publishes every message as a json payload to subject of the nats server at url, it speaks the core
+// text protocol of nats. Core nats has no acknowledgement, so a PING is sent after each batch and the batch is
+// delivered when the server answers PONG, which means the server has processed all the PUBs before it.
+//
+// Only plaintext connections are supported, a tls:// url or a server requiring tls is rejected. A message larger
+// than the max payload of the server can never be published, it is dropped with an error log.
+type NatsSink struct {
+	url     string
+	subject string
+
+	conn       net.Conn
+	reader     *bufio.Reader
+	maxPayload int
+
+	log log15.Logger
+}
+
+type natsInfo struct {
+	MaxPayload  int  `json:"max_payload"`
+	TLSRequired bool `json:"tls_required"`
+}
+
+type natsConnect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Token    string `json:"auth_token,omitempty"`
+}
+
+func NewNatsSink(url string, subject string) *NatsSink {
+	return &NatsSink{
+		url:     url,
+		subject: subject,
+		log:     log15.New("module", "sender/nats_sink"),
+	}
+}
+
+func (sink *NatsSink) Id() string {
+	return config.SinkTypeNats + "|" + sink.url + "|" + sink.subject
+}
+
+func (sink *NatsSink) Type() string {
+	return config.SinkTypeNats
+}
+
+func (sink *NatsSink) Url() string {
+	return sink.url
+}
+
+func (sink *NatsSink) Subject() string {
+	return sink.subject
+}
+
+func (sink *NatsSink) Open() error {
+	if strings.ContainsAny(sink.subject, " \t\r\n") {
+		return fmt.Errorf("invalid nats subject %q", sink.subject)
+	}
+	return sink.connect()
+}
+
+func (sink *NatsSink) connect() error {
+	u, err := url.Parse(sink.url)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "nats" {
+		return fmt.Errorf("unsupported scheme %q of nats url, only nats:// is supported", u.Scheme)
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), defaultNatsPort)
+	}
+
+	conn, err := net.DialTimeout("tcp", host, natsTimeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(natsTimeout))
+
+	sink.conn = conn
+	sink.reader = bufio.NewReader(conn)
+
+	// the server greets with INFO
+	line, err := sink.readLine()
+	if err != nil {
+		sink.Close()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		sink.Close()
+		return fmt.Errorf("unexpected greeting of nats server: %s", line)
+	}
+	info := &natsInfo{}
+	if err := json.Unmarshal([]byte(line[len("INFO "):]), info); err != nil {
+		sink.Close()
+		return err
+	}
+	if info.TLSRequired {
+		sink.Close()
+		return errors.New("nats server requires tls, which is not supported")
+	}
+	sink.maxPayload = info.MaxPayload
+
+	connect := &natsConnect{
+		Name: "gvite",
+		Lang: "go",
+	}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			connect.User = u.User.Username()
+			connect.Pass = pass
+		} else {
+			connect.Token = u.User.Username()
+		}
+	}
+	buf, err := json.Marshal(connect)
+	if err != nil {
+		sink.Close()
+		return err
+	}
+
+	if err := sink.flush([]byte("CONNECT " + string(buf) + "\r\n")); err != nil {
+		sink.Close()
+		return err
+	}
+	return nil
+}
+
+func (sink *NatsSink) readLine() (string, error) {
+	line, err := sink.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// flush writes buf followed by a PING and waits for the PONG
+func (sink *NatsSink) flush(buf []byte) error {
+	sink.conn.SetDeadline(time.Now().Add(natsTimeout))
+
+	buf = append(buf, "PING\r\n"...)
+	if _, err := sink.conn.Write(buf); err != nil {
+		return err
+	}
+
+	for {
+		line, err := sink.readLine()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := sink.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New("nats server responded with " + line)
+		}
+		// +OK and INFO are ignored
+	}
+}
+
+func (sink *NatsSink) Send(msgList []*Message) error {
+	if len(msgList) <= 0 {
+		return nil
+	}
+
+	// reconnect if the last batch failed
+	if sink.conn == nil {
+		if err := sink.connect(); err != nil {
+			return err
+		}
+	}
+
+	var buf []byte
+	for _, msg := range msgList {
+		payload, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		if sink.maxPayload > 0 && len(payload) > sink.maxPayload {
+			// it would block the messages after it forever
+			sink.log.Error(fmt.Sprintf("drop message of event %d, it is %d bytes, exceeds the max payload %d of nats server",
+				msg.EventId, len(payload), sink.maxPayload), "method", "Send")
+			continue
+		}
+
+		buf = append(buf, fmt.Sprintf("PUB %s %d\r\n", sink.subject, len(payload))...)
+		buf = append(buf, payload...)
+		buf = append(buf, "\r\n"...)
+	}
+
+	if len(buf) <= 0 {
+		return nil
+	}
+
+	if err := sink.flush(buf); err != nil {
+		sink.log.Error("publish failed, error is "+err.Error(), "method", "Send")
+		sink.Close()
+		return err
+	}
+	return nil
+}
+
+func (sink *NatsSink) Close() error {
+	if sink.conn == nil {
+		return nil
+	}
+	err := sink.conn.Close()
+	sink.conn = nil
+	sink.reader = nil
+	return err
+}
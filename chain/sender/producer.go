@@ -3,12 +3,12 @@ package sender
 import (
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"github.com/vitelabs/go-vite/vm/contracts/abi"
 	"math/big"
 	"sync"
 	"time"
 
-	"github.com/Shopify/sarama"
 	"github.com/golang/protobuf/proto"
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/vitelabs/go-vite/common"
@@ -24,12 +24,6 @@ const (
 	RUNNING
 )
 
-type message struct {
-	MsgType string `json:"type"`
-	Data    string `json:"data"`
-	EventId uint64 `json:"eventId"`
-}
-
 type MqSnapshotContentItem struct {
 	Start *ledger.HashHeight `json:"start"`
 	End   *ledger.HashHeight `json:"end"`
@@ -59,8 +53,8 @@ type Producer struct {
 	producerId uint8
 	db         *leveldb.DB
 
-	brokerList []string
-	topic      string
+	sinkId string
+	sink   Sink
 
 	hasSendLock      sync.RWMutex
 	hasSend          uint64
@@ -75,13 +69,11 @@ type Producer struct {
 
 	wg sync.WaitGroup
 
-	kafkaProducer sarama.AsyncProducer
-	chain         Chain
-	concurrency   uint64
-
-	sendWg sync.WaitGroup
+	chain       Chain
+	concurrency uint64
 }
 
+// NewProducerFromDb restores a producer persisted by an earlier version, which only knew kafka targets.
 func NewProducerFromDb(producerId uint8, buf []byte, chain Chain, db *leveldb.DB) (*Producer, error) {
 	producer := &Producer{}
 	if dsErr := producer.Deserialize(buf); dsErr != nil {
@@ -95,10 +87,23 @@ func NewProducerFromDb(producerId uint8, buf []byte, chain Chain, db *leveldb.DB
 	return producer, nil
 }
 
-func NewProducer(producerId uint8, brokerList []string, topic string, chain Chain, db *leveldb.DB) (*Producer, error) {
+// NewProducerFromSinkId restores a producer whose sink is not known yet, the sink is set when it's started.
+func NewProducerFromSinkId(producerId uint8, sinkId string, chain Chain, db *leveldb.DB) (*Producer, error) {
 	producer := &Producer{
-		brokerList: brokerList,
-		topic:      topic,
+		sinkId: sinkId,
+	}
+
+	if err := producer.init(producerId, chain, db); err != nil {
+		return nil, err
+	}
+	producer.log = log15.New("module", "sender/producer")
+	return producer, nil
+}
+
+func NewProducer(producerId uint8, sink Sink, chain Chain, db *leveldb.DB) (*Producer, error) {
+	producer := &Producer{
+		sinkId: sink.Id(),
+		sink:   sink,
 	}
 
 	if err := producer.init(producerId, chain, db); err != nil {
@@ -135,6 +140,13 @@ func (producer *Producer) SetHasSend(hasSend uint64) {
 	producer.hasSendLock.Lock()
 	defer producer.hasSendLock.Unlock()
 
+	if rewinder, ok := producer.sink.(Rewinder); ok && hasSend < producer.hasSend {
+		if err := rewinder.Rewind(hasSend); err != nil {
+			producer.log.Error("rewind failed, error is "+err.Error(), "method", "SetHasSend")
+			return
+		}
+	}
+
 	producer.hasSend = hasSend
 	producer.saveHasSend()
 }
@@ -143,12 +155,12 @@ func (producer *Producer) ProducerId() uint8 {
 	return producer.producerId
 }
 
-func (producer *Producer) BrokerList() []string {
-	return producer.brokerList
+func (producer *Producer) SinkId() string {
+	return producer.sinkId
 }
 
-func (producer *Producer) Topic() string {
-	return producer.topic
+func (producer *Producer) Sink() Sink {
+	return producer.sink
 }
 
 func (producer *Producer) HasSend() uint64 {
@@ -159,30 +171,8 @@ func (producer *Producer) Status() int {
 	return producer.status
 }
 
-func (producer *Producer) IsSame(brokerList []string, topic string) bool {
-	if producer.topic != topic ||
-		len(brokerList) != len(producer.brokerList) {
-		return false
-	}
-
-	tmpBrokerList := brokerList[:]
-	for _, aBroker := range producer.brokerList {
-		hasExist := false
-		tmpBrokerListLen := len(tmpBrokerList)
-
-		for i := 0; i < tmpBrokerListLen; i++ {
-			if aBroker == tmpBrokerList[i] {
-				tmpBrokerList = append(tmpBrokerList[:i], tmpBrokerList[i+1:]...)
-				hasExist = true
-				break
-			}
-		}
-
-		if !hasExist {
-			return false
-		}
-	}
-	return true
+func (producer *Producer) IsSame(sinkId string) bool {
+	return producer.sinkId == sinkId
 }
 
 func (producer *Producer) Deserialize(buffer []byte) error {
@@ -191,19 +181,11 @@ func (producer *Producer) Deserialize(buffer []byte) error {
 		return err
 	}
 
-	producer.topic = pb.Topic
-	producer.brokerList = pb.BrokerList
+	producer.sink = NewKafkaSink(pb.BrokerList, pb.Topic)
+	producer.sinkId = producer.sink.Id()
 	return nil
 }
 
-func (producer *Producer) Serialize() ([]byte, error) {
-	pb := &vitepb.Producer{}
-	pb.BrokerList = producer.brokerList
-	pb.Topic = producer.topic
-
-	return proto.Marshal(pb)
-}
-
 func (producer *Producer) Start() error {
 	producer.statusLock.Lock()
 	defer producer.statusLock.Unlock()
@@ -211,16 +193,14 @@ func (producer *Producer) Start() error {
 		return nil
 	}
 
-	config := sarama.NewConfig()
-	config.Producer.Return.Successes = true
-	config.Producer.Return.Errors = true
+	if producer.sink == nil {
+		return errors.New("producer " + producer.sinkId + " has no sink")
+	}
 
-	kafkaProducer, err := sarama.NewAsyncProducer(producer.brokerList, config)
-	if err != nil {
+	if err := producer.sink.Open(); err != nil {
 		return err
 	}
 
-	producer.kafkaProducer = kafkaProducer
 	producer.status = RUNNING
 	producer.termination = make(chan int)
 
@@ -234,18 +214,17 @@ func (producer *Producer) Start() error {
 				closeCount := 0
 
 				for ; closeCount < tryCloseCount; closeCount++ {
-					closeErr := producer.kafkaProducer.Close()
+					closeErr := producer.sink.Close()
 
 					if closeErr != nil {
-						producer.log.Error("kafkaProducer close failed, error is "+closeErr.Error(), "method", "Start")
+						producer.log.Error("sink close failed, error is "+closeErr.Error(), "method", "Start")
 					} else {
-						producer.kafkaProducer = nil
 						return
 					}
 				}
 
 				if closeCount == tryCloseCount {
-					producer.log.Crit("kafkaProducer close failed", "method", "Start")
+					producer.log.Crit("sink close failed", "method", "Start")
 				}
 			default:
 				producer.send()
@@ -313,7 +292,7 @@ func (producer *Producer) send() {
 
 		}
 
-		var msgList []*message
+		var msgList []*Message
 
 		j := i + 1
 		for ; j-i <= producer.concurrency && j <= end; j++ {
@@ -323,7 +302,7 @@ func (producer *Producer) send() {
 				return
 			}
 
-			m := &message{
+			m := &Message{
 				EventId: j,
 			}
 
@@ -488,7 +467,7 @@ func (producer *Producer) send() {
 
 		}
 
-		sendErr := producer.sendMessage(msgList)
+		sendErr := producer.sink.Send(msgList)
 		if sendErr != nil {
 			producer.log.Error("sendMessage failed, error is "+sendErr.Error(), "method", "send")
 			return
@@ -527,33 +506,3 @@ func (producer *Producer) getHasSend() (uint64, error) {
 
 	return binary.BigEndian.Uint64(value), nil
 }
-
-func (producer *Producer) sendMessage(msgList []*message) (err error) {
-	//var err error
-	for i := 0; i < len(msgList); i++ {
-		buf, jsonErr := json.Marshal(msgList[i])
-		if jsonErr != nil {
-			return jsonErr
-		}
-		sMsg := &sarama.ProducerMessage{Topic: producer.topic, Value: sarama.StringEncoder(buf)}
-
-		producer.sendWg.Add(1)
-		// Simple implementation, may be fix
-		go func() {
-			defer producer.sendWg.Done()
-			producer.kafkaProducer.Input() <- sMsg
-			select {
-			// success
-			case <-producer.kafkaProducer.Successes():
-				break
-
-			// error
-			case sendError := <-producer.kafkaProducer.Errors():
-				producer.log.Error("kafka send failed, error is "+sendError.Error(), "method", "sendMessage")
-				err = sendError
-			}
-		}()
-	}
-	producer.sendWg.Wait()
-	return
-}
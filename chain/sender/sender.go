@@ -5,15 +5,17 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/vitelabs/go-vite/log15"
 	"os"
+	"sort"
 	"sync"
 )
 
 const (
 	DBKP_PRODUCER          = byte(1)
 	DBKP_PRODUCER_HAS_SEND = byte(2)
+	DBKP_PRODUCER_SINK     = byte(3)
 )
 
-type KafkaSender struct {
+type Sender struct {
 	producers    []*Producer
 	runProducers []*Producer
 
@@ -24,13 +26,13 @@ type KafkaSender struct {
 	log  log15.Logger
 }
 
-func NewKafkaSender(chain Chain, dirName string) (*KafkaSender, error) {
+func NewSender(chain Chain, dirName string) (*Sender, error) {
 	// create directory
 	if _, err := os.Stat(dirName); os.IsNotExist(err) {
 		os.Mkdir(dirName, 0755)
 	}
 
-	sender := &KafkaSender{
+	sender := &Sender{
 		//producer: producer,
 		chain: chain,
 		log:   log15.New("module", "chain/sender"),
@@ -54,17 +56,17 @@ func NewKafkaSender(chain Chain, dirName string) (*KafkaSender, error) {
 	return sender, nil
 }
 
-func (sender *KafkaSender) Start(brokerList []string, topic string) error {
+func (sender *Sender) Start(sink Sink) error {
 	sender.lock.Lock()
 	defer sender.lock.Unlock()
 
-	producer, err := sender.getProducer(brokerList, topic)
+	producer, err := sender.getProducer(sink)
 	if err != nil {
 		return err
 	}
 
 	for _, runProducer := range sender.runProducers {
-		if runProducer.IsSame(brokerList, topic) {
+		if runProducer.IsSame(sink.Id()) {
 			// has run
 			return nil
 		}
@@ -79,7 +81,7 @@ func (sender *KafkaSender) Start(brokerList []string, topic string) error {
 	return nil
 }
 
-func (sender *KafkaSender) StopById(producerId uint8) {
+func (sender *Sender) StopById(producerId uint8) {
 	sender.lock.Lock()
 	defer sender.lock.Unlock()
 
@@ -92,12 +94,12 @@ func (sender *KafkaSender) StopById(producerId uint8) {
 	}
 }
 
-func (sender *KafkaSender) Stop(brokerList []string, topic string) {
+func (sender *Sender) Stop(sinkId string) {
 	sender.lock.Lock()
 	defer sender.lock.Unlock()
 
 	for index, runProducer := range sender.runProducers {
-		if runProducer.IsSame(brokerList, topic) {
+		if runProducer.IsSame(sinkId) {
 			// has run
 			runProducer.Stop()
 			sender.runProducers = append(sender.runProducers[:index], sender.runProducers[index+1:]...)
@@ -105,7 +107,7 @@ func (sender *KafkaSender) Stop(brokerList []string, topic string) {
 	}
 }
 
-func (sender *KafkaSender) StopAll() {
+func (sender *Sender) StopAll() {
 	sender.lock.Lock()
 	defer sender.lock.Unlock()
 
@@ -114,7 +116,7 @@ func (sender *KafkaSender) StopAll() {
 	}
 }
 
-func (sender *KafkaSender) SetHasSend(producerId uint8, hasSend uint64) {
+func (sender *Sender) SetHasSend(producerId uint8, hasSend uint64) {
 	for _, producer := range sender.producers {
 		if producer.producerId == producerId {
 			producer.SetHasSend(hasSend)
@@ -123,22 +125,23 @@ func (sender *KafkaSender) SetHasSend(producerId uint8, hasSend uint64) {
 	}
 }
 
-func (sender *KafkaSender) Producers() []*Producer {
+func (sender *Sender) Producers() []*Producer {
 	return sender.producers
 }
 
-func (sender *KafkaSender) RunProducers() []*Producer {
+func (sender *Sender) RunProducers() []*Producer {
 	return sender.runProducers
 }
 
-func (sender *KafkaSender) getProducer(brokerList []string, topic string) (*Producer, error) {
+func (sender *Sender) getProducer(sink Sink) (*Producer, error) {
 	for _, producer := range sender.producers {
-		if producer.IsSame(brokerList, topic) {
+		if producer.IsSame(sink.Id()) {
+			producer.sink = sink
 			return producer, nil
 		}
 	}
 
-	newProducer, newErr := NewProducer(byte(len(sender.producers)+1), brokerList, topic, sender.chain, sender.db)
+	newProducer, newErr := NewProducer(byte(len(sender.producers)+1), sink, sender.chain, sender.db)
 	if newErr != nil {
 		return nil, newErr
 	}
@@ -151,19 +154,14 @@ func (sender *KafkaSender) getProducer(brokerList []string, topic string) (*Prod
 	return newProducer, nil
 }
 
-func (sender *KafkaSender) writeProducerToDb(producer *Producer) error {
-	key := append([]byte{DBKP_PRODUCER}, producer.producerId)
-	buf, sErr := producer.Serialize()
+func (sender *Sender) writeProducerToDb(producer *Producer) error {
+	key := append([]byte{DBKP_PRODUCER_SINK}, producer.producerId)
 
-	if sErr != nil {
-		return sErr
-	}
-
-	wErr := sender.db.Put(key, buf, nil)
+	wErr := sender.db.Put(key, []byte(producer.sinkId), nil)
 	return wErr
 }
 
-func (sender *KafkaSender) readProducersFromDb() ([]*Producer, error) {
+func (sender *Sender) readProducersFromDb() ([]*Producer, error) {
 	iter := sender.db.NewIterator(util.BytesPrefix([]byte{byte(DBKP_PRODUCER)}), nil)
 	defer iter.Release()
 
@@ -186,5 +184,24 @@ func (sender *KafkaSender) readProducersFromDb() ([]*Producer, error) {
 		producers = append(producers, producer)
 	}
 
+	sinkIter := sender.db.NewIterator(util.BytesPrefix([]byte{DBKP_PRODUCER_SINK}), nil)
+	defer sinkIter.Release()
+
+	for sinkIter.Next() {
+		producerId := uint8(sinkIter.Key()[1])
+
+		producer, err := NewProducerFromSinkId(producerId, string(sinkIter.Value()), sender.chain, sender.db)
+		if err != nil {
+			return nil, err
+		}
+		producers = append(producers, producer)
+	}
+	if iterErr := sinkIter.Error(); iterErr != nil && iterErr != leveldb.ErrNotFound {
+		return nil, iterErr
+	}
+
+	sort.Slice(producers, func(i, j int) bool {
+		return producers[i].producerId < producers[j].producerId
+	})
 	return producers, nil
 }
@@ -0,0 +1,40 @@
+package sender
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vitelabs/go-vite/config"
+)
+
+type Message struct {
+	MsgType string `json:"type"`
+	Data    string `json:"data"`
+	EventId uint64 `json:"eventId"`
+}
+
+func NewSink(cfg *config.EventSink) (Sink, error) {
+	switch cfg.Type {
+	case config.SinkTypeKafka, "":
+		if len(cfg.BrokerList) <= 0 || cfg.Topic == "" {
+			return nil, errors.New("kafka sink needs BrokerList and Topic")
+		}
+		return NewKafkaSink(cfg.BrokerList, cfg.Topic), nil
+	case config.SinkTypeWebhook:
+		if cfg.Url == "" {
+			return nil, errors.New("webhook sink needs Url")
+		}
+		return NewWebhookSink(cfg.Url, cfg.MaxRetries), nil
+	case config.SinkTypeFile:
+		if cfg.Dir == "" {
+			return nil, errors.New("file sink needs Dir")
+		}
+		return NewFileSink(cfg.Dir, cfg.MaxFileSize)
+	case config.SinkTypeNats:
+		if cfg.Url == "" || cfg.Topic == "" {
+			return nil, errors.New("nats sink needs Url and Topic")
+		}
+		return NewNatsSink(cfg.Url, cfg.Topic), nil
+	}
+	return nil, fmt.Errorf("unknown sink type %s", cfg.Type)
+}
@@ -0,0 +1,397 @@
+package sender
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFileSink_Send(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sink, err := NewFileSink(dir, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	for i := uint64(1); i <= 4; i++ {
+		if err := sink.Send([]*Message{{MsgType: "InsertAccountBlocks", Data: "[]", EventId: i}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fileNames, err := filepath.Glob(filepath.Join(dir, "events_*.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fileNames) < 2 {
+		t.Fatalf("files should be rotated, got %d files", len(fileNames))
+	}
+
+	var eventId uint64
+	for _, fileName := range fileNames {
+		file, err := os.Open(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			msg := &Message{}
+			if err := json.Unmarshal(scanner.Bytes(), msg); err != nil {
+				t.Fatal(err)
+			}
+			eventId++
+			if msg.EventId != eventId {
+				t.Fatalf("event id should be %d, got %d", eventId, msg.EventId)
+			}
+		}
+		file.Close()
+	}
+	if eventId != 4 {
+		t.Fatalf("should read 4 messages, got %d", eventId)
+	}
+}
+
+func TestFileSink_Restart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sink, err := NewFileSink(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send([]*Message{{EventId: 1}, {EventId: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	sink.Close()
+
+	// a line half written before a crash
+	fileName := filepath.Join(dir, fmt.Sprintf("events_%020d.jsonl", 1))
+	file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"type":"InsertAcc`)
+	file.Close()
+
+	sink, err = NewFileSink(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if sink.LastEventId() != 2 {
+		t.Fatalf("last event id should be 2, got %d", sink.LastEventId())
+	}
+
+	// the producer sends the batch again as its record is behind
+	if err := sink.Send([]*Message{{EventId: 2}, {EventId: 3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(buf), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("should have 3 lines, got %q", lines)
+	}
+	for i, line := range lines {
+		msg := &Message{}
+		if err := json.Unmarshal([]byte(line), msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.EventId != uint64(i+1) {
+			t.Fatalf("event id should be %d, got %d", i+1, msg.EventId)
+		}
+	}
+}
+
+// readFileSink returns the messages in the files of dir in order
+func readFileSink(t *testing.T, dir string) []*Message {
+	fileNames, err := filepath.Glob(filepath.Join(dir, "events_*.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msgList []*Message
+	for _, fileName := range fileNames {
+		buf, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(string(buf), "\n"), "\n") {
+			if line == "" {
+				continue
+			}
+			msg := &Message{}
+			if err := json.Unmarshal([]byte(line), msg); err != nil {
+				t.Fatal(err)
+			}
+			msgList = append(msgList, msg)
+		}
+	}
+	return msgList
+}
+
+func TestFileSink_Rewind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sink, err := NewFileSink(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	for i := uint64(1); i <= 6; i++ {
+		if err := sink.Send([]*Message{{Data: "old", EventId: i}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the second file is removed, and the first one is truncated
+	if err := sink.Rewind(2); err != nil {
+		t.Fatal(err)
+	}
+	if sink.LastEventId() != 2 {
+		t.Fatalf("last event id should be 2, got %d", sink.LastEventId())
+	}
+
+	// the messages sent again replace the old ones
+	if err := sink.Send([]*Message{{Data: "new", EventId: 3}, {Data: "new", EventId: 4}, {Data: "new", EventId: 5}}); err != nil {
+		t.Fatal(err)
+	}
+
+	msgList := readFileSink(t, dir)
+	if len(msgList) != 5 {
+		t.Fatalf("should have 5 messages, got %d", len(msgList))
+	}
+	for i, msg := range msgList {
+		data := "old"
+		if i >= 2 {
+			data = "new"
+		}
+		if msg.EventId != uint64(i+1) || msg.Data != data {
+			t.Fatalf("message %d should be %d %s, got %+v", i, i+1, data, msg)
+		}
+	}
+
+	// rewind all
+	if err := sink.Rewind(0); err != nil {
+		t.Fatal(err)
+	}
+	if msgList := readFileSink(t, dir); len(msgList) != 0 || sink.LastEventId() != 0 {
+		t.Fatalf("all the messages should be dropped, got %d", len(msgList))
+	}
+}
+
+func TestWebhookSink_Send(t *testing.T) {
+	requestCount := 0
+	var received []*Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		if requestCount == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, 2)
+	if err := sink.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	if err := sink.Send([]*Message{{MsgType: "DeleteSnapshotBlocks", Data: "[]", EventId: 7}}); err != nil {
+		t.Fatal(err)
+	}
+	if requestCount != 2 {
+		t.Fatalf("should retry once, got %d requests", requestCount)
+	}
+	if len(received) != 1 || received[0].EventId != 7 {
+		t.Fatalf("unexpected messages %+v", received)
+	}
+}
+
+func TestWebhookSink_Rejected(t *testing.T) {
+	requestCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, 2)
+	if err := sink.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	// dropped without retries
+	if err := sink.Send([]*Message{{MsgType: "DeleteSnapshotBlocks", Data: "[]", EventId: 7}}); err != nil {
+		t.Fatal(err)
+	}
+	if requestCount != 1 {
+		t.Fatalf("should not retry the rejected batch, got %d requests", requestCount)
+	}
+}
+
+// serveNats accepts a connection and answers as a nats server with info, the payloads published are sent to received
+func serveNats(t *testing.T, listener net.Listener, info string, received chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("INFO " + info + "\r\n"))
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "PING":
+			conn.Write([]byte("PONG\r\n"))
+		case "PUB":
+			size, _ := strconv.Atoi(fields[2])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			received <- fields[1] + " " + string(payload[:size])
+		}
+	}
+}
+
+func TestNatsSink_Send(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 10)
+	go serveNats(t, listener, `{"max_payload":1048576}`, received)
+
+	sink := NewNatsSink("nats://"+listener.Addr().String(), "vite.events")
+	if err := sink.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	if err := sink.Send([]*Message{{MsgType: "InsertSnapshotBlocks", Data: "[]", EventId: 1}, {MsgType: "DeleteSnapshotBlocks", Data: "[]", EventId: 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the batch is flushed when Send returns
+	if len(received) != 2 {
+		t.Fatalf("should receive 2 messages, got %d", len(received))
+	}
+	for i := uint64(1); i <= 2; i++ {
+		fields := strings.SplitN(<-received, " ", 2)
+		if fields[0] != "vite.events" {
+			t.Fatalf("unexpected subject %s", fields[0])
+		}
+		msg := &Message{}
+		if err := json.Unmarshal([]byte(fields[1]), msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.EventId != i {
+			t.Fatalf("event id should be %d, got %d", i, msg.EventId)
+		}
+	}
+}
+
+func TestNatsSink_MaxPayload(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 10)
+	go serveNats(t, listener, `{"max_payload":100}`, received)
+
+	sink := NewNatsSink("nats://"+listener.Addr().String(), "vite.events")
+	if err := sink.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	// the large message is dropped, the others are published
+	msgList := []*Message{{EventId: 1}, {Data: strings.Repeat("x", 100), EventId: 2}, {EventId: 3}}
+	if err := sink.Send(msgList); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 2 {
+		t.Fatalf("should receive 2 messages, got %d", len(received))
+	}
+	for _, eventId := range []uint64{1, 3} {
+		msg := &Message{}
+		if err := json.Unmarshal([]byte(strings.SplitN(<-received, " ", 2)[1]), msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.EventId != eventId {
+			t.Fatalf("event id should be %d, got %d", eventId, msg.EventId)
+		}
+	}
+}
+
+func TestNatsSink_TLS(t *testing.T) {
+	if err := NewNatsSink("tls://127.0.0.1:4222", "vite.events").Open(); err == nil {
+		t.Fatal("tls url should be rejected")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go serveNats(t, listener, `{"max_payload":1048576,"tls_required":true}`, make(chan string, 10))
+
+	if err := NewNatsSink("nats://"+listener.Addr().String(), "vite.events").Open(); err == nil {
+		t.Fatal("server requiring tls should be rejected")
+	}
+}
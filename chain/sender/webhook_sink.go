@@ -0,0 +1,125 @@
+package sender
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/vitelabs/go-vite/config"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+const (
+	defaultWebhookMaxRetries = 3
+	webhookRetryInterval     = 500 * time.Millisecond
+	webhookTimeout           = 30 * time.Second
+)
+
+// WebhookSink posts each batch of messages as a json array to url. A batch is delivered when the endpoint
+// answers with a 2xx status code, otherwise it is retried with an exponential backoff. A 4xx status code except
+// 408 and 429 means the endpoint never accepts the batch, so it is dropped with an error log instead of blocking
+// the batches after it.
+type WebhookSink struct {
+	url        string
+	maxRetries int
+
+	client *http.Client
+	log    log15.Logger
+}
+
+func NewWebhookSink(url string, maxRetries int) *WebhookSink {
+	if maxRetries <= 0 {
+		maxRetries = defaultWebhookMaxRetries
+	}
+	return &WebhookSink{
+		url:        url,
+		maxRetries: maxRetries,
+		log:        log15.New("module", "sender/webhook_sink"),
+	}
+}
+
+func (sink *WebhookSink) Id() string {
+	return config.SinkTypeWebhook + "|" + sink.url
+}
+
+func (sink *WebhookSink) Type() string {
+	return config.SinkTypeWebhook
+}
+
+func (sink *WebhookSink) Url() string {
+	return sink.url
+}
+
+func (sink *WebhookSink) Open() error {
+	sink.client = &http.Client{Timeout: webhookTimeout}
+	return nil
+}
+
+func (sink *WebhookSink) Send(msgList []*Message) error {
+	if len(msgList) <= 0 {
+		return nil
+	}
+
+	buf, err := json.Marshal(msgList)
+	if err != nil {
+		return err
+	}
+
+	interval := webhookRetryInterval
+	for i := 0; ; i++ {
+		err = sink.post(buf)
+		if err == nil {
+			return nil
+		}
+
+		if _, ok := err.(*webhookRejected); ok {
+			sink.log.Error(fmt.Sprintf("drop messages of event %d to %d, error is %v",
+				msgList[0].EventId, msgList[len(msgList)-1].EventId, err), "method", "Send")
+			return nil
+		}
+
+		sink.log.Error("post failed, error is "+err.Error(), "method", "Send", "retry", i)
+		if i >= sink.maxRetries {
+			return err
+		}
+
+		time.Sleep(interval)
+		interval *= 2
+	}
+}
+
+func (sink *WebhookSink) post(buf []byte) error {
+	resp, err := sink.client.Post(sink.url, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return &webhookRejected{status: resp.Status}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+	return nil
+}
+
+// webhookRejected is a permanent failure, retrying gets the same status
+type webhookRejected struct {
+	status string
+}
+
+func (e *webhookRejected) Error() string {
+	return "webhook rejected with status " + e.status
+}
+
+func (sink *WebhookSink) Close() error {
+	sink.client = nil
+	return nil
+}
@@ -4,7 +4,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/vitelabs/go-vite/chain_db/database"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/ledger"
 	"github.com/vitelabs/go-vite/trie"
@@ -17,7 +17,11 @@ func (c *chain) GenStateTrie(prevStateHash types.Hash, snapshotContent ledger.Sn
 	}
 	currentTrie := prevTrie.Copy()
 	for addr, item := range snapshotContent {
-		block := c.needSnapshotCache.GetBlockByHash(&addr, item.Hash)
+		var block *ledger.AccountBlock
+		// the cache is nil if the chain isn't started
+		if c.needSnapshotCache != nil {
+			block = c.needSnapshotCache.GetBlockByHash(&addr, item.Hash)
+		}
 		if block == nil {
 			var err error
 			block, err = c.chainDb.Ac.GetBlock(&item.Hash)
@@ -41,7 +45,16 @@ func (c *chain) GetNeedSnapshotContent() ledger.SnapshotContent {
 
 func (c *chain) InsertSnapshotBlock(snapshotBlock *ledger.SnapshotBlock) error {
 
-	batch := new(leveldb.Batch)
+	batch := c.chainDb.NewBatch()
+
+	// The saved state trie may skip the nodes existing in db, so they mustn't be pruned until the state is marked
+	c.pruneLock.RLock()
+	pruneLocked := true
+	defer func() {
+		if pruneLocked {
+			c.pruneLock.RUnlock()
+		}
+	}()
 
 	// Check and create account
 	address := types.PubkeyToAddress(snapshotBlock.PublicKey)
@@ -121,9 +134,15 @@ func (c *chain) InsertSnapshotBlock(snapshotBlock *ledger.SnapshotBlock) error {
 	}
 
 	// Write db
-	if err := c.chainDb.Commit(batch); err != nil {
-		c.log.Crit("c.chainDb.Commit(batch) failed, error is "+err.Error(), "method", "InsertSnapshotBlock")
-		return err
+	commitErr := c.chainDb.Commit(batch)
+	if commitErr == nil {
+		c.markInserted(snapshotBlock, nil)
+	}
+	c.pruneLock.RUnlock()
+	pruneLocked = false
+	if commitErr != nil {
+		c.log.Crit("c.chainDb.Commit(batch) failed, error is "+commitErr.Error(), "method", "InsertSnapshotBlock")
+		return commitErr
 	}
 
 	// After write db
@@ -423,7 +442,7 @@ func (c *chain) DeleteSnapshotBlocksToHeight(toHeight uint64) ([]*ledger.Snapsho
 		return nil, nil, nil
 	}
 
-	batch := new(leveldb.Batch)
+	batch := c.chainDb.NewBatch()
 	snapshotBlocks, accountBlocksMap, err := c.deleteSnapshotBlocksByHeight(batch, toHeight)
 	if err != nil {
 		c.log.Error("deleteSnapshotBlocksByHeight failed, error is "+err.Error(), "method", "DeleteSnapshotBlocksToHeight")
@@ -517,6 +536,11 @@ func (c *chain) DeleteSnapshotBlocksToHeight(toHeight uint64) ([]*ledger.Snapsho
 		return nil, nil, prevSnapshotBlockErr
 	}
 
+	if err := c.chainDb.IndexHeight.LowerHeights(batch, prevSnapshotBlock.Height); err != nil {
+		c.log.Error("LowerHeights failed, error is "+err.Error(), "method", "DeleteSnapshotBlocksByHeight")
+		return nil, nil, err
+	}
+
 	// Add delete event
 	var deleteSbHashList []types.Hash
 	var deleteAbHashList []types.Hash
@@ -582,7 +606,7 @@ func (c *chain) CheckNeedSnapshotCache(content ledger.SnapshotContent) bool {
 	return true
 }
 
-func (c *chain) deleteSnapshotBlocksByHeight(batch *leveldb.Batch, toHeight uint64) ([]*ledger.SnapshotBlock, map[types.Address][]*ledger.AccountBlock, error) {
+func (c *chain) deleteSnapshotBlocksByHeight(batch database.Batch, toHeight uint64) ([]*ledger.SnapshotBlock, map[types.Address][]*ledger.AccountBlock, error) {
 	maxAccountId, err := c.chainDb.Account.GetLastAccountId()
 	if err != nil {
 		c.log.Error("GetLastAccountId failed, error is "+err.Error(), "method", "DeleteSnapshotBlocksByHeight")
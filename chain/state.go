@@ -9,6 +9,11 @@ func (c *chain) GetStateTrie(stateHash *types.Hash) *trie.Trie {
 	return trie.NewTrie(c.chainDb.Db(), stateHash, c.trieNodePool)
 }
 
+// GetLazyStateTrie reads the nodes of the state trie when they are visited, for reading a few keys of a large trie.
+func (c *chain) GetLazyStateTrie(stateHash *types.Hash) *trie.LazyTrie {
+	return trie.NewLazyTrie(c.chainDb.Db(), stateHash, c.trieNodePool)
+}
+
 func (c *chain) NewStateTrie() *trie.Trie {
 	return trie.NewTrie(c.chainDb.Db(), nil, c.trieNodePool)
 }
@@ -0,0 +1,240 @@
+package chain
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/vitelabs/go-vite/chain_db/access"
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/vm_context"
+)
+
+// count of snapshot blocks whose sub ledger is indexed in a batch by the rebuild
+const rebuildIndexPageSize = uint64(100)
+
+var ErrTxIndexNotOpen = errors.New("tx index is not open")
+
+// blockIndex is an index of the account blocks. The blocks are written into the index in the same batch as they
+// are inserted or deleted, the blocks inserted while the index was closed are written by the rebuild in the
+// background, which resumes from the snapshot height recorded by access.IndexHeight.
+type blockIndex struct {
+	name string
+	// the key prefix of the index, it also keys the height
+	id byte
+
+	// the vm log list of the block is passed if needLogs is set
+	needLogs    bool
+	writeBlock  func(database.Batch, *ledger.AccountBlock, ledger.VmLogList) error
+	deleteBlock func(database.Batch, *ledger.AccountBlock, ledger.VmLogList) error
+
+	// accessed atomically, 1 after the rebuild caught up with the chain, then the height follows the insertions
+	built int32
+}
+
+func (c *chain) newTxIndex() *blockIndex {
+	return &blockIndex{
+		name: "tx",
+		id:   database.DBKP_TX_INDEX,
+		writeBlock: func(batch database.Batch, block *ledger.AccountBlock, logList ledger.VmLogList) error {
+			c.chainDb.TxIndex.WriteBlock(batch, block)
+			return nil
+		},
+		deleteBlock: func(batch database.Batch, block *ledger.AccountBlock, logList ledger.VmLogList) error {
+			c.chainDb.TxIndex.DeleteBlock(batch, block)
+			return nil
+		},
+	}
+}
+
+// readLogs reads the vm log list of block if index needs it
+func (c *chain) readLogs(index *blockIndex, block *ledger.AccountBlock) (ledger.VmLogList, error) {
+	if !index.needLogs || block.LogHash == nil {
+		return nil, nil
+	}
+	return c.GetVmLogList(block.LogHash)
+}
+
+// writeBlocks writes the blocks of subLedger, which have been saved, into index
+func (c *chain) writeBlocks(index *blockIndex, batch database.Batch, subLedger map[types.Address][]*ledger.AccountBlock) error {
+	for _, blocks := range subLedger {
+		for _, block := range blocks {
+			logList, err := c.readLogs(index, block)
+			if err != nil {
+				return err
+			}
+			if err := index.writeBlock(batch, block, logList); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// registerIndex keeps index in step with the account chains, the index entries are written into the same batch
+// as the blocks.
+func (c *chain) registerIndex(index *blockIndex) {
+	c.RegisterInsertAccountBlocks(func(batch database.Batch, blocks []*vm_context.VmAccountBlock) error {
+		for _, block := range blocks {
+			// the logs are written in the same batch, so they can`t be read yet
+			var logList ledger.VmLogList
+			if index.needLogs {
+				logList = block.VmContext.UnsavedCache().LogList()
+			}
+			if err := index.writeBlock(batch, block.AccountBlock, logList); err != nil {
+				c.log.Error("writeBlock failed, error is "+err.Error(), "method", "registerIndex", "index", index.name)
+				return err
+			}
+		}
+		return nil
+	})
+
+	// the logs are deleted in the same batch, so they can still be read
+	c.RegisterDeleteAccountBlocks(func(batch database.Batch, subLedger map[types.Address][]*ledger.AccountBlock) error {
+		for _, blocks := range subLedger {
+			for _, block := range blocks {
+				logList, err := c.readLogs(index, block)
+				if err != nil {
+					c.log.Error("readLogs failed, error is "+err.Error(), "method", "registerIndex", "index", index.name)
+					return err
+				}
+				if err := index.deleteBlock(batch, block, logList); err != nil {
+					c.log.Error("deleteBlock failed, error is "+err.Error(), "method", "registerIndex", "index", index.name)
+					return err
+				}
+			}
+		}
+		return nil
+	})
+
+	// the blocks confirmed by the snapshot blocks have been indexed when they were inserted
+	c.RegisterInsertSnapshotBlocksSuccess(func(blocks []*ledger.SnapshotBlock) {
+		if atomic.LoadInt32(&index.built) == 0 {
+			return
+		}
+		if err := c.chainDb.IndexHeight.SaveHeight(index.id, blocks[len(blocks)-1].Height); err != nil {
+			c.log.Error("SaveHeight failed, error is "+err.Error(), "method", "registerIndex", "index", index.name)
+		}
+	})
+
+	c.indexes = append(c.indexes, index)
+}
+
+// startIndexes rebuilds the indexes in the background
+func (c *chain) startIndexes() {
+	c.indexTermination = make(chan struct{})
+	for _, index := range c.indexes {
+		index := index
+		c.indexWg.Add(1)
+		common.Go(func() {
+			defer c.indexWg.Done()
+			if err := c.rebuildIndex(index); err != nil {
+				c.log.Error("rebuildIndex failed, error is "+err.Error(), "method", "startIndexes", "index", index.name)
+			}
+		})
+	}
+}
+
+func (c *chain) stopIndexes() {
+	if c.indexTermination == nil {
+		return
+	}
+	close(c.indexTermination)
+	c.indexWg.Wait()
+	c.indexTermination = nil
+}
+
+// rebuildIndex writes the blocks confirmed by the snapshot blocks above the recorded height of index, and the
+// unconfirmed blocks, into the index. Only the blocks inserted before the index was registered are missed by it,
+// so the rebuild stops at the latest snapshot block. The height is recorded with every page, a stopped rebuild
+// resumes from it.
+func (c *chain) rebuildIndex(index *blockIndex) error {
+	height, err := c.chainDb.IndexHeight.GetHeight(index.id)
+	if err != nil {
+		c.log.Error("GetHeight failed, error is "+err.Error(), "method", "rebuildIndex", "index", index.name)
+		return err
+	}
+
+	latestHeight := c.GetLatestSnapshotBlock().Height
+	if height < latestHeight {
+		c.log.Info("Rebuild "+index.name+" index", "fromHeight", height+1, "toHeight", latestHeight)
+	}
+
+	for fromHeight := height + 1; fromHeight <= latestHeight; fromHeight += rebuildIndexPageSize {
+		select {
+		case <-c.indexTermination:
+			return nil
+		default:
+		}
+
+		toHeight := fromHeight + rebuildIndexPageSize - 1
+		if toHeight > latestHeight {
+			toHeight = latestHeight
+		}
+		_, subLedger, err := c.GetConfirmSubLedger(fromHeight, toHeight)
+		if err != nil {
+			c.log.Error("GetConfirmSubLedger failed, error is "+err.Error(), "method", "rebuildIndex", "index", index.name)
+			return err
+		}
+
+		batch := c.chainDb.NewBatch()
+		if err := c.writeBlocks(index, batch, subLedger); err != nil {
+			c.log.Error("writeBlocks failed, error is "+err.Error(), "method", "rebuildIndex", "index", index.name)
+			return err
+		}
+		c.chainDb.IndexHeight.WriteHeight(batch, index.id, toHeight)
+		if err := c.chainDb.Commit(batch); err != nil {
+			c.log.Error("Commit failed, error is "+err.Error(), "method", "rebuildIndex", "index", index.name)
+			return err
+		}
+	}
+
+	// the blocks confirmed by the snapshot blocks rolled back meanwhile are unconfirmed now
+	unconfirmedSubLedger, err := c.getUnConfirmedSubLedger()
+	if err != nil {
+		c.log.Error("getUnConfirmedSubLedger failed, error is "+err.Error(), "method", "rebuildIndex", "index", index.name)
+		return err
+	}
+	batch := c.chainDb.NewBatch()
+	if err := c.writeBlocks(index, batch, unconfirmedSubLedger); err != nil {
+		c.log.Error("writeBlocks failed, error is "+err.Error(), "method", "rebuildIndex", "index", index.name)
+		return err
+	}
+	if err := c.chainDb.Commit(batch); err != nil {
+		c.log.Error("Commit failed, error is "+err.Error(), "method", "rebuildIndex", "index", index.name)
+		return err
+	}
+
+	atomic.StoreInt32(&index.built, 1)
+	c.log.Info(index.name + " index built")
+	return nil
+}
+
+// GetTransfers returns the send blocks of which addr is the sender (access.TxDirectionOut) or the receiver
+// (access.TxDirectionIn), newest first. Pass the returned cursor to get the next page, it is nil on the last page.
+func (c *chain) GetTransfers(addr *types.Address, direction byte, filter *access.TxIndexFilter, cursor []byte, count uint64) ([]*ledger.AccountBlock, []byte, error) {
+	if !c.cfg.OpenTxIndex {
+		return nil, nil, ErrTxIndexNotOpen
+	}
+
+	items, nextCursor, err := c.chainDb.TxIndex.GetTxs(addr, direction, filter, cursor, count)
+	if err != nil {
+		c.log.Error("GetTxs failed, error is "+err.Error(), "method", "GetTransfers")
+		return nil, nil, err
+	}
+
+	blocks := make([]*ledger.AccountBlock, 0, len(items))
+	for _, item := range items {
+		block, err := c.GetAccountBlockByHash(&item.BlockHash)
+		if err != nil {
+			c.log.Error("GetAccountBlockByHash failed, error is "+err.Error(), "method", "GetTransfers")
+			return nil, nil, err
+		}
+		if block != nil {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks, nextCursor, nil
+}
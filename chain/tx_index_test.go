@@ -0,0 +1,123 @@
+package chain
+
+import (
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vitelabs/go-vite/chain_db/access"
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/config"
+)
+
+func TestChain_RebuildIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tx_index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := NewChain(&config.Config{DataDir: dir, Chain: &config.Chain{LedgerInMemory: true, OpenTxIndex: true}}).(*chain)
+	c.Init()
+	c.Start()
+	defer func() {
+		c.Stop()
+		c.Destroy()
+	}()
+
+	index := c.indexes[0]
+	for i := 0; atomic.LoadInt32(&index.built) == 0; i++ {
+		if i >= 100 {
+			t.Fatal("index should be built in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	checkIndex := func() {
+		height, err := c.chainDb.IndexHeight.GetHeight(index.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if height != SecondSnapshotBlock.Height {
+			t.Fatalf("index height should be %d, got %d", SecondSnapshotBlock.Height, height)
+		}
+
+		blocks, _, err := c.GetTransfers(&GenesisMintageSendBlock.ToAddress, access.TxDirectionIn, &access.TxIndexFilter{}, nil, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(blocks) != 1 || blocks[0].Hash != GenesisMintageSendBlock.Hash {
+			t.Fatalf("the genesis send block should be indexed, got %d blocks", len(blocks))
+		}
+	}
+	checkIndex()
+
+	// the index was closed while the blocks were inserted
+	batch := c.chainDb.NewBatch()
+	c.chainDb.TxIndex.DeleteBlock(batch, &GenesisMintageSendBlock)
+	c.chainDb.IndexHeight.WriteHeight(batch, database.DBKP_TX_INDEX, 0)
+	if err := c.chainDb.Commit(batch); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.rebuildIndex(index); err != nil {
+		t.Fatal(err)
+	}
+	checkIndex()
+}
+
+func TestChain_RebuildIndexAfterRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tx_index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := NewChain(&config.Config{DataDir: dir, Chain: &config.Chain{LedgerInMemory: true}}).(*chain)
+	c.Init()
+	c.Start()
+	defer func() {
+		c.Stop()
+		c.Destroy()
+	}()
+
+	topic := types.DataHash([]byte("topic"))
+	addr, _, _ := types.CreateAddress()
+
+	// the log index was built to the snapshot block and closed
+	prevBlock := insertLogBlock(t, c, addr, []types.Hash{topic})
+	snapshotBlock := insertSnapshotBlock(t, c)
+	if err := c.chainDb.IndexHeight.SaveHeight(database.DBKP_LOG_INDEX, snapshotBlock.Height); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := c.DeleteSnapshotBlocksToHeight(snapshotBlock.Height); err != nil {
+		t.Fatal(err)
+	}
+	height, err := c.chainDb.IndexHeight.GetHeight(database.DBKP_LOG_INDEX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != snapshotBlock.Height-1 {
+		t.Fatalf("index height should be lowered to %d, got %d", snapshotBlock.Height-1, height)
+	}
+
+	// the blocks inserted again below the old index height are indexed when the index is opened
+	block := insertLogBlock(t, c, addr, []types.Hash{topic})
+	insertSnapshotBlock(t, c)
+	if err := c.rebuildIndex(c.newLogIndex()); err != nil {
+		t.Fatal(err)
+	}
+	c.cfg.OpenLogIndex = true
+
+	logs, err := c.GetLogs(&addr, 1, 0, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 || logs[0].BlockHash != prevBlock.Hash || logs[1].BlockHash != block.Hash {
+		t.Fatalf("the blocks confirmed after the rollback should be indexed, got %d logs", len(logs))
+	}
+}
@@ -2,7 +2,6 @@ package access
 
 import (
 	"encoding/binary"
-	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/vitelabs/go-vite/chain_db/database"
 	"github.com/vitelabs/go-vite/common/types"
@@ -11,7 +10,7 @@ import (
 )
 
 type BlockEvent struct {
-	db *leveldb.DB
+	db database.KeyValueStore
 
 	log         log15.Logger
 	eventIdLock sync.RWMutex
@@ -19,7 +18,7 @@ type BlockEvent struct {
 	latestEventId uint64
 }
 
-func NewBlockEvent(db *leveldb.DB) *BlockEvent {
+func NewBlockEvent(db database.KeyValueStore) *BlockEvent {
 	blockEvent := &BlockEvent{
 		db:  db,
 		log: log15.New("module", "chain_db/block_event"),
@@ -48,7 +47,7 @@ func (be *BlockEvent) newEventId() uint64 {
 	return be.latestEventId
 }
 
-func (be *BlockEvent) writeEvent(batch *leveldb.Batch, eventPrefix byte, blockHashList []types.Hash) {
+func (be *BlockEvent) writeEvent(batch database.Batch, eventPrefix byte, blockHashList []types.Hash) {
 	if len(blockHashList) <= 0 {
 		return
 	}
@@ -64,10 +63,10 @@ func (be *BlockEvent) writeEvent(batch *leveldb.Batch, eventPrefix byte, blockHa
 }
 
 func (be *BlockEvent) getLatestEventId() (uint64, error) {
-	iter := be.db.NewIterator(util.BytesPrefix([]byte{database.DBKP_BLOCK_EVENT}), nil)
+	iter := be.db.NewIterator(util.BytesPrefix([]byte{database.DBKP_BLOCK_EVENT}))
 	iterOk := iter.Last()
 	if !iterOk {
-		if iterErr := iter.Error(); iterErr != nil && iterErr != leveldb.ErrNotFound {
+		if iterErr := iter.Error(); iterErr != nil && iterErr != database.ErrNotFound {
 			return 0, iterErr
 		}
 		return 0, nil
@@ -82,14 +81,46 @@ func (be *BlockEvent) LatestEventId() (uint64, error) {
 
 func (be *BlockEvent) GetEvent(eventId uint64) (byte, []types.Hash, error) {
 	key, _ := database.EncodeKey(database.DBKP_BLOCK_EVENT, eventId)
-	value, err := be.db.Get(key, nil)
+	value, err := be.db.Get(key)
 	if err != nil {
-		if err != leveldb.ErrNotFound {
+		if err != database.ErrNotFound {
 			return byte(0), nil, err
 		}
 		return byte(0), nil, nil
 	}
 
+	eventType, blockHashList := decodeEvent(value)
+	return eventType, blockHashList, nil
+}
+
+// GetEvents returns at most count events whose id is greater than or equal to startEventId, in ascending order.
+// Event ids are not guaranteed to be continuous, so the ids of the returned events are returned too.
+func (be *BlockEvent) GetEvents(startEventId uint64, count uint64) ([]uint64, []byte, [][]types.Hash, error) {
+	startKey, _ := database.EncodeKey(database.DBKP_BLOCK_EVENT, startEventId)
+	limitKey := util.BytesPrefix([]byte{database.DBKP_BLOCK_EVENT}).Limit
+
+	iter := be.db.NewIterator(&util.Range{Start: startKey, Limit: limitKey})
+	defer iter.Release()
+
+	var eventIdList []uint64
+	var eventTypeList []byte
+	var blockHashLists [][]types.Hash
+	for uint64(len(eventIdList)) < count && iter.Next() {
+		eventType, blockHashList := decodeEvent(iter.Value())
+
+		eventIdList = append(eventIdList, binary.BigEndian.Uint64(iter.Key()[1:9]))
+		eventTypeList = append(eventTypeList, eventType)
+		blockHashLists = append(blockHashLists, blockHashList)
+	}
+
+	if err := iter.Error(); err != nil && err != database.ErrNotFound {
+		return nil, nil, nil, err
+	}
+
+	return eventIdList, eventTypeList, blockHashLists, nil
+}
+
+func decodeEvent(value []byte) (byte, []types.Hash) {
 	eventType := value[0]
 	value = value[1:]
 
@@ -100,21 +131,21 @@ func (be *BlockEvent) GetEvent(eventId uint64) (byte, []types.Hash, error) {
 		copy(blockHash[:], value[i*types.HashSize:(i+1)*types.HashSize])
 		blockHashList = append(blockHashList, blockHash)
 	}
-	return eventType, blockHashList, nil
+	return eventType, blockHashList
 }
 
-func (be *BlockEvent) AddAccountBlocks(batch *leveldb.Batch, blockHashList []types.Hash) {
+func (be *BlockEvent) AddAccountBlocks(batch database.Batch, blockHashList []types.Hash) {
 	be.writeEvent(batch, AddAccountBlocksEvent, blockHashList)
 }
 
-func (be *BlockEvent) DeleteAccountBlocks(batch *leveldb.Batch, blockHashList []types.Hash) {
+func (be *BlockEvent) DeleteAccountBlocks(batch database.Batch, blockHashList []types.Hash) {
 	be.writeEvent(batch, DeleteAccountBlocksEvent, blockHashList)
 }
 
-func (be *BlockEvent) AddSnapshotBlocks(batch *leveldb.Batch, blockHashList []types.Hash) {
+func (be *BlockEvent) AddSnapshotBlocks(batch database.Batch, blockHashList []types.Hash) {
 	be.writeEvent(batch, AddSnapshotBlocksEvent, blockHashList)
 }
 
-func (be *BlockEvent) DeleteSnapshotBlocks(batch *leveldb.Batch, blockHashList []types.Hash) {
+func (be *BlockEvent) DeleteSnapshotBlocks(batch database.Batch, blockHashList []types.Hash) {
 	be.writeEvent(batch, DeleteSnapshotBlocksEvent, blockHashList)
 }
@@ -0,0 +1,35 @@
+package access
+
+import (
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+)
+
+// ContractAbi keeps the json abi registered for a contract address, it is node local data and not part of
+// the ledger.
+type ContractAbi struct {
+	db database.KeyValueStore
+}
+
+func NewContractAbi(db database.KeyValueStore) *ContractAbi {
+	return &ContractAbi{
+		db: db,
+	}
+}
+
+func (ca *ContractAbi) WriteAbi(addr *types.Address, abiJson []byte) error {
+	key, _ := database.EncodeKey(database.DBKP_CONTRACT_ABI, addr.Bytes())
+	return ca.db.Put(key, abiJson)
+}
+
+func (ca *ContractAbi) GetAbi(addr *types.Address) ([]byte, error) {
+	key, _ := database.EncodeKey(database.DBKP_CONTRACT_ABI, addr.Bytes())
+	abiJson, err := ca.db.Get(key)
+	if err != nil {
+		if err != database.ErrNotFound {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return abiJson, nil
+}
@@ -0,0 +1,65 @@
+package access
+
+import (
+	"encoding/binary"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/vitelabs/go-vite/chain_db/database"
+)
+
+// IndexHeight records the snapshot height an index of the account blocks has been built to, the account blocks
+// confirmed by the snapshot blocks not higher than it are in the index. The key prefix of the index identifies it.
+type IndexHeight struct {
+	db database.KeyValueStore
+}
+
+func NewIndexHeight(db database.KeyValueStore) *IndexHeight {
+	return &IndexHeight{
+		db: db,
+	}
+}
+
+func (ih *IndexHeight) GetHeight(indexId byte) (uint64, error) {
+	key, _ := database.EncodeKey(database.DBKP_INDEX_HEIGHT, []byte{indexId})
+	value, err := ih.db.Get(key)
+	if err != nil {
+		if err != database.ErrNotFound {
+			return 0, err
+		}
+		return 0, nil
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
+
+func createIndexHeightKeyValue(indexId byte, height uint64) ([]byte, []byte) {
+	key, _ := database.EncodeKey(database.DBKP_INDEX_HEIGHT, []byte{indexId})
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, height)
+	return key, value
+}
+
+func (ih *IndexHeight) WriteHeight(batch database.Batch, indexId byte, height uint64) {
+	batch.Put(createIndexHeightKeyValue(indexId, height))
+}
+
+func (ih *IndexHeight) SaveHeight(indexId byte, height uint64) error {
+	return ih.db.Put(createIndexHeightKeyValue(indexId, height))
+}
+
+// LowerHeights writes height as the height of every index built beyond it. It's called when the snapshot blocks
+// above height are deleted, so an index closed at the time is rebuilt from the fork point.
+func (ih *IndexHeight) LowerHeights(batch database.Batch, height uint64) error {
+	key, _ := database.EncodeKey(database.DBKP_INDEX_HEIGHT)
+	iter := ih.db.NewIterator(util.BytesPrefix(key))
+	defer iter.Release()
+
+	for iter.Next() {
+		value := iter.Value()
+		if len(value) != 8 || binary.BigEndian.Uint64(value) <= height {
+			continue
+		}
+		indexKey := iter.Key()
+		ih.WriteHeight(batch, indexKey[len(indexKey)-1], height)
+	}
+	return iter.Error()
+}
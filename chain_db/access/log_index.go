@@ -0,0 +1,162 @@
+package access
+
+import (
+	"encoding/binary"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+)
+
+type LogIndexItem struct {
+	AccountAddress types.Address
+	Height         uint64
+	BlockHash      types.Hash
+	LogHash        types.Hash
+}
+
+// LogIndex indexes the blocks which have vm logs by account address and height, so the logs of an address can be
+// found without reading every block of the account chain. The blocks are also indexed by each topic of their
+// logs, regardless of the position of the topic, and by the height of the snapshot block they refer to, so the
+// blocks of a topic can be sought by snapshot height.
+type LogIndex struct {
+	db database.KeyValueStore
+}
+
+func NewLogIndex(db database.KeyValueStore) *LogIndex {
+	return &LogIndex{
+		db: db,
+	}
+}
+
+// distinct topics of logList
+func logTopics(logList ledger.VmLogList) map[types.Hash]struct{} {
+	topics := make(map[types.Hash]struct{})
+	for _, log := range logList {
+		for _, topic := range log.Topics {
+			topics[topic] = struct{}{}
+		}
+	}
+	return topics
+}
+
+func createLogTopicKey(topic types.Hash, snapshotHeight uint64, block *ledger.AccountBlock) []byte {
+	key, _ := database.EncodeKey(database.DBKP_LOG_TOPIC_INDEX, topic.Bytes(), snapshotHeight, block.AccountAddress.Bytes(), block.Height)
+	return key
+}
+
+// WriteBlock indexes block, snapshotHeight is the height of the snapshot block referred by block and logList is
+// the vm log list of block.
+func (li *LogIndex) WriteBlock(batch database.Batch, block *ledger.AccountBlock, snapshotHeight uint64, logList ledger.VmLogList) {
+	if block.LogHash == nil {
+		return
+	}
+	value := append(block.Hash.Bytes(), block.LogHash.Bytes()...)
+
+	key, _ := database.EncodeKey(database.DBKP_LOG_INDEX, block.AccountAddress.Bytes(), block.Height)
+	batch.Put(key, value)
+
+	for topic := range logTopics(logList) {
+		batch.Put(createLogTopicKey(topic, snapshotHeight, block), value)
+	}
+}
+
+// DeleteBlock removes block from the index, the arguments are the same as WriteBlock.
+func (li *LogIndex) DeleteBlock(batch database.Batch, block *ledger.AccountBlock, snapshotHeight uint64, logList ledger.VmLogList) {
+	if block.LogHash == nil {
+		return
+	}
+	key, _ := database.EncodeKey(database.DBKP_LOG_INDEX, block.AccountAddress.Bytes(), block.Height)
+	batch.Delete(key)
+
+	for topic := range logTopics(logList) {
+		batch.Delete(createLogTopicKey(topic, snapshotHeight, block))
+	}
+}
+
+// IsEmpty reports whether nothing has been indexed yet.
+func (li *LogIndex) IsEmpty() (bool, error) {
+	for _, prefix := range []byte{database.DBKP_LOG_INDEX, database.DBKP_LOG_TOPIC_INDEX} {
+		iter := li.db.NewIterator(util.BytesPrefix([]byte{prefix}))
+		ok := iter.First()
+		err := iter.Error()
+		iter.Release()
+
+		if ok {
+			return false, nil
+		}
+		if err != nil && err != database.ErrNotFound {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// IterateItems calls fn with the index items of addr from startHeight to endHeight inclusively in ascending order,
+// endHeight 0 means unbounded. The iteration stops when fn returns false.
+func (li *LogIndex) IterateItems(addr *types.Address, startHeight uint64, endHeight uint64, fn func(*LogIndexItem) bool) error {
+	prefix, _ := database.EncodeKey(database.DBKP_LOG_INDEX, addr.Bytes())
+
+	startKey, _ := database.EncodeKey(database.DBKP_LOG_INDEX, addr.Bytes(), startHeight)
+	limitKey := util.BytesPrefix(prefix).Limit
+	if endHeight > 0 {
+		limitKey, _ = database.EncodeKey(database.DBKP_LOG_INDEX, addr.Bytes(), endHeight+1)
+	}
+
+	iter := li.db.NewIterator(&util.Range{Start: startKey, Limit: limitKey})
+	defer iter.Release()
+
+	for iter.Next() {
+		item := &LogIndexItem{
+			AccountAddress: *addr,
+			Height:         binary.BigEndian.Uint64(iter.Key()[len(prefix):]),
+		}
+		decodeLogIndexValue(item, iter.Value())
+		if !fn(item) {
+			return nil
+		}
+	}
+	if err := iter.Error(); err != nil && err != database.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+// IterateTopicItems calls fn with the index items of the blocks which emitted topic and refer to the snapshot
+// blocks from fromSnapshotHeight to toSnapshotHeight inclusively, toSnapshotHeight 0 means unbounded. The items
+// are ordered by the referred snapshot height, address and height. The iteration stops when fn returns false.
+func (li *LogIndex) IterateTopicItems(topic *types.Hash, fromSnapshotHeight uint64, toSnapshotHeight uint64, fn func(*LogIndexItem) bool) error {
+	prefix, _ := database.EncodeKey(database.DBKP_LOG_TOPIC_INDEX, topic.Bytes())
+
+	startKey, _ := database.EncodeKey(database.DBKP_LOG_TOPIC_INDEX, topic.Bytes(), fromSnapshotHeight)
+	limitKey := util.BytesPrefix(prefix).Limit
+	if toSnapshotHeight > 0 {
+		limitKey, _ = database.EncodeKey(database.DBKP_LOG_TOPIC_INDEX, topic.Bytes(), toSnapshotHeight+1)
+	}
+
+	iter := li.db.NewIterator(&util.Range{Start: startKey, Limit: limitKey})
+	defer iter.Release()
+
+	// the referred snapshot height precedes the address
+	addrOffset := len(prefix) + 8
+	for iter.Next() {
+		key := iter.Key()
+		item := &LogIndexItem{
+			Height: binary.BigEndian.Uint64(key[addrOffset+types.AddressSize:]),
+		}
+		item.AccountAddress, _ = types.BytesToAddress(key[addrOffset : addrOffset+types.AddressSize])
+		decodeLogIndexValue(item, iter.Value())
+		if !fn(item) {
+			return nil
+		}
+	}
+	if err := iter.Error(); err != nil && err != database.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+func decodeLogIndexValue(item *LogIndexItem, value []byte) {
+	item.BlockHash, _ = types.BytesToHash(value[:types.HashSize])
+	item.LogHash, _ = types.BytesToHash(value[types.HashSize:])
+}
@@ -0,0 +1,120 @@
+package access
+
+import (
+	"testing"
+
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+)
+
+func TestLogIndex_IterateItems(t *testing.T) {
+	db := database.NewMemStore()
+
+	logIndex := NewLogIndex(db)
+	addr, _, _ := types.CreateAddress()
+	topicEven := types.DataHash([]byte("even"))
+	topicFour := types.DataHash([]byte("four"))
+
+	var blocks []*ledger.AccountBlock
+	var logLists []ledger.VmLogList
+	batch := db.NewBatch()
+	for i := 1; i <= 10; i++ {
+		block := &ledger.AccountBlock{
+			BlockType:      ledger.BlockTypeReceive,
+			AccountAddress: addr,
+			Height:         uint64(i),
+		}
+		block.Hash = types.DataHash([]byte{byte(i)})
+		// every other block emits logs
+		var logList ledger.VmLogList
+		if i%2 == 0 {
+			logList = ledger.VmLogList{{Topics: []types.Hash{topicEven}}}
+			if i%4 == 0 {
+				logList = append(logList, &ledger.VmLog{Topics: []types.Hash{topicEven, topicFour}})
+			}
+			block.LogHash = logList.Hash()
+		}
+		blocks = append(blocks, block)
+		logLists = append(logLists, logList)
+		// two blocks refer to each snapshot block
+		logIndex.WriteBlock(batch, block, uint64((i+1)/2), logList)
+	}
+	if err := db.Write(batch); err != nil {
+		t.Fatal(err)
+	}
+
+	getItems := func(startHeight, endHeight uint64) []*LogIndexItem {
+		var items []*LogIndexItem
+		if err := logIndex.IterateItems(&addr, startHeight, endHeight, func(item *LogIndexItem) bool {
+			items = append(items, item)
+			return true
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return items
+	}
+
+	items := getItems(1, 0)
+	if len(items) != 5 {
+		t.Fatalf("should get 5 items, got %d", len(items))
+	}
+	for i, item := range items {
+		block := blocks[2*i+1]
+		if item.AccountAddress != addr || item.Height != block.Height || item.BlockHash != block.Hash || item.LogHash != *block.LogHash {
+			t.Fatalf("item %d mismatch", i)
+		}
+	}
+
+	items = getItems(3, 8)
+	if len(items) != 3 || items[0].Height != 4 || items[2].Height != 8 {
+		t.Fatalf("should get items of height 4, 6 and 8, got %d items", len(items))
+	}
+
+	// stop early
+	count := 0
+	if err := logIndex.IterateItems(&addr, 1, 0, func(item *LogIndexItem) bool {
+		count++
+		return count < 2
+	}); err != nil || count != 2 {
+		t.Fatalf("iteration should stop after 2 items, got %d", count)
+	}
+
+	// topics, a block is indexed once for a topic emitted by several logs
+	getTopicItems := func(topic types.Hash, fromSnapshotHeight, toSnapshotHeight uint64) []*LogIndexItem {
+		var items []*LogIndexItem
+		if err := logIndex.IterateTopicItems(&topic, fromSnapshotHeight, toSnapshotHeight, func(item *LogIndexItem) bool {
+			items = append(items, item)
+			return true
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return items
+	}
+	topicItems := getTopicItems(topicFour, 0, 0)
+	if len(topicItems) != 2 || topicItems[0].Height != 4 || topicItems[1].Height != 8 ||
+		topicItems[0].AccountAddress != addr || topicItems[1].BlockHash != blocks[7].Hash {
+		t.Fatalf("should get items of height 4 and 8 by topic, got %d items", len(topicItems))
+	}
+
+	// sought by the referred snapshot height
+	topicItems = getTopicItems(topicEven, 2, 4)
+	if len(topicItems) != 3 || topicItems[0].Height != 4 || topicItems[2].Height != 8 {
+		t.Fatalf("should get items of height 4, 6 and 8 by snapshot height, got %d items", len(topicItems))
+	}
+	if topicItems = getTopicItems(topicEven, 5, 0); len(topicItems) != 1 || topicItems[0].Height != 10 {
+		t.Fatalf("should get the item of height 10 by snapshot height, got %d items", len(topicItems))
+	}
+
+	// delete
+	batch = db.NewBatch()
+	for i, block := range blocks {
+		logIndex.DeleteBlock(batch, block, uint64(i/2+1), logLists[i])
+	}
+	if err := db.Write(batch); err != nil {
+		t.Fatal(err)
+	}
+	if isEmpty, err := logIndex.IsEmpty(); err != nil || !isEmpty {
+		t.Fatal("index should be empty after deleting", err)
+	}
+}
@@ -1,16 +1,15 @@
 package access
 
 import (
-	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/vitelabs/go-vite/chain_db/database"
 	"github.com/vitelabs/go-vite/common/types"
 )
 
 type OnRoad struct {
-	db *leveldb.DB
+	db database.KeyValueStore
 }
 
-func NewOnRoad(db *leveldb.DB) *OnRoad {
+func NewOnRoad(db database.KeyValueStore) *OnRoad {
 	return &OnRoad{
 		db: db,
 	}
@@ -19,14 +18,14 @@ func NewOnRoad(db *leveldb.DB) *OnRoad {
 func (or *OnRoad) GetMeta(addr *types.Address, hash *types.Hash) ([]byte, error) {
 	key, err := database.EncodeKey(database.DBKP_ONROADMETA, addr.Bytes(), hash.Bytes())
 	if err != nil {
-		if err != leveldb.ErrNotFound {
+		if err != database.ErrNotFound {
 			return nil, err
 		}
 		return nil, nil
 	}
-	value, err := or.db.Get(key, nil)
+	value, err := or.db.Get(key)
 	if err != nil {
-		if err != leveldb.ErrNotFound {
+		if err != database.ErrNotFound {
 			return nil, err
 		}
 		return nil, nil
@@ -2,7 +2,6 @@ package access
 
 import (
 	"encoding/binary"
-	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/vitelabs/go-vite/chain_db/database"
 	"github.com/vitelabs/go-vite/common/helper"
@@ -17,16 +16,16 @@ func getSnapshotBlockHash(dbKey []byte) *types.Hash {
 }
 
 type SnapshotChain struct {
-	db *leveldb.DB
+	db database.KeyValueStore
 }
 
-func NewSnapshotChain(db *leveldb.DB) *SnapshotChain {
+func NewSnapshotChain(db database.KeyValueStore) *SnapshotChain {
 	return &SnapshotChain{
 		db: db,
 	}
 }
 
-func (sc *SnapshotChain) WriteSnapshotHash(batch *leveldb.Batch, hash *types.Hash, height uint64) {
+func (sc *SnapshotChain) WriteSnapshotHash(batch database.Batch, hash *types.Hash, height uint64) {
 	key, _ := database.EncodeKey(database.DBKP_SNAPSHOTBLOCKHASH, hash.Bytes())
 	heightBytes := make([]byte, 8)
 	binary.BigEndian.PutUint64(heightBytes, height)
@@ -34,7 +33,7 @@ func (sc *SnapshotChain) WriteSnapshotHash(batch *leveldb.Batch, hash *types.Has
 	batch.Put(key, heightBytes)
 }
 
-func (sc *SnapshotChain) WriteSnapshotContent(batch *leveldb.Batch, snapshotHeight uint64, snapshotContent ledger.SnapshotContent) error {
+func (sc *SnapshotChain) WriteSnapshotContent(batch database.Batch, snapshotHeight uint64, snapshotContent ledger.SnapshotContent) error {
 	key, _ := database.EncodeKey(database.DBKP_SNAPSHOTCONTENT, snapshotHeight)
 	data, sErr := snapshotContent.Serialize()
 	if sErr != nil {
@@ -44,7 +43,7 @@ func (sc *SnapshotChain) WriteSnapshotContent(batch *leveldb.Batch, snapshotHeig
 	return nil
 }
 
-func (sc *SnapshotChain) WriteSnapshotBlock(batch *leveldb.Batch, snapshotBlock *ledger.SnapshotBlock) error {
+func (sc *SnapshotChain) WriteSnapshotBlock(batch database.Batch, snapshotBlock *ledger.SnapshotBlock) error {
 	key, _ := database.EncodeKey(database.DBKP_SNAPSHOTBLOCK, snapshotBlock.Height, snapshotBlock.Hash.Bytes())
 	data, sErr := snapshotBlock.DbSerialize()
 	if sErr != nil {
@@ -57,11 +56,11 @@ func (sc *SnapshotChain) WriteSnapshotBlock(batch *leveldb.Batch, snapshotBlock
 func (sc *SnapshotChain) GetLatestBlock() (*ledger.SnapshotBlock, error) {
 	key, _ := database.EncodeKey(database.DBKP_SNAPSHOTBLOCK)
 
-	iter := sc.db.NewIterator(util.BytesPrefix(key), nil)
+	iter := sc.db.NewIterator(util.BytesPrefix(key))
 	defer iter.Release()
 
 	if !iter.Last() {
-		if err := iter.Error(); err != leveldb.ErrNotFound {
+		if err := iter.Error(); err != database.ErrNotFound {
 			return nil, err
 		}
 		return nil, nil
@@ -87,9 +86,9 @@ func (sc *SnapshotChain) GetLatestBlock() (*ledger.SnapshotBlock, error) {
 
 func (sc *SnapshotChain) GetSnapshotContent(snapshotBlockHeight uint64) (ledger.SnapshotContent, error) {
 	key, _ := database.EncodeKey(database.DBKP_SNAPSHOTCONTENT, snapshotBlockHeight)
-	data, err := sc.db.Get(key, nil)
+	data, err := sc.db.Get(key)
 	if err != nil {
-		if err != leveldb.ErrNotFound {
+		if err != database.ErrNotFound {
 			return nil, err
 		}
 		return nil, nil
@@ -100,6 +99,11 @@ func (sc *SnapshotChain) GetSnapshotContent(snapshotBlockHeight uint64) (ledger.
 
 	return snapshotContent, nil
 }
+func (sc *SnapshotChain) HasSnapshotContent(snapshotBlockHeight uint64) (bool, error) {
+	key, _ := database.EncodeKey(database.DBKP_SNAPSHOTCONTENT, snapshotBlockHeight)
+	return sc.db.Has(key)
+}
+
 func (sc *SnapshotChain) GetSnapshotBlocks(height uint64, count uint64, forward, containSnapshotContent bool) ([]*ledger.SnapshotBlock, error) {
 	var blocks []*ledger.SnapshotBlock
 	var startHeight, endHeight = uint64(0), uint64(0)
@@ -118,7 +122,7 @@ func (sc *SnapshotChain) GetSnapshotBlocks(height uint64, count uint64, forward,
 	startKey, _ := database.EncodeKey(database.DBKP_SNAPSHOTBLOCK, startHeight)
 	endKey, _ := database.EncodeKey(database.DBKP_SNAPSHOTBLOCK, endHeight)
 
-	iter := sc.db.NewIterator(&util.Range{Start: startKey, Limit: endKey}, nil)
+	iter := sc.db.NewIterator(&util.Range{Start: startKey, Limit: endKey})
 
 	for i := uint64(0); i < count && iter.Next(); i++ {
 		data := iter.Value()
@@ -151,9 +155,9 @@ func (sc *SnapshotChain) GetSnapshotBlocks(height uint64, count uint64, forward,
 
 func (sc *SnapshotChain) GetSnapshotBlockHeight(snapshotHash *types.Hash) (uint64, error) {
 	key, _ := database.EncodeKey(database.DBKP_SNAPSHOTBLOCKHASH, snapshotHash.Bytes())
-	data, err := sc.db.Get(key, nil)
+	data, err := sc.db.Get(key)
 	if err != nil {
-		if err == leveldb.ErrNotFound {
+		if err == database.ErrNotFound {
 			return 0, nil
 		}
 		return 0, err
@@ -166,11 +170,11 @@ func (sc *SnapshotChain) GetSnapshotBlockHeight(snapshotHash *types.Hash) (uint6
 func (sc *SnapshotChain) GetSnapshotBlock(height uint64, containsSnapshotContent bool) (*ledger.SnapshotBlock, error) {
 	key, _ := database.EncodeKey(database.DBKP_SNAPSHOTBLOCK, height)
 
-	iter := sc.db.NewIterator(util.BytesPrefix(key), nil)
+	iter := sc.db.NewIterator(util.BytesPrefix(key))
 	defer iter.Release()
 
 	if !iter.Next() {
-		if err := iter.Error(); err != nil && err != leveldb.ErrNotFound {
+		if err := iter.Error(); err != nil && err != database.ErrNotFound {
 			return nil, err
 		}
 		return nil, nil
@@ -195,14 +199,14 @@ func (sc *SnapshotChain) GetSnapshotBlock(height uint64, containsSnapshotContent
 }
 
 // Delete list contains the to height
-func (sc *SnapshotChain) DeleteToHeight(batch *leveldb.Batch, toHeight uint64) ([]*ledger.SnapshotBlock, error) {
+func (sc *SnapshotChain) DeleteToHeight(batch database.Batch, toHeight uint64) ([]*ledger.SnapshotBlock, error) {
 
 	deleteList := make([]*ledger.SnapshotBlock, 0)
 
 	startBlockKey, _ := database.EncodeKey(database.DBKP_SNAPSHOTBLOCK, toHeight)
 	endBlockKey, _ := database.EncodeKey(database.DBKP_SNAPSHOTBLOCK, helper.MaxUint64)
 
-	iter := sc.db.NewIterator(&util.Range{Start: startBlockKey, Limit: endBlockKey}, nil)
+	iter := sc.db.NewIterator(&util.Range{Start: startBlockKey, Limit: endBlockKey})
 	defer iter.Release()
 
 	currentHeight := toHeight
@@ -234,7 +238,7 @@ func (sc *SnapshotChain) DeleteToHeight(batch *leveldb.Batch, toHeight uint64) (
 
 		currentHeight++
 	}
-	if err := iter.Error(); err != nil && err != leveldb.ErrNotFound {
+	if err := iter.Error(); err != nil && err != database.ErrNotFound {
 		iter.Release()
 		return nil, err
 	}
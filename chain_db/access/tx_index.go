@@ -0,0 +1,177 @@
+package access
+
+import (
+	"encoding/binary"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+)
+
+const (
+	TxDirectionIn  = byte(1)
+	TxDirectionOut = byte(2)
+
+	// timestamp + block hash
+	TxIndexCursorSize = 8 + types.HashSize
+
+	// max count of items scanned by a GetTxs call
+	MaxTxIndexScan = 10000
+)
+
+type TxIndexItem struct {
+	BlockHash    types.Hash
+	Timestamp    uint64
+	BlockType    byte
+	TokenId      types.TokenTypeId
+	Counterparty types.Address
+}
+
+type TxIndexFilter struct {
+	TokenId      *types.TokenTypeId
+	BlockType    *byte
+	Counterparty *types.Address
+
+	// unix seconds, zero means unbounded
+	StartTime uint64
+	EndTime   uint64
+}
+
+func (filter *TxIndexFilter) match(item *TxIndexItem) bool {
+	if filter.TokenId != nil && *filter.TokenId != item.TokenId {
+		return false
+	}
+	if filter.BlockType != nil && *filter.BlockType != item.BlockType {
+		return false
+	}
+	if filter.Counterparty != nil && *filter.Counterparty != item.Counterparty {
+		return false
+	}
+	return true
+}
+
+// TxIndex indexes send blocks twice, as an outgoing transfer of the sender and as an incoming transfer of
+// the receiver, ordered by timestamp.
+type TxIndex struct {
+	db database.KeyValueStore
+}
+
+func NewTxIndex(db database.KeyValueStore) *TxIndex {
+	return &TxIndex{
+		db: db,
+	}
+}
+
+func blockTimestamp(block *ledger.AccountBlock) uint64 {
+	if block.Timestamp == nil {
+		return 0
+	}
+	return uint64(block.Timestamp.Unix())
+}
+
+func createTxIndexValue(block *ledger.AccountBlock, counterparty *types.Address) []byte {
+	value := []byte{block.BlockType}
+	value = append(value, block.TokenId.Bytes()...)
+	value = append(value, counterparty.Bytes()...)
+	return value
+}
+
+func (ti *TxIndex) WriteBlock(batch database.Batch, block *ledger.AccountBlock) {
+	if !block.IsSendBlock() {
+		return
+	}
+	timestamp := blockTimestamp(block)
+
+	outKey, _ := database.EncodeKey(database.DBKP_TX_INDEX, block.AccountAddress.Bytes(), []byte{TxDirectionOut}, timestamp, block.Hash.Bytes())
+	batch.Put(outKey, createTxIndexValue(block, &block.ToAddress))
+
+	inKey, _ := database.EncodeKey(database.DBKP_TX_INDEX, block.ToAddress.Bytes(), []byte{TxDirectionIn}, timestamp, block.Hash.Bytes())
+	batch.Put(inKey, createTxIndexValue(block, &block.AccountAddress))
+}
+
+func (ti *TxIndex) DeleteBlock(batch database.Batch, block *ledger.AccountBlock) {
+	if !block.IsSendBlock() {
+		return
+	}
+	timestamp := blockTimestamp(block)
+
+	outKey, _ := database.EncodeKey(database.DBKP_TX_INDEX, block.AccountAddress.Bytes(), []byte{TxDirectionOut}, timestamp, block.Hash.Bytes())
+	batch.Delete(outKey)
+
+	inKey, _ := database.EncodeKey(database.DBKP_TX_INDEX, block.ToAddress.Bytes(), []byte{TxDirectionIn}, timestamp, block.Hash.Bytes())
+	batch.Delete(inKey)
+}
+
+// IsEmpty reports whether nothing has been indexed yet.
+func (ti *TxIndex) IsEmpty() (bool, error) {
+	iter := ti.db.NewIterator(util.BytesPrefix([]byte{database.DBKP_TX_INDEX}))
+	defer iter.Release()
+
+	if !iter.First() {
+		if err := iter.Error(); err != nil && err != database.ErrNotFound {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// GetTxs returns at most count index items of addr in the given direction, newest first. The iteration starts
+// right before cursor if cursor is not nil, the returned cursor is nil when there are no more items. At most
+// MaxTxIndexScan items are scanned in a call, so fewer than count items may be returned with a cursor when the
+// filter rejects many of them.
+func (ti *TxIndex) GetTxs(addr *types.Address, direction byte, filter *TxIndexFilter, cursor []byte, count uint64) ([]*TxIndexItem, []byte, error) {
+	prefix, _ := database.EncodeKey(database.DBKP_TX_INDEX, addr.Bytes(), []byte{direction})
+
+	startKey, _ := database.EncodeKey(database.DBKP_TX_INDEX, addr.Bytes(), []byte{direction}, filter.StartTime)
+	limitKey := util.BytesPrefix(prefix).Limit
+	if filter.EndTime > 0 {
+		limitKey, _ = database.EncodeKey(database.DBKP_TX_INDEX, addr.Bytes(), []byte{direction}, filter.EndTime+1)
+	}
+	if len(cursor) > 0 {
+		cursorKey, _ := database.EncodeKey(database.DBKP_TX_INDEX, addr.Bytes(), []byte{direction}, cursor)
+		if string(cursorKey) < string(limitKey) {
+			limitKey = cursorKey
+		}
+	}
+
+	iter := ti.db.NewIterator(&util.Range{Start: startKey, Limit: limitKey})
+	defer iter.Release()
+
+	var items []*TxIndexItem
+	var nextCursor []byte
+	more := false
+	scanned := 0
+	for ok := iter.Last(); ok; ok = iter.Prev() {
+		if uint64(len(items)) >= count || scanned >= MaxTxIndexScan {
+			more = true
+			break
+		}
+		scanned++
+
+		key := iter.Key()
+		value := iter.Value()
+		keySuffix := key[len(prefix):]
+
+		item := &TxIndexItem{
+			Timestamp: binary.BigEndian.Uint64(keySuffix[:8]),
+			BlockType: value[0],
+		}
+		item.BlockHash, _ = types.BytesToHash(keySuffix[8:])
+		item.TokenId, _ = types.BytesToTokenTypeId(value[1 : 1+types.TokenTypeIdSize])
+		item.Counterparty, _ = types.BytesToAddress(value[1+types.TokenTypeIdSize:])
+
+		nextCursor = append([]byte{}, keySuffix...)
+		if filter.match(item) {
+			items = append(items, item)
+		}
+	}
+	if err := iter.Error(); err != nil && err != database.ErrNotFound {
+		return nil, nil, err
+	}
+
+	if !more {
+		nextCursor = nil
+	}
+	return items, nextCursor, nil
+}
@@ -0,0 +1,150 @@
+package access
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+)
+
+func TestTxIndex_GetTxs(t *testing.T) {
+	db := database.NewMemStore()
+
+	txIndex := NewTxIndex(db)
+	from, _, _ := types.CreateAddress()
+	to, _, _ := types.CreateAddress()
+	other, _, _ := types.CreateAddress()
+
+	var blocks []*ledger.AccountBlock
+	batch := db.NewBatch()
+	for i := 0; i < 10; i++ {
+		timestamp := time.Unix(int64(1000+i), 0)
+		toAddress := to
+		if i%2 == 1 {
+			toAddress = other
+		}
+		block := &ledger.AccountBlock{
+			BlockType:      ledger.BlockTypeSendCall,
+			AccountAddress: from,
+			ToAddress:      toAddress,
+			TokenId:        ledger.ViteTokenId,
+			Amount:         big.NewInt(int64(i)),
+			Timestamp:      &timestamp,
+		}
+		block.Hash = block.ComputeHash()
+		blocks = append(blocks, block)
+		txIndex.WriteBlock(batch, block)
+	}
+	if err := db.Write(batch); err != nil {
+		t.Fatal(err)
+	}
+
+	// paging outgoing transfers
+	var cursor []byte
+	var got []*TxIndexItem
+	for {
+		items, nextCursor, err := txIndex.GetTxs(&from, TxDirectionOut, &TxIndexFilter{}, cursor, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, items...)
+		if nextCursor == nil {
+			break
+		}
+		cursor = nextCursor
+	}
+	if len(got) != len(blocks) {
+		t.Fatalf("should get %d items, got %d", len(blocks), len(got))
+	}
+	for i, item := range got {
+		if item.BlockHash != blocks[len(blocks)-1-i].Hash {
+			t.Fatalf("item %d should be newest first", i)
+		}
+	}
+
+	// incoming transfers with counterparty and time window
+	items, _, err := txIndex.GetTxs(&to, TxDirectionIn, &TxIndexFilter{Counterparty: &from, StartTime: 1002, EndTime: 1006}, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("should get 3 items, got %d", len(items))
+	}
+
+	// delete
+	batch = db.NewBatch()
+	for _, block := range blocks {
+		txIndex.DeleteBlock(batch, block)
+	}
+	if err := db.Write(batch); err != nil {
+		t.Fatal(err)
+	}
+	if isEmpty, err := txIndex.IsEmpty(); err != nil || !isEmpty {
+		t.Fatal("index should be empty after deleting", err)
+	}
+}
+
+func TestTxIndex_GetTxsScanLimit(t *testing.T) {
+	db := database.NewMemStore()
+
+	txIndex := NewTxIndex(db)
+	from, _, _ := types.CreateAddress()
+	to, _, _ := types.CreateAddress()
+	other, _, _ := types.CreateAddress()
+
+	batch := db.NewBatch()
+	for i := 0; i < MaxTxIndexScan+10; i++ {
+		timestamp := time.Unix(int64(1000+i), 0)
+		block := &ledger.AccountBlock{
+			BlockType:      ledger.BlockTypeSendCall,
+			AccountAddress: from,
+			ToAddress:      to,
+			TokenId:        ledger.ViteTokenId,
+			Amount:         big.NewInt(int64(i)),
+			Timestamp:      &timestamp,
+		}
+		block.Hash = block.ComputeHash()
+		txIndex.WriteBlock(batch, block)
+	}
+	if err := db.Write(batch); err != nil {
+		t.Fatal(err)
+	}
+
+	// no item matches, the scan stops with a cursor
+	filter := &TxIndexFilter{Counterparty: &other}
+	items, cursor, err := txIndex.GetTxs(&from, TxDirectionOut, filter, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 || cursor == nil {
+		t.Fatalf("should get no items and a cursor, got %d items", len(items))
+	}
+
+	items, cursor, err = txIndex.GetTxs(&from, TxDirectionOut, filter, cursor, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 || cursor != nil {
+		t.Fatalf("should get no items and no cursor, got %d items", len(items))
+	}
+}
+
+func TestIndexHeight(t *testing.T) {
+	indexHeight := NewIndexHeight(database.NewMemStore())
+
+	if height, err := indexHeight.GetHeight(database.DBKP_TX_INDEX); err != nil || height != 0 {
+		t.Fatalf("height should be 0, got %d, %v", height, err)
+	}
+	if err := indexHeight.SaveHeight(database.DBKP_TX_INDEX, 100); err != nil {
+		t.Fatal(err)
+	}
+	if height, err := indexHeight.GetHeight(database.DBKP_TX_INDEX); err != nil || height != 100 {
+		t.Fatalf("height should be 100, got %d, %v", height, err)
+	}
+	if height, err := indexHeight.GetHeight(database.DBKP_LOG_INDEX); err != nil || height != 0 {
+		t.Fatalf("height of another index should be 0, got %d, %v", height, err)
+	}
+}
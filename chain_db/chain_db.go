@@ -2,7 +2,6 @@ package chain_db
 
 import (
 	"errors"
-	"github.com/syndtr/goleveldb/leveldb"
 	errors2 "github.com/syndtr/goleveldb/leveldb/errors"
 	"github.com/vitelabs/go-vite/chain_db/access"
 	"github.com/vitelabs/go-vite/chain_db/database"
@@ -12,22 +11,38 @@ import (
 
 type ChainDb struct {
 	dbDir string
-	db    *leveldb.DB
+	db    database.KeyValueStore
+	// the db is kept in memory and dbDir isn't used
+	inMemory bool
 
-	Ac      *access.AccountChain
-	Sc      *access.SnapshotChain
-	Account *access.Account
-	Be      *access.BlockEvent
-	OnRoad  *access.OnRoad
+	// wipe the db if it's corrupted and can't be recovered
+	wipeCorrupted bool
+	// the db was corrupted and has been recovered, some data may be lost
+	recovered bool
+
+	Ac       *access.AccountChain
+	Sc       *access.SnapshotChain
+	Account  *access.Account
+	Be       *access.BlockEvent
+	OnRoad   *access.OnRoad
+	TxIndex  *access.TxIndex
+	LogIndex *access.LogIndex
+
+	IndexHeight *access.IndexHeight
+
+	ContractAbi *access.ContractAbi
 
 	log log15.Logger
 }
 
-func NewChainDb(dbDir string) *ChainDb {
+// NewChainDb opens the db in dbDir. The corrupted db is recovered, it's wiped only if wipeCorrupted is true and the
+// recovery failed.
+func NewChainDb(dbDir string, wipeCorrupted bool) *ChainDb {
 	cDb := &ChainDb{
 		log: log15.New("module", "chainDb"),
 
-		dbDir: dbDir,
+		dbDir:         dbDir,
+		wipeCorrupted: wipeCorrupted,
 	}
 
 	err := cDb.initDb()
@@ -39,16 +54,60 @@ func NewChainDb(dbDir string) *ChainDb {
 	return cDb
 }
 
+// NewReadOnlyChainDb opens the db in dbDir for reading, the corrupted db isn't recovered. It's for the tools which
+// only read the ledger, the db mustn't be written.
+func NewReadOnlyChainDb(dbDir string) (*ChainDb, error) {
+	db, err := database.NewReadOnlyLevelDb(dbDir)
+	if err != nil {
+		return nil, err
+	}
+
+	cDb := &ChainDb{
+		log: log15.New("module", "chainDb"),
+
+		dbDir: dbDir,
+	}
+	cDb.setDb(database.NewLevelDbStore(db))
+	return cDb, nil
+}
+
+// NewMemChainDb returns an empty db in memory, which is lost when it's closed. It's for the tests.
+func NewMemChainDb() *ChainDb {
+	cDb := &ChainDb{
+		log: log15.New("module", "chainDb"),
+
+		inMemory: true,
+	}
+	cDb.setDb(database.NewMemStore())
+	return cDb
+}
+
 func (chainDb *ChainDb) initDb() error {
+	if chainDb.inMemory {
+		chainDb.setDb(database.NewMemStore())
+		return nil
+	}
+
 	db, err := database.NewLevelDb(chainDb.dbDir)
 	if err != nil {
-		switch err.(type) {
-		case *errors2.ErrCorrupted:
-			return chainDb.ClearData()
-		default:
+		if !errors2.IsCorrupted(err) {
 			chainDb.log.Error("NewLevelDb failed, error is "+err.Error(), "method", "initDb")
 			return err
 		}
+
+		chainDb.log.Error("Db is corrupted, try to recover it, error is "+err.Error(), "method", "initDb")
+		var recoverErr error
+		db, recoverErr = database.RecoverLevelDb(chainDb.dbDir)
+		if recoverErr != nil {
+			chainDb.log.Error("RecoverLevelDb failed, error is "+recoverErr.Error(), "method", "initDb")
+			if !chainDb.wipeCorrupted {
+				return recoverErr
+			}
+
+			chainDb.log.Warn("Wipe the corrupted db", "method", "initDb")
+			return chainDb.ClearData()
+		}
+		chainDb.recovered = true
 	}
 
 	if db == nil {
@@ -56,14 +115,21 @@ func (chainDb *ChainDb) initDb() error {
 		chainDb.log.Error(err.Error(), "method", "initDb")
 		return err
 	}
+	chainDb.setDb(database.NewLevelDbStore(db))
+	return nil
+}
+
+func (chainDb *ChainDb) setDb(db database.KeyValueStore) {
 	chainDb.db = db
 	chainDb.Ac = access.NewAccountChain(db)
 	chainDb.Sc = access.NewSnapshotChain(db)
 	chainDb.Account = access.NewAccount(db)
 	chainDb.Be = access.NewBlockEvent(db)
 	chainDb.OnRoad = access.NewOnRoad(db)
-
-	return nil
+	chainDb.TxIndex = access.NewTxIndex(db)
+	chainDb.LogIndex = access.NewLogIndex(db)
+	chainDb.IndexHeight = access.NewIndexHeight(db)
+	chainDb.ContractAbi = access.NewContractAbi(db)
 }
 
 func (chainDb *ChainDb) ClearData() error {
@@ -73,18 +139,30 @@ func (chainDb *ChainDb) ClearData() error {
 		}
 	}
 
-	if err := os.RemoveAll(chainDb.dbDir); err != nil && err != os.ErrNotExist {
-		return errors.New("Remove " + chainDb.dbDir + " failed, error is " + err.Error())
+	if !chainDb.inMemory {
+		if err := os.RemoveAll(chainDb.dbDir); err != nil && err != os.ErrNotExist {
+			return errors.New("Remove " + chainDb.dbDir + " failed, error is " + err.Error())
+		}
 	}
 
 	chainDb.db = nil
+	chainDb.recovered = false
 	return chainDb.initDb()
 }
 
-func (chainDb *ChainDb) Db() *leveldb.DB {
+// Recovered returns true if the db was corrupted and has been recovered, the ledger should be checked before use.
+func (chainDb *ChainDb) Recovered() bool {
+	return chainDb.recovered
+}
+
+func (chainDb *ChainDb) Db() database.KeyValueStore {
 	return chainDb.db
 }
 
-func (chainDb *ChainDb) Commit(batch *leveldb.Batch) error {
-	return chainDb.db.Write(batch, nil)
+func (chainDb *ChainDb) NewBatch() database.Batch {
+	return chainDb.db.NewBatch()
+}
+
+func (chainDb *ChainDb) Commit(batch database.Batch) error {
+	return chainDb.db.Write(batch)
 }
@@ -5,15 +5,42 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/opt"
 )
 
+func levelDbOptions() *opt.Options {
+	return &opt.Options{
+		WriteBuffer:        64 * opt.MiB,
+		BlockCacheCapacity: 32 * opt.MiB,
+	}
+}
+
 //, &opt.Options{
 
 //BlockSize:           2 * opt.KiB,
 //}
 func NewLevelDb(dbDir string) (*leveldb.DB, error) {
-	db, err := leveldb.OpenFile(dbDir, &opt.Options{
-		WriteBuffer:        64 * opt.MiB,
-		BlockCacheCapacity: 32 * opt.MiB,
-	})
+	db, err := leveldb.OpenFile(dbDir, levelDbOptions())
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// NewReadOnlyLevelDb opens the existing db in dbDir for reading, nothing is written into dbDir. The corrupted db
+// isn't recovered.
+func NewReadOnlyLevelDb(dbDir string) (*leveldb.DB, error) {
+	options := levelDbOptions()
+	options.ReadOnly = true
+	options.ErrorIfMissing = true
+	db, err := leveldb.OpenFile(dbDir, options)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// RecoverLevelDb ignores the manifest of the corrupted db and rebuilds it from the table files, the keys of the
+// corrupted tables may be lost.
+func RecoverLevelDb(dbDir string) (*leveldb.DB, error) {
+	db, err := leveldb.RecoverFile(dbDir, levelDbOptions())
 	if err != nil {
 		return nil, err
 	}
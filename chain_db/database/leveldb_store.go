@@ -0,0 +1,84 @@
+package database
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+type levelDbStore struct {
+	db *leveldb.DB
+}
+
+// NewLevelDbStore adapts db to KeyValueStore.
+func NewLevelDbStore(db *leveldb.DB) KeyValueStore {
+	return &levelDbStore{
+		db: db,
+	}
+}
+
+func (s *levelDbStore) Get(key []byte) ([]byte, error) {
+	return s.db.Get(key, nil)
+}
+
+func (s *levelDbStore) Has(key []byte) (bool, error) {
+	return s.db.Has(key, nil)
+}
+
+func (s *levelDbStore) NewIterator(slice *util.Range) Iterator {
+	return s.db.NewIterator(slice, nil)
+}
+
+func (s *levelDbStore) Put(key []byte, value []byte) error {
+	return s.db.Put(key, value, nil)
+}
+
+func (s *levelDbStore) Delete(key []byte) error {
+	return s.db.Delete(key, nil)
+}
+
+func (s *levelDbStore) NewBatch() Batch {
+	return &levelDbBatch{
+		batch: new(leveldb.Batch),
+	}
+}
+
+func (s *levelDbStore) Write(batch Batch) error {
+	if b, ok := batch.(*levelDbBatch); ok {
+		return s.db.Write(b.batch, nil)
+	}
+
+	// the batch of another store
+	b := new(leveldb.Batch)
+	if err := batch.Replay(b); err != nil {
+		return err
+	}
+	return s.db.Write(b, nil)
+}
+
+func (s *levelDbStore) Close() error {
+	return s.db.Close()
+}
+
+type levelDbBatch struct {
+	batch *leveldb.Batch
+}
+
+func (b *levelDbBatch) Put(key []byte, value []byte) {
+	b.batch.Put(key, value)
+}
+
+func (b *levelDbBatch) Delete(key []byte) {
+	b.batch.Delete(key)
+}
+
+func (b *levelDbBatch) Len() int {
+	return b.batch.Len()
+}
+
+func (b *levelDbBatch) Reset() {
+	b.batch.Reset()
+}
+
+func (b *levelDbBatch) Replay(r BatchReplay) error {
+	return b.batch.Replay(r)
+}
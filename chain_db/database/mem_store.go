@@ -0,0 +1,250 @@
+package database
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// memStore keeps the keys in memory, it's for the tests which shouldn't touch the disk.
+type memStore struct {
+	lock sync.RWMutex
+
+	// keys are sorted
+	keys   []string
+	values map[string][]byte
+}
+
+// NewMemStore returns an empty KeyValueStore in memory.
+func NewMemStore() KeyValueStore {
+	return &memStore{
+		values: make(map[string][]byte),
+	}
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+func (s *memStore) Get(key []byte) ([]byte, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	value, ok := s.values[string(key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyBytes(value), nil
+}
+
+func (s *memStore) Has(key []byte) (bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	_, ok := s.values[string(key)]
+	return ok, nil
+}
+
+// search returns the index of the first key which isn't less than key.
+func (s *memStore) search(key string) int {
+	return sort.SearchStrings(s.keys, key)
+}
+
+func (s *memStore) NewIterator(slice *util.Range) Iterator {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	start, end := 0, len(s.keys)
+	if slice != nil {
+		if slice.Start != nil {
+			start = s.search(string(slice.Start))
+		}
+		if slice.Limit != nil {
+			end = s.search(string(slice.Limit))
+		}
+	}
+
+	iter := &memIterator{
+		pos: -1,
+	}
+	for i := start; i < end; i++ {
+		key := s.keys[i]
+		iter.keys = append(iter.keys, []byte(key))
+		iter.values = append(iter.values, copyBytes(s.values[key]))
+	}
+	return iter
+}
+
+func (s *memStore) put(key string, value []byte) {
+	if _, ok := s.values[key]; !ok {
+		i := s.search(key)
+		s.keys = append(s.keys, "")
+		copy(s.keys[i+1:], s.keys[i:])
+		s.keys[i] = key
+	}
+	// the empty value is kept as a non nil value
+	s.values[key] = append([]byte{}, value...)
+}
+
+func (s *memStore) delete(key string) {
+	if _, ok := s.values[key]; !ok {
+		return
+	}
+	i := s.search(key)
+	s.keys = append(s.keys[:i], s.keys[i+1:]...)
+	delete(s.values, key)
+}
+
+func (s *memStore) Put(key []byte, value []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.put(string(key), value)
+	return nil
+}
+
+func (s *memStore) Delete(key []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.delete(string(key))
+	return nil
+}
+
+func (s *memStore) NewBatch() Batch {
+	return &memBatch{}
+}
+
+func (s *memStore) Write(batch Batch) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return batch.Replay(memStoreWriter{s})
+}
+
+func (s *memStore) Close() error {
+	return nil
+}
+
+// memStoreWriter applies a batch to the store, the lock of the store is held by the caller.
+type memStoreWriter struct {
+	s *memStore
+}
+
+func (w memStoreWriter) Put(key []byte, value []byte) {
+	w.s.put(string(key), value)
+}
+
+func (w memStoreWriter) Delete(key []byte) {
+	w.s.delete(string(key))
+}
+
+type memBatchOp struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+type memBatch struct {
+	ops []memBatchOp
+}
+
+func (b *memBatch) Put(key []byte, value []byte) {
+	b.ops = append(b.ops, memBatchOp{key: copyBytes(key), value: append([]byte{}, value...)})
+}
+
+func (b *memBatch) Delete(key []byte) {
+	b.ops = append(b.ops, memBatchOp{key: copyBytes(key), delete: true})
+}
+
+func (b *memBatch) Len() int {
+	return len(b.ops)
+}
+
+func (b *memBatch) Reset() {
+	b.ops = b.ops[:0]
+}
+
+func (b *memBatch) Replay(r BatchReplay) error {
+	for _, op := range b.ops {
+		if op.delete {
+			r.Delete(op.key)
+		} else {
+			r.Put(op.key, op.value)
+		}
+	}
+	return nil
+}
+
+// memIterator iterates the copy of the keys taken when it's created.
+type memIterator struct {
+	keys   [][]byte
+	values [][]byte
+	pos    int
+}
+
+func (iter *memIterator) valid() bool {
+	return iter.pos >= 0 && iter.pos < len(iter.keys)
+}
+
+func (iter *memIterator) First() bool {
+	iter.pos = 0
+	return iter.valid()
+}
+
+func (iter *memIterator) Last() bool {
+	iter.pos = len(iter.keys) - 1
+	return iter.valid()
+}
+
+func (iter *memIterator) Seek(key []byte) bool {
+	iter.pos = sort.Search(len(iter.keys), func(i int) bool {
+		return string(iter.keys[i]) >= string(key)
+	})
+	return iter.valid()
+}
+
+func (iter *memIterator) Next() bool {
+	if iter.pos < len(iter.keys) {
+		iter.pos++
+	}
+	return iter.valid()
+}
+
+// Prev moves to the last key after the end, and stays before the first key, as the iterator of leveldb does.
+func (iter *memIterator) Prev() bool {
+	if iter.pos >= len(iter.keys) {
+		iter.pos = len(iter.keys) - 1
+	} else if iter.pos >= 0 {
+		iter.pos--
+	}
+	return iter.valid()
+}
+
+func (iter *memIterator) Key() []byte {
+	if !iter.valid() {
+		return nil
+	}
+	return iter.keys[iter.pos]
+}
+
+func (iter *memIterator) Value() []byte {
+	if !iter.valid() {
+		return nil
+	}
+	return iter.values[iter.pos]
+}
+
+func (iter *memIterator) Release() {
+	iter.keys = nil
+	iter.values = nil
+	iter.pos = -1
+}
+
+func (iter *memIterator) Error() error {
+	return nil
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+func collectKeys(iter Iterator, forward bool) []string {
+	defer iter.Release()
+
+	var keys []string
+	if forward {
+		for iter.Next() {
+			keys = append(keys, string(iter.Key()))
+		}
+	} else {
+		for ok := iter.Last(); ok; ok = iter.Prev() {
+			keys = append(keys, string(iter.Key()))
+		}
+	}
+	return keys
+}
+
+func equalKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestMemStore runs the same operations on the memory store and the leveldb store and compares the results.
+func TestMemStore(t *testing.T) {
+	dbDir, err := ioutil.TempDir("", "mem_store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dbDir)
+
+	db, err := NewLevelDb(dbDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	levelDbStore := NewLevelDbStore(db)
+	defer levelDbStore.Close()
+
+	stores := []KeyValueStore{NewMemStore(), levelDbStore}
+	for _, store := range stores {
+		for _, key := range []string{"b2", "a1", "b1", "c1", "b3"} {
+			if err := store.Put([]byte(key), []byte("v"+key)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		batch := store.NewBatch()
+		batch.Put([]byte("b4"), []byte("vb4"))
+		batch.Delete([]byte("b2"))
+		batch.Put([]byte("a1"), []byte("new"))
+		if batch.Len() != 3 {
+			t.Fatalf("batch length should be 3, got %d", batch.Len())
+		}
+		if err := store.Write(batch); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Delete([]byte("c1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the batch of another store is replayed
+	batch := stores[1].NewBatch()
+	batch.Put([]byte("d1"), []byte("vd1"))
+	if err := stores[0].Write(batch); err != nil {
+		t.Fatal(err)
+	}
+	if err := stores[1].Write(batch); err != nil {
+		t.Fatal(err)
+	}
+
+	var results [][]string
+	for _, store := range stores {
+		if value, err := store.Get([]byte("a1")); err != nil || !bytes.Equal(value, []byte("new")) {
+			t.Fatalf("value of a1 should be new, got %s, %v", value, err)
+		}
+		if _, err := store.Get([]byte("b2")); err != ErrNotFound {
+			t.Fatalf("deleted key should be not found, got %v", err)
+		}
+		if ok, _ := store.Has([]byte("b4")); !ok {
+			t.Fatal("b4 should exist")
+		}
+
+		iter := store.NewIterator(util.BytesPrefix([]byte("b")))
+		// the iterator isn't affected by the later writes
+		if err := store.Put([]byte("b5"), []byte("vb5")); err != nil {
+			t.Fatal(err)
+		}
+
+		result := collectKeys(iter, true)
+		result = append(result, collectKeys(store.NewIterator(&util.Range{Start: []byte("b3")}), false)...)
+
+		iter = store.NewIterator(nil)
+		if !iter.Seek([]byte("b")) || string(iter.Key()) != "b1" {
+			t.Fatalf("seek should move to b1, got %s", iter.Key())
+		}
+		if !iter.Last() || string(iter.Key()) != "d1" || iter.Next() || !iter.Prev() || string(iter.Key()) != "d1" {
+			t.Fatal("iterator should move back to the last key after the end")
+		}
+		if !iter.First() || iter.Prev() || !iter.Next() || string(iter.Key()) != "a1" {
+			t.Fatal("iterator should move forward to the first key before the start")
+		}
+		iter.Release()
+		if iter := store.NewIterator(nil); iter.Prev() {
+			t.Fatal("prev of the new iterator should fail")
+		}
+
+		results = append(results, result)
+	}
+
+	expected := []string{"b1", "b3", "b4", "d1", "b5", "b4", "b3"}
+	if !equalKeys(results[0], expected) || !equalKeys(results[1], expected) {
+		t.Fatalf("keys should be %v, got %v and %v", expected, results[0], results[1])
+	}
+}
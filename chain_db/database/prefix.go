@@ -34,4 +34,14 @@ const (
 	DBKP_BLOCK_EVENT = byte(16)
 
 	DBKP_BE_SNAPSHOT = byte(17)
+
+	DBKP_TX_INDEX = byte(18)
+
+	DBKP_CONTRACT_ABI = byte(19)
+
+	DBKP_LOG_INDEX = byte(20)
+
+	DBKP_INDEX_HEIGHT = byte(21)
+
+	DBKP_LOG_TOPIC_INDEX = byte(22)
 )
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// ErrNotFound is returned by the stores if the key doesn't exist, it's the same error as leveldb.ErrNotFound.
+var ErrNotFound = leveldb.ErrNotFound
+
+// KeyValueReader reads a sorted key value store.
+type KeyValueReader interface {
+	// Get returns ErrNotFound if the key doesn't exist
+	Get(key []byte) ([]byte, error)
+	Has(key []byte) (bool, error)
+
+	// NewIterator returns an iterator of the keys in slice in ascending order, nil slice means all of the keys. The
+	// iterator isn't affected by the writes after it's created.
+	NewIterator(slice *util.Range) Iterator
+}
+
+// KeyValueStore is the storage engine of the ledger.
+type KeyValueStore interface {
+	KeyValueReader
+
+	Put(key []byte, value []byte) error
+	Delete(key []byte) error
+
+	NewBatch() Batch
+	// Write applies the batch atomically
+	Write(batch Batch) error
+
+	Close() error
+}
+
+// BatchReplay receives the operations of a batch in order.
+type BatchReplay interface {
+	Put(key []byte, value []byte)
+	Delete(key []byte)
+}
+
+// Batch collects the writes which are applied to a store together.
+type Batch interface {
+	Put(key []byte, value []byte)
+	Delete(key []byte)
+
+	// Len returns the number of the operations in the batch
+	Len() int
+	Reset()
+
+	Replay(r BatchReplay) error
+}
+
+// Iterator iterates the keys of a store, it must be released after use.
+type Iterator interface {
+	First() bool
+	Last() bool
+	Seek(key []byte) bool
+	Next() bool
+	Prev() bool
+
+	Key() []byte
+	Value() []byte
+
+	Release()
+	Error() error
+}
@@ -0,0 +1,770 @@
+package checkdb
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/vitelabs/go-vite/chain_db"
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/trie"
+	"github.com/vitelabs/go-vite/vm"
+)
+
+const (
+	accountIndexKeySize  = 1 + 8
+	accountKeySize       = 1 + types.AddressSize
+	snapshotBlockKeySize = 1 + 8 + types.HashSize
+	accountBlockKeySize  = 1 + 8 + 8 + types.HashSize
+	onroadKeySize        = 1 + types.AddressSize + types.HashSize
+)
+
+var checkLog = log15.New("module", "checkdb")
+
+// Genesis is the genesis blocks of the ledger, which aren't signed.
+type Genesis struct {
+	SnapshotBlock       *ledger.SnapshotBlock
+	SecondSnapshotBlock *ledger.SnapshotBlock
+	AccountBlocks       []*ledger.AccountBlock
+}
+
+// checker walks the records of the ledger and cross-checks them, the inconsistencies are added to the report.
+type checker struct {
+	chainDb *chain_db.ChainDb
+	genesis *Genesis
+	report  *Report
+
+	// the snapshot blocks below fromHeight and the account blocks confirmed by them aren't checked
+	fromHeight uint64
+
+	accounts    map[types.Address]*ledger.Account
+	accountList []*ledger.Account
+
+	// the highest account block height in the snapshot contents of each account
+	contentHeights map[types.Address]uint64
+	genesisBlocks  map[types.Hash]struct{}
+	latestHeight   uint64
+
+	// the recomputed state trie of the last snapshot block, it's reused for the next one
+	stateTrie *trie.Trie
+}
+
+// Check walks the snapshot chain, the account chains, the block metas, the confirm heights, the on road metas, the
+// log lists and the account id index of chainDb, and reports all the inconsistencies found, at most maxIssues of them
+// are listed. The state hash of a snapshot block is recomputed from the state of the previous one, unless that state
+// has been pruned. chainDb is only read.
+func Check(chainDb *chain_db.ChainDb, genesis *Genesis, maxIssues int) *Report {
+	ck := newChecker(chainDb, genesis, 1, maxIssues)
+
+	if ck.chainDb.Recovered() {
+		ck.addIssue(&Issue{Kind: IssueDb, Message: "ledger db was corrupted and has been recovered from its table files"})
+	}
+
+	checkLog.Info("Check the account id index")
+	ck.checkAccountIndex()
+	checkLog.Info("Check the snapshot chain")
+	ck.checkSnapshotChain()
+	checkLog.Info("Check the account chains")
+	ck.checkAccountChains()
+	checkLog.Info("Check the on road blocks")
+	ck.checkOnroad()
+
+	return ck.report
+}
+
+// CheckTail is Check of the tail of the ledger, the snapshot blocks from fromHeight up, and the account blocks which
+// aren't confirmed below fromHeight. The ledger below is assumed to be consistent, the on road metas aren't checked.
+// The issues found in the snapshot chain carry the snapshot height, and the issues found in the account chains carry
+// the address and the height.
+func CheckTail(chainDb *chain_db.ChainDb, genesis *Genesis, fromHeight uint64, maxIssues int) *Report {
+	ck := newChecker(chainDb, genesis, fromHeight, maxIssues)
+
+	ck.checkAccountIndex()
+	ck.checkSnapshotChain()
+	ck.checkAccountChains()
+
+	return ck.report
+}
+
+func newChecker(chainDb *chain_db.ChainDb, genesis *Genesis, fromHeight uint64, maxIssues int) *checker {
+	ck := &checker{
+		chainDb:    chainDb,
+		genesis:    genesis,
+		report:     &Report{MaxIssues: maxIssues},
+		fromHeight: fromHeight,
+
+		accounts:       make(map[types.Address]*ledger.Account),
+		contentHeights: make(map[types.Address]uint64),
+		genesisBlocks:  make(map[types.Hash]struct{}, len(genesis.AccountBlocks)),
+	}
+	for _, block := range genesis.AccountBlocks {
+		ck.genesisBlocks[block.Hash] = struct{}{}
+	}
+	return ck
+}
+
+func (ck *checker) addIssue(issue *Issue) {
+	ck.report.addIssue(issue)
+}
+
+func (ck *checker) dbIssue(err error, method string) {
+	ck.addIssue(&Issue{Kind: IssueDb, Message: fmt.Sprintf("%s failed, error is %s", method, err)})
+}
+
+// imported reports whether the ledger is imported from a ledger snapshot, the blocks below the base snapshot block
+// aren't in the ledger then.
+func (ck *checker) imported() bool {
+	return ck.report.BaseSnapshotHeight > ck.genesis.SecondSnapshotBlock.Height+1
+}
+
+// isGenesisBlock reports whether block is one of the genesis account blocks, which aren't signed.
+func (ck *checker) isGenesisBlock(block *ledger.AccountBlock) bool {
+	_, ok := ck.genesisBlocks[block.Hash]
+	return ok
+}
+
+func (ck *checker) hasTrieNode(hash *types.Hash) bool {
+	key, _ := database.EncodeKey(database.DBKP_TRIE_NODE, hash.Bytes())
+	ok, err := ck.chainDb.Db().Has(key)
+	if err != nil {
+		ck.dbIssue(err, "Has trie node")
+		return false
+	}
+	return ok
+}
+
+// genStateTrie returns the state trie of the snapshot block with content above the state of prevStateHash, nil if that
+// state has been pruned. The state trie is only loaded from db if the trie recomputed last isn't the previous state.
+func (ck *checker) genStateTrie(prevStateHash *types.Hash, content ledger.SnapshotContent) (*trie.Trie, error) {
+	stateTrie := ck.stateTrie
+	ck.stateTrie = nil
+	if stateTrie == nil || stateTrie.Hash() == nil || *stateTrie.Hash() != *prevStateHash {
+		if !ck.hasTrieNode(prevStateHash) {
+			return nil, nil
+		}
+		stateTrie = trie.NewTrie(ck.chainDb.Db(), prevStateHash, nil)
+	}
+
+	for addr, hashHeight := range content {
+		block, err := ck.chainDb.Ac.GetBlock(&hashHeight.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if block != nil {
+			stateTrie.SetValue(addr.Bytes(), block.StateHash.Bytes())
+		}
+	}
+	ck.stateTrie = stateTrie
+	return stateTrie, nil
+}
+
+// isContract reports whether the account is a contract, of which the send blocks aren't signed.
+func (ck *checker) isContract(account *ledger.Account) bool {
+	if vm.IsPrecompiledContractAddress(account.AccountAddress) {
+		return true
+	}
+	gid, err := ck.chainDb.Ac.GetContractGid(account.AccountId)
+	if err != nil {
+		ck.dbIssue(err, "GetContractGid")
+		return false
+	}
+	return gid != nil
+}
+
+func (ck *checker) checkAccountIndex() {
+	db := ck.chainDb.Db()
+
+	prefix, _ := database.EncodeKey(database.DBKP_ACCOUNTID_INDEX)
+	iter := db.NewIterator(util.BytesPrefix(prefix))
+	lastAccountId := uint64(0)
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != accountIndexKeySize {
+			ck.addIssue(&Issue{Kind: IssueAccountIndex, Message: fmt.Sprintf("key %x of the account id index is invalid", key)})
+			continue
+		}
+		accountId := binary.BigEndian.Uint64(key[1:])
+		if accountId != lastAccountId+1 {
+			ck.addIssue(&Issue{Kind: IssueAccountIndex, Message: fmt.Sprintf("account ids %d to %d are missing", lastAccountId+1, accountId-1)})
+		}
+		lastAccountId = accountId
+
+		addr, err := types.BytesToAddress(iter.Value())
+		if err != nil {
+			ck.addIssue(&Issue{Kind: IssueAccountIndex, Message: fmt.Sprintf("address of account id %d is invalid", accountId)})
+			continue
+		}
+		if _, ok := ck.accounts[addr]; ok {
+			ck.addIssue(&Issue{Kind: IssueAccountIndex, Address: &addr, Message: fmt.Sprintf("account id %d is a duplicate", accountId)})
+			continue
+		}
+
+		account, err := ck.chainDb.Account.GetAccountByAddress(&addr)
+		if err != nil {
+			ck.addIssue(&Issue{Kind: IssueAccountIndex, Address: &addr, Message: "account is invalid, error is " + err.Error()})
+			continue
+		}
+		if account == nil {
+			ck.addIssue(&Issue{Kind: IssueAccountIndex, Address: &addr, Message: fmt.Sprintf("account of account id %d is missing", accountId)})
+			continue
+		}
+		if account.AccountId != accountId {
+			ck.addIssue(&Issue{Kind: IssueAccountIndex, Address: &addr, Message: fmt.Sprintf("account id is %d, but %d in the account id index", account.AccountId, accountId)})
+			continue
+		}
+		ck.accounts[addr] = account
+		ck.accountList = append(ck.accountList, account)
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil && err != database.ErrNotFound {
+		ck.dbIssue(err, "Iterate account id index")
+	}
+
+	prefix, _ = database.EncodeKey(database.DBKP_ACCOUNT)
+	iter = db.NewIterator(util.BytesPrefix(prefix))
+	for iter.Next() {
+		key := iter.Key()
+		addr, err := types.BytesToAddress(key[1:])
+		if len(key) != accountKeySize || err != nil {
+			ck.addIssue(&Issue{Kind: IssueAccountIndex, Message: fmt.Sprintf("key %x of the account is invalid", key)})
+			continue
+		}
+		if _, ok := ck.accounts[addr]; !ok {
+			ck.addIssue(&Issue{Kind: IssueAccountIndex, Address: &addr, Message: "account isn't in the account id index"})
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil && err != database.ErrNotFound {
+		ck.dbIssue(err, "Iterate accounts")
+	}
+
+	ck.report.Accounts = uint64(len(ck.accountList))
+}
+
+func (ck *checker) checkSnapshotChain() {
+	prefix, _ := database.EncodeKey(database.DBKP_SNAPSHOTBLOCK)
+	startKey, _ := database.EncodeKey(database.DBKP_SNAPSHOTBLOCK, ck.fromHeight)
+	iter := ck.chainDb.Db().NewIterator(&util.Range{Start: startKey, Limit: util.BytesPrefix(prefix).Limit})
+	defer iter.Release()
+
+	var prevBlock, latestBlock *ledger.SnapshotBlock
+	if ck.fromHeight > 1 {
+		ck.report.BaseSnapshotHeight = ck.baseSnapshotHeight()
+
+		// the tail is linked to the block below it
+		block, err := ck.chainDb.Sc.GetSnapshotBlock(ck.fromHeight-1, false)
+		if err != nil {
+			ck.dbIssue(err, "GetSnapshotBlock")
+		}
+		prevBlock, latestBlock = block, block
+	}
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != snapshotBlockKeySize {
+			ck.addIssue(&Issue{Kind: IssueSnapshotBlock, Message: fmt.Sprintf("key %x of the snapshot block is invalid", key)})
+			continue
+		}
+		height := binary.BigEndian.Uint64(key[1:9])
+		hash, _ := types.BytesToHash(key[9:])
+
+		block := &ledger.SnapshotBlock{}
+		if err := block.Deserialize(iter.Value()); err != nil {
+			ck.addIssue(&Issue{Kind: IssueSnapshotBlock, SnapshotHeight: height, Hash: &hash, Message: "snapshot block is invalid, error is " + err.Error()})
+			prevBlock = nil
+			continue
+		}
+		block.Hash = hash
+
+		if ck.checkSnapshotBlock(prevBlock, latestBlock, height, block) {
+			prevBlock = block
+		} else {
+			prevBlock = nil
+		}
+		latestBlock = block
+		ck.report.SnapshotBlocks++
+
+		if ck.report.SnapshotBlocks%10000 == 0 {
+			checkLog.Info(fmt.Sprintf("Snapshot chain is checked to height %d", height))
+		}
+	}
+	if err := iter.Error(); err != nil && err != database.ErrNotFound {
+		ck.dbIssue(err, "Iterate snapshot blocks")
+	}
+
+	if latestBlock == nil {
+		ck.addIssue(&Issue{Kind: IssueSnapshotBlock, Message: "ledger has no snapshot block"})
+		return
+	}
+	ck.latestHeight = latestBlock.Height
+	ck.report.LatestSnapshotHeight = latestBlock.Height
+
+	if !ck.hasTrieNode(&latestBlock.StateHash) {
+		ck.addIssue(&Issue{Kind: IssueState, SnapshotHeight: latestBlock.Height, Hash: &latestBlock.Hash, Message: "state of the latest snapshot block is missing"})
+	}
+}
+
+// baseSnapshotHeight returns the height of the first snapshot block above the genesis blocks.
+func (ck *checker) baseSnapshotHeight() uint64 {
+	prefix, _ := database.EncodeKey(database.DBKP_SNAPSHOTBLOCK)
+	startKey, _ := database.EncodeKey(database.DBKP_SNAPSHOTBLOCK, ck.genesis.SecondSnapshotBlock.Height+1)
+	iter := ck.chainDb.Db().NewIterator(&util.Range{Start: startKey, Limit: util.BytesPrefix(prefix).Limit})
+	defer iter.Release()
+
+	if !iter.Next() || len(iter.Key()) != snapshotBlockKeySize {
+		return 0
+	}
+	return binary.BigEndian.Uint64(iter.Key()[1:9])
+}
+
+// checkSnapshotBlock checks the snapshot block of height, prevBlock is the consistent block below it, and lastBlock
+// is the last block iterated. It reports whether the block is consistent enough to be the previous block of the next.
+func (ck *checker) checkSnapshotBlock(prevBlock, lastBlock *ledger.SnapshotBlock, height uint64, block *ledger.SnapshotBlock) bool {
+	issue := func(kind string, message string) {
+		ck.addIssue(&Issue{Kind: kind, SnapshotHeight: height, Hash: &block.Hash, Message: message})
+	}
+	consistent := true
+
+	if block.Height != height {
+		issue(IssueSnapshotBlock, fmt.Sprintf("height of the snapshot block is %d", block.Height))
+		consistent = false
+	}
+
+	linked := false
+	switch {
+	case lastBlock == nil:
+		if height != 1 {
+			issue(IssueSnapshotBlock, fmt.Sprintf("snapshot blocks 1 to %d are missing", height-1))
+		}
+	case height == lastBlock.Height:
+		issue(IssueSnapshotBlock, "snapshot block is a duplicate")
+		consistent = false
+	case height == lastBlock.Height+1:
+		linked = prevBlock != nil
+	case lastBlock.Height == ck.genesis.SecondSnapshotBlock.Height && ck.report.BaseSnapshotHeight == 0:
+		// imported from a ledger snapshot
+	default:
+		issue(IssueSnapshotBlock, fmt.Sprintf("snapshot blocks %d to %d are missing", lastBlock.Height+1, height-1))
+	}
+	if height > ck.genesis.SecondSnapshotBlock.Height && ck.report.BaseSnapshotHeight == 0 {
+		ck.report.BaseSnapshotHeight = height
+	}
+
+	if block.ComputeHash() != block.Hash {
+		issue(IssueSnapshotBlock, "hash of the snapshot block is invalid")
+		consistent = false
+	}
+	if linked && block.PrevHash != prevBlock.Hash {
+		issue(IssueSnapshotBlock, "snapshot block isn't linked to the previous snapshot block")
+	}
+
+	switch height {
+	case ck.genesis.SnapshotBlock.Height:
+		if block.Hash != ck.genesis.SnapshotBlock.Hash {
+			issue(IssueSnapshotBlock, "genesis snapshot block doesn't match")
+		}
+	case ck.genesis.SecondSnapshotBlock.Height:
+		if block.Hash != ck.genesis.SecondSnapshotBlock.Hash {
+			issue(IssueSnapshotBlock, "second snapshot block doesn't match")
+		}
+	default:
+		if len(block.Signature) == 0 || len(block.PublicKey) == 0 || !block.VerifySignature() {
+			issue(IssueSignature, "signature of the snapshot block is invalid")
+		}
+	}
+
+	if hashHeight, err := ck.chainDb.Sc.GetSnapshotBlockHeight(&block.Hash); err != nil {
+		ck.dbIssue(err, "GetSnapshotBlockHeight")
+	} else if hashHeight != height {
+		issue(IssueSnapshotBlock, fmt.Sprintf("height of the snapshot block is %d in the hash index", hashHeight))
+	}
+
+	content, ok := ck.checkSnapshotContent(height, block)
+	if !ok {
+		consistent = false
+	}
+
+	if linked && ok && height > ck.genesis.SecondSnapshotBlock.Height {
+		if stateTrie, err := ck.genStateTrie(&prevBlock.StateHash, content); err != nil {
+			ck.dbIssue(err, "genStateTrie")
+		} else if stateTrie == nil {
+			ck.report.SkippedStates++
+		} else {
+			ck.report.CheckedStates++
+			if stateHash := stateTrie.Hash(); stateHash == nil || *stateHash != block.StateHash {
+				issue(IssueState, "state hash of the snapshot block doesn't match the recomputed state")
+			}
+		}
+	}
+	return consistent
+}
+
+// checkSnapshotContent checks that the account blocks in the snapshot content exist and are confirmed by the
+// snapshot block, it reports whether the content exists.
+func (ck *checker) checkSnapshotContent(height uint64, block *ledger.SnapshotBlock) (ledger.SnapshotContent, bool) {
+	if hasContent, err := ck.chainDb.Sc.HasSnapshotContent(height); err != nil {
+		ck.dbIssue(err, "HasSnapshotContent")
+		return nil, false
+	} else if !hasContent {
+		ck.addIssue(&Issue{Kind: IssueSnapshotContent, SnapshotHeight: height, Hash: &block.Hash, Message: "snapshot content is missing"})
+		return nil, false
+	}
+
+	content, err := ck.chainDb.Sc.GetSnapshotContent(height)
+	if err != nil {
+		ck.dbIssue(err, "GetSnapshotContent")
+		return nil, false
+	}
+
+	for addr, hashHeight := range content {
+		addr := addr
+		issue := func(kind string, message string) {
+			ck.addIssue(&Issue{Kind: kind, SnapshotHeight: height, Address: &addr, Height: hashHeight.Height, Hash: &hashHeight.Hash, Message: message})
+		}
+
+		account, ok := ck.accounts[addr]
+		if !ok {
+			issue(IssueSnapshotContent, "account of the snapshot content is missing")
+			continue
+		}
+		if hashHeight.Height <= ck.contentHeights[addr] {
+			issue(IssueSnapshotContent, fmt.Sprintf("snapshot content is below the block %d confirmed before", ck.contentHeights[addr]))
+		} else {
+			ck.contentHeights[addr] = hashHeight.Height
+		}
+
+		meta, err := ck.chainDb.Ac.GetBlockMeta(&hashHeight.Hash)
+		if err != nil {
+			ck.dbIssue(err, "GetBlockMeta")
+			continue
+		}
+		if meta == nil {
+			issue(IssueSnapshotContent, "account block of the snapshot content is missing")
+			continue
+		}
+		if meta.AccountId != account.AccountId || meta.Height != hashHeight.Height {
+			issue(IssueSnapshotContent, "account block of the snapshot content doesn't match its meta")
+		}
+		if meta.SnapshotHeight != height {
+			issue(IssueBeSnapshot, fmt.Sprintf("confirm height of the account block is %d", meta.SnapshotHeight))
+		}
+	}
+	return content, true
+}
+
+func (ck *checker) checkAccountChains() {
+	for _, account := range ck.accountList {
+		ck.checkAccountChain(account, ck.tailHeight(account))
+	}
+}
+
+// tailHeight returns the height of the first account block of the account which isn't confirmed below fromHeight.
+func (ck *checker) tailHeight(account *ledger.Account) uint64 {
+	if ck.fromHeight <= 1 {
+		return 1
+	}
+
+	prefix, _ := database.EncodeKey(database.DBKP_ACCOUNTBLOCK, account.AccountId)
+	iter := ck.chainDb.Db().NewIterator(util.BytesPrefix(prefix))
+	defer iter.Release()
+
+	// the latest block confirmed by a snapshot block is in its snapshot content, which has the confirm height
+	for ok := iter.Last(); ok; ok = iter.Prev() {
+		key := iter.Key()
+		if len(key) != accountBlockKeySize {
+			continue
+		}
+		hash, _ := types.BytesToHash(key[17:])
+		meta, err := ck.chainDb.Ac.GetBlockMeta(&hash)
+		if err != nil {
+			ck.dbIssue(err, "GetBlockMeta")
+			return 1
+		}
+		if meta != nil && meta.SnapshotHeight > 0 && meta.SnapshotHeight < ck.fromHeight {
+			return binary.BigEndian.Uint64(key[9:17]) + 1
+		}
+	}
+	return 1
+}
+
+// checkAccountChain checks the account blocks of the account from fromHeight up.
+func (ck *checker) checkAccountChain(account *ledger.Account, fromHeight uint64) {
+	addr := account.AccountAddress
+	prefix, _ := database.EncodeKey(database.DBKP_ACCOUNTBLOCK, account.AccountId)
+	startKey, _ := database.EncodeKey(database.DBKP_ACCOUNTBLOCK, account.AccountId, fromHeight)
+	iter := ck.chainDb.Db().NewIterator(&util.Range{Start: startKey, Limit: util.BytesPrefix(prefix).Limit})
+	defer iter.Release()
+
+	isContract := ck.isContract(account)
+
+	var prevBlock, latestBlock *ledger.AccountBlock
+	if fromHeight > 1 {
+		// the tail is linked to the block below it
+		block, err := ck.chainDb.Ac.GetBlockByHeight(account.AccountId, fromHeight-1)
+		if err != nil {
+			ck.dbIssue(err, "GetBlockByHeight")
+		}
+		prevBlock, latestBlock = block, block
+	}
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != accountBlockKeySize {
+			ck.addIssue(&Issue{Kind: IssueAccountBlock, Address: &addr, Message: fmt.Sprintf("key %x of the account block is invalid", key)})
+			continue
+		}
+		height := binary.BigEndian.Uint64(key[9:17])
+		hash, _ := types.BytesToHash(key[17:])
+		issue := func(kind string, message string) {
+			ck.addIssue(&Issue{Kind: kind, Address: &addr, Height: height, Hash: &hash, Message: message})
+		}
+
+		linked := false
+		switch {
+		case latestBlock == nil:
+			// the account chains of an imported ledger start at the latest blocks confirmed by the base snapshot block
+			if height != 1 && !ck.imported() {
+				issue(IssueAccountBlock, fmt.Sprintf("account blocks 1 to %d are missing", height-1))
+			}
+		case height == latestBlock.Height:
+			issue(IssueAccountBlock, "account block is a duplicate")
+			continue
+		case height == latestBlock.Height+1:
+			linked = prevBlock != nil
+		default:
+			issue(IssueAccountBlock, fmt.Sprintf("account blocks %d to %d are missing", latestBlock.Height+1, height-1))
+		}
+
+		block := &ledger.AccountBlock{}
+		if err := block.DbDeserialize(iter.Value()); err != nil {
+			issue(IssueAccountBlock, "account block is invalid, error is "+err.Error())
+			prevBlock = nil
+			continue
+		}
+		block.Hash = hash
+		block.AccountAddress = addr
+		if len(block.PublicKey) <= 0 && len(block.Signature) > 0 {
+			block.PublicKey = account.PublicKey
+		}
+
+		consistent := true
+		if block.Height != height {
+			issue(IssueAccountBlock, fmt.Sprintf("height of the account block is %d", block.Height))
+			consistent = false
+		}
+		if block.Timestamp == nil || block.ComputeHash() != block.Hash {
+			issue(IssueAccountBlock, "hash of the account block is invalid")
+			consistent = false
+		}
+		if linked && block.PrevHash != prevBlock.Hash {
+			issue(IssueAccountBlock, "account block isn't linked to the previous account block")
+		}
+		if !ck.isGenesisBlock(block) {
+			ck.checkSignature(block, isContract, issue)
+		}
+
+		ck.checkAccountBlockMeta(account, block, issue)
+		ck.checkLogList(block, issue)
+
+		if consistent {
+			prevBlock = block
+		} else {
+			prevBlock = nil
+		}
+		latestBlock = block
+		ck.report.AccountBlocks++
+
+		if ck.report.AccountBlocks%100000 == 0 {
+			checkLog.Info(fmt.Sprintf("%d account blocks are checked", ck.report.AccountBlocks))
+		}
+	}
+	if err := iter.Error(); err != nil && err != database.ErrNotFound {
+		ck.dbIssue(err, "Iterate account blocks")
+	}
+
+	if latestBlock != nil && !ck.hasTrieNode(&latestBlock.StateHash) {
+		ck.addIssue(&Issue{Kind: IssueState, Address: &addr, Height: latestBlock.Height, Hash: &latestBlock.Hash, Message: "state of the latest account block is missing"})
+	}
+}
+
+// checkSignature checks the signature of block, only the send blocks of contracts may be unsigned, the blocks of a
+// general account are signed by the account.
+func (ck *checker) checkSignature(block *ledger.AccountBlock, isContract bool, issue func(kind string, message string)) {
+	if isContract && block.IsSendBlock() && len(block.Signature) == 0 && len(block.PublicKey) == 0 {
+		return
+	}
+	if len(block.Signature) == 0 || len(block.PublicKey) == 0 {
+		issue(IssueSignature, "account block isn't signed")
+		return
+	}
+	if !block.VerifySignature() {
+		issue(IssueSignature, "signature of the account block is invalid")
+		return
+	}
+	if !isContract && types.PubkeyToAddress(block.PublicKey) != block.AccountAddress {
+		issue(IssueSignature, "account block isn't signed by the account")
+	}
+}
+
+// isChainBase reports whether the block of meta is the first block in an account chain of an imported ledger, the
+// blocks received by it or receiving it may be missing.
+func (ck *checker) isChainBase(meta *ledger.AccountBlockMeta) bool {
+	if !ck.imported() || meta.Height <= 1 {
+		return false
+	}
+	prevBlock, err := ck.chainDb.Ac.GetBlockByHeight(meta.AccountId, meta.Height-1)
+	return err == nil && prevBlock == nil
+}
+
+func (ck *checker) checkAccountBlockMeta(account *ledger.Account, block *ledger.AccountBlock, issue func(kind string, message string)) {
+	chainDb := ck.chainDb
+
+	meta, err := chainDb.Ac.GetBlockMeta(&block.Hash)
+	if err != nil {
+		ck.dbIssue(err, "GetBlockMeta")
+		return
+	}
+	if meta == nil {
+		issue(IssueBlockMeta, "meta of the account block is missing")
+		return
+	}
+	if meta.AccountId != account.AccountId || meta.Height != block.Height {
+		issue(IssueBlockMeta, fmt.Sprintf("meta of the account block is account id %d and height %d", meta.AccountId, meta.Height))
+	}
+
+	// the confirm height is only written for the blocks in the snapshot contents
+	if meta.SnapshotHeight > 0 {
+		if meta.SnapshotHeight > ck.latestHeight {
+			issue(IssueBeSnapshot, fmt.Sprintf("account block is confirmed by snapshot block %d above the latest", meta.SnapshotHeight))
+		} else if content, err := chainDb.Sc.GetSnapshotContent(meta.SnapshotHeight); err != nil {
+			ck.dbIssue(err, "GetSnapshotContent")
+		} else if hashHeight, ok := content[account.AccountAddress]; !ok || hashHeight.Hash != block.Hash {
+			issue(IssueBeSnapshot, fmt.Sprintf("account block isn't in the snapshot content of its confirm height %d", meta.SnapshotHeight))
+		}
+		if meta.RefSnapshotHeight > meta.SnapshotHeight {
+			issue(IssueBlockMeta, fmt.Sprintf("account block refers to snapshot block %d above its confirm height %d", meta.RefSnapshotHeight, meta.SnapshotHeight))
+		}
+	}
+
+	if refHeight, err := chainDb.Sc.GetSnapshotBlockHeight(&block.SnapshotHash); err != nil {
+		ck.dbIssue(err, "GetSnapshotBlockHeight")
+	} else if refHeight == 0 {
+		if !ck.imported() || meta.RefSnapshotHeight >= ck.report.BaseSnapshotHeight {
+			issue(IssueBlockMeta, "snapshot block referred by the account block is missing")
+		}
+	} else if refHeight != meta.RefSnapshotHeight {
+		issue(IssueBlockMeta, fmt.Sprintf("account block refers to snapshot block %d, but %d in its meta", refHeight, meta.RefSnapshotHeight))
+	}
+
+	// the send blocks received by the genesis blocks aren't in the ledger
+	if block.IsReceiveBlock() && !ck.isGenesisBlock(block) {
+		sendMeta, err := chainDb.Ac.GetBlockMeta(&block.FromBlockHash)
+		if err != nil {
+			ck.dbIssue(err, "GetBlockMeta")
+		} else if sendMeta == nil {
+			if !ck.imported() {
+				issue(IssueAccountBlock, "send block received by the account block is missing")
+			}
+		} else if !containsHeight(sendMeta.ReceiveBlockHeights, block.Height) && !ck.isChainBase(meta) && !ck.isChainBase(sendMeta) {
+			issue(IssueBlockMeta, "account block isn't in the receive heights of the send block")
+		}
+	}
+
+	if block.IsSendBlock() && len(meta.ReceiveBlockHeights) > 0 {
+		toAccount, ok := ck.accounts[block.ToAddress]
+		if !ok {
+			issue(IssueBlockMeta, "account of the receive blocks is missing")
+			return
+		}
+		for _, height := range meta.ReceiveBlockHeights {
+			receiveBlock, err := chainDb.Ac.GetBlockByHeight(toAccount.AccountId, height)
+			if err != nil {
+				ck.dbIssue(err, "GetBlockByHeight")
+			} else if receiveBlock == nil || !receiveBlock.IsReceiveBlock() || receiveBlock.FromBlockHash != block.Hash {
+				issue(IssueBlockMeta, fmt.Sprintf("receive block %d of the send block is missing", height))
+			}
+		}
+	}
+}
+
+func (ck *checker) checkLogList(block *ledger.AccountBlock, issue func(kind string, message string)) {
+	if block.LogHash == nil {
+		return
+	}
+
+	logList, err := ck.chainDb.Ac.GetVmLogList(block.LogHash)
+	if err != nil {
+		ck.dbIssue(err, "GetVmLogList")
+		return
+	}
+	if logList == nil {
+		issue(IssueLogList, "log list of the account block is missing")
+		return
+	}
+	if logHash := logList.Hash(); logHash == nil || *logHash != *block.LogHash {
+		issue(IssueLogList, "log list doesn't match the log hash of the account block")
+	}
+}
+
+func (ck *checker) checkOnroad() {
+	chainDb := ck.chainDb
+
+	prefix, _ := database.EncodeKey(database.DBKP_ONROADMETA)
+	iter := chainDb.Db().NewIterator(util.BytesPrefix(prefix))
+	defer iter.Release()
+
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != onroadKeySize {
+			ck.addIssue(&Issue{Kind: IssueOnroad, Message: fmt.Sprintf("key %x of the on road meta is invalid", key)})
+			continue
+		}
+		addr, _ := types.BytesToAddress(key[1 : 1+types.AddressSize])
+		hash, _ := types.BytesToHash(key[1+types.AddressSize:])
+		issue := func(message string) {
+			ck.addIssue(&Issue{Kind: IssueOnroad, Address: &addr, Hash: &hash, Message: message})
+		}
+		ck.report.OnroadBlocks++
+
+		sendBlock, err := chainDb.Ac.GetBlock(&hash)
+		if err != nil {
+			ck.dbIssue(err, "GetBlock")
+			continue
+		}
+		if sendBlock == nil {
+			issue("send block on road is missing")
+			continue
+		}
+		if !sendBlock.IsSendBlock() || sendBlock.ToAddress != addr {
+			issue("block on road isn't a send block to the account")
+			continue
+		}
+
+		// the send blocks failed to be received by contracts stay on road until retried enough times
+		account, ok := ck.accounts[addr]
+		if !ok {
+			continue
+		}
+		for _, height := range sendBlock.Meta.ReceiveBlockHeights {
+			receiveBlock, err := chainDb.Ac.GetBlockByHeight(account.AccountId, height)
+			if err != nil {
+				ck.dbIssue(err, "GetBlockByHeight")
+			} else if receiveBlock != nil && receiveBlock.BlockType == ledger.BlockTypeReceive && receiveBlock.FromBlockHash == hash {
+				issue(fmt.Sprintf("send block is received by block %d but still on road", height))
+			}
+		}
+	}
+	if err := iter.Error(); err != nil && err != database.ErrNotFound {
+		ck.dbIssue(err, "Iterate on road metas")
+	}
+}
+
+func containsHeight(heights []uint64, height uint64) bool {
+	for _, h := range heights {
+		if h == height {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package checkdb_test
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/checkdb"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/config"
+	"github.com/vitelabs/go-vite/crypto/ed25519"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/vm_context"
+)
+
+func TestCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := chain.NewChain(&config.Config{DataDir: dir, Chain: &config.Chain{LedgerInMemory: true}})
+	c.Init()
+	c.Start()
+	defer func() {
+		c.Stop()
+		c.Destroy()
+	}()
+
+	report := checkdb.Check(c.ChainDb(), chain.LedgerGenesis(), checkdb.DefaultMaxIssues)
+	if !report.Consistent() {
+		report.Write(os.Stderr)
+		t.Fatal("genesis ledger should be consistent")
+	}
+	if report.LatestSnapshotHeight != chain.SecondSnapshotBlock.Height || report.AccountBlocks != 4 {
+		t.Fatalf("report is %+v", report)
+	}
+
+	// drop the meta of an account block confirmed by the second snapshot block
+	key, _ := database.EncodeKey(database.DBKP_ACCOUNTBLOCKMETA, chain.GenesisRegisterBlock.Hash.Bytes())
+	if err := c.ChainDb().Db().Delete(key); err != nil {
+		t.Fatal(err)
+	}
+
+	// an unsigned send block of a general account
+	addr, _, _ := types.CreateAddress()
+	vmContext, err := vm_context.NewVmContext(c, nil, nil, &addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	block := &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeSendCall,
+		AccountAddress: addr,
+		ToAddress:      addr,
+		TokenId:        ledger.ViteTokenId,
+		Amount:         big.NewInt(0),
+		Fee:            big.NewInt(0),
+		Height:         1,
+		SnapshotHash:   c.GetLatestSnapshotBlock().Hash,
+		Timestamp:      &now,
+	}
+	block.Hash = block.ComputeHash()
+	if err := c.InsertAccountBlocks([]*vm_context.VmAccountBlock{{AccountBlock: block, VmContext: vmContext}}); err != nil {
+		t.Fatal(err)
+	}
+
+	report = checkdb.Check(c.ChainDb(), chain.LedgerGenesis(), checkdb.DefaultMaxIssues)
+	kinds := make(map[string]bool)
+	for _, issue := range report.Issues {
+		kinds[issue.Kind] = true
+	}
+	if !kinds[checkdb.IssueSnapshotContent] || !kinds[checkdb.IssueBlockMeta] || !kinds[checkdb.IssueSignature] {
+		t.Fatalf("missing meta and signature should be reported, got %+v", report.Issues)
+	}
+
+	// all the issues are counted
+	limited := checkdb.Check(c.ChainDb(), chain.LedgerGenesis(), 1)
+	if len(limited.Issues) != 1 || limited.IssueCount != report.IssueCount || limited.Omitted() != report.IssueCount-1 {
+		t.Fatalf("1 of %d issues should be listed, got %d of %d", report.IssueCount, len(limited.Issues), limited.IssueCount)
+	}
+}
+
+func TestCheck_State(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := chain.NewChain(&config.Config{DataDir: dir, Chain: &config.Chain{LedgerInMemory: true}})
+	c.Init()
+	c.Start()
+	defer func() {
+		c.Stop()
+		c.Destroy()
+	}()
+
+	// every snapshot block confirms a block which sets a storage
+	_, producerKey, _ := types.CreateAddress()
+	var blocks []*ledger.AccountBlock
+	var snapshotBlocks []*ledger.SnapshotBlock
+	for i := 0; i < 3; i++ {
+		addr, key, _ := types.CreateAddress()
+		vmContext, err := vm_context.NewVmContext(c, nil, nil, &addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vmContext.SetStorage([]byte{byte(i)}, []byte{byte(i)})
+
+		now := time.Now()
+		block := &ledger.AccountBlock{
+			BlockType:      ledger.BlockTypeSendCall,
+			AccountAddress: addr,
+			ToAddress:      addr,
+			TokenId:        ledger.ViteTokenId,
+			Amount:         big.NewInt(0),
+			Fee:            big.NewInt(0),
+			Height:         1,
+			SnapshotHash:   c.GetLatestSnapshotBlock().Hash,
+			Timestamp:      &now,
+			StateHash:      *vmContext.GetStorageHash(),
+		}
+		block.Hash = block.ComputeHash()
+		block.PublicKey = key.PubByte()
+		block.Signature = ed25519.Sign(key, block.Hash.Bytes())
+		if err := c.InsertAccountBlocks([]*vm_context.VmAccountBlock{{AccountBlock: block, VmContext: vmContext}}); err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, block)
+
+		latestBlock := c.GetLatestSnapshotBlock()
+		snapshotBlock := &ledger.SnapshotBlock{
+			Height:          latestBlock.Height + 1,
+			PrevHash:        latestBlock.Hash,
+			Timestamp:       &now,
+			SnapshotContent: c.GetNeedSnapshotContent(),
+		}
+		stateTrie, err := c.GenStateTrie(latestBlock.StateHash, snapshotBlock.SnapshotContent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshotBlock.StateTrie = stateTrie
+		snapshotBlock.StateHash = *stateTrie.Hash()
+		snapshotBlock.Hash = snapshotBlock.ComputeHash()
+		snapshotBlock.PublicKey = producerKey.PubByte()
+		snapshotBlock.Signature = ed25519.Sign(producerKey, snapshotBlock.Hash.Bytes())
+		if err := c.InsertSnapshotBlock(snapshotBlock); err != nil {
+			t.Fatal(err)
+		}
+		snapshotBlocks = append(snapshotBlocks, snapshotBlock)
+	}
+
+	report := checkdb.Check(c.ChainDb(), chain.LedgerGenesis(), checkdb.DefaultMaxIssues)
+	if !report.Consistent() {
+		report.Write(os.Stderr)
+		t.Fatal("ledger should be consistent")
+	}
+	if report.CheckedStates != 3 || report.SkippedStates != 0 {
+		t.Fatalf("3 states should be checked, got %+v", report)
+	}
+
+	// the state hash isn't covered by the hash of the account block
+	block := blocks[1]
+	block.StateHash = types.Hash{1}
+	account, err := c.ChainDb().Account.GetAccountByAddress(&block.AccountAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := c.ChainDb().NewBatch()
+	if err := c.ChainDb().Ac.WriteBlock(batch, account.AccountId, block); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ChainDb().Commit(batch); err != nil {
+		t.Fatal(err)
+	}
+
+	// the state of the next snapshot block is recomputed from the state in db, the state of the tampered block is
+	// missing
+	report = checkdb.Check(c.ChainDb(), chain.LedgerGenesis(), checkdb.DefaultMaxIssues)
+	var stateIssues []*checkdb.Issue
+	for _, issue := range report.Issues {
+		if issue.Kind == checkdb.IssueState && issue.SnapshotHeight > 0 {
+			stateIssues = append(stateIssues, issue)
+		}
+	}
+	if len(stateIssues) != 1 || stateIssues[0].SnapshotHeight != snapshotBlocks[1].Height || report.CheckedStates != 3 {
+		report.Write(os.Stderr)
+		t.Fatalf("state of snapshot block %d should be inconsistent", snapshotBlocks[1].Height)
+	}
+}
@@ -0,0 +1,84 @@
+package checkdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/vitelabs/go-vite/common/types"
+)
+
+// Kinds of the issues, each kind is the kind of the records which are inconsistent.
+const (
+	IssueDb              = "db"
+	IssueAccountIndex    = "account_index"
+	IssueSnapshotBlock   = "snapshot_block"
+	IssueSnapshotContent = "snapshot_content"
+	IssueAccountBlock    = "account_block"
+	IssueBlockMeta       = "block_meta"
+	IssueBeSnapshot      = "be_snapshot"
+	IssueSignature       = "signature"
+	IssueState           = "state"
+	IssueLogList         = "log_list"
+	IssueOnroad          = "onroad"
+)
+
+// DefaultMaxIssues is the default max number of the issues listed in a report, all of them are counted.
+const DefaultMaxIssues = 1000
+
+type Issue struct {
+	Kind string `json:"kind"`
+
+	SnapshotHeight uint64         `json:"snapshotHeight,omitempty"`
+	Address        *types.Address `json:"address,omitempty"`
+	Height         uint64         `json:"height,omitempty"`
+	Hash           *types.Hash    `json:"hash,omitempty"`
+
+	Message string `json:"message"`
+}
+
+type Report struct {
+	LatestSnapshotHeight uint64 `json:"latestSnapshotHeight"`
+	// The first snapshot block above the genesis blocks, it's above 3 if the ledger is imported from a ledger snapshot
+	BaseSnapshotHeight uint64 `json:"baseSnapshotHeight"`
+
+	SnapshotBlocks uint64 `json:"snapshotBlocks"`
+	Accounts       uint64 `json:"accounts"`
+	AccountBlocks  uint64 `json:"accountBlocks"`
+	OnroadBlocks   uint64 `json:"onroadBlocks"`
+
+	// The states of which the previous state has been pruned can't be recomputed
+	CheckedStates uint64 `json:"checkedStates"`
+	SkippedStates uint64 `json:"skippedStates"`
+
+	// MaxIssues is the max number of the listed issues, 0 lists all of them
+	MaxIssues  int      `json:"maxIssues"`
+	IssueCount uint64   `json:"issueCount"`
+	Issues     []*Issue `json:"issues"`
+}
+
+func (r *Report) addIssue(issue *Issue) {
+	r.IssueCount++
+	if r.MaxIssues <= 0 || len(r.Issues) < r.MaxIssues {
+		r.Issues = append(r.Issues, issue)
+	}
+}
+
+// Omitted returns the number of the issues which aren't listed.
+func (r *Report) Omitted() uint64 {
+	return r.IssueCount - uint64(len(r.Issues))
+}
+
+// Consistent reports whether no issue is found.
+func (r *Report) Consistent() bool {
+	return r.IssueCount == 0
+}
+
+func (r *Report) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
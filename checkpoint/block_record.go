@@ -0,0 +1,76 @@
+package checkpoint
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+)
+
+const (
+	stateRecordNode     = byte(0)
+	stateRecordRefValue = byte(1)
+
+	blockRecordHeaderSize = 8 + 8 + types.HashSize
+	onroadRecordSize      = types.AddressSize + types.HashSize
+)
+
+var ErrInvalidRecord = errors.New("record is invalid")
+
+// blockRecord is an account block with the fields which aren't in its network format.
+type blockRecord struct {
+	block *ledger.AccountBlock
+
+	confirmHeight     uint64
+	refSnapshotHeight uint64
+}
+
+func (r *blockRecord) serialize() ([]byte, error) {
+	blockData, err := r.block.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, blockRecordHeaderSize, blockRecordHeaderSize+len(blockData))
+	binary.BigEndian.PutUint64(data[:8], r.confirmHeight)
+	binary.BigEndian.PutUint64(data[8:16], r.refSnapshotHeight)
+	copy(data[16:blockRecordHeaderSize], r.block.StateHash.Bytes())
+	return append(data, blockData...), nil
+}
+
+func (r *blockRecord) deserialize(data []byte) error {
+	if len(data) < blockRecordHeaderSize {
+		return ErrInvalidRecord
+	}
+
+	block := &ledger.AccountBlock{}
+	if err := block.Deserialize(data[blockRecordHeaderSize:]); err != nil {
+		return err
+	}
+	block.StateHash, _ = types.BytesToHash(data[16:blockRecordHeaderSize])
+
+	r.block = block
+	r.confirmHeight = binary.BigEndian.Uint64(data[:8])
+	r.refSnapshotHeight = binary.BigEndian.Uint64(data[8:16])
+	return nil
+}
+
+// onroadRecord is a send block which hasn't been received at the snapshot block.
+type onroadRecord struct {
+	addr types.Address
+	hash types.Hash
+}
+
+func (r *onroadRecord) serialize() []byte {
+	return append(r.addr.Bytes(), r.hash.Bytes()...)
+}
+
+func (r *onroadRecord) deserialize(data []byte) error {
+	if len(data) != onroadRecordSize {
+		return ErrInvalidRecord
+	}
+	r.addr, _ = types.BytesToAddress(data[:types.AddressSize])
+	r.hash, _ = types.BytesToHash(data[types.AddressSize:])
+	return nil
+}
@@ -0,0 +1,363 @@
+package checkpoint
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/trie"
+)
+
+var (
+	ErrSnapshotBlockNotFound = errors.New("snapshot block not found")
+	ErrHeightTooLow          = errors.New("ledger snapshot should be above the genesis snapshot blocks")
+	ErrSnapshotExists        = errors.New("dir already contains a ledger snapshot")
+)
+
+// count of account blocks read at a time
+const exportPageSize = uint64(1000)
+
+var exportLog = log15.New("module", "checkpoint_export")
+
+// exporter writes the records of a ledger snapshot.
+type exporter struct {
+	chain  chain.Chain
+	height uint64
+
+	latestHeights map[types.Address]uint64
+	onroad        map[onroadRecord]struct{}
+}
+
+// Export writes the ledger snapshot at the snapshot block of height into dir. The snapshot contains the snapshot
+// blocks from the snapshot block down to the genesis ones, the account blocks confirmed by it except for the genesis
+// ones, the state tries of the latest ones and the send blocks which haven't been received at the snapshot block.
+func Export(c chain.Chain, height uint64, dir string) (*Manifest, error) {
+	if height <= chain.SecondSnapshotBlock.Height {
+		return nil, ErrHeightTooLow
+	}
+	snapshotBlock, err := c.GetSnapshotBlockByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	if snapshotBlock == nil {
+		return nil, ErrSnapshotBlockNotFound
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, manifestFileName)); err == nil {
+		return nil, ErrSnapshotExists
+	}
+
+	e := &exporter{
+		chain:  c,
+		height: height,
+
+		latestHeights: make(map[types.Address]uint64),
+		onroad:        make(map[onroadRecord]struct{}),
+	}
+
+	manifest := &Manifest{
+		Version:        ManifestVersion,
+		SnapshotHash:   snapshotBlock.Hash,
+		SnapshotHeight: snapshotBlock.Height,
+		StateHash:      snapshotBlock.StateHash,
+	}
+
+	writeFuncs := []func(string) (*FileInfo, error){
+		e.writeSnapshotBlocks,
+		e.writeAccountBlocks,
+		func(dir string) (*FileInfo, error) {
+			return e.writeState(dir, snapshotBlock)
+		},
+		e.writeOnroad,
+	}
+	for _, writeFunc := range writeFuncs {
+		info, err := writeFunc(dir)
+		if err != nil {
+			exportLog.Error("Write ledger snapshot failed, error is "+err.Error(), "method", "Export")
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, info)
+	}
+
+	if err := manifest.write(dir); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// newRecord returns the record of the block, or nil if it's a genesis block, which is in every ledger.
+func (e *exporter) newRecord(block *ledger.AccountBlock) (*blockRecord, error) {
+	chainDb := e.chain.ChainDb()
+	confirmHeight, err := chainDb.Ac.GetConfirmHeight(&block.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if confirmHeight > 0 && confirmHeight <= chain.SecondSnapshotBlock.Height {
+		return nil, nil
+	}
+	meta, err := chainDb.Ac.GetBlockMeta(&block.Hash)
+	if err != nil {
+		return nil, err
+	}
+	refSnapshotHeight := uint64(0)
+	if meta != nil {
+		refSnapshotHeight = meta.RefSnapshotHeight
+	}
+
+	return &blockRecord{
+		block:             block,
+		confirmHeight:     confirmHeight,
+		refSnapshotHeight: refSnapshotHeight,
+	}, nil
+}
+
+// writeSnapshotBlocks writes the snapshot blocks from the snapshot block down to the genesis ones, so they are
+// verified by the hashes from the snapshot block.
+func (e *exporter) writeSnapshotBlocks(dir string) (*FileInfo, error) {
+	writer, err := newRecordWriter(dir, snapshotBlockFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	for height := e.height; height > chain.SecondSnapshotBlock.Height; {
+		count := height - chain.SecondSnapshotBlock.Height
+		if count > exportPageSize {
+			count = exportPageSize
+		}
+		blocks, err := e.chain.GetSnapshotBlocksByHeight(height, count, false, true)
+		if err != nil {
+			writer.close()
+			return nil, err
+		}
+		if uint64(len(blocks)) != count {
+			writer.close()
+			return nil, ErrSnapshotBlockNotFound
+		}
+
+		// the blocks read backward are in the descending order
+		for _, block := range blocks {
+			data, err := block.Serialize()
+			if err != nil {
+				writer.close()
+				return nil, err
+			}
+			if err := writer.write(data); err != nil {
+				writer.close()
+				return nil, err
+			}
+		}
+		height -= count
+	}
+	return writer.close()
+}
+
+// writeAccountBlocks writes the blocks of each account up to the latest one confirmed by the snapshot block, so the
+// chains can be verified down to the genesis blocks. The blocks of an account are written together in the order of
+// the heights.
+func (e *exporter) writeAccountBlocks(dir string) (*FileInfo, error) {
+	writer, err := newRecordWriter(dir, accountBlocksFileName)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.writeAccounts(writer); err != nil {
+		writer.close()
+		return nil, err
+	}
+	return writer.close()
+}
+
+func (e *exporter) writeAccounts(writer *recordWriter) error {
+	chainDb := e.chain.ChainDb()
+	lastAccountId, err := chainDb.Account.GetLastAccountId()
+	if err != nil {
+		return err
+	}
+
+	for accountId := uint64(1); accountId <= lastAccountId; accountId++ {
+		addr, err := chainDb.Account.GetAddressById(accountId)
+		if err != nil {
+			return err
+		}
+
+		latestBlock, err := e.chain.GetConfirmAccountBlock(e.height, addr)
+		if err != nil {
+			return err
+		}
+		if latestBlock == nil {
+			continue
+		}
+		e.latestHeights[*addr] = latestBlock.Height
+
+		for startHeight := uint64(1); startHeight <= latestBlock.Height; startHeight += exportPageSize {
+			endHeight := startHeight + exportPageSize - 1
+			if endHeight > latestBlock.Height {
+				endHeight = latestBlock.Height
+			}
+			// the blocks are completed with the addresses and the public keys
+			blocks, err := e.chain.GetAccountBlocksByHeight(*addr, startHeight, endHeight-startHeight+1, true)
+			if err != nil {
+				return err
+			}
+			for _, block := range blocks {
+				record, err := e.newRecord(block)
+				if err != nil {
+					return err
+				}
+				if record == nil {
+					continue
+				}
+				data, err := record.serialize()
+				if err != nil {
+					return err
+				}
+				if err := writer.write(data); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// collectOnroad collects the send blocks confirmed by the snapshot block which are still on road, and which are
+// received by the blocks after the latest confirmed blocks. It's called after the account blocks are written.
+func (e *exporter) collectOnroad() error {
+	chainDb := e.chain.ChainDb()
+
+	isOnroad := func(addr *types.Address, sendHash *types.Hash) error {
+		confirmHeight, err := chainDb.Ac.GetConfirmHeight(sendHash)
+		if err != nil {
+			return err
+		}
+		if confirmHeight > 0 && confirmHeight <= e.height {
+			e.onroad[onroadRecord{addr: *addr, hash: *sendHash}] = struct{}{}
+		}
+		return nil
+	}
+
+	prefix, _ := database.EncodeKey(database.DBKP_ONROADMETA)
+	iter := chainDb.Db().NewIterator(util.BytesPrefix(prefix))
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != 1+onroadRecordSize {
+			continue
+		}
+		item := &onroadRecord{}
+		item.deserialize(key[1:])
+		if err := isOnroad(&item.addr, &item.hash); err != nil {
+			iter.Release()
+			return err
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	lastAccountId, err := chainDb.Account.GetLastAccountId()
+	if err != nil {
+		return err
+	}
+	for accountId := uint64(1); accountId <= lastAccountId; accountId++ {
+		addr, err := chainDb.Account.GetAddressById(accountId)
+		if err != nil {
+			return err
+		}
+		latestBlock, err := chainDb.Ac.GetLatestBlock(accountId)
+		if err != nil {
+			return err
+		}
+		startHeight := e.latestHeights[*addr] + 1
+		if latestBlock == nil || latestBlock.Height < startHeight {
+			continue
+		}
+
+		blocks, err := chainDb.Ac.GetBlockListByAccountId(accountId, startHeight, latestBlock.Height, true)
+		if err != nil {
+			return err
+		}
+		for _, block := range blocks {
+			if !block.IsReceiveBlock() {
+				continue
+			}
+			if err := isOnroad(addr, &block.FromBlockHash); err != nil {
+				return err
+			}
+		}
+	}
+	// the send blocks are in the collected chains of the senders, or are genesis blocks
+	return nil
+}
+
+// writeState writes the nodes of the state trie of the snapshot block and of the storage tries of the accounts.
+func (e *exporter) writeState(dir string, snapshotBlock *ledger.SnapshotBlock) (*FileInfo, error) {
+	writer, err := newRecordWriter(dir, stateFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeFunc := func(data []byte) error {
+		return writer.write(append([]byte{stateRecordNode}, data...))
+	}
+	refValueFunc := func(value []byte) error {
+		return writer.write(append([]byte{stateRecordRefValue}, value...))
+	}
+
+	var accountStateHashList []types.Hash
+	leafFunc := func(value []byte) error {
+		if stateHash, err := types.BytesToHash(value); err == nil {
+			accountStateHashList = append(accountStateHashList, stateHash)
+		}
+		return nil
+	}
+
+	nodeExporter := trie.NewNodeExporter(e.chain.ChainDb().Db())
+	if err := nodeExporter.Export(&snapshotBlock.StateHash, nodeFunc, refValueFunc, leafFunc); err != nil {
+		writer.close()
+		return nil, err
+	}
+	for i := range accountStateHashList {
+		if err := nodeExporter.Export(&accountStateHashList[i], nodeFunc, refValueFunc, nil); err != nil {
+			writer.close()
+			return nil, err
+		}
+	}
+	return writer.close()
+}
+
+func (e *exporter) writeOnroad(dir string) (*FileInfo, error) {
+	if err := e.collectOnroad(); err != nil {
+		return nil, err
+	}
+
+	items := make([]onroadRecord, 0, len(e.onroad))
+	for item := range e.onroad {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return bytes.Compare(items[i].serialize(), items[j].serialize()) < 0
+	})
+
+	writer, err := newRecordWriter(dir, onroadFileName)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if err := writer.write(item.serialize()); err != nil {
+			writer.close()
+			return nil, err
+		}
+	}
+	return writer.close()
+}
@@ -0,0 +1,724 @@
+package checkpoint
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/onroad/model"
+	"github.com/vitelabs/go-vite/trie"
+	"github.com/vitelabs/go-vite/vm"
+	"github.com/vitelabs/go-vite/vm/contracts"
+)
+
+var (
+	ErrLedgerNotEmpty        = errors.New("ledger snapshot can only be imported into a new ledger")
+	ErrInvalidSnapshotBlock  = errors.New("snapshot blocks don't chain from the snapshot block of the manifest down to the genesis blocks")
+	ErrInvalidAccountBlock   = errors.New("account block hash or signature is invalid")
+	ErrInvalidAccountChain   = errors.New("account blocks don't form a chain confirmed by the snapshot blocks")
+	ErrInvalidAccountState   = errors.New("account block doesn't match the state of the snapshot block")
+	ErrInvalidReceiveBlock   = errors.New("receive block doesn't receive a send block to the account confirmed before it")
+	ErrInvalidOnroad         = errors.New("on road block isn't an unreceived send block to the account")
+	ErrMissingAccountBlock   = errors.New("account of the state has no account block")
+	ErrUnexpectedRecordCount = errors.New("number of records doesn't match the manifest")
+)
+
+// count of the operations written into the ledger at a time
+const importBatchSize = 10000
+
+// prefixes of the keys of the import index
+const (
+	// address, block height => snapshot height, block hash of the snapshot contents
+	indexConfirmPrefix = byte(1)
+	// send block hash => address, height and confirm height of the receive block, whether it's the first block of a
+	// contract
+	indexReceivePrefix = byte(2)
+)
+
+var importLog = log15.New("module", "checkpoint_import")
+
+// accountChain is the account of which the blocks are being read.
+type accountChain struct {
+	addr       types.Address
+	isContract bool
+
+	prevBlock         *ledger.AccountBlock
+	prevConfirmHeight uint64
+}
+
+// importer verifies and writes the ledger snapshot in dir. The records are verified while they are read, the
+// snapshot contents and the receive blocks are indexed in a temporary db, so only the accounts are kept in memory.
+type importer struct {
+	chain    chain.Chain
+	dir      string
+	manifest *Manifest
+
+	genesisSnapshotBlock *ledger.SnapshotBlock
+	snapshotBlock        *ledger.SnapshotBlock
+	lowestSnapshotBlock  *ledger.SnapshotBlock
+
+	index     database.KeyValueStore
+	batch     database.Batch
+	onroadSet *model.OnroadSet
+
+	lastAccountId uint64
+	accounts      map[types.Address]*ledger.Account
+	account       *accountChain
+	// state hash of the latest block of each account
+	latestStates    map[types.Address]types.Hash
+	contractAddrMap map[types.Gid][]types.Address
+	nodeSet         *trie.NodeSet
+}
+
+// Import verifies the ledger snapshot in dir against trustedHash, the hash of the snapshot block which the snapshot
+// is taken at, and writes it into c, which has to be a new ledger with the genesis blocks only. The snapshot blocks
+// have to chain from the trusted one down to the genesis blocks, the account blocks of each account have to chain
+// down to its latest block in c and be confirmed as the snapshot contents say, the signed blocks are verified by
+// their signatures and the receive blocks by the send blocks they receive. The states are verified by the state
+// hash of the snapshot block, and the on road blocks have to be unreceived send blocks. The ledger is written while
+// it's verified, so c has to be discarded if Import fails. The states below the snapshot block aren't in the
+// ledger, so it can't be rolled back below it.
+func Import(c chain.Chain, dir string, trustedHash types.Hash) (*Manifest, error) {
+	genesisSnapshotBlock := c.GetLatestSnapshotBlock()
+	if genesisSnapshotBlock.Height > chain.SecondSnapshotBlock.Height {
+		return nil, ErrLedgerNotEmpty
+	}
+
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.SnapshotHash != trustedHash {
+		return nil, ErrUntrustedHash
+	}
+
+	index, closeIndex, err := newImportIndex()
+	if err != nil {
+		return nil, err
+	}
+	defer closeIndex()
+
+	lastAccountId, err := c.ChainDb().Account.GetLastAccountId()
+	if err != nil {
+		return nil, err
+	}
+
+	i := &importer{
+		chain:    c,
+		dir:      dir,
+		manifest: manifest,
+
+		genesisSnapshotBlock: genesisSnapshotBlock,
+
+		index:     index,
+		batch:     c.ChainDb().NewBatch(),
+		onroadSet: model.NewOnroadSet(c),
+
+		lastAccountId:   lastAccountId,
+		accounts:        make(map[types.Address]*ledger.Account),
+		latestStates:    make(map[types.Address]types.Hash),
+		contractAddrMap: make(map[types.Gid][]types.Address),
+		nodeSet:         trie.NewNodeSet(),
+	}
+
+	readFuncs := []struct {
+		name string
+		read func(data []byte) error
+		// called after all of the records are read
+		done func() error
+	}{
+		{snapshotBlockFileName, i.readSnapshotBlock, i.verifySnapshotChain},
+		{accountBlocksFileName, i.readAccountBlock, i.verifyAccounts},
+		{stateFileName, i.readState, i.verifyState},
+		{onroadFileName, i.readOnroad, i.writeContracts},
+	}
+	for _, readFunc := range readFuncs {
+		if err := i.readFile(readFunc.name, readFunc.read); err != nil {
+			importLog.Error("Read "+readFunc.name+" failed, error is "+err.Error(), "method", "Import")
+			return nil, err
+		}
+		if err := readFunc.done(); err != nil {
+			importLog.Error("Verify "+readFunc.name+" failed, error is "+err.Error(), "method", "Import")
+			return nil, err
+		}
+	}
+
+	if err := i.flush(true); err != nil {
+		importLog.Error("Commit failed, error is "+err.Error(), "method", "Import")
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// newImportIndex returns an empty db for the index of Import, and the func closing and removing it.
+func newImportIndex() (database.KeyValueStore, func(), error) {
+	indexDir, err := ioutil.TempDir("", "vite_import_index")
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := database.NewLevelDb(indexDir)
+	if err != nil {
+		os.RemoveAll(indexDir)
+		return nil, nil, err
+	}
+
+	index := database.NewLevelDbStore(db)
+	return index, func() {
+		index.Close()
+		os.RemoveAll(indexDir)
+	}, nil
+}
+
+// readFile verifies the file against the manifest, then calls readFunc with every record of it.
+func (i *importer) readFile(name string, readFunc func(data []byte) error) error {
+	if err := i.manifest.verifyFile(i.dir, name); err != nil {
+		return err
+	}
+
+	reader, err := newRecordReader(i.dir, name)
+	if err != nil {
+		return err
+	}
+	defer reader.close()
+
+	count := uint64(0)
+	for {
+		data, err := reader.read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := readFunc(data); err != nil {
+			return err
+		}
+		count++
+	}
+
+	if count != i.manifest.File(name).Records {
+		return ErrUnexpectedRecordCount
+	}
+	return nil
+}
+
+// flush commits the batch if it's large enough or force is true.
+func (i *importer) flush(force bool) error {
+	if i.batch.Len() <= 0 || (!force && i.batch.Len() < importBatchSize) {
+		return nil
+	}
+	if err := i.chain.ChainDb().Commit(i.batch); err != nil {
+		return err
+	}
+	i.batch = i.chain.ChainDb().NewBatch()
+	return nil
+}
+
+func (i *importer) getOrCreateAccount(addr types.Address, publicKey []byte) (*ledger.Account, error) {
+	if account, ok := i.accounts[addr]; ok {
+		return account, nil
+	}
+
+	chainDb := i.chain.ChainDb()
+	account, err := chainDb.Account.GetAccountByAddress(&addr)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		i.lastAccountId++
+		account = &ledger.Account{
+			AccountAddress: addr,
+			AccountId:      i.lastAccountId,
+			PublicKey:      publicKey,
+		}
+		chainDb.Account.WriteAccountIndex(i.batch, account.AccountId, &addr)
+		if err := chainDb.Account.WriteAccount(i.batch, account); err != nil {
+			return nil, err
+		}
+	}
+	i.accounts[addr] = account
+	return account, nil
+}
+
+func confirmKey(addr types.Address, height uint64) []byte {
+	key := make([]byte, 1+types.AddressSize+8)
+	key[0] = indexConfirmPrefix
+	copy(key[1:], addr.Bytes())
+	binary.BigEndian.PutUint64(key[1+types.AddressSize:], height)
+	return key
+}
+
+func receiveKey(sendHash types.Hash) []byte {
+	return append([]byte{indexReceivePrefix}, sendHash.Bytes()...)
+}
+
+// readSnapshotBlock reads the snapshot blocks from the one of the manifest downwards, every block has to be the
+// previous block of the last one.
+func (i *importer) readSnapshotBlock(data []byte) error {
+	block := &ledger.SnapshotBlock{}
+	if err := block.Deserialize(data); err != nil {
+		return err
+	}
+	if block.ComputeHash() != block.Hash || !block.VerifySignature() ||
+		block.Height <= i.genesisSnapshotBlock.Height {
+		return ErrInvalidSnapshotBlock
+	}
+	if i.snapshotBlock == nil {
+		if block.Hash != i.manifest.SnapshotHash || block.Height != i.manifest.SnapshotHeight ||
+			block.StateHash != i.manifest.StateHash {
+			return ErrInvalidSnapshotBlock
+		}
+		i.snapshotBlock = block
+	} else if block.Hash != i.lowestSnapshotBlock.PrevHash || block.Height+1 != i.lowestSnapshotBlock.Height {
+		return ErrInvalidSnapshotBlock
+	}
+	i.lowestSnapshotBlock = block
+
+	for addr, hashHeight := range block.SnapshotContent {
+		key := confirmKey(addr, hashHeight.Height)
+		// a block is confirmed once
+		confirmed, err := i.index.Has(key)
+		if err != nil {
+			return err
+		}
+		if confirmed {
+			return ErrInvalidSnapshotBlock
+		}
+
+		value := make([]byte, 8, 8+types.HashSize)
+		binary.BigEndian.PutUint64(value, block.Height)
+		if err := i.index.Put(key, append(value, hashHeight.Hash.Bytes()...)); err != nil {
+			return err
+		}
+	}
+
+	chainDb := i.chain.ChainDb()
+	if _, err := i.getOrCreateAccount(block.Producer(), block.PublicKey); err != nil {
+		return err
+	}
+	if err := chainDb.Sc.WriteSnapshotBlock(i.batch, block); err != nil {
+		return err
+	}
+	if err := chainDb.Sc.WriteSnapshotContent(i.batch, block.Height, block.SnapshotContent); err != nil {
+		return err
+	}
+	chainDb.Sc.WriteSnapshotHash(i.batch, &block.Hash, block.Height)
+	return i.flush(false)
+}
+
+// verifySnapshotChain checks that the snapshot blocks chain down to the genesis blocks.
+func (i *importer) verifySnapshotChain() error {
+	if i.lowestSnapshotBlock == nil || i.lowestSnapshotBlock.PrevHash != i.genesisSnapshotBlock.Hash ||
+		i.lowestSnapshotBlock.Height != i.genesisSnapshotBlock.Height+1 {
+		return ErrInvalidSnapshotBlock
+	}
+	return nil
+}
+
+// confirmedBy returns the snapshot height of the snapshot content which confirms the block of addr at height,
+// and the block of addr in the snapshot content.
+func (i *importer) confirmedBy(addr types.Address, height uint64) (uint64, *ledger.HashHeight, error) {
+	iter := i.index.NewIterator(util.BytesPrefix(confirmKey(addr, 0)[:1+types.AddressSize]))
+	defer iter.Release()
+
+	if !iter.Seek(confirmKey(addr, height)) {
+		if err := iter.Error(); err != nil && err != database.ErrNotFound {
+			return 0, nil, err
+		}
+		return 0, nil, nil
+	}
+
+	key, value := iter.Key(), iter.Value()
+	hash, _ := types.BytesToHash(value[8:])
+	return binary.BigEndian.Uint64(value[:8]), &ledger.HashHeight{
+		Height: binary.BigEndian.Uint64(key[1+types.AddressSize:]),
+		Hash:   hash,
+	}, nil
+}
+
+// readAccountBlock reads the blocks of an account after another, the blocks of an account are in the order of the
+// heights.
+func (i *importer) readAccountBlock(data []byte) error {
+	record := &blockRecord{}
+	if err := record.deserialize(data); err != nil {
+		return err
+	}
+	block := record.block
+	if block.ComputeHash() != block.Hash {
+		return ErrInvalidAccountBlock
+	}
+
+	if i.account == nil || i.account.addr != block.AccountAddress {
+		if err := i.finishAccount(); err != nil {
+			return err
+		}
+		if err := i.startAccount(block); err != nil {
+			return err
+		}
+	}
+	account := i.account
+
+	prevBlock := account.prevBlock
+	if prevBlock == nil {
+		if block.Height != 1 || !block.PrevHash.IsZero() {
+			return ErrInvalidAccountChain
+		}
+	} else if block.Height != prevBlock.Height+1 || block.PrevHash != prevBlock.Hash {
+		return ErrInvalidAccountChain
+	}
+
+	confirmHeight, confirmedBlock, err := i.confirmedBy(account.addr, block.Height)
+	if err != nil {
+		return err
+	}
+	if confirmedBlock == nil || confirmHeight != record.confirmHeight || confirmHeight < account.prevConfirmHeight {
+		return ErrInvalidAccountChain
+	}
+	isConfirmedBlock := confirmedBlock.Height == block.Height
+	if isConfirmedBlock && confirmedBlock.Hash != block.Hash {
+		return ErrInvalidAccountChain
+	}
+
+	if !verifySignature(block, account.isContract, prevBlock) {
+		return ErrInvalidAccountBlock
+	}
+	if block.IsReceiveBlock() {
+		if err := i.indexReceive(block, confirmHeight, account.isContract && prevBlock == nil); err != nil {
+			return err
+		}
+	}
+
+	if err := i.writeAccountBlock(record, isConfirmedBlock); err != nil {
+		return err
+	}
+	account.prevBlock = block
+	account.prevConfirmHeight = confirmHeight
+	i.latestStates[account.addr] = block.StateHash
+	return nil
+}
+
+// startAccount starts the chain of the account of block, which is its lowest block in the snapshot.
+func (i *importer) startAccount(block *ledger.AccountBlock) error {
+	addr := block.AccountAddress
+	// the blocks of an account are together
+	if _, ok := i.latestStates[addr]; ok {
+		return ErrInvalidAccountChain
+	}
+
+	prevBlock, err := i.chain.GetLatestAccountBlock(&addr)
+	if err != nil {
+		return err
+	}
+	isContract, err := i.isContract(addr, block, prevBlock)
+	if err != nil {
+		return err
+	}
+
+	i.account = &accountChain{
+		addr:       addr,
+		isContract: isContract,
+		prevBlock:  prevBlock,
+	}
+	return nil
+}
+
+// finishAccount checks that the latest block of the account is the latest one in the snapshot contents.
+func (i *importer) finishAccount() error {
+	if i.account == nil {
+		return nil
+	}
+	_, confirmedBlock, err := i.confirmedBy(i.account.addr, i.account.prevBlock.Height+1)
+	if err != nil {
+		return err
+	}
+	if confirmedBlock != nil {
+		return ErrInvalidAccountChain
+	}
+	i.account = nil
+	return nil
+}
+
+// isContract reports whether addr is a contract, firstBlock is the lowest block of it in the snapshot and prevBlock
+// is its latest block in the ledger.
+func (i *importer) isContract(addr types.Address, firstBlock *ledger.AccountBlock, prevBlock *ledger.AccountBlock) (bool, error) {
+	if vm.IsPrecompiledContractAddress(addr) {
+		return true, nil
+	}
+	if prevBlock != nil {
+		gid, err := i.chain.GetContractGid(&addr)
+		return gid != nil, err
+	}
+	// the first block of a new contract is the receive block of the create block, which is verified by
+	// verifyAccounts
+	return firstBlock.IsReceiveBlock() && types.PubkeyToAddress(firstBlock.PublicKey) != addr, nil
+}
+
+// verifySignature checks the signature of block. The blocks of general accounts have to be signed by the accounts,
+// the receive blocks of contracts are signed by the producers and the send blocks of contracts, which follow the
+// receive blocks, are unsigned.
+func verifySignature(block *ledger.AccountBlock, isContract bool, prevBlock *ledger.AccountBlock) bool {
+	if isContract && block.IsSendBlock() {
+		return prevBlock != nil && len(block.Signature) <= 0 && len(block.PublicKey) <= 0
+	}
+	if len(block.Signature) <= 0 || len(block.PublicKey) <= 0 || !block.VerifySignature() {
+		return false
+	}
+	return isContract || types.PubkeyToAddress(block.PublicKey) == block.AccountAddress
+}
+
+// indexReceive indexes the receive block, the send block it receives is verified by verifyAccounts once all of the
+// blocks are written.
+func (i *importer) indexReceive(block *ledger.AccountBlock, confirmHeight uint64, isCreate bool) error {
+	key := receiveKey(block.FromBlockHash)
+	// a send block is received once
+	received, err := i.index.Has(key)
+	if err != nil {
+		return err
+	}
+	if received {
+		return ErrInvalidReceiveBlock
+	}
+
+	value := make([]byte, types.AddressSize+8+8+1)
+	copy(value, block.AccountAddress.Bytes())
+	binary.BigEndian.PutUint64(value[types.AddressSize:], block.Height)
+	binary.BigEndian.PutUint64(value[types.AddressSize+8:], confirmHeight)
+	if isCreate {
+		value[types.AddressSize+16] = 1
+	}
+	return i.index.Put(key, value)
+}
+
+func (i *importer) writeAccountBlock(record *blockRecord, isConfirmedBlock bool) error {
+	block := record.block
+	chainDb := i.chain.ChainDb()
+
+	var publicKey []byte
+	if block.Producer() == block.AccountAddress {
+		publicKey = block.PublicKey
+	}
+	account, err := i.getOrCreateAccount(block.AccountAddress, publicKey)
+	if err != nil {
+		return err
+	}
+
+	if err := chainDb.Ac.WriteBlock(i.batch, account.AccountId, block); err != nil {
+		return err
+	}
+	if err := chainDb.Ac.WriteBlockMeta(i.batch, &block.Hash, &ledger.AccountBlockMeta{
+		AccountId:         account.AccountId,
+		Height:            block.Height,
+		RefSnapshotHeight: record.refSnapshotHeight,
+	}); err != nil {
+		return err
+	}
+	// like the snapshot blocks do, the blocks below the ones in the snapshot contents are confirmed by them
+	if isConfirmedBlock {
+		if err := chainDb.Ac.WriteBeSnapshot(i.batch, &block.Hash, record.confirmHeight); err != nil {
+			return err
+		}
+	}
+	return i.flush(false)
+}
+
+// verifyAccounts checks that every account in the snapshot contents has its blocks, and that every receive block
+// receives a send block to its account, which is a create block for the first block of a new contract. The receive
+// heights are written into the metas of the send blocks.
+func (i *importer) verifyAccounts() error {
+	if err := i.finishAccount(); err != nil {
+		return err
+	}
+	// the send blocks are read from the ledger
+	if err := i.flush(true); err != nil {
+		return err
+	}
+
+	iter := i.index.NewIterator(util.BytesPrefix([]byte{indexConfirmPrefix}))
+	defer iter.Release()
+	for iter.Next() {
+		addr, _ := types.BytesToAddress(iter.Key()[1 : 1+types.AddressSize])
+		if _, ok := i.latestStates[addr]; !ok {
+			return ErrInvalidAccountChain
+		}
+	}
+	if err := iter.Error(); err != nil && err != database.ErrNotFound {
+		return err
+	}
+
+	receiveIter := i.index.NewIterator(util.BytesPrefix([]byte{indexReceivePrefix}))
+	defer receiveIter.Release()
+	for receiveIter.Next() {
+		sendHash, _ := types.BytesToHash(receiveIter.Key()[1:])
+		value := receiveIter.Value()
+		addr, _ := types.BytesToAddress(value[:types.AddressSize])
+		receiveHeight := binary.BigEndian.Uint64(value[types.AddressSize:])
+		receiveConfirmHeight := binary.BigEndian.Uint64(value[types.AddressSize+8:])
+		isCreate := value[types.AddressSize+16] == 1
+
+		sendBlock, err := i.chain.GetAccountBlockByHash(&sendHash)
+		if err != nil {
+			return err
+		}
+		if sendBlock == nil || !sendBlock.IsSendBlock() || sendBlock.ToAddress != addr {
+			return ErrInvalidReceiveBlock
+		}
+		chainDb := i.chain.ChainDb()
+		sendConfirmHeight, err := chainDb.Ac.GetConfirmHeight(&sendHash)
+		if err != nil {
+			return err
+		}
+		if sendConfirmHeight <= 0 || sendConfirmHeight > receiveConfirmHeight {
+			return ErrInvalidReceiveBlock
+		}
+
+		// a send block is received once, so its meta isn't in the batch
+		sendMeta, err := chainDb.Ac.GetBlockMeta(&sendHash)
+		if err != nil {
+			return err
+		}
+		sendMeta.ReceiveBlockHeights = append(sendMeta.ReceiveBlockHeights, receiveHeight)
+		if err := chainDb.Ac.WriteBlockMeta(i.batch, &sendHash, sendMeta); err != nil {
+			return err
+		}
+		if err := i.flush(false); err != nil {
+			return err
+		}
+
+		if isCreate {
+			if sendBlock.BlockType != ledger.BlockTypeSendCreate {
+				return ErrInvalidReceiveBlock
+			}
+			gid := contracts.GetGidFromCreateContractData(sendBlock.Data)
+			i.contractAddrMap[gid] = append(i.contractAddrMap[gid], addr)
+		}
+	}
+	if err := receiveIter.Error(); err != nil && err != database.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+func (i *importer) readState(data []byte) error {
+	if len(data) <= 0 {
+		return ErrInvalidRecord
+	}
+
+	switch data[0] {
+	case stateRecordNode:
+		return i.nodeSet.AddNode(data[1:])
+	case stateRecordRefValue:
+		i.nodeSet.AddRefValue(data[1:])
+		return nil
+	default:
+		return ErrInvalidRecord
+	}
+}
+
+// verifyState checks that the state trie is complete, and every account of it has its latest block and storage
+// trie, then writes the state.
+func (i *importer) verifyState() error {
+	stateTrie, err := i.nodeSet.Trie(&i.snapshotBlock.StateHash)
+	if err != nil {
+		return err
+	}
+
+	for addr, stateHash := range i.latestStates {
+		value, err := types.BytesToHash(stateTrie.GetValue(addr.Bytes()))
+		if err != nil || value != stateHash {
+			return ErrInvalidAccountState
+		}
+	}
+
+	iterator := stateTrie.NewIterator(nil)
+	for {
+		key, value, ok := iterator.Next()
+		if !ok {
+			break
+		}
+		addr, err := types.BytesToAddress(key)
+		if err != nil {
+			return ErrInvalidAccountState
+		}
+		stateHash, err := types.BytesToHash(value)
+		if err != nil {
+			return ErrInvalidAccountState
+		}
+
+		// the accounts which have the genesis blocks only
+		if _, ok := i.latestStates[addr]; !ok {
+			latestBlock, err := i.chain.GetLatestAccountBlock(&addr)
+			if err != nil {
+				return err
+			}
+			if latestBlock == nil {
+				return ErrMissingAccountBlock
+			}
+			if latestBlock.StateHash != stateHash {
+				return ErrInvalidAccountState
+			}
+		}
+		if _, err := i.nodeSet.Trie(&stateHash); err != nil {
+			return err
+		}
+	}
+
+	if err := i.nodeSet.Save(i.batch); err != nil {
+		return err
+	}
+	return i.flush(false)
+}
+
+// readOnroad checks that the on road block is an unreceived send block to the account, which is confirmed by the
+// snapshot block.
+func (i *importer) readOnroad(data []byte) error {
+	record := &onroadRecord{}
+	if err := record.deserialize(data); err != nil {
+		return err
+	}
+
+	received, err := i.index.Has(receiveKey(record.hash))
+	if err != nil {
+		return err
+	}
+	if received {
+		return ErrInvalidOnroad
+	}
+	sendBlock, err := i.chain.GetAccountBlockByHash(&record.hash)
+	if err != nil {
+		return err
+	}
+	if sendBlock == nil || !sendBlock.IsSendBlock() || sendBlock.ToAddress != record.addr {
+		return ErrInvalidOnroad
+	}
+	confirmHeight, err := i.chain.ChainDb().Ac.GetConfirmHeight(&record.hash)
+	if err != nil {
+		return err
+	}
+	if confirmHeight <= 0 || confirmHeight > i.snapshotBlock.Height {
+		return ErrInvalidOnroad
+	}
+
+	if err := i.onroadSet.WriteMeta(i.batch, &record.addr, &record.hash); err != nil {
+		return err
+	}
+	return i.flush(false)
+}
+
+// writeContracts writes the contracts created in the snapshot into the address lists of their consensus groups.
+func (i *importer) writeContracts() error {
+	for gid, addrList := range i.contractAddrMap {
+		gid := gid
+		if err := i.onroadSet.WriteGidAddrList(i.batch, &gid, addrList); err != nil {
+			return err
+		}
+	}
+	return nil
+}
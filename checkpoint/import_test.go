@@ -0,0 +1,438 @@
+package checkpoint
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/checkdb"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/config"
+	"github.com/vitelabs/go-vite/crypto/ed25519"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/onroad/model"
+	"github.com/vitelabs/go-vite/vm_context"
+)
+
+func newTestChain(t *testing.T) (chain.Chain, func()) {
+	dir, err := ioutil.TempDir("", "checkpoint_chain")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := chain.NewChain(&config.Config{DataDir: dir, Chain: &config.Chain{LedgerInMemory: true}})
+	c.Init()
+	c.Start()
+	return c, func() {
+		c.Stop()
+		c.Destroy()
+		os.RemoveAll(dir)
+	}
+}
+
+// insertAccountBlock completes the block of addr, signs it with key if key isn't nil and inserts it.
+func insertAccountBlock(t *testing.T, c chain.Chain, block *ledger.AccountBlock, key ed25519.PrivateKey, storage []byte) *ledger.AccountBlock {
+	var prevHash *types.Hash
+	if latestBlock, _ := c.GetLatestAccountBlock(&block.AccountAddress); latestBlock != nil {
+		block.Height = latestBlock.Height + 1
+		block.PrevHash = latestBlock.Hash
+		prevHash = &latestBlock.Hash
+	} else {
+		block.Height = 1
+	}
+	vmContext, err := vm_context.NewVmContext(c, nil, prevHash, &block.AccountAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	block.SnapshotHash = c.GetLatestSnapshotBlock().Hash
+	block.Timestamp = &now
+	if block.Amount == nil {
+		block.Amount = big.NewInt(0)
+	}
+	block.Fee = big.NewInt(0)
+	if block.IsSendBlock() {
+		block.TokenId = ledger.ViteTokenId
+	}
+	if len(storage) > 0 {
+		vmContext.SetStorage(storage, storage)
+	}
+	if stateHash := vmContext.GetStorageHash(); stateHash != nil {
+		block.StateHash = *stateHash
+	}
+	block.Hash = block.ComputeHash()
+	if key != nil {
+		block.PublicKey = key.PubByte()
+		block.Signature = ed25519.Sign(key, block.Hash.Bytes())
+	}
+
+	if err := c.InsertAccountBlocks([]*vm_context.VmAccountBlock{{AccountBlock: block, VmContext: vmContext}}); err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+func insertSnapshotBlock(t *testing.T, c chain.Chain, key ed25519.PrivateKey) *ledger.SnapshotBlock {
+	latestBlock := c.GetLatestSnapshotBlock()
+	now := time.Now()
+	snapshotBlock := &ledger.SnapshotBlock{
+		Height:          latestBlock.Height + 1,
+		PrevHash:        latestBlock.Hash,
+		Timestamp:       &now,
+		SnapshotContent: c.GetNeedSnapshotContent(),
+	}
+
+	stateTrie, err := c.GenStateTrie(latestBlock.StateHash, snapshotBlock.SnapshotContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshotBlock.StateTrie = stateTrie
+	snapshotBlock.StateHash = *stateTrie.Hash()
+	snapshotBlock.Hash = snapshotBlock.ComputeHash()
+	snapshotBlock.PublicKey = key.PubByte()
+	snapshotBlock.Signature = ed25519.Sign(key, snapshotBlock.Hash.Bytes())
+
+	if err := c.InsertSnapshotBlock(snapshotBlock); err != nil {
+		t.Fatal(err)
+	}
+	return snapshotBlock
+}
+
+type testLedger struct {
+	userKey      ed25519.PrivateKey
+	producerKey  ed25519.PrivateKey
+	user         types.Address
+	contract     types.Address
+	receiver     types.Address
+	gid          types.Gid
+	onroadBlock  *ledger.AccountBlock
+	contractSend *ledger.AccountBlock
+
+	snapshotBlock *ledger.SnapshotBlock
+}
+
+// prepareLedger inserts a user which sends to an account and creates a contract, the contract receives the create
+// block and sends back to the user. The send to the account isn't received.
+func prepareLedger(t *testing.T, c chain.Chain) *testLedger {
+	l := &testLedger{}
+	l.user, l.userKey, _ = types.CreateAddress()
+	_, l.producerKey, _ = types.CreateAddress()
+	l.receiver, _, _ = types.CreateAddress()
+	l.contract, _, _ = types.CreateAddress()
+	l.gid = types.DataToGid([]byte("gid"))
+
+	l.onroadBlock = insertAccountBlock(t, c, &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeSendCall,
+		AccountAddress: l.user,
+		ToAddress:      l.receiver,
+		Amount:         big.NewInt(10),
+	}, l.userKey, []byte("user"))
+	createBlock := insertAccountBlock(t, c, &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeSendCreate,
+		AccountAddress: l.user,
+		ToAddress:      l.contract,
+		Data:           append(l.gid.Bytes(), []byte("code")...),
+	}, l.userKey, nil)
+	insertSnapshotBlock(t, c, l.producerKey)
+
+	insertAccountBlock(t, c, &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeReceive,
+		AccountAddress: l.contract,
+		FromBlockHash:  createBlock.Hash,
+	}, l.producerKey, []byte("contract"))
+	l.contractSend = insertAccountBlock(t, c, &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeSendCall,
+		AccountAddress: l.contract,
+		ToAddress:      l.user,
+	}, nil, nil)
+	insertSnapshotBlock(t, c, l.producerKey)
+
+	insertAccountBlock(t, c, &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeReceive,
+		AccountAddress: l.user,
+		FromBlockHash:  l.contractSend.Hash,
+	}, l.userKey, nil)
+	l.snapshotBlock = insertSnapshotBlock(t, c, l.producerKey)
+
+	// unconfirmed
+	insertAccountBlock(t, c, &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeSendCall,
+		AccountAddress: l.user,
+		ToAddress:      l.receiver,
+	}, l.userKey, nil)
+
+	batch := c.ChainDb().NewBatch()
+	if err := model.NewOnroadSet(c).WriteMeta(batch, &l.receiver, &l.onroadBlock.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ChainDb().Commit(batch); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func exportTestLedger(t *testing.T) (*testLedger, string, func()) {
+	c, closeChain := newTestChain(t)
+	defer closeChain()
+	l := prepareLedger(t, c)
+
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Export(c, l.snapshotBlock.Height, dir); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return l, dir, func() { os.RemoveAll(dir) }
+}
+
+func TestExportImport(t *testing.T) {
+	l, dir, removeDir := exportTestLedger(t)
+	defer removeDir()
+
+	c, closeChain := newTestChain(t)
+	defer closeChain()
+	if _, err := Import(c, dir, types.DataHash([]byte("untrusted"))); err != ErrUntrustedHash {
+		t.Fatalf("import with an untrusted hash should fail with %v, got %v", ErrUntrustedHash, err)
+	}
+	manifest, err := Import(c, dir, l.snapshotBlock.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.SnapshotHeight != l.snapshotBlock.Height {
+		t.Fatalf("snapshot height should be %d, got %d", l.snapshotBlock.Height, manifest.SnapshotHeight)
+	}
+
+	snapshotBlock, err := c.GetSnapshotBlockByHeight(l.snapshotBlock.Height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshotBlock == nil || snapshotBlock.Hash != l.snapshotBlock.Hash {
+		t.Fatal("snapshot block should be imported")
+	}
+
+	contractSend, err := c.GetAccountBlockByHash(&l.contractSend.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contractSend == nil || contractSend.AccountAddress != l.contract {
+		t.Fatal("send block of the contract should be imported")
+	}
+	if confirmHeight, err := c.ChainDb().Ac.GetConfirmHeight(&l.contractSend.Hash); err != nil || confirmHeight != l.snapshotBlock.Height-1 {
+		t.Fatalf("send block of the contract should be confirmed at %d, got %d, error %v", l.snapshotBlock.Height-1, confirmHeight, err)
+	}
+	if meta, err := c.ChainDb().Ac.GetBlockMeta(&l.contractSend.Hash); err != nil || len(meta.ReceiveBlockHeights) != 1 || meta.ReceiveBlockHeights[0] != 3 {
+		t.Fatalf("send block of the contract should be received at 3, error %v", err)
+	}
+	if latestBlock, err := c.GetLatestAccountBlock(&l.user); err != nil || latestBlock.Height != 3 {
+		t.Fatalf("the unconfirmed block of the user shouldn't be imported, error %v", err)
+	}
+
+	gid, err := c.GetContractGid(&l.contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gid == nil || *gid != l.gid {
+		t.Fatalf("gid of the contract should be %s, got %v", l.gid, gid)
+	}
+
+	stateHash, _ := types.BytesToHash(c.GetStateTrie(&l.snapshotBlock.StateHash).GetValue(l.contract.Bytes()))
+	if value := c.GetStateTrie(&stateHash).GetValue([]byte("contract")); !bytes.Equal(value, []byte("contract")) {
+		t.Fatalf("storage of the contract should be imported, got %s", value)
+	}
+
+	if report := checkdb.Check(c.ChainDb(), chain.LedgerGenesis(), 10); !report.Consistent() {
+		t.Fatalf("imported ledger should be consistent, got %d issues", report.IssueCount)
+	}
+
+	onroadKey, _ := database.EncodeKey(database.DBKP_ONROADMETA, l.receiver.Bytes(), l.onroadBlock.Hash.Bytes())
+	if ok, err := c.ChainDb().Db().Has(onroadKey); err != nil || !ok {
+		t.Fatalf("on road block should be imported, error %v", err)
+	}
+	iter := c.ChainDb().Db().NewIterator(util.BytesPrefix([]byte{database.DBKP_ONROADMETA}))
+	defer iter.Release()
+	for iter.Next() {
+		if !bytes.Equal(iter.Key(), onroadKey) && bytes.HasPrefix(iter.Key()[1:], l.user.Bytes()) {
+			t.Fatal("the received send block to the user shouldn't be on road")
+		}
+	}
+}
+
+// rewriteFile rewrites the records of the file with modify, and updates the manifest.
+func rewriteFile(t *testing.T, dir string, name string, modify func(records [][]byte) [][]byte) {
+	reader, err := newRecordReader(dir, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records [][]byte
+	for {
+		data, err := reader.read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, data)
+	}
+	reader.close()
+
+	writer, err := newRecordWriter(dir, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range modify(records) {
+		if err := writer.write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := writer.close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	*manifest.File(name) = *info
+	if err := manifest.write(dir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func copyDir(t *testing.T, dir string) string {
+	copied, err := ioutil.TempDir("", "checkpoint_tampered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(copied, file.Name()), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return copied
+}
+
+func TestImport_Tampered(t *testing.T) {
+	l, dir, removeDir := exportTestLedger(t)
+	defer removeDir()
+
+	// the records of a block of the contract
+	modifyContractBlock := func(height uint64, modify func(record *blockRecord)) func(records [][]byte) [][]byte {
+		return func(records [][]byte) [][]byte {
+			for i, data := range records {
+				record := &blockRecord{}
+				if err := record.deserialize(data); err != nil {
+					t.Fatal(err)
+				}
+				if record.block.AccountAddress == l.contract && record.block.Height == height {
+					modify(record)
+					records[i], _ = record.serialize()
+				}
+			}
+			return records
+		}
+	}
+
+	cases := []struct {
+		name   string
+		tamper func(dir string)
+		err    error
+	}{
+		{"unsigned contract receive", func(dir string) {
+			rewriteFile(t, dir, accountBlocksFileName, modifyContractBlock(1, func(record *blockRecord) {
+				record.block.Signature = nil
+				record.block.PublicKey = nil
+			}))
+		}, ErrInvalidAccountBlock},
+		{"signed contract send", func(dir string) {
+			_, key, _ := types.CreateAddress()
+			rewriteFile(t, dir, accountBlocksFileName, modifyContractBlock(2, func(record *blockRecord) {
+				record.block.PublicKey = key.PubByte()
+				record.block.Signature = ed25519.Sign(key, record.block.Hash.Bytes())
+			}))
+		}, ErrInvalidAccountBlock},
+		{"contract receive of no send block", func(dir string) {
+			// the snapshot content isn't covered by the hash of the snapshot block
+			_, key, _ := types.CreateAddress()
+			forged := &ledger.AccountBlock{
+				BlockType:      ledger.BlockTypeReceive,
+				Height:         3,
+				PrevHash:       l.contractSend.Hash,
+				AccountAddress: l.contract,
+				FromBlockHash:  types.DataHash([]byte("no send block")),
+				SnapshotHash:   l.snapshotBlock.PrevHash,
+				Timestamp:      l.contractSend.Timestamp,
+				StateHash:      l.contractSend.StateHash,
+				Fee:            big.NewInt(0),
+			}
+			forged.Hash = forged.ComputeHash()
+			forged.PublicKey = key.PubByte()
+			forged.Signature = ed25519.Sign(key, forged.Hash.Bytes())
+
+			rewriteFile(t, dir, snapshotBlockFileName, func(records [][]byte) [][]byte {
+				block := &ledger.SnapshotBlock{}
+				if err := block.Deserialize(records[0]); err != nil {
+					t.Fatal(err)
+				}
+				block.SnapshotContent[l.contract] = &ledger.HashHeight{Height: forged.Height, Hash: forged.Hash}
+				records[0], _ = block.Serialize()
+				return records
+			})
+			rewriteFile(t, dir, accountBlocksFileName, func(records [][]byte) [][]byte {
+				for i, data := range records {
+					record := &blockRecord{}
+					if err := record.deserialize(data); err != nil {
+						t.Fatal(err)
+					}
+					if record.block.Hash == l.contractSend.Hash {
+						forgedData, _ := (&blockRecord{block: forged, confirmHeight: l.snapshotBlock.Height}).serialize()
+						return append(records[:i+1], append([][]byte{forgedData}, records[i+1:]...)...)
+					}
+				}
+				t.Fatal("send block of the contract isn't exported")
+				return nil
+			})
+		}, ErrInvalidReceiveBlock},
+		{"confirm height", func(dir string) {
+			rewriteFile(t, dir, accountBlocksFileName, modifyContractBlock(1, func(record *blockRecord) {
+				record.confirmHeight = l.snapshotBlock.Height
+			}))
+		}, ErrInvalidAccountChain},
+		{"missing snapshot block", func(dir string) {
+			rewriteFile(t, dir, snapshotBlockFileName, func(records [][]byte) [][]byte {
+				return records[:len(records)-1]
+			})
+		}, ErrInvalidSnapshotBlock},
+	}
+	for _, tc := range cases {
+		tamperedDir := copyDir(t, dir)
+		tc.tamper(tamperedDir)
+
+		c, closeChain := newTestChain(t)
+		if _, err := Import(c, tamperedDir, l.snapshotBlock.Hash); err != tc.err {
+			t.Errorf("import with tampered %s should fail with %v, got %v", tc.name, tc.err, err)
+		}
+		closeChain()
+		os.RemoveAll(tamperedDir)
+	}
+}
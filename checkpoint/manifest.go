@@ -0,0 +1,105 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/vitelabs/go-vite/common/types"
+)
+
+const (
+	ManifestVersion = uint32(1)
+
+	manifestFileName = "manifest.json"
+
+	snapshotBlockFileName = "snapshot_block"
+	accountBlocksFileName = "account_blocks"
+	stateFileName         = "state"
+	onroadFileName        = "onroad"
+)
+
+var (
+	ErrManifestVersion  = errors.New("manifest version isn't supported")
+	ErrUntrustedHash    = errors.New("snapshot hash of the manifest isn't the trusted hash")
+	ErrFileNotInList    = errors.New("file isn't in the manifest")
+	ErrFileHashMismatch = errors.New("file size or hash doesn't match the manifest")
+)
+
+// Manifest describes a ledger snapshot, which is the state of the chain at the snapshot block with SnapshotHash.
+// Every file of the snapshot is listed with its size and hash, so a manifest of which the SnapshotHash is trusted
+// verifies all the files.
+type Manifest struct {
+	Version        uint32     `json:"version"`
+	SnapshotHash   types.Hash `json:"snapshotHash"`
+	SnapshotHeight uint64     `json:"snapshotHeight"`
+	StateHash      types.Hash `json:"stateHash"`
+
+	Files []*FileInfo `json:"files"`
+}
+
+type FileInfo struct {
+	Name    string     `json:"name"`
+	Size    uint64     `json:"size"`
+	Records uint64     `json:"records"`
+	Hash    types.Hash `json:"hash"`
+}
+
+func (m *Manifest) File(name string) *FileInfo {
+	for _, file := range m.Files {
+		if file.Name == name {
+			return file
+		}
+	}
+	return nil
+}
+
+func (m *Manifest) write(dir string) error {
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, manifestFileName), data, 0644)
+}
+
+// ReadManifest reads the manifest of the ledger snapshot in dir, the files aren't verified.
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, manifestFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if m.Version != ManifestVersion {
+		return nil, ErrManifestVersion
+	}
+	return m, nil
+}
+
+// verifyFile checks the size and the hash of the file against the manifest before it is read.
+func (m *Manifest) verifyFile(dir string, name string) error {
+	info := m.File(name)
+	if info == nil {
+		return ErrFileNotInList
+	}
+
+	file, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	size, hash, err := hashReader(file)
+	if err != nil {
+		return err
+	}
+	if size != info.Size || hash != info.Hash {
+		return ErrFileHashMismatch
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package checkpoint
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"golang.org/x/crypto/blake2b"
+)
+
+const maxRecordSize = 64 * 1024 * 1024
+
+var ErrRecordTooLarge = errors.New("record is too large")
+
+// A record file is a sequence of records, each record is its length in 4 bytes big endian followed by the data.
+type recordWriter struct {
+	name string
+	file *os.File
+	buf  *bufio.Writer
+
+	hasher  hash.Hash
+	size    uint64
+	records uint64
+}
+
+func newRecordWriter(dir string, name string) (*recordWriter, error) {
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	hasher, _ := blake2b.New256(nil)
+	return &recordWriter{
+		name:   name,
+		file:   file,
+		buf:    bufio.NewWriter(io.MultiWriter(file, hasher)),
+		hasher: hasher,
+	}, nil
+}
+
+func (w *recordWriter) write(data []byte) error {
+	if len(data) > maxRecordSize {
+		return ErrRecordTooLarge
+	}
+
+	lengthBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(lengthBytes, uint32(len(data)))
+	if _, err := w.buf.Write(lengthBytes); err != nil {
+		return err
+	}
+	if _, err := w.buf.Write(data); err != nil {
+		return err
+	}
+
+	w.size += uint64(len(lengthBytes) + len(data))
+	w.records++
+	return nil
+}
+
+// close flushes and closes the file, then returns the file info for the manifest.
+func (w *recordWriter) close() (*FileInfo, error) {
+	if err := w.buf.Flush(); err != nil {
+		w.file.Close()
+		return nil, err
+	}
+	if err := w.file.Close(); err != nil {
+		return nil, err
+	}
+
+	fileHash, _ := types.BytesToHash(w.hasher.Sum(nil))
+	return &FileInfo{
+		Name:    w.name,
+		Size:    w.size,
+		Records: w.records,
+		Hash:    fileHash,
+	}, nil
+}
+
+type recordReader struct {
+	file *os.File
+	buf  *bufio.Reader
+}
+
+func newRecordReader(dir string, name string) (*recordReader, error) {
+	file, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	return &recordReader{
+		file: file,
+		buf:  bufio.NewReader(file),
+	}, nil
+}
+
+// read returns the next record, io.EOF means there is no record anymore.
+func (r *recordReader) read() ([]byte, error) {
+	lengthBytes := make([]byte, 4)
+	if _, err := io.ReadFull(r.buf, lengthBytes); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(lengthBytes)
+	if length > maxRecordSize {
+		return nil, ErrRecordTooLarge
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r.buf, data); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+func (r *recordReader) close() error {
+	return r.file.Close()
+}
+
+func hashReader(reader io.Reader) (uint64, types.Hash, error) {
+	hasher, _ := blake2b.New256(nil)
+	size, err := io.Copy(hasher, reader)
+	if err != nil {
+		return 0, types.Hash{}, err
+	}
+
+	readerHash, _ := types.BytesToHash(hasher.Sum(nil))
+	return uint64(size), readerHash, nil
+}
@@ -0,0 +1,83 @@
+package checkpoint
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	records := [][]byte{[]byte("first"), {}, bytes.Repeat([]byte("third"), 1000)}
+	writer, err := newRecordWriter(dir, stateFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if err := writer.write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := writer.close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Records != uint64(len(records)) {
+		t.Fatalf("records should be %d, got %d", len(records), info.Records)
+	}
+
+	manifest := &Manifest{Version: ManifestVersion, Files: []*FileInfo{info}}
+	if err := manifest.write(dir); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err = ReadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manifest.verifyFile(dir, stateFileName); err != nil {
+		t.Fatal(err)
+	}
+	if err := manifest.verifyFile(dir, onroadFileName); err != ErrFileNotInList {
+		t.Fatalf("file not in the manifest should fail, got %v", err)
+	}
+
+	reader, err := newRecordReader(dir, stateFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, record := range records {
+		data, err := reader.read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, record) {
+			t.Fatalf("record %d doesn't match", i)
+		}
+	}
+	if _, err := reader.read(); err != io.EOF {
+		t.Fatalf("read should return io.EOF at the end, got %v", err)
+	}
+	reader.close()
+
+	// tamper with the last byte
+	path := filepath.Join(dir, stateFileName)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 1
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := manifest.verifyFile(dir, stateFileName); err != ErrFileHashMismatch {
+		t.Fatalf("tampered file should fail, got %v", err)
+	}
+}
@@ -0,0 +1,458 @@
+package gvite_plugins
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/chain_db"
+	"github.com/vitelabs/go-vite/checkdb"
+	"github.com/vitelabs/go-vite/checkpoint"
+	"github.com/vitelabs/go-vite/cmd/nodemanager"
+	"github.com/vitelabs/go-vite/cmd/utils"
+	"github.com/vitelabs/go-vite/cmd/utils/flock"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/config"
+	"github.com/vitelabs/go-vite/pow"
+	"github.com/vitelabs/go-vite/replay"
+	"github.com/vitelabs/go-vite/statedump"
+	"github.com/vitelabs/go-vite/vm"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	ledgerFlags = utils.MergeFlags(configFlags, generalFlags, logFlags)
+
+	pruneCommand = cli.Command{
+		Action:    utils.MigrateFlags(pruneAction),
+		Name:      "prune",
+		Usage:     "Delete the states which aren't needed anymore from the ledger",
+		ArgsUsage: " ",
+		Flags:     append(ledgerFlags, utils.KeepHeightFlag),
+		Category:  "LEDGER COMMANDS",
+		Description: `
+Delete the trie nodes which are neither reachable from the states of the latest
+keepheight snapshot blocks nor from the states of the account blocks confirmed
+by them or unconfirmed. The node must be stopped.`,
+	}
+
+	exportSnapshotCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportSnapshotAction),
+		Name:      "export-snapshot",
+		Usage:     "Export the ledger snapshot at a snapshot block",
+		ArgsUsage: " ",
+		Flags:     append(ledgerFlags, utils.SnapshotHeightFlag, utils.SnapshotDirFlag),
+		Category:  "LEDGER COMMANDS",
+		Description: `
+Write the snapshot blocks from snapshotheight down to the genesis ones, the
+account blocks confirmed by it except for the genesis ones, the states of the
+latest ones and the on road blocks into snapshotdir, with a manifest of the
+hashes of the files. The node must be stopped.`,
+	}
+
+	importSnapshotCommand = cli.Command{
+		Action:    utils.MigrateFlags(importSnapshotAction),
+		Name:      "import-snapshot",
+		Usage:     "Bootstrap the ledger from a ledger snapshot",
+		ArgsUsage: " ",
+		Flags:     append(ledgerFlags, utils.SnapshotDirFlag, utils.TrustedHashFlag),
+		Category:  "LEDGER COMMANDS",
+		Description: `
+Verify the ledger snapshot in snapshotdir against trustedhash, the hash of the
+snapshot block it is taken at, and use it as the ledger of the node. The data
+dir mustn't have a ledger, the states below the snapshot block aren't imported.`,
+	}
+
+	checkDbCommand = cli.Command{
+		Action:    utils.MigrateFlags(checkDbAction),
+		Name:      "checkdb",
+		Usage:     "Check the integrity of the ledger",
+		ArgsUsage: " ",
+		Flags:     append(ledgerFlags, utils.ReportFileFlag, utils.MaxIssuesFlag),
+		Category:  "LEDGER COMMANDS",
+		Description: `
+Walk the snapshot chain, the account chains, the block metas, the confirm
+heights, the on road blocks, the log lists and the account id index, check the
+hashes, links, signatures and states, and write the inconsistencies as a JSON
+report, at most maxissues of them are listed and all of them are counted. The
+ledger is opened read-only, a corrupted db isn't recovered. The node must be
+stopped.`,
+	}
+
+	dumpStateCommand = cli.Command{
+		Action:    utils.MigrateFlags(dumpStateAction),
+		Name:      "dump-state",
+		Usage:     "Dump the balances of all the accounts at a snapshot block",
+		ArgsUsage: " ",
+		Flags:     append(ledgerFlags, utils.SnapshotHeightFlag, utils.TokenIdFlag, utils.DumpFormatFlag, utils.OutputFileFlag),
+		Category:  "LEDGER COMMANDS",
+		Description: `
+Write the balances of every account at the snapshot block of snapshotheight, as
+a csv row for each balance or a JSON line for each account. Only the balances
+of tokenid are written if it's set, the accounts without balance are skipped.
+The ledger isn't modified, the node must be stopped.`,
+	}
+
+	replayCommand = cli.Command{
+		Action:    utils.MigrateFlags(replayAction),
+		Name:      "replay",
+		Usage:     "Re-execute the ledger and compare the results with the recorded blocks",
+		ArgsUsage: " ",
+		Flags: append(ledgerFlags, utils.VMTestFlag, utils.VMTestParamFlag, utils.SourceDirFlag, utils.FromFilesFlag,
+			utils.ReplayDirFlag, utils.ToHeightFlag, utils.ReportFileFlag),
+		Category: "LEDGER COMMANDS",
+		Description: `
+Run every account block of the ledger in sourcedir, or of its compressed ledger
+files with fromfiles, through the generator and the vm into a new ledger in
+replaydir, and compare the hash, the state hash, the log hash and the balances
+of each block with the recorded one. The replay stops at the first mismatch,
+which is written as a JSON report. A replay into an existing replaydir resumes
+from its latest snapshot block. The source ledger isn't modified.`,
+	}
+)
+
+// lockDataDir makes sure the data dir isn't used by a running node.
+func lockDataDir(dataDir string) (flock.Releaser, error) {
+	lockDir := filepath.Join(dataDir, "LOCK")
+	release, _, err := flock.New(lockDir)
+	if err != nil {
+		return nil, fmt.Errorf("lock %s failed, the node may be running: %v", lockDir, err)
+	}
+	return release, nil
+}
+
+// openLedger opens the chain of the data dir offline, the returned function closes it.
+func openLedger(ctx *cli.Context) (chain.Chain, func(), error) {
+	nodeConfig := nodemanager.FullNodeMaker{}.MakeNodeConfig(ctx)
+
+	release, err := lockDataDir(nodeConfig.DataDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	viteConfig := nodeConfig.ViteConfig()
+	viteConfig.Chain = offlineChainConfig(viteConfig)
+	c := chain.NewChain(viteConfig)
+	c.Init()
+	c.Start()
+	return c, func() {
+		c.Stop()
+		c.Destroy()
+		release.Release()
+	}, nil
+}
+
+func pruneAction(ctx *cli.Context) error {
+	c, closeLedger, err := openLedger(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeLedger()
+
+	keepHeight := ctx.Uint64(utils.KeepHeightFlag.Name)
+	fmt.Printf("Prune the states before the latest %d snapshot blocks, latest snapshot height is %d\n", keepHeight, c.GetLatestSnapshotBlock().Height)
+	if err := c.PruneState(keepHeight); err != nil {
+		return err
+	}
+	fmt.Println("Prune finished")
+	return nil
+}
+
+func exportSnapshotAction(ctx *cli.Context) error {
+	dir := ctx.String(utils.SnapshotDirFlag.Name)
+	if len(dir) <= 0 {
+		return errors.New("snapshotdir is required")
+	}
+
+	c, closeLedger, err := openLedger(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeLedger()
+
+	height := ctx.Uint64(utils.SnapshotHeightFlag.Name)
+	if height <= 0 {
+		height = c.GetLatestSnapshotBlock().Height
+	}
+
+	fmt.Printf("Export the ledger snapshot at snapshot height %d to %s\n", height, dir)
+	manifest, err := checkpoint.Export(c, height, dir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Export finished, snapshot hash is %s\n", manifest.SnapshotHash)
+	return nil
+}
+
+func importSnapshotAction(ctx *cli.Context) error {
+	dir := ctx.String(utils.SnapshotDirFlag.Name)
+	if len(dir) <= 0 {
+		return errors.New("snapshotdir is required")
+	}
+	trustedHash, err := types.HexToHash(ctx.String(utils.TrustedHashFlag.Name))
+	if err != nil {
+		return fmt.Errorf("trustedhash is invalid: %v", err)
+	}
+
+	nodeConfig := nodemanager.FullNodeMaker{}.MakeNodeConfig(ctx)
+	release, err := lockDataDir(nodeConfig.DataDir)
+	if err != nil {
+		return err
+	}
+	defer release.Release()
+
+	ledgerDir := filepath.Join(nodeConfig.DataDir, "ledger")
+	if _, err := os.Stat(ledgerDir); err == nil {
+		return fmt.Errorf("%s already exists, remove it before importing a ledger snapshot", ledgerDir)
+	}
+
+	// the snapshot is imported into a new ledger in the staging dir, which is activated after the import succeeded
+	stagingDir := filepath.Join(nodeConfig.DataDir, "ledger_import")
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return err
+	}
+	defer os.RemoveAll(stagingDir)
+
+	viteConfig := nodeConfig.ViteConfig()
+	viteConfig.Chain = offlineChainConfig(viteConfig)
+	viteConfig.DataDir = stagingDir
+
+	c := chain.NewChain(viteConfig)
+	c.Init()
+	c.Start()
+	fmt.Printf("Import the ledger snapshot in %s\n", dir)
+	manifest, importErr := checkpoint.Import(c, dir, trustedHash)
+	c.Stop()
+	c.Destroy()
+	if importErr != nil {
+		return importErr
+	}
+
+	if err := os.Rename(filepath.Join(stagingDir, "ledger"), ledgerDir); err != nil {
+		return err
+	}
+	fmt.Printf("Import finished, the ledger starts at snapshot height %d\n", manifest.SnapshotHeight)
+	return nil
+}
+
+func checkDbAction(ctx *cli.Context) error {
+	nodeConfig := nodemanager.FullNodeMaker{}.MakeNodeConfig(ctx)
+	release, err := lockDataDir(nodeConfig.DataDir)
+	if err != nil {
+		return err
+	}
+	defer release.Release()
+
+	ledgerDir := filepath.Join(nodeConfig.DataDir, "ledger")
+	if _, err := os.Stat(ledgerDir); err != nil {
+		return fmt.Errorf("%s doesn't have a ledger: %v", nodeConfig.DataDir, err)
+	}
+
+	// the chain isn't created, it would recover the corrupted db and rewrite the index of the ledger files
+	chainDb, err := chain_db.NewReadOnlyChainDb(ledgerDir)
+	if err != nil {
+		return fmt.Errorf("open %s failed, start the node to recover the corrupted db: %v", ledgerDir, err)
+	}
+	defer chainDb.Db().Close()
+
+	// the genesis blocks are loaded by NewChain, the chain isn't initialized
+	chain.NewChain(nodeConfig.ViteConfig())
+
+	fmt.Fprintf(os.Stderr, "Check the ledger in %s\n", ledgerDir)
+	report := checkdb.Check(chainDb, chain.LedgerGenesis(), ctx.Int(utils.MaxIssuesFlag.Name))
+
+	if reportFile := ctx.String(utils.ReportFileFlag.Name); len(reportFile) > 0 {
+		file, err := os.Create(reportFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if err := report.Write(file); err != nil {
+			return err
+		}
+	} else if err := report.Write(os.Stdout); err != nil {
+		return err
+	}
+
+	if !report.Consistent() {
+		if omitted := report.Omitted(); omitted > 0 {
+			fmt.Fprintf(os.Stderr, "%d of the issues aren't listed in the report\n", omitted)
+		}
+		return fmt.Errorf("ledger has %d inconsistencies", report.IssueCount)
+	}
+	fmt.Fprintln(os.Stderr, "Ledger is consistent")
+	return nil
+}
+
+// offlineChainConfig copies the chain config for the ledger opened by a command, no event is sent and no state is
+// pruned.
+func offlineChainConfig(viteConfig *config.Config) *config.Chain {
+	chainConfig := &config.Chain{}
+	if viteConfig.Chain != nil {
+		*chainConfig = *viteConfig.Chain
+	}
+	chainConfig.EventSinks = nil
+	chainConfig.StatePruneHeight = 0
+	chainConfig.WipeCorruptedLedger = false
+	return chainConfig
+}
+
+func replayAction(ctx *cli.Context) error {
+	nodeConfig := nodemanager.FullNodeMaker{}.MakeNodeConfig(ctx)
+	viteConfig := nodeConfig.ViteConfig()
+	vm.InitVmConfig(viteConfig.Vm.IsVmTest, viteConfig.Vm.IsUseVmTestParam)
+	pow.Init(nodeConfig.VMTestParamEnabled)
+
+	sourceDir := ctx.String(utils.SourceDirFlag.Name)
+	if len(sourceDir) <= 0 {
+		sourceDir = nodeConfig.DataDir
+	}
+	release, err := lockDataDir(sourceDir)
+	if err != nil {
+		return err
+	}
+	defer release.Release()
+
+	var source replay.Source
+	if ctx.Bool(utils.FromFilesFlag.Name) {
+		if source, err = replay.NewFileSource(filepath.Join(sourceDir, "ledger_files")); err != nil {
+			return err
+		}
+	} else {
+		ledgerDir := filepath.Join(sourceDir, "ledger")
+		if _, err := os.Stat(ledgerDir); err != nil {
+			return fmt.Errorf("%s doesn't have a ledger: %v", sourceDir, err)
+		}
+
+		// the source chain is only initialized, starting it may repair or wipe the ledger
+		sourceConfig := *viteConfig
+		sourceConfig.Chain = offlineChainConfig(viteConfig)
+		sourceConfig.DataDir = sourceDir
+
+		sourceChain := chain.NewChain(&sourceConfig)
+		sourceChain.Init()
+		defer sourceChain.Destroy()
+
+		if source, err = replay.NewChainSource(sourceChain); err != nil {
+			return err
+		}
+	}
+
+	replayDir := ctx.String(utils.ReplayDirFlag.Name)
+	if len(replayDir) <= 0 {
+		if replayDir, err = ioutil.TempDir("", "gvite_replay"); err != nil {
+			return err
+		}
+		defer os.RemoveAll(replayDir)
+	} else {
+		if err := os.MkdirAll(replayDir, 0700); err != nil {
+			return err
+		}
+		replayRelease, err := lockDataDir(replayDir)
+		if err != nil {
+			return err
+		}
+		defer replayRelease.Release()
+	}
+
+	targetConfig := *viteConfig
+	targetConfig.Chain = offlineChainConfig(viteConfig)
+	targetConfig.Chain.OpenTxIndex = false
+	targetConfig.Chain.OpenLogIndex = false
+	targetConfig.DataDir = replayDir
+
+	target := chain.NewChain(&targetConfig)
+	target.Init()
+	target.Start()
+	// the replayed ledger isn't compressed
+	target.Compressor().Stop()
+	defer func() {
+		target.Stop()
+		target.Destroy()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Replay the ledger in %s into %s\n", sourceDir, replayDir)
+	result, replayErr := replay.Replay(source, target, ctx.Uint64(utils.ToHeightFlag.Name))
+
+	if reportFile := ctx.String(utils.ReportFileFlag.Name); len(reportFile) > 0 {
+		file, err := os.Create(reportFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if err := result.Write(file); err != nil {
+			return err
+		}
+	} else if err := result.Write(os.Stdout); err != nil {
+		return err
+	}
+
+	if replayErr != nil {
+		return replayErr
+	}
+	if !result.Consistent() {
+		return fmt.Errorf("replay mismatched at snapshot height %d", result.Mismatch.SnapshotHeight)
+	}
+	fmt.Fprintf(os.Stderr, "Replay finished, %d snapshot blocks and %d account blocks match\n", result.SnapshotBlocks, result.AccountBlocks)
+	return nil
+}
+
+func dumpStateAction(ctx *cli.Context) error {
+	var tokenId *types.TokenTypeId
+	if tokenIdStr := ctx.String(utils.TokenIdFlag.Name); len(tokenIdStr) > 0 {
+		id, err := types.HexToTokenTypeId(tokenIdStr)
+		if err != nil {
+			return fmt.Errorf("tokenid is invalid: %v", err)
+		}
+		tokenId = &id
+	}
+
+	output := os.Stdout
+	if outputFile := ctx.String(utils.OutputFileFlag.Name); len(outputFile) > 0 {
+		file, err := os.Create(outputFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		output = file
+	}
+	w, err := statedump.NewWriter(ctx.String(utils.DumpFormatFlag.Name), output)
+	if err != nil {
+		return err
+	}
+
+	nodeConfig := nodemanager.FullNodeMaker{}.MakeNodeConfig(ctx)
+	release, err := lockDataDir(nodeConfig.DataDir)
+	if err != nil {
+		return err
+	}
+	defer release.Release()
+
+	ledgerDir := filepath.Join(nodeConfig.DataDir, "ledger")
+	if _, err := os.Stat(ledgerDir); err != nil {
+		return fmt.Errorf("%s doesn't have a ledger: %v", nodeConfig.DataDir, err)
+	}
+
+	// the chain is only initialized, starting it may repair or wipe the ledger
+	viteConfig := nodeConfig.ViteConfig()
+	viteConfig.Chain = offlineChainConfig(viteConfig)
+
+	c := chain.NewChain(viteConfig)
+	c.Init()
+	defer c.Destroy()
+
+	d, err := statedump.NewDumper(c, ctx.Uint64(utils.SnapshotHeightFlag.Name), tokenId)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Dump the state at snapshot height %d\n", d.SnapshotBlock().Height)
+	count, err := statedump.Dump(d, w)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Dump finished, %d accounts\n", count)
+	return nil
+}
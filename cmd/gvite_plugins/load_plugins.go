@@ -104,6 +104,14 @@ var (
 		utils.PProfEnabledFlag,
 		utils.PProfPortFlag,
 	}
+
+	//Ledger
+	chainFlags = []cli.Flag{
+		utils.RepairLedgerFlag,
+		utils.WipeCorruptedLedgerFlag,
+		utils.LedgerArchiveAddrFlag,
+		utils.LedgerArchivePortFlag,
+	}
 )
 
 func init() {
@@ -128,11 +136,17 @@ func init() {
 		licenseCommand,
 		consoleCommand,
 		attachCommand,
+		pruneCommand,
+		exportSnapshotCommand,
+		importSnapshotCommand,
+		checkDbCommand,
+		replayCommand,
+		dumpStateCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
 	//Import: Please add the New Flags here
-	app.Flags = utils.MergeFlags(configFlags, generalFlags, p2pFlags, ipcFlags, httpFlags, wsFlags, consoleFlags, producerFlags, logFlags, vmFlags, netFlags, statFlags)
+	app.Flags = utils.MergeFlags(configFlags, generalFlags, p2pFlags, ipcFlags, httpFlags, wsFlags, consoleFlags, producerFlags, logFlags, vmFlags, netFlags, statFlags, chainFlags)
 
 	app.Before = beforeAction
 	app.Action = action
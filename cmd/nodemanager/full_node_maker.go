@@ -162,6 +162,22 @@ func mappingNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(utils.FilePortFlag.Name) {
 		cfg.FilePort = ctx.GlobalInt(utils.FilePortFlag.Name)
 	}
+
+	//Ledger
+	if ctx.GlobalIsSet(utils.RepairLedgerFlag.Name) {
+		cfg.RepairLedger = ctx.GlobalBool(utils.RepairLedgerFlag.Name)
+	}
+	if ctx.GlobalIsSet(utils.WipeCorruptedLedgerFlag.Name) {
+		cfg.WipeCorruptedLedger = ctx.GlobalBool(utils.WipeCorruptedLedgerFlag.Name)
+	}
+
+	if archiveHost := ctx.GlobalString(utils.LedgerArchiveAddrFlag.Name); len(archiveHost) > 0 {
+		cfg.LedgerArchiveHost = archiveHost
+	}
+
+	if ctx.GlobalIsSet(utils.LedgerArchivePortFlag.Name) {
+		cfg.LedgerArchivePort = ctx.GlobalInt(utils.LedgerArchivePortFlag.Name)
+	}
 }
 
 func overrideNodeConfigs(ctx *cli.Context, cfg *node.Config) {
@@ -176,6 +176,79 @@ var (
 		Name:  "pprofport",
 		Usage: "pporof visit `port`, you can visit the address[http://localhost:`port`/debug/pprof]",
 	}
+
+	//Ledger
+	RepairLedgerFlag = cli.BoolFlag{
+		Name:  "repairledger",
+		Usage: "Check the latest snapshot blocks of the ledger at start up and truncate it back to the last consistent one",
+	}
+	WipeCorruptedLedgerFlag = cli.BoolFlag{
+		Name:  "wipecorrupted",
+		Usage: "Wipe the ledger and sync again if it's corrupted and can't be repaired",
+	}
+	LedgerArchiveAddrFlag = cli.StringFlag{
+		Name:  "archiveaddr",
+		Usage: "Ledger archive listening interface, the ledger files are served over HTTP if it's set",
+	}
+	LedgerArchivePortFlag = cli.IntFlag{
+		Name:  "archiveport",
+		Usage: "Ledger archive listening port",
+	}
+	KeepHeightFlag = cli.Uint64Flag{
+		Name:  "keepheight",
+		Usage: "Number of the latest snapshot blocks of which the states are kept",
+		Value: 1000,
+	}
+	SnapshotHeightFlag = cli.Uint64Flag{
+		Name:  "snapshotheight",
+		Usage: "Height of the snapshot block which the ledger snapshot or the state dump is taken at, 0 means the latest",
+	}
+	SnapshotDirFlag = DirectoryFlag{
+		Name:  "snapshotdir",
+		Usage: "Directory of the ledger snapshot",
+	}
+	SourceDirFlag = DirectoryFlag{
+		Name:  "sourcedir",
+		Usage: "Data directory of the ledger to replay, the data dir of the node if empty",
+	}
+	FromFilesFlag = cli.BoolFlag{
+		Name:  "fromfiles",
+		Usage: "Replay the blocks of the compressed ledger files instead of the ledger db",
+	}
+	ReplayDirFlag = DirectoryFlag{
+		Name:  "replaydir",
+		Usage: "Data directory of the replayed ledger, a temporary directory removed after the replay if empty",
+	}
+	ToHeightFlag = cli.Uint64Flag{
+		Name:  "toheight",
+		Usage: "Height of the last snapshot block to replay, 0 means the latest",
+	}
+	TrustedHashFlag = cli.StringFlag{
+		Name:  "trustedhash",
+		Usage: "Trusted hash of the snapshot block which the ledger snapshot is taken at",
+	}
+	TokenIdFlag = cli.StringFlag{
+		Name:  "tokenid",
+		Usage: "Only dump the balances of the token",
+	}
+	DumpFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "Format of the state dump, csv or json",
+		Value: "csv",
+	}
+	OutputFileFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "Write the state dump into `file` instead of the standard output",
+	}
+	ReportFileFlag = cli.StringFlag{
+		Name:  "report",
+		Usage: "Write the report into `file` instead of the standard output",
+	}
+	MaxIssuesFlag = cli.IntFlag{
+		Name:  "maxissues",
+		Usage: "Max number of the issues listed in the report, 0 lists all of them",
+		Value: 1000,
+	}
 )
 
 // This allows the use of the existing configuration functionality.
@@ -14,6 +14,8 @@ const (
 	DefaultWSHost   = "localhost" // Default host interface for the websocket RPC server
 	DefaultWSPort   = 31420       // Default TCP port for the websocket RPC server
 	DefaultP2PPort  = 8483
+
+	DefaultLedgerArchivePort = 48133 // Default TCP port for the HTTP ledger archive
 )
 
 // DefaultDataDir is  $HOME/viteisbest/
@@ -0,0 +1,164 @@
+package compress
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+const (
+	// ArchivePath is the path of the list of the ledger files, each file is served at ArchivePath + "/" + filename
+	ArchivePath = "/ledger_files"
+
+	// ArchiveHashAlgorithm is the algorithm of the hashes of the ledger files
+	ArchiveHashAlgorithm = "blake2b-256"
+	// ArchiveHashHeader carries the hash of the served file, as ArchiveHashAlgorithm + "=" + hex of the hash
+	ArchiveHashHeader = "X-Content-Hash"
+)
+
+// ArchiveFile is the meta of a ledger file in the list served by the archive.
+type ArchiveFile struct {
+	StartHeight uint64 `json:"startHeight"`
+	EndHeight   uint64 `json:"endHeight"`
+
+	Filename     string `json:"filename"`
+	FileSize     int64  `json:"fileSize"`
+	BlockNumbers uint64 `json:"blockNumbers"`
+
+	Codec string     `json:"codec"`
+	Hash  types.Hash `json:"hash"`
+}
+
+func newArchiveFile(meta *ledger.CompressedFileMeta) *ArchiveFile {
+	file := &ArchiveFile{
+		StartHeight:  meta.StartHeight,
+		EndHeight:    meta.EndHeight,
+		Filename:     meta.Filename,
+		FileSize:     meta.FileSize,
+		BlockNumbers: meta.BlockNumbers,
+		Hash:         meta.Hash,
+	}
+
+	if codec, err := GetCodec(meta.Codec); err == nil {
+		file.Codec = codec.Name()
+	} else {
+		file.Codec = strconv.FormatUint(uint64(meta.Codec), 10)
+	}
+	return file
+}
+
+// archiveHandler serves the ledger files of the compressor over http, it's read only.
+type archiveHandler struct {
+	compressor *Compressor
+	log        log15.Logger
+}
+
+// NewArchiveHandler returns a http handler which lists the ledger files indexed by the compressor at ArchivePath, and
+// serves each file with range requests, so the ledger files can be mirrored without the p2p protocol.
+func NewArchiveHandler(compressor *Compressor) http.Handler {
+	return &archiveHandler{
+		compressor: compressor,
+		log:        log15.New("module", "compressor/archive"),
+	}
+}
+
+func (h *archiveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// the indexer is replaced when the ledger files are cleared
+	indexer := h.compressor.Indexer()
+	if indexer == nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	urlPath := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case urlPath == ArchivePath:
+		h.serveList(w, r, indexer)
+	case strings.HasPrefix(urlPath, ArchivePath+"/"):
+		h.serveFile(w, r, indexer, strings.TrimPrefix(urlPath, ArchivePath+"/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveList writes the metas of the ledger files as json, the files can be filtered by the snapshot heights with
+// the start and end parameters.
+func (h *archiveHandler) serveList(w http.ResponseWriter, r *http.Request, indexer *Indexer) {
+	query := r.URL.Query()
+
+	var metas []*ledger.CompressedFileMeta
+	if query.Get("start") == "" && query.Get("end") == "" {
+		metas = indexer.List()
+	} else {
+		startHeight, endHeight := uint64(0), indexer.LatestHeight()
+		var err error
+		if value := query.Get("start"); value != "" {
+			if startHeight, err = strconv.ParseUint(value, 10, 64); err != nil {
+				http.Error(w, "invalid start height", http.StatusBadRequest)
+				return
+			}
+		}
+		if value := query.Get("end"); value != "" {
+			if endHeight, err = strconv.ParseUint(value, 10, 64); err != nil {
+				http.Error(w, "invalid end height", http.StatusBadRequest)
+				return
+			}
+		}
+		metas = indexer.Get(startHeight, endHeight)
+	}
+
+	files := make([]*ArchiveFile, 0, len(metas))
+	for _, meta := range metas {
+		files = append(files, newArchiveFile(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(files); err != nil {
+		h.log.Error("Encode failed, error is "+err.Error(), "method", "serveList")
+	}
+}
+
+// serveFile serves the ledger file, only the files in the index are served.
+func (h *archiveHandler) serveFile(w http.ResponseWriter, r *http.Request, indexer *Indexer, filename string) {
+	meta := indexer.GetByFilename(filename)
+	if meta == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := os.Open(filepath.Join(h.compressor.dir, meta.Filename))
+	if err != nil {
+		h.log.Error("Open file failed, error is "+err.Error(), "method", "serveFile")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		h.log.Error("File stat error, error is "+err.Error(), "method", "serveFile")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "application/octet-stream")
+	// the file is never changed once it's indexed, so the hash is a strong etag for the conditional range requests
+	header.Set("ETag", strconv.Quote(meta.Hash.Hex()))
+	header.Set(ArchiveHashHeader, ArchiveHashAlgorithm+"="+meta.Hash.Hex())
+
+	http.ServeContent(w, r, meta.Filename, fileInfo.ModTime(), file)
+}
@@ -0,0 +1,100 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestArchiveHandler(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+
+	c := NewCompressor(nil, dataDir, "")
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	tmpFile := filepath.Join(c.dir, "tmp_file")
+	if err := ioutil.WriteFile(tmpFile, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, hash, err := hashFile(tmpFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Indexer().Add(&taskInfo{beginHeight: 1, targetHeight: 3600}, tmpFile, 3600, CodecGzip, hash); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(NewArchiveHandler(c))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + ArchivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []*ArchiveFile
+	err = json.NewDecoder(resp.Body).Decode(&files)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Codec != "gzip" || files[0].Hash != hash || files[0].FileSize != int64(len(content)) {
+		t.Fatalf("unexpected list %+v", files)
+	}
+
+	resp, err = http.Get(server.URL + ArchivePath + "?start=3601")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files = nil
+	json.NewDecoder(resp.Body).Decode(&files)
+	resp.Body.Close()
+	if len(files) != 0 {
+		t.Fatalf("no file is above 3600, got %+v", files)
+	}
+
+	// range request
+	req, _ := http.NewRequest(http.MethodGet, server.URL+ArchivePath+"/"+c.Indexer().List()[0].Filename, nil)
+	req.Header.Set("Range", "bytes=10-19")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, content[10:20]) {
+		t.Fatalf("unexpected range response %d %s", resp.StatusCode, body)
+	}
+	if resp.Header.Get("ETag") != strconv.Quote(hash.Hex()) || resp.Header.Get(ArchiveHashHeader) != ArchiveHashAlgorithm+"="+hash.Hex() {
+		t.Fatalf("unexpected headers %v", resp.Header)
+	}
+
+	// only the indexed files are served
+	for _, path := range []string{ArchivePath + "/index", ArchivePath + "/../index", "/"} {
+		resp, err = http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("%s should be not found, got %d", path, resp.StatusCode)
+		}
+	}
+
+	resp, err = http.Post(server.URL+ArchivePath, "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("archive should be read only, got %d", resp.StatusCode)
+	}
+}
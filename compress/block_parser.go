@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/ledger"
 	"github.com/vitelabs/go-vite/log15"
+	"golang.org/x/crypto/blake2b"
 	"io"
+	"io/ioutil"
+	"os"
 )
 
 type blockProcessor func(block ledger.Block, err error)
@@ -33,8 +37,91 @@ var blockParserLog = log15.New("module", "compress/block_parser")
 
 var readNum = 1024 * 1024 * 10 // 10M
 
-// If blockNum is zero, finish when stream encounter io.EOF
-func BlockParser(reader io.Reader, blockNum uint64, processor blockProcessor) {
+// a file is a little larger than writeMax at most, the limit prevents a corrupted file size from using up the memory
+const maxFileSize = writeMax * 2
+
+var (
+	ErrFileSizeMismatch = errors.New("size of the ledger file doesn't match its meta")
+	ErrFileHashMismatch = errors.New("hash of the ledger file doesn't match its meta")
+	ErrFileTooLarge     = errors.New("ledger file is too large")
+	ErrFileNoHash       = errors.New("ledger file has no hash")
+	ErrFileInvalid      = errors.New("ledger file can't be decoded")
+)
+
+// CheckFileMeta checks that the ledger file of meta can be verified and decoded.
+func CheckFileMeta(meta *ledger.CompressedFileMeta) error {
+	if meta.FileSize < 0 || meta.FileSize > maxFileSize {
+		return ErrFileTooLarge
+	}
+	if meta.Hash == (types.Hash{}) {
+		return ErrFileNoHash
+	}
+	_, err := GetCodec(meta.Codec)
+	return err
+}
+
+// ParseFile reads the ledger file of meta from reader, verifies it by the size and the hash of meta, then decodes it
+// with the codec of meta and parses its blocks. No block of a corrupted file is processed. The file is hashed while
+// it's read, a reader which can't seek, like a connection, is copied into a temporary file in tmpDir to be decoded
+// after it's verified. The file is read to the end unless meta doesn't pass CheckFileMeta or the read fails.
+func ParseFile(reader io.Reader, meta *ledger.CompressedFileMeta, tmpDir string, processor blockProcessor) error {
+	if err := CheckFileMeta(meta); err != nil {
+		return err
+	}
+	codec, _ := GetCodec(meta.Codec)
+
+	source, ok := reader.(io.ReadSeeker)
+	hasher, _ := blake2b.New256(nil)
+	if ok {
+		start, err := source.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		if _, err := io.CopyN(hasher, source, meta.FileSize); err != nil {
+			return err
+		}
+		if _, err := source.Seek(start, io.SeekStart); err != nil {
+			return err
+		}
+	} else {
+		tmpFile, err := ioutil.TempFile(tmpDir, "download_")
+		if err != nil {
+			return err
+		}
+		defer func() {
+			tmpFile.Close()
+			os.Remove(tmpFile.Name())
+		}()
+
+		if _, err := io.CopyN(io.MultiWriter(tmpFile, hasher), reader, meta.FileSize); err != nil {
+			return err
+		}
+		if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		source = tmpFile
+	}
+	if !bytes.Equal(hasher.Sum(nil), meta.Hash.Bytes()) {
+		return ErrFileHashMismatch
+	}
+
+	decoder, err := codec.NewReader(io.LimitReader(source, meta.FileSize))
+	if err != nil {
+		blockParserLog.Error("NewReader failed, error is "+err.Error(), "method", "ParseFile")
+		return ErrFileInvalid
+	}
+	defer decoder.Close()
+
+	if err := BlockParser(decoder, meta.BlockNumbers, processor); err != nil {
+		blockParserLog.Error("BlockParser failed, error is "+err.Error(), "method", "ParseFile")
+		return ErrFileInvalid
+	}
+	return nil
+}
+
+// If blockNum is zero, finish when stream encounter io.EOF. The blocks which can't be deserialized are passed to
+// processor with the error, the returned error is of the stream.
+func BlockParser(reader io.Reader, blockNum uint64, processor blockProcessor) error {
 	blockParser := &blockParserCache{
 		reader:        reader,
 		processor:     processor,
@@ -50,7 +137,7 @@ func BlockParser(reader io.Reader, blockNum uint64, processor blockProcessor) {
 
 		if rErr != nil && rErr != io.EOF {
 			blockParserLog.Error("Read failed, error is " + rErr.Error())
-			return
+			return rErr
 		}
 
 		buffer := bytes.NewBuffer(readBytes[:readN])
@@ -97,8 +184,16 @@ func BlockParser(reader io.Reader, blockNum uint64, processor blockProcessor) {
 			}
 		}
 
-		if rErr == io.EOF || (blockNum > 0 && blockParser.hasReadBlocks >= blockNum) {
-			return
+		if blockNum > 0 && blockParser.hasReadBlocks >= blockNum {
+			return nil
+		}
+
+		if rErr == io.EOF {
+			// the stream ends in the middle of a block
+			if blockParser.currentBlockSize != 0 || len(blockParser.currentBlockSizeBuffer) > 0 {
+				return io.ErrUnexpectedEOF
+			}
+			return nil
 		}
 	}
 }
@@ -0,0 +1,136 @@
+package compress
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/golang/snappy"
+)
+
+const (
+	CodecNone   = uint32(0)
+	CodecGzip   = uint32(1)
+	CodecSnappy = uint32(2)
+
+	DefaultCodecName = "gzip"
+)
+
+var ErrUnknownCodec = errors.New("unknown codec")
+
+// Codec compresses the blocks written into the ledger files. A file records the id of its codec in its meta, so
+// the id of a codec must never change.
+type Codec interface {
+	Id() uint32
+	Name() string
+
+	NewWriter(w io.Writer) io.WriteCloser
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	codecLock sync.RWMutex
+	codecs    = make(map[uint32]Codec)
+)
+
+func init() {
+	RegisterCodec(noneCodec{})
+	RegisterCodec(gzipCodec{})
+	RegisterCodec(snappyCodec{})
+}
+
+// RegisterCodec makes codec usable by the compressor and the readers of the ledger files.
+func RegisterCodec(codec Codec) {
+	codecLock.Lock()
+	defer codecLock.Unlock()
+
+	codecs[codec.Id()] = codec
+}
+
+func GetCodec(id uint32) (Codec, error) {
+	codecLock.RLock()
+	defer codecLock.RUnlock()
+
+	if codec, ok := codecs[id]; ok {
+		return codec, nil
+	}
+	return nil, ErrUnknownCodec
+}
+
+func GetCodecByName(name string) (Codec, error) {
+	codecLock.RLock()
+	defer codecLock.RUnlock()
+
+	for _, codec := range codecs {
+		if codec.Name() == name {
+			return codec, nil
+		}
+	}
+	return nil, ErrUnknownCodec
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// noneCodec writes the blocks as they are, it's the codec of the files written before the codecs were added.
+type noneCodec struct{}
+
+func (noneCodec) Id() uint32 {
+	return CodecNone
+}
+
+func (noneCodec) Name() string {
+	return "none"
+}
+
+func (noneCodec) NewWriter(w io.Writer) io.WriteCloser {
+	return nopWriteCloser{w}
+}
+
+func (noneCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(r), nil
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Id() uint32 {
+	return CodecGzip
+}
+
+func (gzipCodec) Name() string {
+	return "gzip"
+}
+
+func (gzipCodec) NewWriter(w io.Writer) io.WriteCloser {
+	return gzip.NewWriter(w)
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// snappyCodec uses the framing format of snappy, which is faster than gzip but compresses less.
+type snappyCodec struct{}
+
+func (snappyCodec) Id() uint32 {
+	return CodecSnappy
+}
+
+func (snappyCodec) Name() string {
+	return "snappy"
+}
+
+func (snappyCodec) NewWriter(w io.Writer) io.WriteCloser {
+	return snappy.NewBufferedWriter(w)
+}
+
+func (snappyCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(snappy.NewReader(r)), nil
+}
@@ -0,0 +1,91 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"golang.org/x/crypto/blake2b"
+)
+
+func writeTestFile(t *testing.T, codec Codec, blocks []ledger.Block) ([]byte, *ledger.CompressedFileMeta) {
+	buffer := new(bytes.Buffer)
+	writer := codec.NewWriter(buffer)
+	if err := BlockFormatter(writer, func(uint64, uint64) ([]ledger.Block, error) {
+		return blocks, io.EOF
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buffer.Bytes()
+	return data, &ledger.CompressedFileMeta{
+		FileSize:     int64(len(data)),
+		BlockNumbers: uint64(len(blocks)),
+		Codec:        codec.Id(),
+		Hash:         types.Hash(blake2b.Sum256(data)),
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	now := time.Now()
+	var blocks []ledger.Block
+	for i := uint64(1); i <= 20; i++ {
+		blocks = append(blocks, &ledger.SnapshotBlock{Height: i, Timestamp: &now})
+	}
+
+	tmpDir, err := ioutil.TempDir("", "parse_file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, id := range []uint32{CodecNone, CodecGzip, CodecSnappy} {
+		codec, err := GetCodec(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, meta := writeTestFile(t, codec, blocks)
+
+		// a file and a stream copied into tmpDir
+		readers := []func() io.Reader{
+			func() io.Reader { return bytes.NewReader(data) },
+			func() io.Reader { return struct{ io.Reader }{bytes.NewReader(data)} },
+		}
+		for _, newReader := range readers {
+			var heights []uint64
+			if err := ParseFile(newReader(), meta, tmpDir, func(block ledger.Block, err error) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				heights = append(heights, block.(*ledger.SnapshotBlock).Height)
+			}); err != nil {
+				t.Fatalf("%s: %v", codec.Name(), err)
+			}
+			if len(heights) != len(blocks) || heights[0] != 1 || heights[len(heights)-1] != 20 {
+				t.Fatalf("%s: parsed heights are %v", codec.Name(), heights)
+			}
+		}
+
+		// corrupted file is detected before any block is processed
+		data[len(data)/2] ^= 1
+		for _, newReader := range readers {
+			if err := ParseFile(newReader(), meta, tmpDir, func(ledger.Block, error) {
+				t.Fatalf("%s: block of corrupted file is processed", codec.Name())
+			}); err != ErrFileHashMismatch {
+				t.Fatalf("%s: corrupted file should fail, got %v", codec.Name(), err)
+			}
+		}
+	}
+
+	if _, err := GetCodecByName("zip"); err != ErrUnknownCodec {
+		t.Fatalf("unknown codec should fail, got %v", err)
+	}
+}
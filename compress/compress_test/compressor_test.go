@@ -6,7 +6,6 @@ import (
 	"github.com/vitelabs/go-vite/common"
 	"github.com/vitelabs/go-vite/common/helper"
 	"github.com/vitelabs/go-vite/common/types"
-	"github.com/vitelabs/go-vite/compress"
 	"github.com/vitelabs/go-vite/config"
 	"github.com/vitelabs/go-vite/ledger"
 	"github.com/vitelabs/go-vite/vm_context"
@@ -205,7 +204,7 @@ func TestBlockParser(t *testing.T) {
 			fmt.Printf("%+v\n", meta)
 			fileReader := chainInstance.Compressor().FileReader(meta.Filename)
 
-			compress.BlockParser(fileReader, 0, func(block ledger.Block, err error) {
+			parseErr := chainInstance.Compressor().BlockParser(fileReader, meta, func(block ledger.Block, err error) {
 				if err != nil {
 					t.Fatal(err.Error())
 				}
@@ -221,6 +220,10 @@ func TestBlockParser(t *testing.T) {
 					}
 				}
 			})
+			fileReader.Close()
+			if parseErr != nil {
+				t.Fatal(parseErr.Error())
+			}
 		}
 
 		fmt.Printf("min is %d, max is %d", min, max)
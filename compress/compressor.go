@@ -36,10 +36,13 @@ type Compressor struct {
 	ticker  *time.Ticker
 
 	tickerDuration time.Duration
+	codec          Codec
 	log            log15.Logger
 }
 
-func NewCompressor(chain Chain, dataDir string) *Compressor {
+// NewCompressor creates a compressor which writes the ledger files with the codec of codecName, the default codec is
+// used if codecName is empty.
+func NewCompressor(chain Chain, dataDir string, codecName string) *Compressor {
 	c := &Compressor{
 		stopSignal: make(chan int),
 		status:     STOPPED,
@@ -50,6 +53,15 @@ func NewCompressor(chain Chain, dataDir string) *Compressor {
 		log:            log15.New("module", "compressor"),
 	}
 
+	if codecName == "" {
+		codecName = DefaultCodecName
+	}
+	codec, err := GetCodecByName(codecName)
+	if err != nil {
+		c.log.Crit("GetCodecByName failed, error is "+err.Error(), "method", "NewCompressor", "codec", codecName)
+	}
+	c.codec = codec
+
 	if err := c.createDataDir(); err != nil {
 		c.log.Crit("Create data directory failed, error is "+err.Error(), "method", "NewCompressor")
 	}
@@ -98,8 +110,15 @@ func (c *Compressor) FileReader(filename string) io.ReadCloser {
 	return NewFileReader(path.Join(c.dir, filename))
 }
 
-func (c *Compressor) BlockParser(reader io.Reader, blockNum uint64, processFunc func(block ledger.Block, err error)) {
-	BlockParser(reader, blockNum, processFunc)
+// VerifyFile checks the ledger file by the size and the hash in the index.
+func (c *Compressor) VerifyFile(filename string) error {
+	return c.indexer.Verify(filename)
+}
+
+// BlockParser parses the blocks of the ledger file of meta read from reader, the file is verified before any block
+// is processed.
+func (c *Compressor) BlockParser(reader io.Reader, meta *ledger.CompressedFileMeta, processFunc func(block ledger.Block, err error)) error {
+	return ParseFile(reader, meta, c.dir, processFunc)
 }
 
 func (c *Compressor) Start() bool {
@@ -145,9 +164,9 @@ func (c *Compressor) RunTask() {
 	c.status = TASK_RUNNING
 
 	tmpFileName := filepath.Join(c.dir, "subgraph_tmp")
-	task := NewCompressorTask(c.chain, tmpFileName, c.indexer.LatestHeight())
+	task := NewCompressorTask(c.chain, tmpFileName, c.indexer.LatestHeight(), c.codec)
 	if result := task.Run(); result.IsSuccess {
-		c.indexer.Add(result.Ti, tmpFileName, result.BlockNumbers)
+		c.indexer.Add(result.Ti, tmpFileName, result.BlockNumbers, c.codec.Id(), result.Hash)
 	}
 	task.Clear()
 
@@ -1,7 +1,9 @@
 package compress
 
 import (
+	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/log15"
+	"golang.org/x/crypto/blake2b"
 	"io"
 	"os"
 )
@@ -18,3 +20,21 @@ func NewFileReader(filename string) io.ReadCloser {
 
 	return file
 }
+
+// hashFile returns the size and the hash of the content of the file.
+func hashFile(filename string) (int64, types.Hash, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, types.Hash{}, err
+	}
+	defer file.Close()
+
+	hasher, _ := blake2b.New256(nil)
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return 0, types.Hash{}, err
+	}
+
+	hash, err := types.BytesToHash(hasher.Sum(nil))
+	return size, hash, err
+}
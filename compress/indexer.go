@@ -2,7 +2,9 @@ package compress
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/ledger"
 	"github.com/vitelabs/go-vite/log15"
 	"io"
@@ -17,6 +19,8 @@ import (
 
 const INDEX_SEP = ",,,"
 
+var ErrFileNotIndexed = errors.New("ledger file isn't in the index")
+
 type Indexer struct {
 	file      *os.File
 	indexList []*ledger.CompressedFileMeta
@@ -24,6 +28,9 @@ type Indexer struct {
 	lock      sync.RWMutex
 
 	dir string
+
+	// some lines of the index file were written without codec and hash
+	hasLegacyLine bool
 }
 
 func NewIndexer(dir string) *Indexer {
@@ -48,11 +55,18 @@ func NewIndexer(dir string) *Indexer {
 
 	indexer.file = file
 
-	if parsedSize, err := indexer.loadFromFile(); err != nil {
+	parsedSize, err := indexer.loadFromFile()
+	if err != nil {
 		indexer.log.Error("loadFromFile failed, error is "+err.Error(), "method", "NewIndexer")
-		if tErr := indexer.truncate(parsedSize); tErr != nil {
-			indexer.log.Crit("truncate failed, error is "+tErr.Error(), "method", "NewIndexer")
-		}
+	}
+	if tErr := indexer.truncate(parsedSize); tErr != nil {
+		indexer.log.Crit("truncate failed, error is "+tErr.Error(), "method", "NewIndexer")
+	}
+
+	// rewrite the lines of the old format with the hashes of the files
+	if indexer.hasLegacyLine {
+		indexer.flushToFile()
+		indexer.hasLegacyLine = false
 	}
 
 	indexer.checkAndDeleteDataFile()
@@ -96,9 +110,11 @@ func (indexer *Indexer) newFileName(startHeight uint64, endHeight uint64) string
 	return "subgraph_" + strconv.FormatUint(startHeight, 10) + "_" + strconv.FormatUint(endHeight, 10)
 }
 
+// check compares the size of the file with the index, the hash in the index is trusted, the file is verified by
+// it when it's sent. The file indexed without a hash is hashed once, the hash is written by flushToFile.
 func (indexer *Indexer) check(item *ledger.CompressedFileMeta) (bool, error) {
-	// Rename err
-	fileSize, fileSizeErr := indexer.getFileSize(filepath.Join(indexer.dir, item.Filename))
+	filename := filepath.Join(indexer.dir, item.Filename)
+	fileSize, fileSizeErr := indexer.getFileSize(filename)
 
 	if fileSizeErr != nil {
 		indexer.log.Error("getFileSize failed, error is "+fileSizeErr.Error(), "method", "check")
@@ -108,9 +124,62 @@ func (indexer *Indexer) check(item *ledger.CompressedFileMeta) (bool, error) {
 	if fileSize != item.FileSize {
 		return false, nil
 	}
+
+	if item.Hash == (types.Hash{}) {
+		_, hash, hashErr := hashFile(filename)
+		if hashErr != nil {
+			indexer.log.Error("hashFile failed, error is "+hashErr.Error(), "method", "check")
+			return false, hashErr
+		}
+		item.Hash = hash
+		indexer.hasLegacyLine = true
+	}
 	return true, nil
 }
 
+// GetByFilename returns the meta of the ledger file, nil if the file isn't in the index.
+func (indexer *Indexer) GetByFilename(filename string) *ledger.CompressedFileMeta {
+	indexer.lock.RLock()
+	defer indexer.lock.RUnlock()
+
+	for _, indexItem := range indexer.indexList {
+		if indexItem.Filename == filename {
+			return indexItem
+		}
+	}
+	return nil
+}
+
+// List returns the metas of all the ledger files in the order of height.
+func (indexer *Indexer) List() []*ledger.CompressedFileMeta {
+	indexer.lock.RLock()
+	defer indexer.lock.RUnlock()
+
+	list := make([]*ledger.CompressedFileMeta, len(indexer.indexList))
+	copy(list, indexer.indexList)
+	return list
+}
+
+// Verify checks the ledger file against its meta in the index.
+func (indexer *Indexer) Verify(filename string) error {
+	item := indexer.GetByFilename(filename)
+	if item == nil {
+		return ErrFileNotIndexed
+	}
+
+	fileSize, hash, err := hashFile(filepath.Join(indexer.dir, item.Filename))
+	if err != nil {
+		return err
+	}
+	if fileSize != item.FileSize {
+		return ErrFileSizeMismatch
+	}
+	if hash != item.Hash {
+		return ErrFileHashMismatch
+	}
+	return nil
+}
+
 func (indexer *Indexer) checkAndDeleteDataFile() {
 	indexListMap := make(map[string]int, 0)
 
@@ -129,6 +198,7 @@ func (indexer *Indexer) checkAndDeleteDataFile() {
 
 func (indexer *Indexer) flushToFile() {
 	indexer.file.Truncate(0)
+	indexer.file.Seek(0, io.SeekStart)
 	for _, indexItem := range indexer.indexList {
 		_, err := indexer.file.WriteString(indexer.formatToLine(indexItem) + "\n")
 		if err != nil {
@@ -161,7 +231,13 @@ func (indexer *Indexer) getAllFileNames() []string {
 }
 
 func (indexer *Indexer) truncate(n int64) error {
-	return indexer.file.Truncate(n)
+	if err := indexer.file.Truncate(n); err != nil {
+		return err
+	}
+
+	// the lines are appended after the parsed ones
+	_, err := indexer.file.Seek(n, io.SeekStart)
+	return err
 }
 
 func (indexer *Indexer) loadFromFile() (int64, error) {
@@ -218,6 +294,9 @@ func (indexer *Indexer) loadFromFile() (int64, error) {
 
 func (indexer *Indexer) parseLine(line []byte) (*ledger.CompressedFileMeta, error) {
 	segs := strings.Split(string(line), INDEX_SEP)
+	if len(segs) != 5 && len(segs) != 7 {
+		return nil, errors.New("wrong number of fields: " + strconv.Itoa(len(segs)))
+	}
 
 	startHeight, err1 := strconv.ParseUint(segs[0], 10, 64)
 	if err1 != nil {
@@ -248,6 +327,25 @@ func (indexer *Indexer) parseLine(line []byte) (*ledger.CompressedFileMeta, erro
 		FileSize:     fileSize,
 		BlockNumbers: blockNumbers,
 	}
+
+	// the line written by the old version has no codec and hash, its file isn't compressed
+	if len(segs) == 5 {
+		indexer.hasLegacyLine = true
+		return item, nil
+	}
+
+	codec, err5 := strconv.ParseUint(segs[5], 10, 32)
+	if err5 != nil {
+		return nil, err5
+	}
+	item.Codec = uint32(codec)
+
+	hash, err6 := types.HexToHash(segs[6])
+	if err6 != nil {
+		return nil, err6
+	}
+	item.Hash = hash
+
 	return item, nil
 }
 
@@ -257,7 +355,9 @@ func (indexer *Indexer) formatToLine(item *ledger.CompressedFileMeta) string {
 	lineString += strconv.FormatUint(item.EndHeight, 10) + INDEX_SEP
 	lineString += item.Filename + INDEX_SEP
 	lineString += strconv.FormatInt(item.FileSize, 10) + INDEX_SEP
-	lineString += strconv.FormatUint(item.BlockNumbers, 10)
+	lineString += strconv.FormatUint(item.BlockNumbers, 10) + INDEX_SEP
+	lineString += strconv.FormatUint(uint64(item.Codec), 10) + INDEX_SEP
+	lineString += item.Hash.Hex()
 	return lineString
 }
 
@@ -352,7 +452,7 @@ func (indexer *Indexer) Delete(toHeight uint64) error {
 	return nil
 }
 
-func (indexer *Indexer) Add(ti *taskInfo, tmpFile string, blockNumbers uint64) error {
+func (indexer *Indexer) Add(ti *taskInfo, tmpFile string, blockNumbers uint64, codec uint32, hash types.Hash) error {
 	indexer.lock.Lock()
 	defer indexer.lock.Unlock()
 
@@ -391,6 +491,9 @@ func (indexer *Indexer) Add(ti *taskInfo, tmpFile string, blockNumbers uint64) e
 
 		FileSize:     fileSize,
 		BlockNumbers: blockNumbers,
+
+		Codec: codec,
+		Hash:  hash,
 	}
 
 	_, writeErr := indexer.file.WriteString(indexer.formatToLine(newItem) + "\n")
@@ -1,8 +1,10 @@
 package compress
 
 import (
+	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/ledger"
 	"github.com/vitelabs/go-vite/log15"
+	"golang.org/x/crypto/blake2b"
 	"io"
 	"os"
 )
@@ -45,14 +47,16 @@ type CompressorTask struct {
 	indexerHeight  uint64
 	startHeightGap uint64
 	taskGap        uint64
+	codec          Codec
 	log            log15.Logger
 }
 
-func NewCompressorTask(chain Chain, tmpFile string, indexerHeight uint64) *CompressorTask {
+func NewCompressorTask(chain Chain, tmpFile string, indexerHeight uint64, codec Codec) *CompressorTask {
 	compressorTask := &CompressorTask{
 		splitSize: 10,
 		chain:     chain,
 		tmpFile:   tmpFile,
+		codec:     codec,
 		log:       log15.New("module", "compressor/task"),
 
 		indexerHeight:  indexerHeight,
@@ -67,6 +71,9 @@ type TaskRunResult struct {
 	Ti           *taskInfo
 	IsSuccess    bool
 	BlockNumbers uint64
+
+	// Hash is the hash of the compressed file content
+	Hash types.Hash
 }
 
 func (task *CompressorTask) Run() *TaskRunResult {
@@ -86,10 +93,21 @@ func (task *CompressorTask) Run() *TaskRunResult {
 	currentTaskIndex := 0
 
 	tmpFileWriter := NewFileWriter(task.tmpFile)
+	if tmpFileWriter == nil {
+		return &TaskRunResult{
+			Ti:           ti,
+			IsSuccess:    false,
+			BlockNumbers: 0,
+		}
+	}
 	var blockNumbers = uint64(0)
 
+	// the blocks are compressed by the codec, the hash is of the compressed content
+	hasher, _ := blake2b.New256(nil)
+	codecWriter := task.codec.NewWriter(io.MultiWriter(tmpFileWriter, hasher))
+
 	// Limit write length
-	formatterErr := BlockFormatter(tmpFileWriter, func(hasWrite uint64, hasWriteBlocks uint64) ([]ledger.Block, error) {
+	formatterErr := BlockFormatter(codecWriter, func(hasWrite uint64, hasWriteBlocks uint64) ([]ledger.Block, error) {
 
 		if currentTaskIndex >= taskLen ||
 			hasWrite >= writeMax {
@@ -104,6 +122,9 @@ func (task *CompressorTask) Run() *TaskRunResult {
 		return blocks, err
 	})
 
+	if closeErr := codecWriter.Close(); closeErr != nil && formatterErr == nil {
+		formatterErr = closeErr
+	}
 	tmpFileWriter.Close()
 
 	if formatterErr != nil {
@@ -115,10 +136,12 @@ func (task *CompressorTask) Run() *TaskRunResult {
 		}
 	}
 
+	hash, _ := types.BytesToHash(hasher.Sum(nil))
 	return &TaskRunResult{
 		Ti:           ti,
 		IsSuccess:    true,
 		BlockNumbers: blockNumbers,
+		Hash:         hash,
 	}
 }
 
@@ -1,12 +1,49 @@
 package config
 
-type KafkaProducer struct {
+const (
+	SinkTypeKafka   = "kafka"
+	SinkTypeWebhook = "webhook"
+	SinkTypeFile    = "file"
+	SinkTypeNats    = "nats"
+)
+
+// EventSink describes a target the block event log is delivered to. Only the fields of the chosen Type are used.
+type EventSink struct {
+	Type string
+
+	// kafka, Topic is the subject of nats
 	BrokerList []string
 	Topic      string
+
+	// webhook and nats
+	Url string
+	// webhook only, the nats sink retries in the next round of the producer
+	MaxRetries int
+
+	// file
+	Dir         string
+	MaxFileSize int64
 }
 
 type Chain struct {
-	KafkaProducers []*KafkaProducer
+	EventSinks     []*EventSink
 	OpenBlackBlock bool
 	GenesisFile    string
+	OpenTxIndex    bool
+	OpenLogIndex   bool
+
+	// keep the states of the latest StatePruneHeight snapshot blocks, 0 means never prune
+	StatePruneHeight uint64
+
+	// codec of the ledger files: none, gzip or snappy, gzip if empty
+	LedgerFileCodec string
+
+	// check the tail of the ledger at start up and repair it, it's always done if the ledger db has been recovered
+	RepairLedger bool
+
+	// wipe the ledger and sync again if it's corrupted and can't be repaired
+	WipeCorruptedLedger bool
+
+	// keep the ledger db in memory, it's lost when the chain is stopped. It's for the tests.
+	LedgerInMemory bool
 }
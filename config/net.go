@@ -7,4 +7,16 @@ type Net struct {
 	Topic        string   `json:"Topic"`
 	Interval     int64    `json:"Interval"`
 	TopoDisabled bool     `json:"TopoDisabled"`
+	BanThreshold int      `json:"BanThreshold"`
+	BanDuration  int64    `json:"BanDuration"`
+
+	ReputationDisabled bool `json:"ReputationDisabled"` // the offences of peers are only logged
+
+	RateLimits map[string]RateLimit `json:"RateLimits"`
+}
+
+// RateLimit allows Rate tokens per second and Burst tokens at most in a moment, a request costs tokens by its range
+type RateLimit struct {
+	Rate  float64 `json:"Rate"`
+	Burst int     `json:"Burst"`
 }
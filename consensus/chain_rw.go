@@ -123,7 +123,13 @@ func (self *chainRw) GetMemberInfo(gid types.Gid, genesis time.Time) *core.Group
 
 func (self *chainRw) getGid(block *ledger.AccountBlock) (types.Gid, error) {
 	gid, e := self.rw.GetContractGidByAccountBlock(block)
-	return *gid, e
+	if e != nil {
+		return types.Gid{}, e
+	}
+	if gid == nil {
+		return types.Gid{}, errors.Errorf("account is not a contract. addr:%s", block.AccountAddress)
+	}
+	return *gid, nil
 }
 func (self *chainRw) GetLatestSnapshotBlock() *ledger.SnapshotBlock {
 	return self.rw.GetLatestSnapshotBlock()
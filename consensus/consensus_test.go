@@ -171,7 +171,8 @@ func getChainInstance() chain.Chain {
 
 			DataDir: filepath.Join(common.HomeDir(), "Library/GVite/devdata/ledger"),
 			//Chain: &config.Chain{
-			//	KafkaProducers: []*config.KafkaProducer{{
+			//	EventSinks: []*config.EventSink{{
+			//		Type:       "kafka",
 			//		Topic:      "test003",
 			//		BrokerList: []string{"ckafka-r3rbhht9.ap-guangzhou.ckafka.tencentcloudmq.com:6061"},
 			//	}},
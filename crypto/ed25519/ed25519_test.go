@@ -224,3 +224,56 @@ func TestPrivateKey_Clear(t *testing.T) {
 		}
 	}
 }
+
+func TestToX25519(t *testing.T) {
+	public, private, _ := GenerateKey(rand.Reader)
+
+	xPrivatePublic, err := X25519(private.ToX25519(), X25519Basepoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xPublic, err := public.ToX25519()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(xPrivatePublic, xPublic) {
+		t.Errorf("converted public key %x doesn't match the one of the converted private key %x", xPublic, xPrivatePublic)
+	}
+
+	if _, err := PublicKey(make([]byte, PublicKeySize-1)).ToX25519(); err == nil {
+		t.Error("public key of invalid size is converted")
+	}
+}
+
+// the vectors of RFC 7748 section 6.1
+func TestX25519(t *testing.T) {
+	alicePrivate, _ := hex.DecodeString("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	alicePublic, _ := hex.DecodeString("8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a")
+	bobPrivate, _ := hex.DecodeString("5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb")
+	bobPublic, _ := hex.DecodeString("de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f")
+	shared, _ := hex.DecodeString("4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742")
+
+	for _, c := range []struct {
+		scalar, point, expected []byte
+	}{
+		{alicePrivate, X25519Basepoint, alicePublic},
+		{bobPrivate, X25519Basepoint, bobPublic},
+		{alicePrivate, bobPublic, shared},
+		{bobPrivate, alicePublic, shared},
+	} {
+		result, err := X25519(c.scalar, c.point)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(result, c.expected) {
+			t.Errorf("X25519 should be %x, got %x", c.expected, result)
+		}
+	}
+
+	if _, err := X25519(alicePrivate, make([]byte, X25519KeySize)); err == nil {
+		t.Error("X25519 of a low order point should fail")
+	}
+	if _, err := X25519(alicePrivate[1:], bobPublic); err == nil {
+		t.Error("scalar of invalid size is accepted")
+	}
+}
@@ -0,0 +1,147 @@
+package ed25519
+
+import (
+	"errors"
+
+	"github.com/vitelabs/go-vite/crypto/ed25519/internal/edwards25519"
+	"golang.org/x/crypto/blake2b"
+)
+
+// X25519KeySize is the size of the X25519 private and public keys.
+const X25519KeySize = 32
+
+// X25519Basepoint is the u-coordinate of the base point, X25519(scalar, X25519Basepoint) returns the public key of
+// scalar.
+var X25519Basepoint = []byte{9, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+
+var errInvalidPublicKey = errors.New("ed25519: invalid public key")
+var errInvalidX25519Key = errors.New("ed25519: invalid X25519 key")
+var errLowOrderPoint = errors.New("ed25519: X25519 of a low order point")
+
+// ToX25519 returns the X25519 private key of the same scalar as priv, the clamped scalar of the blake2b digest of
+// the seed, so the X25519 public key of it is the one converted from the ed25519 public key.
+func (priv PrivateKey) ToX25519() []byte {
+	digest := blake2b.Sum512(priv[:32])
+	digest[0] &= 248
+	digest[31] &= 127
+	digest[31] |= 64
+
+	key := make([]byte, X25519KeySize)
+	copy(key, digest[:X25519KeySize])
+	return key
+}
+
+// ToX25519 converts the edwards point pub to the montgomery u-coordinate, u = (1 + y) / (1 - y).
+func (pub PublicKey) ToX25519() ([]byte, error) {
+	if len(pub) != PublicKeySize {
+		return nil, errInvalidPublicKey
+	}
+
+	var pubBytes [32]byte
+	copy(pubBytes[:], pub)
+	var A edwards25519.ExtendedGroupElement
+	if !A.FromBytes(&pubBytes) {
+		return nil, errInvalidPublicKey
+	}
+
+	// y = Y / Z
+	var y, invZ edwards25519.FieldElement
+	edwards25519.FeInvert(&invZ, &A.Z)
+	edwards25519.FeMul(&y, &A.Y, &invZ)
+
+	var one, numerator, denominator, u edwards25519.FieldElement
+	edwards25519.FeOne(&one)
+	edwards25519.FeAdd(&numerator, &one, &y)
+	edwards25519.FeSub(&denominator, &one, &y)
+	edwards25519.FeInvert(&denominator, &denominator)
+	edwards25519.FeMul(&u, &numerator, &denominator)
+
+	var uBytes [32]byte
+	edwards25519.FeToBytes(&uBytes, &u)
+	return uBytes[:], nil
+}
+
+// X25519 returns the u-coordinate of the scalar multiple of the point of u-coordinate point, as RFC 7748 defines it,
+// the scalar is clamped. The shared secret of two keys is X25519(our private key, their public key). The all-zero
+// result of a low order point is rejected.
+func X25519(scalar, point []byte) ([]byte, error) {
+	if len(scalar) != X25519KeySize || len(point) != X25519KeySize {
+		return nil, errInvalidX25519Key
+	}
+
+	var k, u [32]byte
+	copy(k[:], scalar)
+	copy(u[:], point)
+	k[0] &= 248
+	k[31] &= 127
+	k[31] |= 64
+
+	var out [32]byte
+	x25519Ladder(&out, &k, &u)
+
+	var zero [32]byte
+	if out == zero {
+		return nil, errLowOrderPoint
+	}
+	return out[:], nil
+}
+
+// x25519Ladder is the montgomery ladder of RFC 7748, it runs in constant time.
+func x25519Ladder(out, k, u *[32]byte) {
+	var x1, x2, z2, x3, z3 edwards25519.FieldElement
+	edwards25519.FeFromBytes(&x1, u)
+	edwards25519.FeOne(&x2)
+	edwards25519.FeCopy(&x3, &x1)
+	edwards25519.FeOne(&z3)
+
+	// (A - 2) / 4 of the curve
+	var a24 edwards25519.FieldElement
+	a24[0] = 121665
+
+	var a, aa, b, bb, e, c, d, da, cb, t edwards25519.FieldElement
+	swap := int32(0)
+	for pos := 254; pos >= 0; pos-- {
+		bit := int32(k[pos/8]>>uint(pos&7)) & 1
+		swap ^= bit
+		feCSwap(&x2, &x3, swap)
+		feCSwap(&z2, &z3, swap)
+		swap = bit
+
+		edwards25519.FeAdd(&a, &x2, &z2)
+		edwards25519.FeSquare(&aa, &a)
+		edwards25519.FeSub(&b, &x2, &z2)
+		edwards25519.FeSquare(&bb, &b)
+		edwards25519.FeSub(&e, &aa, &bb)
+		edwards25519.FeAdd(&c, &x3, &z3)
+		edwards25519.FeSub(&d, &x3, &z3)
+		edwards25519.FeMul(&da, &d, &a)
+		edwards25519.FeMul(&cb, &c, &b)
+
+		edwards25519.FeAdd(&t, &da, &cb)
+		edwards25519.FeSquare(&x3, &t)
+		edwards25519.FeSub(&t, &da, &cb)
+		edwards25519.FeSquare(&t, &t)
+		edwards25519.FeMul(&z3, &x1, &t)
+
+		edwards25519.FeMul(&x2, &aa, &bb)
+		edwards25519.FeMul(&t, &a24, &e)
+		edwards25519.FeAdd(&t, &aa, &t)
+		edwards25519.FeMul(&z2, &e, &t)
+	}
+	feCSwap(&x2, &x3, swap)
+	feCSwap(&z2, &z3, swap)
+
+	edwards25519.FeInvert(&z2, &z2)
+	edwards25519.FeMul(&x2, &x2, &z2)
+	edwards25519.FeToBytes(out, &x2)
+}
+
+// feCSwap swaps f and g if b is 1, keeps them if b is 0.
+func feCSwap(f, g *edwards25519.FieldElement, b int32) {
+	b = -b
+	for i := range f {
+		t := b & (f[i] ^ g[i])
+		f[i] ^= t
+		g[i] ^= t
+	}
+}
@@ -46,6 +46,11 @@ func NewGenerator(chain vm_context.Chain, snapshotBlockHash, prevBlockHash *type
 	return gen, nil
 }
 
+// SetTracer sets the tracer which is notified about the contract code execution of generated blocks.
+func (gen *Generator) SetTracer(tracer vm.Tracer) {
+	gen.vm.Tracer = tracer
+}
+
 func (gen *Generator) GenerateWithMessage(message *IncomingMessage, signFunc SignFunc) (*GenResult, error) {
 	var genResult *GenResult
 	var errGenMsg error
@@ -31,13 +31,15 @@ var (
 	defaultDifficulty = big.NewInt(65535)
 )
 
-func init() {
-	var isTest bool
-	flag.BoolVar(&isTest, "vm.test", false, "test net gets unlimited balance and quota")
+var isVmTest bool
+
+func TestMain(m *testing.M) {
+	flag.BoolVar(&isVmTest, "vm.test", false, "test net gets unlimited balance and quota")
 	flag.StringVar(&genesisAccountPrivKeyStr, "k", "", "")
 
 	flag.Parse()
-	vm.InitVmConfig(isTest, false)
+	vm.InitVmConfig(isVmTest, false)
+	os.Exit(m.Run())
 }
 
 type VitePrepared struct {
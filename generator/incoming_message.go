@@ -71,10 +71,9 @@ func (im *IncomingMessage) ToSendBlock() (*ledger.AccountBlock, error) {
 		block.BlockType = im.BlockType
 		block.FromBlockHash = types.Hash{}
 
+		// the contract address is derived by vm if not specified
 		if im.ToAddress != nil {
 			block.ToAddress = *im.ToAddress
-		} else {
-			return nil, errors.New("block toaddress can't be nil")
 		}
 
 		if im.Amount == nil {
@@ -101,10 +100,10 @@ func (im *IncomingMessage) ToSendBlock() (*ledger.AccountBlock, error) {
 			block.Fee = im.Fee
 		}
 
-		if len(im.Data) > 0 {
+		if len(im.Data) > types.GidSize {
 			block.Data = im.Data
 		} else {
-			return nil, errors.New("block data can't be nil")
+			return nil, errors.New("block data should contain gid and contract code")
 		}
 
 	default:
@@ -0,0 +1,139 @@
+package generator
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/vm"
+	"github.com/vitelabs/go-vite/vm_context"
+)
+
+var ErrContractReceiveFailed = errors.New("contract receive failed")
+
+// SimulateResult is the outcome of a simulated send block and, if the to address is a contract, of its receive
+// block. ReceiveBlocks starts with the receive block, followed by the send blocks triggered by the contract.
+type SimulateResult struct {
+	SendBlock     *ledger.AccountBlock
+	ReceiveBlocks []*vm_context.VmAccountBlock
+	ReturnData    []byte
+	Logs          ledger.VmLogList
+	Err           error
+}
+
+func (r *SimulateResult) SendQuota() uint64 {
+	if r.SendBlock == nil {
+		return 0
+	}
+	return r.SendBlock.Quota
+}
+
+func (r *SimulateResult) ReceiveQuota() uint64 {
+	if len(r.ReceiveBlocks) == 0 {
+		return 0
+	}
+	return r.ReceiveBlocks[0].AccountBlock.Quota
+}
+
+// Simulate runs the send block built from message on the latest snapshot block and, if the to address is a
+// built-in contract or has contract code, the receive block of it. Both run on frozen copies of the vm database,
+// nothing is signed, calculated by pow or written to the pool or chain.
+func Simulate(chain vm_context.Chain, message *IncomingMessage) (result *SimulateResult, resultErr error) {
+	defer func() {
+		if err := recover(); err != nil {
+			result = nil
+			resultErr = errors.New("simulate vm panic error")
+		}
+	}()
+
+	if message.BlockType != ledger.BlockTypeSendCall {
+		return nil, errors.New("only send call block can be simulated")
+	}
+
+	sendContext, err := vm_context.NewVmContext(chain, nil, nil, &message.AccountAddress)
+	if err != nil {
+		return nil, err
+	}
+	prevBlock := sendContext.PrevAccountBlock()
+	if prevBlock == nil {
+		return nil, errors.New("account address doesn't exist")
+	}
+	snapshotHash := sendContext.CurrentSnapshotBlock().Hash
+
+	block, err := message.ToSendBlock()
+	if err != nil {
+		return nil, err
+	}
+	block.Height = prevBlock.Height + 1
+	block.PrevHash = prevBlock.Hash
+	block.SnapshotHash = snapshotHash
+	block.Difficulty = message.Difficulty
+	now := time.Now()
+	block.Timestamp = &now
+
+	result = &SimulateResult{}
+	sendDb := sendContext.CopyAndFreeze()
+	blockList, _, err := vm.NewVM().Run(sendDb, block, nil)
+	if err != nil {
+		result.Err = err
+		return result, nil
+	}
+	sendBlock := blockList[0].AccountBlock
+	sendBlock.Hash = sendBlock.ComputeHash()
+	result.SendBlock = sendBlock
+
+	toAddr := sendBlock.ToAddress
+	if !vm.IsPrecompiledContractAddress(toAddr) && len(sendDb.GetContractCode(&toAddr)) == 0 {
+		return result, nil
+	}
+
+	receiveContext, err := vm_context.NewVmContext(chain, &snapshotHash, nil, &toAddr)
+	if err != nil {
+		return nil, err
+	}
+	receiveGen := &Generator{vmContext: receiveContext, log: log15.New("module", "Generator")}
+	receiveBlock, err := receiveGen.packBlockWithSendBlock(sendBlock, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tracer := &returnDataTracer{}
+	receiveVm := vm.NewVM()
+	receiveVm.Tracer = tracer
+	result.ReceiveBlocks, _, err = receiveVm.Run(receiveContext.CopyAndFreeze(), receiveBlock, sendBlock)
+	result.ReturnData = tracer.output
+	if len(result.ReceiveBlocks) > 0 {
+		receive := result.ReceiveBlocks[0]
+		result.Logs = receive.VmContext.UnsavedCache().LogList()
+		if err == nil && receiveFailed(receive.AccountBlock) {
+			err = ErrContractReceiveFailed
+		}
+	}
+	result.Err = err
+	return result, nil
+}
+
+func receiveFailed(block *ledger.AccountBlock) bool {
+	return block.BlockType == ledger.BlockTypeReceiveError ||
+		(len(block.Data) == types.HashSize+1 && block.Data[types.HashSize] == vm.ResultFail)
+}
+
+// returnDataTracer keeps the output of the outermost code run.
+type returnDataTracer struct {
+	output []byte
+}
+
+func (t *returnDataTracer) CaptureStart(from types.Address, to types.Address, codeAddr types.Address, input []byte, quota uint64, depth int) {
+}
+
+func (t *returnDataTracer) CaptureState(pc uint64, op string, quotaLeft uint64, cost uint64, stack []*big.Int, memory []byte, depth int, err error) {
+}
+
+func (t *returnDataTracer) CaptureEnd(output []byte, quotaUsed uint64, depth int, err error) {
+	if depth == 1 {
+		t.output = output
+	}
+}
@@ -0,0 +1,236 @@
+package generator
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/config"
+	"github.com/vitelabs/go-vite/crypto/ed25519"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/vm"
+	"github.com/vitelabs/go-vite/vm/contracts"
+	"github.com/vitelabs/go-vite/vm/util"
+)
+
+/*
+ * pragma solidity ^0.4.18;
+ * contract MyContract {
+ * 	uint256 v;
+ * 	constructor() payable public {}
+ * 	function AddV(uint256 addition) payable public {
+ * 	   v = v + addition;
+ * 	}
+ * }
+ */
+const simulateContractCode = "608060405260858060116000396000f300608060405260043610603e5763ffffffff7c0100000000000000000000000000000000000000000000000000000000600035041663f021ab8f81146043575b600080fd5b604c600435604e565b005b6000805490910190555600a165627a7a72305820b8d8d60a46c6ac6569047b17b012aa1ea458271f9bc8078ef0cff9208999d0900029"
+
+func insertSnapshotBlock(t *testing.T, c chain.Chain) {
+	latestBlock := c.GetLatestSnapshotBlock()
+	now := time.Now()
+	snapshotBlock := &ledger.SnapshotBlock{
+		Height:          latestBlock.Height + 1,
+		PrevHash:        latestBlock.Hash,
+		Timestamp:       &now,
+		SnapshotContent: c.GetNeedSnapshotContent(),
+	}
+
+	stateTrie, err := c.GenStateTrie(latestBlock.StateHash, snapshotBlock.SnapshotContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshotBlock.StateTrie = stateTrie
+	snapshotBlock.StateHash = *stateTrie.Hash()
+	snapshotBlock.Hash = snapshotBlock.ComputeHash()
+
+	if err := c.InsertSnapshotBlock(snapshotBlock); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// generateBlocks generates the blocks of message, inserts them into the chain and snapshots them. The blocks
+// aren't verified by the chain, so they are signed by a random key.
+func generateBlocks(t *testing.T, c chain.Chain, message *IncomingMessage) *ledger.AccountBlock {
+	gen, err := NewGenerator(c, nil, nil, &message.AccountAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genResult, err := gen.GenerateWithMessage(message, func(addr types.Address, data []byte) (signedData, pubkey []byte, err error) {
+		return ed25519.Sign(addr1PrivKey, data), addr1PubKey, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if genResult.Err != nil {
+		t.Fatal(genResult.Err)
+	}
+	if err := c.InsertAccountBlocks(genResult.BlockGenList); err != nil {
+		t.Fatal(err)
+	}
+	insertSnapshotBlock(t, c)
+	return genResult.BlockGenList[0].AccountBlock
+}
+
+// prepareChain returns a chain in memory, on which the genesis account has received the genesis vite.
+func prepareChain(t *testing.T) (chain.Chain, func()) {
+	dir, err := ioutil.TempDir("", "simulate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := chain.NewChain(&config.Config{DataDir: dir, Chain: &config.Chain{LedgerInMemory: true}})
+	c.Init()
+	c.Start()
+	closeChain := func() {
+		c.Stop()
+		c.Destroy()
+		os.RemoveAll(dir)
+	}
+
+	generateBlocks(t, c, &IncomingMessage{
+		BlockType:      ledger.BlockTypeReceive,
+		AccountAddress: ledger.GenesisAccountAddress,
+		FromBlockHash:  &chain.GenesisMintageSendBlock.Hash,
+	})
+	return c, closeChain
+}
+
+func createContractMessage() *IncomingMessage {
+	code, _ := hex.DecodeString(simulateContractCode)
+	return &IncomingMessage{
+		BlockType:      ledger.BlockTypeSendCreate,
+		AccountAddress: ledger.GenesisAccountAddress,
+		Amount:         big.NewInt(0),
+		TokenId:        &ledger.ViteTokenId,
+		Data:           contracts.GetCreateContractData(code, types.DELEGATE_GID),
+	}
+}
+
+// prepareSimulateChain returns the chain of prepareChain, on which the genesis account has deployed the contract.
+func prepareSimulateChain(t *testing.T) (chain.Chain, types.Address, func()) {
+	c, closeChain := prepareChain(t)
+	createBlock := generateBlocks(t, c, createContractMessage())
+
+	generateBlocks(t, c, &IncomingMessage{
+		BlockType:      ledger.BlockTypeReceive,
+		AccountAddress: createBlock.ToAddress,
+		FromBlockHash:  &createBlock.Hash,
+	})
+	return c, createBlock.ToAddress, closeChain
+}
+
+func TestCreateContractFork(t *testing.T) {
+	// contract creation isn't activated on the main net
+	vm.InitVmConfig(true, false)
+	defer vm.InitVmConfig(isVmTest, false)
+
+	c, closeChain := prepareChain(t)
+	defer closeChain()
+
+	gen, err := NewGenerator(c, nil, nil, &ledger.GenesisAccountAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genResult, err := gen.GenerateWithMessage(createContractMessage(), func(addr types.Address, data []byte) (signedData, pubkey []byte, err error) {
+		return ed25519.Sign(addr1PrivKey, data), addr1PubKey, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if genResult.Err != util.ErrCreateContractNotForked || len(genResult.BlockGenList) != 0 {
+		t.Fatalf("the contract should not be created before the fork, got %v", genResult.Err)
+	}
+
+	// contract creation is activated from the first snapshot block on the test net
+	vm.InitVmConfig(true, true)
+	createBlock := generateBlocks(t, c, createContractMessage())
+	receiveBlock := generateBlocks(t, c, &IncomingMessage{
+		BlockType:      ledger.BlockTypeReceive,
+		AccountAddress: createBlock.ToAddress,
+		FromBlockHash:  &createBlock.Hash,
+	})
+	if gid, err := c.GetContractGid(&receiveBlock.AccountAddress); err != nil || gid == nil || *gid != types.DELEGATE_GID {
+		t.Fatalf("the contract should be created after the fork, got %v", err)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	// the accounts get quota without pledge or PoW, and create contracts
+	vm.InitVmConfig(true, true)
+	defer vm.InitVmConfig(isVmTest, false)
+
+	c, contractAddr, closeChain := prepareSimulateChain(t)
+	defer closeChain()
+
+	latestBlock, err := c.GetLatestAccountBlock(&ledger.GenesisAccountAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	simulate := func(toAddr types.Address, amount *big.Int, data string) *SimulateResult {
+		buf, _ := hex.DecodeString(data)
+		result, err := Simulate(c, &IncomingMessage{
+			BlockType:      ledger.BlockTypeSendCall,
+			AccountAddress: ledger.GenesisAccountAddress,
+			ToAddress:      &toAddr,
+			Amount:         amount,
+			TokenId:        &ledger.ViteTokenId,
+			Data:           buf,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	// call AddV(5)
+	result := simulate(contractAddr, big.NewInt(0), "f021ab8f0000000000000000000000000000000000000000000000000000000000000005")
+	if result.Err != nil || result.SendBlock == nil || len(result.ReceiveBlocks) != 1 {
+		t.Fatalf("call should succeed, got %v", result.Err)
+	}
+	if result.SendQuota() != 21464 || result.ReceiveQuota() != 41330 {
+		t.Fatalf("quota of the call is %d and %d", result.SendQuota(), result.ReceiveQuota())
+	}
+	if result.ReceiveBlocks[0].AccountBlock.AccountAddress != contractAddr || result.ReceiveBlocks[0].AccountBlock.FromBlockHash != result.SendBlock.Hash {
+		t.Fatal("receive block should receive the send block")
+	}
+
+	// the fallback function reverts
+	result = simulate(contractAddr, big.NewInt(0), "")
+	if result.Err != util.ErrExecutionReverted || result.SendQuota() == 0 || result.ReceiveQuota() == 0 {
+		t.Fatalf("call should revert, got %v, quota %d and %d", result.Err, result.SendQuota(), result.ReceiveQuota())
+	}
+
+	// transfer to a user account
+	result = simulate(addr2, big.NewInt(10), "")
+	if result.Err != nil || result.SendQuota() != 21000 || len(result.ReceiveBlocks) != 0 || result.ReceiveQuota() != 0 {
+		t.Fatalf("transfer should succeed without receive block, got %v, quota %d and %d", result.Err, result.SendQuota(), result.ReceiveQuota())
+	}
+
+	// insufficient balance
+	result = simulate(addr2, new(big.Int).Add(chain.GenesisMintageSendBlock.Amount, big.NewInt(1)), "")
+	if result.Err != util.ErrInsufficientBalance || result.SendBlock != nil || result.SendQuota() != 0 {
+		t.Fatalf("transfer should fail for insufficient balance, got %v", result.Err)
+	}
+
+	// nothing is written
+	if block, err := c.GetLatestAccountBlock(&ledger.GenesisAccountAddress); err != nil || block.Hash != latestBlock.Hash {
+		t.Fatal("simulated blocks should not be inserted")
+	}
+	if block, err := c.GetLatestAccountBlock(&contractAddr); err != nil || block.Height != 1 {
+		t.Fatal("simulated receive blocks should not be inserted")
+	}
+
+	// invalid messages
+	if _, err := Simulate(c, &IncomingMessage{BlockType: ledger.BlockTypeSendCreate, AccountAddress: ledger.GenesisAccountAddress}); err == nil {
+		t.Fatal("only send call should be simulated")
+	}
+	if _, err := Simulate(c, &IncomingMessage{BlockType: ledger.BlockTypeSendCall, AccountAddress: addr2, ToAddress: &addr1, TokenId: &ledger.ViteTokenId}); err == nil {
+		t.Fatal("the account without blocks should not be simulated")
+	}
+}
@@ -2,6 +2,7 @@ package ledger
 
 import (
 	"github.com/golang/protobuf/proto"
+	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/vitepb"
 )
 
@@ -13,6 +14,10 @@ type CompressedFileMeta struct {
 	FileSize int64
 
 	BlockNumbers uint64
+
+	// Codec is the id of the codec the blocks are compressed with, Hash is the hash of the file content
+	Codec uint32
+	Hash  types.Hash
 }
 
 func (f *CompressedFileMeta) Serialize() ([]byte, error) {
@@ -32,13 +37,18 @@ func (f *CompressedFileMeta) Deserialize(buf []byte) error {
 }
 
 func (f *CompressedFileMeta) Proto() *vitepb.CompressedFileMeta {
-	return &vitepb.CompressedFileMeta{
+	pb := &vitepb.CompressedFileMeta{
 		StartHeight:  f.StartHeight,
 		EndHeight:    f.EndHeight,
 		Filename:     f.Filename,
 		FileSize:     f.FileSize,
 		BlockNumbers: f.BlockNumbers,
+		Codec:        f.Codec,
+	}
+	if f.Hash != (types.Hash{}) {
+		pb.Hash = f.Hash.Bytes()
 	}
+	return pb
 }
 
 func (f *CompressedFileMeta) Deproto(pb *vitepb.CompressedFileMeta) {
@@ -47,4 +57,8 @@ func (f *CompressedFileMeta) Deproto(pb *vitepb.CompressedFileMeta) {
 	f.Filename = pb.Filename
 	f.FileSize = pb.FileSize
 	f.BlockNumbers = pb.BlockNumbers
+	f.Codec = pb.Codec
+
+	// the files of the old nodes have no hash, it's left zero
+	f.Hash, _ = types.BytesToHash(pb.Hash)
 }
@@ -0,0 +1,56 @@
+package node
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/vitelabs/go-vite/common"
+	"github.com/vitelabs/go-vite/compress"
+)
+
+// startLedgerArchive serves the ledger files over http, so they can be mirrored without the p2p protocol.
+func (node *Node) startLedgerArchive() error {
+	// Short circuit if the ledger archive isn't being exposed
+	if node.ledgerArchiveEndpoint == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", node.ledgerArchiveEndpoint)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	handler := compress.NewArchiveHandler(node.viteServer.Chain().Compressor())
+	mux.Handle(compress.ArchivePath, handler)
+	mux.Handle(compress.ArchivePath+"/", handler)
+
+	// no write timeout, the ledger files are large
+	server := &http.Server{
+		Handler:     mux,
+		ReadTimeout: 30 * time.Second,
+		IdleTimeout: 120 * time.Second,
+	}
+	node.ledgerArchiveServer = server
+
+	common.Go(func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error(fmt.Sprintf("Ledger archive serve error: %v", err))
+		}
+	})
+
+	log.Info("Ledger archive opened", "url", fmt.Sprintf("http://%s%s", listener.Addr(), compress.ArchivePath))
+	return nil
+}
+
+// stopLedgerArchive closes the listener and the connections of the ledger archive.
+func (node *Node) stopLedgerArchive() {
+	if node.ledgerArchiveServer != nil {
+		node.ledgerArchiveServer.Close()
+		node.ledgerArchiveServer = nil
+
+		log.Info("Ledger archive closed", "endpoint", node.ledgerArchiveEndpoint)
+	}
+}
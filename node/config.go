@@ -24,11 +24,24 @@ type Config struct {
 	KeyStoreDir string `json:"KeyStoreDir"`
 
 	// template：["broker1,broker2,...|topic",""]
-	KafkaProducers []string `json:"KafkaProducers"`
+	KafkaProducers []string            `json:"KafkaProducers"`
+	EventSinks     []*config.EventSink `json:"EventSinks"`
 
 	// chain
 	OpenBlackBlock bool   `json:"OpenBlackBlock"`
 	GenesisFile    string `json:"GenesisFile"`
+	OpenTxIndex    bool   `json:"OpenTxIndex"`
+	OpenLogIndex   bool   `json:"OpenLogIndex"`
+
+	StatePruneHeight uint64 `json:"StatePruneHeight"`
+	LedgerFileCodec  string `json:"LedgerFileCodec"`
+
+	RepairLedger        bool `json:"RepairLedger"`
+	WipeCorruptedLedger bool `json:"WipeCorruptedLedger"`
+
+	// serve the ledger files over http, it's disabled if LedgerArchiveHost is empty
+	LedgerArchiveHost string `json:"LedgerArchiveHost"`
+	LedgerArchivePort int    `json:"LedgerArchivePort"`
 
 	// p2p
 	NetSelect            string
@@ -39,6 +52,9 @@ type Config struct {
 	MaxPendingPeers      uint     `json:"MaxPendingPeers"`
 	BootNodes            []string `json:"BootNodes"`
 	StaticNodes          []string `json:"StaticNodes"`
+	TrustedNodes         []string `json:"TrustedNodes"`
+	PrivateNetwork       bool     `json:"PrivateNetwork"`
+	RequireEncryption    bool     `json:"RequireEncryption"`
 	Port                 uint     `json:"Port"`
 	NetID                uint     `json:"NetID"`
 	Discovery            bool     `json:"Discovery"`
@@ -87,6 +103,15 @@ type Config struct {
 	TopologyTopic          string   `json:"TopologyTopic"`
 	TopologyReportInterval int      `json:"TopologyReportInterval"`
 	TopoDisabled           bool     `json:"TopoDisabled"`
+
+	// peers whose reputation score drops to BanThreshold are banned for BanDuration seconds, the peers aren`t
+	// scored if ReputationDisabled is true
+	BanThreshold       int   `json:"BanThreshold"`
+	BanDuration        int64 `json:"BanDuration"`
+	ReputationDisabled bool  `json:"ReputationDisabled"`
+
+	// limit the requests of each peer by message name, eg. {"GetSubLedgerMsg": {"Rate": 1, "Burst": 10}}
+	RateLimits map[string]config.RateLimit `json:"RateLimits"`
 }
 
 func (c *Config) makeWalletConfig() *wallet.Config {
@@ -104,6 +129,11 @@ func (c *Config) makeViteConfig() *config.Config {
 	}
 }
 
+// ViteConfig returns the config of the vite modules, it is used by the commands working on the ledger offline.
+func (c *Config) ViteConfig() *config.Config {
+	return c.makeViteConfig()
+}
+
 func (c *Config) makeNetConfig() *config.Net {
 	return &config.Net{
 		Single:       c.Single,
@@ -112,6 +142,11 @@ func (c *Config) makeNetConfig() *config.Net {
 		Topic:        c.TopologyTopic,
 		Interval:     int64(c.TopologyReportInterval),
 		TopoDisabled: c.TopoDisabled,
+		BanThreshold: c.BanThreshold,
+		BanDuration:  c.BanDuration,
+		RateLimits:   c.RateLimits,
+
+		ReputationDisabled: c.ReputationDisabled,
 	}
 }
 
@@ -132,56 +167,59 @@ func (c *Config) makeMinerConfig() *config.Producer {
 
 func (c *Config) makeP2PConfig() *p2p.Config {
 	return &p2p.Config{
-		Name:            c.Identity,
-		NetID:           network.ID(c.NetID),
-		MaxPeers:        c.MaxPeers,
-		MaxPendingPeers: c.MaxPendingPeers,
-		MaxInboundRatio: c.MaxPassivePeersRatio,
-		Port:            c.Port,
-		DataDir:         filepath.Join(c.DataDir, p2p.Dirname),
-		PrivateKey:      c.GetPrivateKey(),
-		BootNodes:       c.BootNodes,
-		StaticNodes:     c.StaticNodes,
-		Discovery:       c.Discovery,
+		Name:              c.Identity,
+		NetID:             network.ID(c.NetID),
+		MaxPeers:          c.MaxPeers,
+		MaxPendingPeers:   c.MaxPendingPeers,
+		MaxInboundRatio:   c.MaxPassivePeersRatio,
+		Port:              c.Port,
+		DataDir:           filepath.Join(c.DataDir, p2p.Dirname),
+		PrivateKey:        c.GetPrivateKey(),
+		BootNodes:         c.BootNodes,
+		StaticNodes:       c.StaticNodes,
+		TrustedNodes:      c.TrustedNodes,
+		PrivateNetwork:    c.PrivateNetwork,
+		RequireEncryption: c.RequireEncryption,
+		Discovery:         c.Discovery,
 	}
 }
 
 func (c *Config) makeChainConfig() *config.Chain {
+	eventSinks := c.EventSinks
 
-	if len(c.KafkaProducers) == 0 {
-		return &config.Chain{
-			KafkaProducers: nil,
-			OpenBlackBlock: c.OpenBlackBlock,
-			GenesisFile:    c.GenesisFile,
-		}
-	}
-
-	// init kafkaProducers
-	kafkaProducers := make([]*config.KafkaProducer, len(c.KafkaProducers))
-
-	for i, kafkaProducer := range c.KafkaProducers {
+	// kafkaProducers are kept for compatibility, each one is a kafka sink
+	for _, kafkaProducer := range c.KafkaProducers {
 		splitKafkaProducer := strings.Split(kafkaProducer, "|")
 		if len(splitKafkaProducer) != 2 {
 			log.Warn(fmt.Sprintf("KafkaProducers is setting error，The program will skip here and continue processing"))
-			goto END
+			continue
 		}
 
 		splitKafkaBroker := strings.Split(splitKafkaProducer[0], ",")
 		if len(splitKafkaBroker) == 0 {
 			log.Warn(fmt.Sprintf("KafkaProducers is setting error，The program will skip here and continue processing"))
-			goto END
+			continue
 		}
 
-		kafkaProducers[i] = &config.KafkaProducer{
+		eventSinks = append(eventSinks, &config.EventSink{
+			Type:       config.SinkTypeKafka,
 			BrokerList: splitKafkaBroker,
 			Topic:      splitKafkaProducer[1],
-		}
+		})
 	}
-END:
+
 	return &config.Chain{
-		KafkaProducers: kafkaProducers,
+		EventSinks:     eventSinks,
 		OpenBlackBlock: c.OpenBlackBlock,
 		GenesisFile:    c.GenesisFile,
+		OpenTxIndex:    c.OpenTxIndex,
+		OpenLogIndex:   c.OpenLogIndex,
+
+		StatePruneHeight: c.StatePruneHeight,
+		LedgerFileCodec:  c.LedgerFileCodec,
+
+		RepairLedger:        c.RepairLedger,
+		WipeCorruptedLedger: c.WipeCorruptedLedger,
 	}
 }
 
@@ -192,6 +230,13 @@ func (c *Config) HTTPEndpoint() string {
 	return fmt.Sprintf("%s:%d", c.HttpHost, c.HttpPort)
 }
 
+func (c *Config) LedgerArchiveEndpoint() string {
+	if c.LedgerArchiveHost == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", c.LedgerArchiveHost, c.LedgerArchivePort)
+}
+
 func (c *Config) WSEndpoint() string {
 	if c.WSHost == "" {
 		return ""
@@ -15,6 +15,7 @@ var DefaultNodeConfig = Config{
 	KeyStoreDir:          DefaultDataDir(),
 	HttpPort:             common.DefaultHTTPPort,
 	WSPort:               common.DefaultWSPort,
+	LedgerArchivePort:    common.DefaultLedgerArchivePort,
 	PrivateKey:           "",
 	MaxPeers:             0,
 	MaxPassivePeersRatio: 0,
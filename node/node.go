@@ -3,6 +3,7 @@ package node
 import (
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sync"
@@ -56,6 +57,9 @@ type Node struct {
 	wsListener net.Listener
 	wsHandler  *rpc.Server
 
+	ledgerArchiveEndpoint string
+	ledgerArchiveServer   *http.Server
+
 	// Channel to wait for termination notifications
 	stop            chan struct{}
 	lock            sync.RWMutex
@@ -72,6 +76,8 @@ func New(conf *Config) (*Node, error) {
 		httpEndpoint: conf.HTTPEndpoint(),
 		wsEndpoint:   conf.WSEndpoint(),
 		stop:         make(chan struct{}),
+
+		ledgerArchiveEndpoint: conf.LedgerArchiveEndpoint(),
 	}, nil
 }
 
@@ -171,6 +177,12 @@ func (node *Node) Start() error {
 		return err
 	}
 
+	//ledger archive start
+	if err := node.startLedgerArchive(); err != nil {
+		log.Error(fmt.Sprintf("Node startLedgerArchive error: %v", err))
+		return err
+	}
+
 	return nil
 }
 
@@ -192,6 +204,10 @@ func (node *Node) Stop() error {
 		log.Error(fmt.Sprintf("Node stopP2P error: %v", err))
 	}
 
+	//ledger archive, the ledger files are served until vite is stopped
+	log.Info(fmt.Sprintf("Begin Stop Ledger Archive... "))
+	node.stopLedgerArchive()
+
 	//vite
 	log.Info(fmt.Sprintf("Begin Stop Vite... "))
 	if err := node.stopVite(); err != nil {
@@ -9,17 +9,17 @@ import (
 
 //In-proc apis
 func (node *Node) GetInProcessApis() []rpc.API {
-	return rpcapi.GetApis(node.viteServer, "ledger", "wallet", "private_onroad", "net", "contract", "pledge", "register", "vote", "mintage", "consensusGroup", "testapi", "pow", "tx")
+	return rpcapi.GetApis(node.viteServer, "ledger", "wallet", "private_onroad", "net", "admin", "contract", "private_contract", "pledge", "register", "vote", "mintage", "consensusGroup", "testapi", "pow", "tx", "private_debug", "subscribe", "filter")
 }
 
 //Ipc apis
 func (node *Node) GetIpcApis() []rpc.API {
-	return rpcapi.GetApis(node.viteServer, "ledger", "wallet", "private_onroad", "net", "contract", "pledge", "register", "vote", "mintage", "consensusGroup", "testapi", "pow", "tx")
+	return rpcapi.GetApis(node.viteServer, "ledger", "wallet", "private_onroad", "net", "admin", "contract", "private_contract", "pledge", "register", "vote", "mintage", "consensusGroup", "testapi", "pow", "tx", "private_debug", "subscribe", "filter")
 }
 
 //Http apis
 func (node *Node) GetHttpApis() []rpc.API {
-	apiModules := []string{"ledger", "public_onroad", "net", "contract", "pledge", "register", "vote", "mintage", "consensusGroup", "pow", "tx"}
+	apiModules := []string{"ledger", "public_onroad", "net", "contract", "pledge", "register", "vote", "mintage", "consensusGroup", "pow", "tx", "filter"}
 	if node.Config().NetID > 1 {
 		apiModules = append(apiModules, "testapi")
 	}
@@ -28,7 +28,7 @@ func (node *Node) GetHttpApis() []rpc.API {
 
 //WS apis
 func (node *Node) GetWSApis() []rpc.API {
-	apiModules := []string{"ledger", "public_onroad", "net", "contract", "pledge", "register", "vote", "mintage", "consensusGroup", "pow", "tx"}
+	apiModules := []string{"ledger", "public_onroad", "net", "contract", "pledge", "register", "vote", "mintage", "consensusGroup", "pow", "tx", "subscribe", "filter"}
 	if node.Config().NetID > 1 {
 		apiModules = append(apiModules, "testapi")
 	}
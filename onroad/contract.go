@@ -90,8 +90,8 @@ func (w *ContractWorker) Start(accEvent producerevent.AccountStartEvent) {
 			return
 		}
 		if len(addressList) == 0 {
+			// keep listening, contracts created in this group later are scheduled by the listener
 			w.log.Info("newContractWorker addressList nil")
-			return
 		}
 		w.contractAddressList = addressList
 		log.Info("get addresslist", "len", len(addressList))
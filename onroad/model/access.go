@@ -3,8 +3,8 @@ package model
 import (
 	"bytes"
 	"github.com/pkg/errors"
-	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/chain_db/database"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/ledger"
 	"github.com/vitelabs/go-vite/log15"
@@ -44,7 +44,7 @@ func (access *UAccess) GetContractAddrListByGid(gid *types.Gid) ([]types.Address
 	return addrList, nil
 }
 
-func (access *UAccess) WriteContractAddrToGid(batch *leveldb.Batch, gid types.Gid, address types.Address) error {
+func (access *UAccess) WriteContractAddrToGid(batch database.Batch, gid types.Gid, address types.Address) error {
 	var addrList []types.Address
 	var err error
 
@@ -63,7 +63,7 @@ func (access *UAccess) WriteContractAddrToGid(batch *leveldb.Batch, gid types.Gi
 	}
 }
 
-func (access *UAccess) DeleteContractAddrFromGid(batch *leveldb.Batch, gid types.Gid, address types.Address) error {
+func (access *UAccess) DeleteContractAddrFromGid(batch database.Batch, gid types.Gid, address types.Address) error {
 	var addrList []types.Address
 	var err error
 
@@ -74,7 +74,7 @@ func (access *UAccess) DeleteContractAddrFromGid(batch *leveldb.Batch, gid types
 	} else {
 		for k, v := range addrList {
 			if bytes.Equal(v.Bytes(), address.Bytes()) {
-				addrList = append(addrList[0:k-1], addrList[k+1:]...)
+				addrList = append(addrList[:k], addrList[k+1:]...)
 				break
 			}
 		}
@@ -82,7 +82,7 @@ func (access *UAccess) DeleteContractAddrFromGid(batch *leveldb.Batch, gid types
 	}
 }
 
-func (access *UAccess) writeOnroadMeta(batch *leveldb.Batch, block *ledger.AccountBlock) error {
+func (access *UAccess) writeOnroadMeta(batch database.Batch, block *ledger.AccountBlock) error {
 	if block.IsSendBlock() {
 		// call from the common WriteOnroad func to add new onRoadTx, sendBlock
 		return access.store.WriteMeta(batch, &block.ToAddress, &block.Hash)
@@ -125,7 +125,7 @@ func (access *UAccess) writeOnroadMeta(batch *leveldb.Batch, block *ledger.Accou
 	}
 }
 
-func (access *UAccess) deleteOnroadMeta(batch *leveldb.Batch, block *ledger.AccountBlock) error {
+func (access *UAccess) deleteOnroadMeta(batch database.Batch, block *ledger.AccountBlock) error {
 	if block.IsReceiveBlock() {
 		// call from the WriteOnroad func to handle the onRoadTx's receiveBlock
 		addr := &block.AccountAddress
@@ -178,7 +178,7 @@ func (access *UAccess) deleteOnroadMeta(batch *leveldb.Batch, block *ledger.Acco
 func (access *UAccess) GetOnroadHashs(index, num, count uint64, addr *types.Address) ([]*types.Hash, error) {
 	totalCount := (index + num) * count
 	maxCount, err := access.store.GetCountByAddress(addr)
-	if err != nil && err != leveldb.ErrNotFound {
+	if err != nil && err != database.ErrNotFound {
 		access.log.Error("GetOnroadHashs", "error", err)
 		return nil, err
 	}
@@ -4,7 +4,6 @@
 package model
 
 import (
-	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/vitelabs/go-vite/chain"
 	"github.com/vitelabs/go-vite/chain_db/database"
@@ -17,7 +16,7 @@ type OnroadSet struct {
 	chain chain.Chain
 }
 
-func (o OnroadSet) db() *leveldb.DB {
+func (o OnroadSet) db() database.KeyValueStore {
 	return o.chain.ChainDb().Db()
 }
 func NewOnroadSet(chain chain.Chain) *OnroadSet {
@@ -34,7 +33,7 @@ func (ucf *OnroadSet) GetCountByAddress(addr *types.Address) (count uint64, err
 		return 0, err
 	}
 
-	iter := ucf.db().NewIterator(util.BytesPrefix(key), nil)
+	iter := ucf.db().NewIterator(util.BytesPrefix(key))
 	defer iter.Release()
 
 	for iter.Next() {
@@ -49,7 +48,7 @@ func (ucf *OnroadSet) GetHashsByCount(count uint64, addr *types.Address) (hashs
 		return nil, err
 	}
 
-	iter := ucf.db().NewIterator(util.BytesPrefix(key), nil)
+	iter := ucf.db().NewIterator(util.BytesPrefix(key))
 	defer iter.Release()
 	i := uint64(1)
 	for iter.Next() {
@@ -64,7 +63,7 @@ func (ucf *OnroadSet) GetHashsByCount(count uint64, addr *types.Address) (hashs
 		hashs = append(hashs, &hash)
 		i++
 	}
-	if err := iter.Error(); err != nil && err != leveldb.ErrNotFound {
+	if err := iter.Error(); err != nil && err != database.ErrNotFound {
 		return nil, err
 	}
 	return hashs, nil
@@ -76,7 +75,7 @@ func (ucf *OnroadSet) GetHashList(addr *types.Address) (hashs []*types.Hash, err
 		return nil, err
 	}
 
-	iter := ucf.db().NewIterator(util.BytesPrefix(createKey), nil)
+	iter := ucf.db().NewIterator(util.BytesPrefix(createKey))
 	defer iter.Release()
 
 	for iter.Next() {
@@ -87,13 +86,13 @@ func (ucf *OnroadSet) GetHashList(addr *types.Address) (hashs []*types.Hash, err
 		}
 		hashs = append(hashs, &hash)
 	}
-	if err := iter.Error(); err != nil && err != leveldb.ErrNotFound {
+	if err := iter.Error(); err != nil && err != database.ErrNotFound {
 		return nil, err
 	}
 	return hashs, nil
 }
 
-func (ucf *OnroadSet) WriteMeta(batch *leveldb.Batch, addr *types.Address, hash *types.Hash) error {
+func (ucf *OnroadSet) WriteMeta(batch database.Batch, addr *types.Address, hash *types.Hash) error {
 	value := []byte{byte(0)}
 
 	key, err := database.EncodeKey(database.DBKP_ONROADMETA, addr.Bytes(), hash.Bytes())
@@ -101,7 +100,7 @@ func (ucf *OnroadSet) WriteMeta(batch *leveldb.Batch, addr *types.Address, hash
 		return err
 	}
 	if batch == nil {
-		if err := ucf.db().Put(key, value, nil); err != nil {
+		if err := ucf.db().Put(key, value); err != nil {
 			return err
 		}
 	} else {
@@ -110,13 +109,13 @@ func (ucf *OnroadSet) WriteMeta(batch *leveldb.Batch, addr *types.Address, hash
 	return nil
 }
 
-func (ucf *OnroadSet) DeleteMeta(batch *leveldb.Batch, addr *types.Address, hash *types.Hash) error {
+func (ucf *OnroadSet) DeleteMeta(batch database.Batch, addr *types.Address, hash *types.Hash) error {
 	key, err := database.EncodeKey(database.DBKP_ONROADMETA, addr.Bytes(), hash.Bytes())
 	if err != nil {
 		return err
 	}
 	if batch == nil {
-		if err := ucf.db().Delete(key, nil); err != nil {
+		if err := ucf.db().Delete(key); err != nil {
 			return err
 		}
 	} else {
@@ -125,7 +124,7 @@ func (ucf *OnroadSet) DeleteMeta(batch *leveldb.Batch, addr *types.Address, hash
 	return nil
 }
 
-func (ucf *OnroadSet) WriteGidAddrList(batch *leveldb.Batch, gid *types.Gid, addrList []types.Address) error {
+func (ucf *OnroadSet) WriteGidAddrList(batch database.Batch, gid *types.Gid, addrList []types.Address) error {
 	key, err := database.EncodeKey(database.DBKP_GID_ADDR, gid.Bytes())
 	if err != nil {
 		return err
@@ -136,7 +135,7 @@ func (ucf *OnroadSet) WriteGidAddrList(batch *leveldb.Batch, gid *types.Gid, add
 	}
 
 	if batch == nil {
-		if err := ucf.db().Put(key, data, nil); err != nil {
+		if err := ucf.db().Put(key, data); err != nil {
 			return err
 		}
 	} else {
@@ -152,9 +151,9 @@ func (ucf *OnroadSet) GetContractAddrList(gid *types.Gid) ([]types.Address, erro
 		return nil, err
 	}
 
-	data, err := ucf.db().Get(key, nil)
+	data, err := ucf.db().Get(key)
 	if err != nil {
-		if err != leveldb.ErrNotFound {
+		if err != database.ErrNotFound {
 			return nil, err
 		}
 		return nil, nil
@@ -162,7 +161,7 @@ func (ucf *OnroadSet) GetContractAddrList(gid *types.Gid) ([]types.Address, erro
 	return AddrListDbDeserialize(data)
 }
 
-func (ucf *OnroadSet) IncreaseReceiveErrCount(batch *leveldb.Batch, hash *types.Hash, addr *types.Address) error {
+func (ucf *OnroadSet) IncreaseReceiveErrCount(batch database.Batch, hash *types.Hash, addr *types.Address) error {
 	key, err := database.EncodeKey(database.DBKP_ONROADRECEIVEERR, hash.Bytes(), addr.Bytes())
 	if err != nil {
 		return err
@@ -176,11 +175,11 @@ func (ucf *OnroadSet) IncreaseReceiveErrCount(batch *leveldb.Batch, hash *types.
 		batch.Put(key, []byte{count})
 		return nil
 	} else {
-		return ucf.db().Put(key, []byte{count}, nil)
+		return ucf.db().Put(key, []byte{count})
 	}
 }
 
-func (ucf *OnroadSet) DecreaseReceiveErrCount(batch *leveldb.Batch, hash *types.Hash, addr *types.Address) error {
+func (ucf *OnroadSet) DecreaseReceiveErrCount(batch database.Batch, hash *types.Hash, addr *types.Address) error {
 	key, err := database.EncodeKey(database.DBKP_ONROADRECEIVEERR, hash.Bytes(), addr.Bytes())
 	if err != nil {
 		return err
@@ -199,19 +198,19 @@ func (ucf *OnroadSet) DecreaseReceiveErrCount(batch *leveldb.Batch, hash *types.
 		return nil
 	} else {
 		if count > 0 {
-			return ucf.db().Put(key, []byte{count}, nil)
+			return ucf.db().Put(key, []byte{count})
 		} else {
-			return ucf.db().Delete(key, nil)
+			return ucf.db().Delete(key)
 		}
 	}
 }
-func (ucf *OnroadSet) DeleteReceiveErrCount(batch *leveldb.Batch, hash *types.Hash, addr *types.Address) error {
+func (ucf *OnroadSet) DeleteReceiveErrCount(batch database.Batch, hash *types.Hash, addr *types.Address) error {
 	key, err := database.EncodeKey(database.DBKP_ONROADRECEIVEERR, hash.Bytes(), addr.Bytes())
 	if err != nil {
 		return err
 	}
-	if _, err := ucf.db().Get(key, nil); err != nil {
-		if err != leveldb.ErrNotFound {
+	if _, err := ucf.db().Get(key); err != nil {
+		if err != database.ErrNotFound {
 			return err
 		}
 		return nil
@@ -219,7 +218,7 @@ func (ucf *OnroadSet) DeleteReceiveErrCount(batch *leveldb.Batch, hash *types.Ha
 	if batch != nil {
 		batch.Delete(key)
 	} else {
-		return ucf.db().Delete(key, nil)
+		return ucf.db().Delete(key)
 	}
 	return nil
 }
@@ -230,9 +229,9 @@ func (ucf *OnroadSet) GetReceiveErrCount(hash *types.Hash, addr *types.Address)
 		return 0, err
 	}
 
-	data, err := ucf.db().Get(key, nil)
+	data, err := ucf.db().Get(key)
 	if err != nil {
-		if err != leveldb.ErrNotFound {
+		if err != database.ErrNotFound {
 			return 0, err
 		}
 		return 0, nil
@@ -6,7 +6,7 @@ import (
 	"sync"
 	"time"
 
-	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/vitelabs/go-vite/chain_db/database"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/ledger"
 	"github.com/vitelabs/go-vite/log15"
@@ -195,9 +195,13 @@ func (p *OnroadBlocksPool) WriteOnroadSuccess(blocks []*vm_context.VmAccountBloc
 	for _, v := range blocks {
 		if v.AccountBlock.IsSendBlock() {
 			code, _ := p.dbAccess.Chain.AccountType(&v.AccountBlock.ToAddress)
+			if code == ledger.AccountTypeError {
+				continue
+			}
 			if (code == ledger.AccountTypeNotExist && v.AccountBlock.BlockType == ledger.BlockTypeSendCreate) ||
-				code == ledger.AccountTypeContract || code == ledger.AccountTypeError {
-				return
+				code == ledger.AccountTypeContract {
+				p.NewSignalToWorker(v.AccountBlock)
+				continue
 			}
 			p.updateCache(true, v.AccountBlock)
 			p.NewSignalToWorker(v.AccountBlock)
@@ -210,7 +214,7 @@ func (p *OnroadBlocksPool) WriteOnroadSuccess(blocks []*vm_context.VmAccountBloc
 	}
 }
 
-func (p *OnroadBlocksPool) WriteOnroad(batch *leveldb.Batch, blockList []*vm_context.VmAccountBlock) error {
+func (p *OnroadBlocksPool) WriteOnroad(batch database.Batch, blockList []*vm_context.VmAccountBlock) error {
 	for _, v := range blockList {
 		if v.AccountBlock.IsSendBlock() {
 			// basic writeMeta func
@@ -262,7 +266,7 @@ func (p *OnroadBlocksPool) RevertOnroadSuccess(subLedger map[types.Address][]*le
 }
 
 // RevertOnroad means to revert according to bifurcation
-func (p *OnroadBlocksPool) RevertOnroad(batch *leveldb.Batch, subLedger map[types.Address][]*ledger.AccountBlock) error {
+func (p *OnroadBlocksPool) RevertOnroad(batch database.Batch, subLedger map[types.Address][]*ledger.AccountBlock) error {
 
 	cutMap := excludeSubordinate(subLedger)
 	for _, blocks := range cutMap {
@@ -435,16 +439,24 @@ func (p *OnroadBlocksPool) updateCache(writeType bool, block *ledger.AccountBloc
 }
 
 func (p *OnroadBlocksPool) NewSignalToWorker(block *ledger.AccountBlock) {
-	gid, err := p.dbAccess.Chain.GetContractGid(&block.ToAddress)
-	if err != nil {
-		p.log.Error("NewSignalToWorker", "err", err)
-		return
+	var gid *types.Gid
+	if block.BlockType == ledger.BlockTypeSendCreate {
+		// the contract account doesn't exist until the create block is received
+		createGid := contracts.GetGidFromCreateContractData(block.Data)
+		gid = &createGid
+	} else {
+		var err error
+		gid, err = p.dbAccess.Chain.GetContractGid(&block.ToAddress)
+		if err != nil {
+			p.log.Error("NewSignalToWorker", "err", err)
+			return
+		}
 	}
 	if gid != nil {
 		p.contractListenerMutex.RLock()
 		defer p.contractListenerMutex.RUnlock()
 		if f, ok := p.newContractListener[*gid]; ok {
-			f(block.AccountAddress)
+			f(block.ToAddress)
 		}
 	} else {
 		p.commonTxListenerMutex.RLock()
@@ -0,0 +1,141 @@
+package p2p
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vitelabs/go-vite/p2p/discovery"
+)
+
+// @section banList
+// banList records the nodes can`t be connected until their bans expire
+type banList struct {
+	lock sync.RWMutex
+	bans map[discovery.NodeID]time.Time
+}
+
+func newBanList() *banList {
+	return &banList{
+		bans: make(map[discovery.NodeID]time.Time),
+	}
+}
+
+func (b *banList) ban(id discovery.NodeID, expiration time.Time) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.bans[id] = expiration
+}
+
+func (b *banList) unban(id discovery.NodeID) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	_, ok := b.bans[id]
+	delete(b.bans, id)
+	return ok
+}
+
+func (b *banList) banned(id discovery.NodeID) bool {
+	b.lock.RLock()
+	expiration, ok := b.bans[id]
+	b.lock.RUnlock()
+
+	if !ok {
+		return false
+	}
+
+	if time.Now().Before(expiration) {
+		return true
+	}
+
+	// expired
+	b.unban(id)
+	return false
+}
+
+func (b *banList) list() []*BanInfo {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	now := time.Now()
+	list := make([]*BanInfo, 0, len(b.bans))
+	for id, expiration := range b.bans {
+		if now.Before(expiration) {
+			list = append(list, &BanInfo{
+				ID:         id.String(),
+				Expiration: expiration,
+			})
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Expiration.Before(list[j].Expiration)
+	})
+
+	return list
+}
+
+// @section BanInfo
+type BanInfo struct {
+	ID         string    `json:"id"`
+	Expiration time.Time `json:"expiration"`
+}
+
+// Ban disconnects the peer of id and refuses its connections in duration, the ban will be stored if discovery is enabled
+func (svr *Server) Ban(id discovery.NodeID, duration time.Duration) error {
+	if id == svr.self.ID {
+		return fmt.Errorf("can`t ban self")
+	}
+	if duration <= 0 {
+		return fmt.Errorf("ban duration should be positive")
+	}
+
+	expiration := time.Now().Add(duration)
+	svr.bans.ban(id, expiration)
+
+	if svr.discv != nil {
+		svr.discv.Ban(id, expiration)
+	}
+
+	svr.log.Warn(fmt.Sprintf("ban %s until %s", id, expiration))
+
+	svr.disconnect(id, DiscBanned)
+
+	return nil
+}
+
+// Unban removes the ban of id, return false if id isn`t banned
+func (svr *Server) Unban(id discovery.NodeID) bool {
+	if svr.discv != nil {
+		svr.discv.Unban(id)
+	}
+
+	if svr.bans.unban(id) {
+		svr.log.Info(fmt.Sprintf("unban %s", id))
+		return true
+	}
+
+	return false
+}
+
+// Bans returns the bans not expired, sorted by expiration
+func (svr *Server) Bans() []*BanInfo {
+	return svr.bans.list()
+}
+
+// restore the bans stored by discovery
+func (svr *Server) loadBans() {
+	if svr.discv == nil {
+		return
+	}
+
+	bans := svr.discv.Bans()
+	for id, expiration := range bans {
+		svr.bans.ban(id, expiration)
+	}
+
+	svr.log.Info(fmt.Sprintf("load %d bans", len(bans)))
+}
@@ -27,6 +27,8 @@ const (
 	DiscResponseTimeout
 	DiscUnKnownProtocol
 	DiscHandshakeFail
+	DiscBanned
+	DiscNotAllowed
 )
 
 var discReasonStr = [...]string{
@@ -47,10 +49,12 @@ var discReasonStr = [...]string{
 	DiscResponseTimeout:     "wait response timeout",
 	DiscUnKnownProtocol:     "missing protocol handler",
 	DiscHandshakeFail:       "p2p handshake error",
+	DiscBanned:              "banned",
+	DiscNotAllowed:          "not allowed in private network",
 }
 
 func (d DiscReason) String() string {
-	if d > DiscHandshakeFail {
+	if d > DiscNotAllowed {
 		return "unknown disc reason"
 	}
 	return discReasonStr[d]
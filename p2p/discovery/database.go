@@ -32,6 +32,7 @@ var (
 	dbDiscvPingBytes     = []byte(dbDiscvPing)     // store the last time ping received from node
 	dbDiscvPongBytes     = []byte(dbDiscvPong)     // store the last time pong received from node
 	dbDiscvFindFailBytes = []byte(dbDiscvFindFail) // store the fail times node respond our findnode message
+	dbBanPrefix          = []byte("ban:")          // store the expiration of banned node, not cleaned with stale nodes
 )
 
 func newDB(path string, version int, id NodeID) (db *nodeDB, err error) {
@@ -266,6 +267,44 @@ func (db *nodeDB) setFindNodeFails(id NodeID, fails int) error {
 	return db.storeInt64(genKey(id, dbDiscvFindFailBytes), int64(fails))
 }
 
+func genBanKey(id NodeID) []byte {
+	return bytes.Join([][]byte{
+		dbBanPrefix,
+		id[:],
+	}, nil)
+}
+
+// set the time when the ban of id expires
+func (db *nodeDB) setBan(id NodeID, expiration time.Time) error {
+	return db.storeInt64(genBanKey(id), expiration.Unix())
+}
+
+func (db *nodeDB) deleteBan(id NodeID) error {
+	return db.db.Delete(genBanKey(id), nil)
+}
+
+// retrieve all bans not expired, and delete the expired
+func (db *nodeDB) retrieveBans() map[NodeID]time.Time {
+	itr := db.db.NewIterator(util.BytesPrefix(dbBanPrefix), nil)
+	defer itr.Release()
+
+	now := time.Now()
+	bans := make(map[NodeID]time.Time)
+	for itr.Next() {
+		var id NodeID
+		copy(id[:], itr.Key()[len(dbBanPrefix):])
+
+		expiration := time.Unix(decodeVarint(itr.Value()), 0)
+		if expiration.After(now) {
+			bans[id] = expiration
+		} else {
+			db.db.Delete(itr.Key(), nil)
+		}
+	}
+
+	return bans
+}
+
 func (db *nodeDB) cleanStaleNodes() {
 	now := time.Now()
 
@@ -0,0 +1,37 @@
+package discovery
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNodeDB_Bans(t *testing.T) {
+	var self, id, expired NodeID
+	self[0], id[0], expired[0] = 1, 2, 3
+
+	db, err := newMemDB(self)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+
+	expiration := time.Unix(time.Now().Add(time.Hour).Unix(), 0)
+	db.setBan(id, expiration)
+	db.setBan(expired, time.Now().Add(-time.Hour))
+
+	bans := db.retrieveBans()
+	if len(bans) != 1 || !bans[id].Equal(expiration) {
+		t.Fatalf("should retrieve the ban of %s until %s, got %v", id, expiration, bans)
+	}
+
+	// stale nodes are cleaned, bans are kept
+	db.cleanStaleNodes()
+	if len(db.retrieveBans()) != 1 {
+		t.Fatal("ban should not be cleaned with stale nodes")
+	}
+
+	db.deleteBan(id)
+	if len(db.retrieveBans()) != 0 {
+		t.Fatal("ban should be deleted")
+	}
+}
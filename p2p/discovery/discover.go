@@ -43,6 +43,7 @@ type Config struct {
 	Addr      *net.UDPAddr
 	Self      *Node
 	NetID     network.ID
+	Allow     func(id NodeID) bool // filter nodes, nil means all nodes are allowed
 }
 
 type Discovery struct {
@@ -132,6 +133,36 @@ func (d *Discovery) Block(ID NodeID, IP net.IP) {
 	}
 }
 
+// Ban stores the expiration of the ban of id, so the ban will be restored after restart
+func (d *Discovery) Ban(id NodeID, expiration time.Time) {
+	if d.db == nil {
+		return
+	}
+
+	if err := d.db.setBan(id, expiration); err != nil {
+		d.log.Error(fmt.Sprintf("store ban of %s error: %v", id, err))
+	}
+}
+
+func (d *Discovery) Unban(id NodeID) {
+	if d.db == nil {
+		return
+	}
+
+	if err := d.db.deleteBan(id); err != nil {
+		d.log.Error(fmt.Sprintf("delete ban of %s error: %v", id, err))
+	}
+}
+
+// Bans returns the stored bans which are not expired
+func (d *Discovery) Bans() map[NodeID]time.Time {
+	if d.db == nil {
+		return nil
+	}
+
+	return d.db.retrieveBans()
+}
+
 func (d *Discovery) Mark(id NodeID, lifetime int64) {
 	// todo
 }
@@ -321,6 +352,10 @@ func (d *Discovery) HandleMsg(res *packet) {
 		return
 	}
 
+	if !d.allowed(res.fromID) {
+		return
+	}
+
 	switch res.code {
 	case pingCode:
 		monitor.LogEvent("p2p/discv", "ping-receive")
@@ -440,7 +475,15 @@ func (d *Discovery) UnSubNodes(ch chan<- *Node) {
 	}
 }
 
+func (d *Discovery) allowed(id NodeID) bool {
+	return d.cfg.Allow == nil || d.cfg.Allow(id)
+}
+
 func (d *Discovery) notify(node *Node) {
+	if !d.allowed(node.ID) {
+		return
+	}
+
 	for _, ch := range d.subs {
 		select {
 		case ch <- node:
@@ -9,7 +9,6 @@ import (
 	"github.com/vitelabs/go-vite/crypto/ed25519"
 	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/monitor"
-	"github.com/vitelabs/go-vite/p2p/block"
 	"github.com/vitelabs/go-vite/p2p/discovery"
 	"github.com/vitelabs/go-vite/p2p/nat"
 	"github.com/vitelabs/go-vite/p2p/network"
@@ -30,23 +29,29 @@ type Discovery interface {
 	UnSubNodes(ch chan<- *discovery.Node)
 	Mark(id discovery.NodeID, lifetime int64)
 	Block(id discovery.NodeID, ip net.IP)
+	Ban(id discovery.NodeID, expiration time.Time)
+	Unban(id discovery.NodeID)
+	Bans() map[discovery.NodeID]time.Time
 	Need(n uint)
 	Nodes() []*discovery.Node
 }
 
 type Config struct {
-	Discovery       bool
-	Name            string
-	NetID           network.ID         // which network server runs on
-	MaxPeers        uint               // max peers can be connected
-	MaxPendingPeers uint               // max peers waiting for connect
-	MaxInboundRatio uint               // max inbound peers: MaxPeers / MaxInboundRatio
-	Port            uint               // TCP and UDP listen port
-	DataDir         string             // the directory for storing node table, default is "~/viteisbest/p2p"
-	PrivateKey      ed25519.PrivateKey // use for encrypt message, the corresponding public key use for NodeID
-	Protocols       []*Protocol        // protocols server supported
-	BootNodes       []string           // nodes as discovery seed
-	StaticNodes     []string           // nodes to connect
+	Discovery         bool
+	Name              string
+	NetID             network.ID         // which network server runs on
+	MaxPeers          uint               // max peers can be connected
+	MaxPendingPeers   uint               // max peers waiting for connect
+	MaxInboundRatio   uint               // max inbound peers: MaxPeers / MaxInboundRatio
+	Port              uint               // TCP and UDP listen port
+	DataDir           string             // the directory for storing node table, default is "~/viteisbest/p2p"
+	PrivateKey        ed25519.PrivateKey // use for encrypt message, the corresponding public key use for NodeID
+	Protocols         []*Protocol        // protocols server supported
+	BootNodes         []string           // nodes as discovery seed
+	StaticNodes       []string           // nodes to connect
+	TrustedNodes      []string           // nodes can be connected even if peers too many, and will be redialed forever
+	PrivateNetwork    bool               // only trusted nodes can be connected, it implies RequireEncryption
+	RequireEncryption bool               // only the nodes support secureVersion can be connected, the connections are encrypted
 }
 
 type Server struct {
@@ -60,10 +65,12 @@ type Server struct {
 	pending   chan struct{} // how many connection can wait for handshake
 	addPeer   chan *transport
 	delPeer   chan *Peer
+	discPeer  chan *discPeer
 	discv     Discovery
 	handshake *Handshake
 	peers     *PeerSet
-	blockList *block.Set
+	bans      *banList
+	trusted   *trustedSet
 	self      *discovery.Node
 	ln        net.Listener
 	nodeChan  chan *discovery.Node // sub discovery nodes
@@ -103,7 +110,9 @@ func New(cfg *Config) (svr *Server, err error) {
 		pending:     make(chan struct{}, cfg.MaxPendingPeers),
 		addPeer:     make(chan *transport, 1),
 		delPeer:     make(chan *Peer, 1),
-		blockList:   block.New(100),
+		discPeer:    make(chan *discPeer, 1),
+		bans:        newBanList(),
+		trusted:     newTrustedSet(parseNodes(cfg.TrustedNodes)),
 		self:        node,
 		nodeChan:    make(chan *discovery.Node, 10),
 		log:         log15.New("module", "p2p/server"),
@@ -118,6 +127,12 @@ func New(cfg *Config) (svr *Server, err error) {
 			return
 		}
 
+		// in private network, discovery only talk with trusted nodes
+		var allow func(id discovery.NodeID) bool
+		if cfg.PrivateNetwork {
+			allow = svr.trusted.has
+		}
+
 		svr.discv = discovery.New(&discovery.Config{
 			Priv:      cfg.PrivateKey,
 			DBPath:    cfg.DataDir,
@@ -125,6 +140,7 @@ func New(cfg *Config) (svr *Server, err error) {
 			Addr:      udpAddr,
 			Self:      node,
 			NetID:     cfg.NetID,
+			Allow:     allow,
 		})
 	}
 
@@ -176,6 +192,9 @@ func (svr *Server) Start() error {
 			svr.ln.Close()
 			return err
 		}
+
+		// bans are stored in the db of discovery
+		svr.loadBans()
 	}
 
 	svr.wg.Add(1)
@@ -250,10 +269,18 @@ func (svr *Server) dialLoop() {
 		svr.dial(node.ID, node.TCPAddr(), static)
 	}
 
+	// redial disconnected trusted nodes
+	ticker := time.NewTicker(minTrustedBackoff)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-svr.term:
 			return
+		case now := <-ticker.C:
+			for _, node := range svr.trusted.due(now) {
+				svr.dial(node.ID, node.TCPAddr(), trusted)
+			}
 		case node := <-svr.nodeChan:
 			svr.dial(node.ID, node.TCPAddr(), outbound)
 		}
@@ -264,11 +291,6 @@ func (svr *Server) dialLoop() {
 // we can get ID and addr only from peer, but not Node
 // so dial(id, addr, flag) not dial(Node, flag)
 func (svr *Server) dial(id discovery.NodeID, addr *net.TCPAddr, flag connFlag) {
-	// has been blocked
-	//if svr.blockList.Has(id[:]) {
-	//	return
-	//}
-
 	if err := svr.checkConn(id, flag); err != nil {
 		return
 	}
@@ -281,7 +303,6 @@ func (svr *Server) dial(id discovery.NodeID, addr *net.TCPAddr, flag connFlag) {
 	} else {
 		<-svr.pending
 		svr.log.Warn(fmt.Sprintf("dial node %s@%s failed: %v", id, addr, err))
-		//svr.blockList.Add(id[:])
 	}
 }
 
@@ -342,10 +363,11 @@ func (svr *Server) releasePending() {
 func (svr *Server) setupConn(c net.Conn, flag connFlag, id discovery.NodeID) {
 	defer svr.releasePending()
 
-	head, err := headShake(c, &headMsg{
+	ourHead := &headMsg{
 		Version: Version,
 		NetID:   svr.NetID,
-	})
+	}
+	head, err := headShake(c, ourHead)
 
 	if err != nil {
 		c.Close()
@@ -353,15 +375,9 @@ func (svr *Server) setupConn(c net.Conn, flag connFlag, id discovery.NodeID) {
 		return
 	}
 
-	if svr.NetID != head.NetID {
+	if err = svr.checkHead(head); err != nil {
 		c.Close()
-		svr.log.Warn(fmt.Sprintf("different NetID %s: our %s, their %s", c.RemoteAddr(), svr.NetID, head.NetID))
-		return
-	}
-
-	if head.Version < Version {
-		c.Close()
-		svr.log.Warn(fmt.Sprintf("different Version %s: our %s, their %s", c.RemoteAddr(), Version, head.Version))
+		svr.log.Warn(fmt.Sprintf("check head of %s error: %v", c.RemoteAddr(), err))
 		return
 	}
 
@@ -370,6 +386,30 @@ func (svr *Server) setupConn(c net.Conn, flag connFlag, id discovery.NodeID) {
 		flags: flag,
 	}
 
+	// the old peers which don`t support secureVersion can still connect in plaintext, unless encryption is required
+	if head.Version >= secureVersion {
+		secure, theirID, err := secureShake(c, svr.PrivateKey, !flag.is(inbound), ourHead, head)
+		if err != nil {
+			c.Close()
+			svr.log.Warn(fmt.Sprintf("secureShake with %s error: %v", c.RemoteAddr(), err))
+			return
+		}
+		if !id.IsZero() && theirID != id {
+			c.Close()
+			svr.log.Warn(fmt.Sprintf("unmatched id of %s: expect %s, got %s", c.RemoteAddr(), id, theirID))
+			return
+		}
+		// don`t handshake with the nodes not allowed
+		if !svr.allowed(theirID) {
+			c.Close()
+			svr.log.Warn(fmt.Sprintf("%s@%s is not allowed", theirID, c.RemoteAddr()))
+			return
+		}
+
+		ts.secure = secure
+		ts.remoteID = theirID
+	}
+
 	// handshake data, add remoteIP and remotePort
 	// handshake is not same for every peer
 	handshake := *svr.handshake
@@ -385,6 +425,12 @@ func (svr *Server) setupConn(c net.Conn, flag connFlag, id discovery.NodeID) {
 	} else if !id.IsZero() && their.ID != id {
 		ts.Close()
 		svr.log.Warn(fmt.Sprintf("unmatched id"))
+	} else if ts.secure != nil && their.ID != ts.remoteID {
+		ts.Close()
+		svr.log.Warn(fmt.Sprintf("handshake id of %s is different from the secure id", c.RemoteAddr()))
+	} else if !svr.allowed(their.ID) {
+		ts.Close()
+		svr.log.Warn(fmt.Sprintf("%s@%s is not allowed", their.ID, c.RemoteAddr()))
 	} else {
 		ts.name = their.Name
 		ts.cmdSets = their.CmdSets
@@ -402,17 +448,49 @@ func (svr *Server) setupConn(c net.Conn, flag connFlag, id discovery.NodeID) {
 	}
 }
 
+// checkHead returns an error if the connection of their head message can't be set up
+func (svr *Server) checkHead(head *headMsg) error {
+	if svr.NetID != head.NetID {
+		return fmt.Errorf("different NetID: our %s, their %s", svr.NetID, head.NetID)
+	}
+
+	if head.Version < minVersion {
+		return fmt.Errorf("different Version: our %d, their %d", Version, head.Version)
+	}
+
+	if svr.requireEncryption() && head.Version < secureVersion {
+		return errNotEncrypted
+	}
+
+	return nil
+}
+
+// requireEncryption reports whether the plaintext connections are rejected. The ID of a plaintext peer isn`t
+// authenticated, the handshake of a trusted node may be replayed, so private network requires encryption.
+func (svr *Server) requireEncryption() bool {
+	return svr.RequireEncryption || svr.PrivateNetwork
+}
+
 func (svr *Server) checkConn(id discovery.NodeID, flag connFlag) error {
 	if id == svr.self.ID {
 		return DiscSelf
 	}
 
+	// banned peers can`t be connected even if static
+	if svr.bans.banned(id) {
+		return DiscBanned
+	}
+
+	if !svr.allowed(id) {
+		return DiscNotAllowed
+	}
+
 	if svr.peers.Has(id) {
 		return DiscAlreadyConnected
 	}
 
-	// static can be connected even if peers too many
-	if flag == static {
+	// static and trusted can be connected even if peers too many
+	if flag == static || svr.trusted.has(id) {
 		return nil
 	}
 
@@ -442,6 +520,7 @@ loop:
 			if err == nil {
 				if p, err := NewPeer(c, svr.Protocols); err == nil {
 					svr.peers.Add(p)
+					svr.trusted.connect(p.ID(), time.Now())
 					peersCount = svr.peers.Size()
 					svr.log.Info(fmt.Sprintf("create new peer %s, total: %d", p, peersCount))
 
@@ -471,9 +550,17 @@ loop:
 				svr.dial(p.ID(), p.RemoteAddr(), static)
 			}
 
+			// trusted will be redialed in dialLoop
+			svr.trusted.disconnect(p.ID(), time.Now())
+
 			if peersCount == 0 && svr.discv != nil {
 				svr.discv.Need(svr.MaxPeers)
 			}
+
+		case d := <-svr.discPeer:
+			if p := svr.peers.Get(d.id); p != nil {
+				p.Disconnect(d.reason)
+			}
 		}
 	}
 
@@ -482,6 +569,23 @@ loop:
 	})
 }
 
+type discPeer struct {
+	id     discovery.NodeID
+	reason DiscReason
+}
+
+// disconnect the peer of id in loop, peers can only be accessed in loop
+func (svr *Server) disconnect(id discovery.NodeID, reason DiscReason) {
+	if svr.term == nil {
+		return
+	}
+
+	select {
+	case <-svr.term:
+	case svr.discPeer <- &discPeer{id, reason}:
+	}
+}
+
 func (svr *Server) runPeer(p *Peer) {
 	err := p.run()
 	if err != nil {
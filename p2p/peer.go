@@ -29,6 +29,7 @@ var errPeerTermed = errors.New("peer has been terminated")
 
 type transport struct {
 	net.Conn
+	secure     *secureRW // nil if the connection isn't encrypted
 	flags      connFlag
 	cmdSets    []CmdSet
 	name       string
@@ -42,11 +43,17 @@ type transport struct {
 
 func (t *transport) ReadMsg() (*Msg, error) {
 	//t.SetReadDeadline(time.Now().Add(msgReadTimeout))
+	if t.secure != nil {
+		return t.secure.ReadMsg()
+	}
 	return ReadMsg(t)
 }
 
 func (t *transport) WriteMsg(msg *Msg) error {
 	//t.SetWriteDeadline(time.Now().Add(msgWriteTimeout))
+	if t.secure != nil {
+		return t.secure.WriteMsg(msg)
+	}
 	return WriteMsg(t, msg)
 }
 
@@ -70,7 +77,7 @@ func (t *transport) Handshake(key ed25519.PrivateKey, our *Handshake) (their *Ha
 		msg.Cmd = handshakeCmd
 		msg.Payload = data
 
-		send <- WriteMsg(t, msg)
+		send <- t.WriteMsg(msg)
 	})
 
 	if their, err = readHandshake(t); err != nil {
@@ -426,11 +433,12 @@ func (p *Peer) Info() *PeerInfo {
 	}
 
 	return &PeerInfo{
-		ID:      p.ID().String(),
-		Name:    p.Name(),
-		CmdSets: caps,
-		Address: p.RemoteAddr().String(),
-		Inbound: p.ts.is(inbound),
+		ID:        p.ID().String(),
+		Name:      p.Name(),
+		CmdSets:   caps,
+		Address:   p.RemoteAddr().String(),
+		Inbound:   p.ts.is(inbound),
+		Encrypted: p.ts.secure != nil,
 	}
 }
 
@@ -471,6 +479,10 @@ func (s *PeerSet) Del(p *Peer) {
 	s.size--
 }
 
+func (s *PeerSet) Get(id discovery.NodeID) *Peer {
+	return s.peers[id]
+}
+
 func (s *PeerSet) Has(id discovery.NodeID) bool {
 	_, ok := s.peers[id]
 	return ok
@@ -505,6 +517,8 @@ type PeerInfo struct {
 	CmdSets []string `json:"cmdSets"`
 	Address string   `json:"address"`
 	Inbound bool     `json:"inbound"`
+	// Encrypted is false if the peer doesn't support secureVersion
+	Encrypted bool `json:"encrypted"`
 }
 
 // @section ConnProperty
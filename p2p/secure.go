@@ -0,0 +1,234 @@
+package p2p
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vitelabs/go-vite/common"
+	"github.com/vitelabs/go-vite/crypto/ed25519"
+	"github.com/vitelabs/go-vite/p2p/discovery"
+	"golang.org/x/crypto/blake2b"
+)
+
+// secureVersion is the first P2PVersion which encrypts the connection, if both sides support it the messages after
+// headShake are sent in secure frames.
+const secureVersion P2PVersion = 2
+
+// auth message is the first message of a secure connection
+const authMsgLen = 64 // ephemeral X25519 public key[32] + node ID[32]
+
+const frameLenSize = 4
+const frameOverhead = 16 // tag of AES-GCM
+const maxFrameSize = headerLength + maxPayloadSize + frameOverhead
+
+var initiatorKeyLabel = []byte("vite p2p initiator")
+var responderKeyLabel = []byte("vite p2p responder")
+
+var errFrameTooLarge = errors.New("secure frame is too large")
+var errNotEncrypted = errors.New("connection can't be encrypted")
+
+// secureShake runs the key exchange after headShake. Each side sends an ephemeral X25519 key and its node ID, the
+// session keys are derived from the DH of the two ephemeral keys and the DHs between each ephemeral key and the
+// X25519 key converted from the other node ID, so only the owner of the node ID can decrypt the messages. The
+// initiator is the side which dialed the connection. The head messages are bound into the keys with the auth
+// messages, so the messages can't be decrypted if the versions were changed on the way.
+func secureShake(conn net.Conn, key ed25519.PrivateKey, initiator bool, our, their *headMsg) (rw *secureRW, theirID discovery.NodeID, err error) {
+	ephemeral := make([]byte, ed25519.X25519KeySize)
+	if _, err = io.ReadFull(rand.Reader, ephemeral); err != nil {
+		return
+	}
+	ephemeralPub, err := ed25519.X25519(ephemeral, ed25519.X25519Basepoint)
+	if err != nil {
+		return
+	}
+	staticKey := key.ToX25519()
+
+	ourAuth := make([]byte, 0, authMsgLen)
+	ourAuth = append(ourAuth, ephemeralPub...)
+	ourAuth = append(ourAuth, key.PubByte()...)
+
+	send := make(chan error, 1)
+	common.Go(func() {
+		_, err := conn.Write(ourAuth)
+		send <- err
+	})
+
+	theirAuth := make([]byte, authMsgLen)
+	if _, err = io.ReadFull(conn, theirAuth); err != nil {
+		return
+	}
+	if err = <-send; err != nil {
+		return
+	}
+
+	if theirID, err = discovery.Bytes2NodeID(theirAuth[32:]); err != nil {
+		return
+	}
+	theirEphemeral := theirAuth[:32]
+	theirStatic, err := ed25519.PublicKey(theirID[:]).ToX25519()
+	if err != nil {
+		return
+	}
+
+	// ee, se and es of the initiator, the responder computes the same secrets with the opposite keys
+	var ee, se, es []byte
+	if ee, err = ed25519.X25519(ephemeral, theirEphemeral); err != nil {
+		return
+	}
+	transcript := make([]byte, 0, 2*headMsgLen+2*authMsgLen)
+	if initiator {
+		if se, err = ed25519.X25519(staticKey, theirEphemeral); err != nil {
+			return
+		}
+		if es, err = ed25519.X25519(ephemeral, theirStatic); err != nil {
+			return
+		}
+		transcript = append(append(transcript, our.encode()...), their.encode()...)
+		transcript = append(append(transcript, ourAuth...), theirAuth...)
+	} else {
+		if se, err = ed25519.X25519(ephemeral, theirStatic); err != nil {
+			return
+		}
+		if es, err = ed25519.X25519(staticKey, theirEphemeral); err != nil {
+			return
+		}
+		transcript = append(append(transcript, their.encode()...), our.encode()...)
+		transcript = append(append(transcript, theirAuth...), ourAuth...)
+	}
+
+	h, _ := blake2b.New256(nil)
+	h.Write(ee)
+	h.Write(se)
+	h.Write(es)
+	h.Write(transcript)
+	secret := h.Sum(nil)
+
+	initiatorAEAD, err := newFrameAEAD(secret, initiatorKeyLabel)
+	if err != nil {
+		return
+	}
+	responderAEAD, err := newFrameAEAD(secret, responderKeyLabel)
+	if err != nil {
+		return
+	}
+
+	rw = &secureRW{conn: conn}
+	if initiator {
+		rw.wAEAD, rw.rAEAD = initiatorAEAD, responderAEAD
+	} else {
+		rw.wAEAD, rw.rAEAD = responderAEAD, initiatorAEAD
+	}
+	return
+}
+
+// newFrameAEAD returns the AES-256-GCM of the key derived from secret with label, each direction has its own key.
+func newFrameAEAD(secret, label []byte) (cipher.AEAD, error) {
+	h, err := blake2b.New256(secret)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(label)
+
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// secureRW reads and writes the messages in secure frames, a frame is the length of the sealed message followed by
+// the message header and payload sealed with the counter of the direction as nonce, so a frame can't be modified,
+// reordered or replayed.
+type secureRW struct {
+	conn net.Conn
+
+	rAEAD  cipher.AEAD
+	rNonce uint64
+
+	// messages may be written by the write loop and the peer concurrently
+	wLock  sync.Mutex
+	wAEAD  cipher.AEAD
+	wNonce uint64
+}
+
+func frameNonce(counter uint64) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[4:], counter)
+	return nonce
+}
+
+func (rw *secureRW) ReadMsg() (msg *Msg, err error) {
+	lenBytes := make([]byte, frameLenSize)
+	if _, err = io.ReadFull(rw.conn, lenBytes); err != nil {
+		return
+	}
+
+	size := binary.BigEndian.Uint32(lenBytes)
+	if size > maxFrameSize {
+		return nil, errFrameTooLarge
+	}
+	if size < headerLength+frameOverhead {
+		return nil, fmt.Errorf("secure frame is too small: %d bytes", size)
+	}
+
+	frame := make([]byte, size)
+	if _, err = io.ReadFull(rw.conn, frame); err != nil {
+		return
+	}
+
+	data, err := rw.rAEAD.Open(frame[:0], frameNonce(rw.rNonce), frame, lenBytes)
+	if err != nil {
+		return nil, err
+	}
+	rw.rNonce++
+
+	msg, payloadSize := decodeHeader(data[:headerLength])
+	if payloadSize != uint32(len(data)-headerLength) {
+		return nil, fmt.Errorf("message payload should be %d bytes, got %d bytes", payloadSize, len(data)-headerLength)
+	}
+
+	msg.Payload = data[headerLength:]
+	msg.ReceivedAt = time.Now()
+	return
+}
+
+func (rw *secureRW) WriteMsg(msg *Msg) (err error) {
+	defer msg.Recycle()
+
+	size := uint32(len(msg.Payload))
+	if size == 0 {
+		return errMsgNull
+	}
+	if size > maxPayloadSize {
+		return errMsgTooLarge
+	}
+
+	data := make([]byte, headerLength, headerLength+int(size))
+	encodeHeader(data, msg)
+	data = append(data, msg.Payload...)
+
+	rw.wLock.Lock()
+	defer rw.wLock.Unlock()
+
+	frame := make([]byte, frameLenSize, frameLenSize+len(data)+frameOverhead)
+	binary.BigEndian.PutUint32(frame, uint32(len(data)+frameOverhead))
+	frame = rw.wAEAD.Seal(frame, frameNonce(rw.wNonce), data, frame[:frameLenSize])
+	rw.wNonce++
+
+	var n int
+	if n, err = rw.conn.Write(frame); err != nil {
+		return
+	} else if n != len(frame) {
+		return fmt.Errorf("write incomplement secure frame %d/%d bytes", n, len(frame))
+	}
+
+	return
+}
@@ -0,0 +1,135 @@
+package p2p
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/vitelabs/go-vite/crypto/ed25519"
+	"github.com/vitelabs/go-vite/p2p/discovery"
+	"github.com/vitelabs/go-vite/p2p/network"
+)
+
+type shakeResult struct {
+	rw  *secureRW
+	id  discovery.NodeID
+	err error
+}
+
+// shakePipe runs secureShake on both sides of a pipe, the responder receives theirHead as the head of the initiator
+func shakePipe(initiatorKey, responderKey ed25519.PrivateKey, initiatorHead, theirHead *headMsg) (initiator, responder shakeResult) {
+	c1, c2 := net.Pipe()
+
+	responderHead := &headMsg{Version: Version, NetID: network.Aquarius}
+	done := make(chan shakeResult, 1)
+	go func() {
+		var r shakeResult
+		r.rw, r.id, r.err = secureShake(c2, responderKey, false, responderHead, theirHead)
+		done <- r
+	}()
+
+	initiator.rw, initiator.id, initiator.err = secureShake(c1, initiatorKey, true, initiatorHead, responderHead)
+	responder = <-done
+	return
+}
+
+func sendMsg(t *testing.T, w, r *secureRW, payload []byte) (*Msg, error) {
+	sent := make(chan error, 1)
+	go func() {
+		msg := NewMsg()
+		msg.CmdSet = 1
+		msg.Cmd = 2
+		msg.Id = 3
+		msg.Payload = payload
+		sent <- w.WriteMsg(msg)
+	}()
+
+	msg, err := r.ReadMsg()
+	if err != nil {
+		return nil, err
+	}
+	if err := <-sent; err != nil {
+		t.Fatal(err)
+	}
+	return msg, nil
+}
+
+func TestSecureShake(t *testing.T) {
+	_, initiatorKey, _ := ed25519.GenerateKey(nil)
+	_, responderKey, _ := ed25519.GenerateKey(nil)
+	initiatorID, _ := discovery.Priv2NodeID(initiatorKey)
+	responderID, _ := discovery.Priv2NodeID(responderKey)
+
+	head := &headMsg{Version: Version, NetID: network.Aquarius}
+	initiator, responder := shakePipe(initiatorKey, responderKey, head, head)
+	if initiator.err != nil || responder.err != nil {
+		t.Fatal(initiator.err, responder.err)
+	}
+	if initiator.id != responderID || responder.id != initiatorID {
+		t.Fatal("the node IDs of the other sides are wrong")
+	}
+
+	// round-trip
+	for i, payload := range [][]byte{[]byte("hello"), bytes.Repeat([]byte("vite"), 1000)} {
+		msg, err := sendMsg(t, initiator.rw, responder.rw, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.CmdSet != 1 || msg.Cmd != 2 || msg.Id != 3 || !bytes.Equal(msg.Payload, payload) {
+			t.Fatalf("message %d is changed", i)
+		}
+		if msg, err = sendMsg(t, responder.rw, initiator.rw, payload); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(msg.Payload, payload) {
+			t.Fatalf("reply %d is changed", i)
+		}
+	}
+
+	// the version changed on the way gives different keys
+	changedHead := &headMsg{Version: Version + 1, NetID: network.Aquarius}
+	initiator, responder = shakePipe(initiatorKey, responderKey, head, changedHead)
+	if initiator.err != nil || responder.err != nil {
+		t.Fatal(initiator.err, responder.err)
+	}
+	done := make(chan struct{})
+	go func() {
+		// the message can't be written to the pipe if the reader fails
+		msg := NewMsg()
+		msg.Payload = []byte("hello")
+		initiator.rw.WriteMsg(msg)
+		close(done)
+	}()
+	if _, err := responder.rw.ReadMsg(); err == nil {
+		t.Fatal("the message should not be decrypted with the changed version")
+	}
+	responder.rw.conn.Close()
+	<-done
+}
+
+func TestServer_checkHead(t *testing.T) {
+	svr := &Server{
+		Config: &Config{
+			NetID: network.Aquarius,
+		},
+	}
+
+	if err := svr.checkHead(&headMsg{Version: Version, NetID: network.Aquarius + 1}); err == nil {
+		t.Error("the head of another network should be rejected")
+	}
+	if err := svr.checkHead(&headMsg{Version: minVersion - 1, NetID: network.Aquarius}); err == nil {
+		t.Error("the head of an old version should be rejected")
+	}
+	if err := svr.checkHead(&headMsg{Version: minVersion, NetID: network.Aquarius}); err != nil {
+		t.Errorf("the plaintext connection should be allowed: %v", err)
+	}
+
+	// downgrade
+	svr.RequireEncryption = true
+	if err := svr.checkHead(&headMsg{Version: secureVersion - 1, NetID: network.Aquarius}); err != errNotEncrypted {
+		t.Errorf("the plaintext connection should be rejected, got %v", err)
+	}
+	if err := svr.checkHead(&headMsg{Version: secureVersion, NetID: network.Aquarius}); err != nil {
+		t.Errorf("the encrypted connection should be allowed: %v", err)
+	}
+}
@@ -14,7 +14,10 @@ import (
 
 type P2PVersion = uint32
 
-const Version P2PVersion = 1
+const Version P2PVersion = 2
+
+// minVersion is the oldest P2PVersion can be connected, the connection uses the lower version of the two sides
+const minVersion P2PVersion = 1
 
 const baseProtocolCmdSet = 0
 const handshakeCmd = 0
@@ -65,10 +68,15 @@ func readHead(conn net.Conn) (head *headMsg, err error) {
 	return
 }
 
-func writeHead(conn net.Conn, head *headMsg) error {
+func (head *headMsg) encode() []byte {
 	headPacket := make([]byte, headMsgLen)
 	binary.BigEndian.PutUint32(headPacket[:4], uint32(head.NetID))
 	binary.BigEndian.PutUint32(headPacket[4:8], head.Version)
+	return headPacket
+}
+
+func writeHead(conn net.Conn, head *headMsg) error {
+	headPacket := head.encode()
 
 	//conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 	if n, err := conn.Write(headPacket); err != nil {
@@ -128,6 +136,25 @@ func PackMsg(cmdSetId CmdSet, cmd Cmd, id uint64, s Serializable) (*Msg, error)
 	return msg, nil
 }
 
+func decodeHeader(head []byte) (msg *Msg, size uint32) {
+	msg = new(Msg)
+	msg.CmdSet = binary.BigEndian.Uint32(head[:4])
+	msg.Cmd = binary.BigEndian.Uint16(head[4:6])
+	msg.Id = binary.BigEndian.Uint64(head[6:14])
+	size = binary.BigEndian.Uint32(head[14:18])
+	msg.SendAt = time.Unix(int64(binary.BigEndian.Uint64(head[18:26])), 0)
+
+	return
+}
+
+func encodeHeader(head []byte, msg *Msg) {
+	binary.BigEndian.PutUint32(head[:4], msg.CmdSet)
+	binary.BigEndian.PutUint16(head[4:6], msg.Cmd)
+	binary.BigEndian.PutUint64(head[6:14], msg.Id)
+	binary.BigEndian.PutUint32(head[14:18], uint32(len(msg.Payload)))
+	binary.BigEndian.PutUint64(head[18:26], uint64(time.Now().Unix()))
+}
+
 func ReadMsg(reader io.Reader) (msg *Msg, err error) {
 	head := make([]byte, headerLength)
 
@@ -135,11 +162,7 @@ func ReadMsg(reader io.Reader) (msg *Msg, err error) {
 		return
 	}
 
-	msg = new(Msg)
-	msg.CmdSet = binary.BigEndian.Uint32(head[:4])
-	msg.Cmd = binary.BigEndian.Uint16(head[4:6])
-	msg.Id = binary.BigEndian.Uint64(head[6:14])
-	size := binary.BigEndian.Uint32(head[14:18])
+	msg, size := decodeHeader(head)
 
 	if size > maxPayloadSize {
 		return nil, errMsgTooLarge
@@ -151,7 +174,6 @@ func ReadMsg(reader io.Reader) (msg *Msg, err error) {
 	}
 
 	msg.Payload = payload
-	msg.SendAt = time.Unix(int64(binary.BigEndian.Uint64(head[18:26])), 0)
 	msg.ReceivedAt = time.Now()
 
 	return
@@ -171,11 +193,7 @@ func WriteMsg(writer io.Writer, msg *Msg) (err error) {
 	}
 
 	head := make([]byte, headerLength)
-	binary.BigEndian.PutUint32(head[:4], msg.CmdSet)
-	binary.BigEndian.PutUint16(head[4:6], msg.Cmd)
-	binary.BigEndian.PutUint64(head[6:14], msg.Id)
-	binary.BigEndian.PutUint32(head[14:18], size)
-	binary.BigEndian.PutUint64(head[18:26], uint64(time.Now().Unix()))
+	encodeHeader(head, msg)
 
 	// write header
 	var n int
@@ -198,8 +216,8 @@ func WriteMsg(writer io.Writer, msg *Msg) (err error) {
 var errHandshakeVerify = errors.New("signature of handshake Msg verify failed")
 var errHandshakeNotComp = errors.New("handshake payload is too small, maybe old version")
 
-func readHandshake(r io.Reader) (h *Handshake, err error) {
-	msg, err := ReadMsg(r)
+func readHandshake(r MsgReader) (h *Handshake, err error) {
+	msg, err := r.ReadMsg()
 	if err != nil {
 		return nil, err
 	}
@@ -1,151 +1,59 @@
 package p2p
 
 import (
-	"fmt"
+	"bytes"
 	"math/rand"
-	"net"
 	"testing"
 )
 
-func mockPayload() ([]byte, int) {
-	var size int
-
-	for {
-		size = rand.Intn(10000)
-		if size > 0 {
-			break
-		}
-	}
+func mockPayload() []byte {
+	size := rand.Intn(10000) + 1
 
 	buf := make([]byte, size)
-	for i := 0; i < size; i++ {
-		buf[i] = byte(rand.Intn(254))
-	}
+	rand.Read(buf)
 
-	return buf, size
+	return buf
 }
 
-func mockMsg() (*Msg, error) {
+func mockMsg() *Msg {
 	msg := NewMsg()
 
-	msg.CmdSet = rand.Uint64()
-	msg.Cmd = rand.Uint32()
+	msg.CmdSet = rand.Uint32()
+	msg.Cmd = Cmd(rand.Intn(1 << 16))
 	msg.Id = rand.Uint64()
+	msg.Payload = mockPayload()
 
-	payload, size := mockPayload()
-	msg.Payload = payload
-	msg.Size = uint32(size)
-
-	return msg, nil
+	return msg
 }
 
-func TestAsyncMsgConn_Start(t *testing.T) {
-	errch := make(chan error)
-	const addr = "localhost:8888"
-
-	go func() {
-		ln, err := net.Listen("tcp", addr)
-		if err != nil {
-			t.Error(err)
-		}
-
-		conn, err := ln.Accept()
-		if err != nil {
-			t.Error(err)
-		}
-
-		if err = handleConn(conn, "server"); err != nil {
-			t.Error(err)
-			errch <- err
-		}
-	}()
-
-	go func() {
-		conn, err := net.Dial("tcp", addr)
-		if err != nil {
-			t.Error(err)
-		}
+func TestWriteMsg_ReadMsg(t *testing.T) {
+	buf := new(bytes.Buffer)
 
-		if err = handleConn(conn, "client"); err != nil {
-			t.Error(err)
-			errch <- err
+	msgs := make([]*Msg, 10)
+	for i := range msgs {
+		msgs[i] = mockMsg()
+		if err := WriteMsg(buf, msgs[i]); err != nil {
+			t.Fatal(err)
 		}
-	}()
-
-	fmt.Println("test fail", <-errch)
-}
-
-func handleConn(conn net.Conn, mark string) error {
-	errch := make(chan error)
-
-	c := NewAsyncMsgConn(conn)
-
-	c.handler = func(msg *Msg) {
-		fmt.Println(mark, "receive", msg.CmdSet, msg.Cmd, msg.Id, msg.Size)
 	}
 
-	c.Start()
-
-	go func() {
-		select {
-		case err := <-c.errch:
-			fmt.Println(mark, "error", err)
-			errch <- err
-		}
-	}()
-
-	for {
-		msg, err := mockMsg()
+	for _, msg := range msgs {
+		received, err := ReadMsg(buf)
 		if err != nil {
-			continue
+			t.Fatal(err)
 		}
 
-		err = c.SendMsg(msg)
-		if err != nil {
-			fmt.Println(mark, "send error", err)
-			errch <- err
-		} else {
-			fmt.Println(mark, "send", msg.CmdSet, msg.Cmd, msg.Id, msg.Size)
+		if received.CmdSet != msg.CmdSet || received.Cmd != msg.Cmd || received.Id != msg.Id || !bytes.Equal(received.Payload, msg.Payload) {
+			t.Fatalf("message %d/%d/%d should be read back", msg.CmdSet, msg.Cmd, msg.Id)
 		}
 	}
-
-	err := <-errch
-	c.Close()
-	return err
 }
 
-func TestAsyncMsgConn_Error(t *testing.T) {
-	errch := make(chan error)
-	const addr = "localhost:8890"
-
-	go func() {
-		ln, err := net.Listen("tcp", addr)
-		if err != nil {
-			t.Error(err)
-		}
-
-		conn, err := ln.Accept()
-		if err != nil {
-			t.Error(err)
-		}
-
-		if err = handleConn(conn, "server"); err != nil {
-			t.Error(err)
-			errch <- err
-		}
-	}()
-
-	go func() {
-		conn, err := net.Dial("tcp", addr)
-		if err != nil {
-			t.Error(err)
-		}
+func TestWriteMsg_Null(t *testing.T) {
+	msg := mockMsg()
+	msg.Payload = nil
 
-		if err = handleConn(conn, "client"); err != nil {
-			t.Error(err)
-			errch <- err
-		}
-	}()
-
-	fmt.Println("test fail", <-errch)
+	if err := WriteMsg(new(bytes.Buffer), msg); err != errMsgNull {
+		t.Fatalf("should get errMsgNull, got %v", err)
+	}
 }
@@ -0,0 +1,178 @@
+package p2p
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vitelabs/go-vite/p2p/discovery"
+)
+
+const minTrustedBackoff = time.Second
+const maxTrustedBackoff = 5 * time.Minute
+
+// @section trustedSet
+// trusted nodes can be connected even if peers too many, and will be redialed with backoff forever.
+// if Server.PrivateNetwork is true, only trusted nodes can be connected, and the connections must be encrypted.
+type trustedNode struct {
+	node      *discovery.Node
+	backoff   time.Duration
+	nextDial  time.Time
+	connected bool
+	since     time.Time // when the node was connected
+}
+
+type trustedSet struct {
+	lock  sync.RWMutex
+	nodes map[discovery.NodeID]*trustedNode
+}
+
+func newTrustedSet(nodes []*discovery.Node) *trustedSet {
+	t := &trustedSet{
+		nodes: make(map[discovery.NodeID]*trustedNode),
+	}
+
+	for _, node := range nodes {
+		t.add(node)
+	}
+
+	return t
+}
+
+// add node or update the address of node, return false if node has been trusted
+func (t *trustedSet) add(node *discovery.Node) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if n, ok := t.nodes[node.ID]; ok {
+		n.node = node
+		return false
+	}
+
+	t.nodes[node.ID] = &trustedNode{
+		node:    node,
+		backoff: minTrustedBackoff,
+	}
+	return true
+}
+
+func (t *trustedSet) remove(id discovery.NodeID) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	_, ok := t.nodes[id]
+	delete(t.nodes, id)
+	return ok
+}
+
+func (t *trustedSet) has(id discovery.NodeID) bool {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	_, ok := t.nodes[id]
+	return ok
+}
+
+// due returns the disconnected nodes should be dialed at now, and double their backoff
+func (t *trustedSet) due(now time.Time) (nodes []*discovery.Node) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, n := range t.nodes {
+		if n.connected || now.Before(n.nextDial) {
+			continue
+		}
+
+		nodes = append(nodes, n.node)
+
+		n.nextDial = now.Add(n.backoff)
+		if n.backoff *= 2; n.backoff > maxTrustedBackoff {
+			n.backoff = maxTrustedBackoff
+		}
+	}
+
+	return
+}
+
+// connect marks id connected, it won`t be dialed until disconnected
+func (t *trustedSet) connect(id discovery.NodeID, now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if n, ok := t.nodes[id]; ok {
+		n.connected = true
+		n.since = now
+	}
+}
+
+// disconnect marks id disconnected. if the connection has lasted maxTrustedBackoff, the backoff of id is reset
+// and id will be redialed immediately, else id is redialed with the backoff, so a node drops us soon after
+// connected won`t be redialed again and again.
+func (t *trustedSet) disconnect(id discovery.NodeID, now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if n, ok := t.nodes[id]; ok && n.connected {
+		n.connected = false
+		if now.Sub(n.since) >= maxTrustedBackoff {
+			n.backoff = minTrustedBackoff
+			n.nextDial = time.Time{}
+		}
+	}
+}
+
+func (t *trustedSet) list() []string {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	urls := make([]string, 0, len(t.nodes))
+	for _, n := range t.nodes {
+		urls = append(urls, n.node.String())
+	}
+
+	return urls
+}
+
+// AddTrustedNode trusts the node of url, it will be dialed soon
+func (svr *Server) AddTrustedNode(url string) error {
+	node, err := discovery.ParseNode(url)
+	if err != nil {
+		return err
+	}
+
+	if node.ID == svr.self.ID {
+		return fmt.Errorf("can`t trust self")
+	}
+
+	if svr.trusted.add(node) {
+		svr.log.Info(fmt.Sprintf("add trusted node %s", node))
+	}
+
+	return nil
+}
+
+// RemoveTrustedNode untrusts the node of id, if PrivateNetwork is true, the peer of id will be disconnected.
+// return false if id isn`t trusted
+func (svr *Server) RemoveTrustedNode(id discovery.NodeID) bool {
+	if !svr.trusted.remove(id) {
+		return false
+	}
+
+	svr.log.Info(fmt.Sprintf("remove trusted node %s", id))
+
+	if svr.PrivateNetwork {
+		svr.disconnect(id, DiscNotAllowed)
+	}
+
+	return true
+}
+
+// TrustedNodes returns the urls of trusted nodes
+func (svr *Server) TrustedNodes() []string {
+	return svr.trusted.list()
+}
+
+// allowed returns false if PrivateNetwork is true and id isn`t trusted
+func (svr *Server) allowed(id discovery.NodeID) bool {
+	return !svr.PrivateNetwork || svr.trusted.has(id)
+}
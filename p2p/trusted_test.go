@@ -0,0 +1,156 @@
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/vitelabs/go-vite/crypto/ed25519"
+	"github.com/vitelabs/go-vite/p2p/discovery"
+	"github.com/vitelabs/go-vite/p2p/network"
+)
+
+func newTestNode(t *testing.T) *discovery.Node {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := discovery.Priv2NodeID(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &discovery.Node{ID: id, IP: net.IPv4(127, 0, 0, 1), UDP: 8483, TCP: 8483}
+}
+
+func TestTrustedSet_due(t *testing.T) {
+	node := newTestNode(t)
+	set := newTrustedSet([]*discovery.Node{node})
+
+	now := time.Now()
+	if nodes := set.due(now); len(nodes) != 1 || nodes[0].ID != node.ID {
+		t.Fatalf("the new trusted node should be dialed at once, got %v", nodes)
+	}
+
+	// backoff doubles when dialed
+	if nodes := set.due(now.Add(minTrustedBackoff - time.Millisecond)); len(nodes) != 0 {
+		t.Fatalf("the node should be dialed after the backoff, got %v", nodes)
+	}
+	if nodes := set.due(now.Add(minTrustedBackoff)); len(nodes) != 1 {
+		t.Fatal("the node should be dialed after the backoff")
+	}
+	if nodes := set.due(now.Add(2 * minTrustedBackoff)); len(nodes) != 0 {
+		t.Fatalf("the backoff should be doubled, got %v", nodes)
+	}
+
+	// the connected node isn`t dialed
+	now = now.Add(time.Hour)
+	set.connect(node.ID, now)
+	if nodes := set.due(now); len(nodes) != 0 {
+		t.Fatalf("the connected node should not be dialed, got %v", nodes)
+	}
+
+	// the node dropped us soon is redialed with the backoff
+	set.disconnect(node.ID, now.Add(time.Second))
+	if nodes := set.due(now.Add(time.Second)); len(nodes) != 1 {
+		t.Fatal("the disconnected node should be dialed")
+	}
+	set.connect(node.ID, now.Add(2*time.Second))
+	set.disconnect(node.ID, now.Add(3*time.Second))
+	if nodes := set.due(now.Add(3 * time.Second)); len(nodes) != 0 {
+		t.Fatalf("the node dropped us soon should be redialed with the backoff, got %v", nodes)
+	}
+
+	// the stable connection resets the backoff
+	now = now.Add(time.Hour)
+	set.connect(node.ID, now)
+	now = now.Add(maxTrustedBackoff)
+	set.disconnect(node.ID, now)
+	if nodes := set.due(now); len(nodes) != 1 {
+		t.Fatal("the node disconnected after a stable connection should be redialed at once")
+	}
+	if nodes := set.due(now.Add(minTrustedBackoff)); len(nodes) != 1 {
+		t.Fatal("the backoff should be reset")
+	}
+
+	// the removed node isn`t dialed
+	if !set.remove(node.ID) || set.has(node.ID) {
+		t.Fatal("the node should be removed")
+	}
+	if nodes := set.due(now.Add(time.Hour)); len(nodes) != 0 {
+		t.Fatalf("the removed node should not be dialed, got %v", nodes)
+	}
+}
+
+func TestTrustedSet_add(t *testing.T) {
+	node := newTestNode(t)
+	set := newTrustedSet(nil)
+
+	if !set.add(node) {
+		t.Fatal("the node should be added")
+	}
+
+	moved := *node
+	moved.TCP = node.TCP + 1
+	if set.add(&moved) {
+		t.Fatal("the trusted node should not be added again")
+	}
+	if nodes := set.due(time.Now()); len(nodes) != 1 || nodes[0].TCP != moved.TCP {
+		t.Fatal("the address of the trusted node should be updated")
+	}
+	if urls := set.list(); len(urls) != 1 || urls[0] != moved.String() {
+		t.Fatalf("should list %s, got %v", moved.String(), urls)
+	}
+}
+
+func TestServer_PrivateNetwork(t *testing.T) {
+	self := newTestNode(t)
+	trustedNode := newTestNode(t)
+	otherNode := newTestNode(t)
+
+	svr := &Server{
+		Config: &Config{
+			NetID:          network.Aquarius,
+			MaxPeers:       10,
+			PrivateNetwork: true,
+		},
+		peers:   NewPeerSet(),
+		bans:    newBanList(),
+		trusted: newTrustedSet([]*discovery.Node{trustedNode}),
+		self:    self,
+	}
+
+	if !svr.allowed(trustedNode.ID) || svr.checkConn(trustedNode.ID, outbound) != nil {
+		t.Error("the trusted node should be allowed")
+	}
+	if svr.allowed(otherNode.ID) || svr.checkConn(otherNode.ID, inbound) != DiscNotAllowed {
+		t.Error("the untrusted node should not be allowed in private network")
+	}
+	if svr.checkConn(otherNode.ID, static) != DiscNotAllowed {
+		t.Error("the untrusted static node should not be allowed in private network")
+	}
+
+	// the plaintext peers are rejected even if encryption isn`t required
+	if err := svr.checkHead(&headMsg{Version: secureVersion - 1, NetID: network.Aquarius}); err != errNotEncrypted {
+		t.Errorf("the plaintext connection should be rejected in private network, got %v", err)
+	}
+	if err := svr.checkHead(&headMsg{Version: secureVersion, NetID: network.Aquarius}); err != nil {
+		t.Errorf("the encrypted connection should be allowed in private network: %v", err)
+	}
+
+	// the trusted node is connected even if peers too many
+	svr.MaxPeers = 0
+	if err := svr.checkConn(trustedNode.ID, inbound); err != nil {
+		t.Errorf("the trusted node should be allowed even if peers too many: %v", err)
+	}
+
+	svr.PrivateNetwork = false
+	if !svr.allowed(otherNode.ID) {
+		t.Error("the untrusted node should be allowed in public network")
+	}
+	if err := svr.checkHead(&headMsg{Version: secureVersion - 1, NetID: network.Aquarius}); err != nil {
+		t.Errorf("the plaintext connection should be allowed in public network: %v", err)
+	}
+	if err := svr.checkConn(otherNode.ID, inbound); err != DiscTooManyPeers {
+		t.Errorf("the untrusted node should not be allowed if peers too many, got %v", err)
+	}
+}
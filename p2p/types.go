@@ -16,6 +16,7 @@ const (
 	outbound connFlag = 1 << iota
 	inbound
 	static
+	trusted
 )
 
 func (f connFlag) is(f2 connFlag) bool {
@@ -30,7 +30,8 @@ func getChainInstance() ch.Chain {
 
 			DataDir: filepath.Join(common.HomeDir(), "viteisbest"),
 			//Chain: &config.Chain{
-			//	KafkaProducers: []*config.KafkaProducer{{
+			//	EventSinks: []*config.EventSink{{
+			//		Type:       "kafka",
 			//		Topic:      "test",
 			//		BrokerList: []string{"abc", "def"},
 			//	}},
@@ -0,0 +1,323 @@
+package replay
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/generator"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+// logInterval is the number of the snapshot blocks between the progress logs
+const logInterval = 1000
+
+var replayLog = log15.New("module", "replay")
+
+type replayer struct {
+	source      Source
+	stateSource StateSource
+	target      chain.Chain
+	result      *Result
+}
+
+// Replay re-executes the account blocks of source through the generator and the vm into target, and compares the
+// hash, the state hash, the log hash and the balances of each replayed block with the recorded one. The recorded
+// blocks are inserted with the replayed states, and the snapshot blocks with the states recomputed from them. The
+// replay stops at the first mismatch, which is in the result.
+//
+// target must be started and have the same genesis as source, the snapshot blocks already in target are only
+// compared by hash, so a replay can be resumed. The snapshot blocks above toHeight aren't replayed, 0 means all.
+func Replay(source Source, target chain.Chain, toHeight uint64) (*Result, error) {
+	r := &replayer{
+		source: source,
+		target: target,
+		result: &Result{},
+	}
+	r.stateSource, _ = source.(StateSource)
+
+	latestHeight := target.GetLatestSnapshotBlock().Height
+	r.result.FromSnapshotHeight = latestHeight + 1
+
+	for {
+		snapshotBlock, subLedger, err := source.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return r.result, err
+		}
+		if toHeight > 0 && snapshotBlock.Height > toHeight {
+			break
+		}
+
+		if snapshotBlock.Height <= latestHeight {
+			targetBlock, err := target.GetSnapshotBlockByHeight(snapshotBlock.Height)
+			if err != nil {
+				return r.result, err
+			}
+			if targetBlock == nil || targetBlock.Hash != snapshotBlock.Hash {
+				r.mismatchSnapshot(snapshotBlock, "snapshot block is different from the one in the replayed ledger, the genesis may be different")
+				return r.result, nil
+			}
+			continue
+		}
+
+		if err := r.replaySubLedger(snapshotBlock.Height, subLedger); err != nil || r.result.Mismatch != nil {
+			return r.result, err
+		}
+		if err := r.replaySnapshotBlock(snapshotBlock); err != nil || r.result.Mismatch != nil {
+			return r.result, err
+		}
+
+		r.result.ToSnapshotHeight = snapshotBlock.Height
+		r.result.SnapshotBlocks++
+		if r.result.SnapshotBlocks%logInterval == 0 {
+			replayLog.Info(fmt.Sprintf("Replayed to snapshot height %d, %d account blocks", snapshotBlock.Height, r.result.AccountBlocks))
+		}
+	}
+	return r.result, nil
+}
+
+func (r *replayer) mismatchSnapshot(snapshotBlock *ledger.SnapshotBlock, message string, diffs ...*Diff) {
+	r.result.Mismatch = &Mismatch{
+		SnapshotHeight: snapshotBlock.Height,
+		Height:         snapshotBlock.Height,
+		Hash:           snapshotBlock.Hash,
+		Message:        message,
+		Diffs:          diffs,
+	}
+}
+
+func (r *replayer) mismatchAccount(snapshotHeight uint64, block *ledger.AccountBlock, message string, diffs ...*Diff) {
+	addr := block.AccountAddress
+	r.result.Mismatch = &Mismatch{
+		SnapshotHeight: snapshotHeight,
+		Address:        &addr,
+		Height:         block.Height,
+		Hash:           block.Hash,
+		Message:        message,
+		Diffs:          diffs,
+	}
+}
+
+// replaySubLedger replays the account blocks confirmed by a snapshot block, a receive block is replayed after its
+// send block.
+func (r *replayer) replaySubLedger(snapshotHeight uint64, subLedger map[types.Address][]*ledger.AccountBlock) error {
+	unreplayed := make(map[types.Hash]struct{})
+	addrList := make([]types.Address, 0, len(subLedger))
+	queues := make(map[types.Address][]*ledger.AccountBlock, len(subLedger))
+	for addr, blocks := range subLedger {
+		addrList = append(addrList, addr)
+		queues[addr] = blocks
+		for _, block := range blocks {
+			unreplayed[block.Hash] = struct{}{}
+		}
+	}
+	sort.Slice(addrList, func(i, j int) bool {
+		return bytes.Compare(addrList[i].Bytes(), addrList[j].Bytes()) < 0
+	})
+
+	for len(unreplayed) > 0 {
+		replayed := false
+		for _, addr := range addrList {
+			for len(queues[addr]) > 0 {
+				block := queues[addr][0]
+				if block.IsReceiveBlock() {
+					if _, ok := unreplayed[block.FromBlockHash]; ok {
+						break
+					}
+				}
+
+				count, err := r.replayAccountBlocks(snapshotHeight, queues[addr])
+				if err != nil || r.result.Mismatch != nil {
+					return err
+				}
+
+				for _, replayedBlock := range queues[addr][:count] {
+					delete(unreplayed, replayedBlock.Hash)
+				}
+				queues[addr] = queues[addr][count:]
+				replayed = true
+			}
+		}
+
+		if !replayed {
+			for _, addr := range addrList {
+				if len(queues[addr]) > 0 {
+					r.mismatchAccount(snapshotHeight, queues[addr][0], "send block of the receive block isn't replayed, the account blocks can't be ordered")
+					return nil
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// replayAccountBlocks replays the first block of blocks, the send blocks generated by a contract with it are compared
+// with the following blocks. It returns the number of the replayed blocks.
+func (r *replayer) replayAccountBlocks(snapshotHeight uint64, blocks []*ledger.AccountBlock) (int, error) {
+	block := blocks[0]
+
+	var prevHash *types.Hash
+	if block.Height > 1 {
+		prevHash = &block.PrevHash
+	}
+	gen, err := generator.NewGenerator(r.target, &block.SnapshotHash, prevHash, &block.AccountAddress)
+	if err != nil {
+		r.mismatchAccount(snapshotHeight, block, "NewGenerator failed, error is "+err.Error())
+		return 0, nil
+	}
+
+	genResult, err := gen.GenerateWithBlock(block, nil)
+	if err != nil {
+		r.mismatchAccount(snapshotHeight, block, "GenerateWithBlock failed, error is "+err.Error())
+		return 0, nil
+	}
+	if len(genResult.BlockGenList) <= 0 {
+		message := "vm failed, blockList is empty"
+		if genResult.Err != nil {
+			message = "vm failed, error is " + genResult.Err.Error()
+		}
+		r.mismatchAccount(snapshotHeight, block, message)
+		return 0, nil
+	}
+
+	genBlocks := genResult.BlockGenList
+	if len(genBlocks) > len(blocks) {
+		r.mismatchAccount(snapshotHeight, block, fmt.Sprintf("vm generated %d blocks, only %d blocks are confirmed", len(genBlocks), len(blocks)))
+		return 0, nil
+	}
+
+	for i, genBlock := range genBlocks {
+		if diffs := diffBlock(blocks[i], genBlock.AccountBlock); len(diffs) > 0 {
+			r.mismatchAccount(snapshotHeight, blocks[i], "replayed block is different", diffs...)
+			return 0, nil
+		}
+
+		if r.stateSource == nil {
+			continue
+		}
+		expected, ok := r.stateSource.Balances(blocks[i])
+		if !ok {
+			r.result.SkippedBalances++
+			continue
+		}
+		if diffs := diffBalances(expected, balances(genBlock.VmContext.UnsavedCache().Trie())); len(diffs) > 0 {
+			r.mismatchAccount(snapshotHeight, blocks[i], "replayed balances are different", diffs...)
+			return 0, nil
+		}
+		r.result.CheckedBalances++
+	}
+
+	// the recorded blocks are inserted, they have the signatures
+	for i, genBlock := range genBlocks {
+		genBlock.AccountBlock = blocks[i]
+	}
+	if err := r.target.InsertAccountBlocks(genBlocks); err != nil {
+		return 0, err
+	}
+
+	r.result.AccountBlocks += uint64(len(genBlocks))
+	return len(genBlocks), nil
+}
+
+func (r *replayer) replaySnapshotBlock(snapshotBlock *ledger.SnapshotBlock) error {
+	prevBlock := r.target.GetLatestSnapshotBlock()
+	if snapshotBlock.PrevHash != prevBlock.Hash {
+		r.mismatchSnapshot(snapshotBlock, "snapshot block doesn't follow the latest replayed one",
+			newDiff("PrevHash", snapshotBlock.PrevHash, prevBlock.Hash))
+		return nil
+	}
+
+	stateTrie, err := r.target.GenStateTrie(prevBlock.StateHash, snapshotBlock.SnapshotContent)
+	if err != nil {
+		return err
+	}
+	if *stateTrie.Hash() != snapshotBlock.StateHash {
+		r.mismatchSnapshot(snapshotBlock, "replayed snapshot state is different",
+			newDiff("StateHash", snapshotBlock.StateHash, *stateTrie.Hash()))
+		return nil
+	}
+
+	snapshotBlock.StateTrie = stateTrie
+	return r.target.InsertSnapshotBlock(snapshotBlock)
+}
+
+func newDiff(field string, expected, actual interface{}) *Diff {
+	return &Diff{
+		Field:    field,
+		Expected: fmt.Sprint(expected),
+		Actual:   fmt.Sprint(actual),
+	}
+}
+
+func formatHashPtr(hash *types.Hash) string {
+	if hash == nil {
+		return ""
+	}
+	return hash.String()
+}
+
+func formatBigInt(n *big.Int) string {
+	if n == nil {
+		return "0"
+	}
+	return n.String()
+}
+
+// diffBlock compares the replayed block with the recorded one, the hash covers the fields which aren't compared.
+func diffBlock(expected, actual *ledger.AccountBlock) []*Diff {
+	var diffs []*Diff
+	if expected.BlockType != actual.BlockType {
+		diffs = append(diffs, newDiff("BlockType", expected.BlockType, actual.BlockType))
+	}
+	if expected.Height != actual.Height {
+		diffs = append(diffs, newDiff("Height", expected.Height, actual.Height))
+	}
+	if expected.StateHash != actual.StateHash {
+		diffs = append(diffs, newDiff("StateHash", expected.StateHash, actual.StateHash))
+	}
+	if formatHashPtr(expected.LogHash) != formatHashPtr(actual.LogHash) {
+		diffs = append(diffs, newDiff("LogHash", formatHashPtr(expected.LogHash), formatHashPtr(actual.LogHash)))
+	}
+	if expected.Quota != actual.Quota {
+		diffs = append(diffs, newDiff("Quota", expected.Quota, actual.Quota))
+	}
+	if formatBigInt(expected.Amount) != formatBigInt(actual.Amount) {
+		diffs = append(diffs, newDiff("Amount", formatBigInt(expected.Amount), formatBigInt(actual.Amount)))
+	}
+	if formatBigInt(expected.Fee) != formatBigInt(actual.Fee) {
+		diffs = append(diffs, newDiff("Fee", formatBigInt(expected.Fee), formatBigInt(actual.Fee)))
+	}
+	if !bytes.Equal(expected.Data, actual.Data) {
+		diffs = append(diffs, newDiff("Data", fmt.Sprintf("%x", expected.Data), fmt.Sprintf("%x", actual.Data)))
+	}
+	if expected.Hash != actual.Hash {
+		diffs = append(diffs, newDiff("Hash", expected.Hash, actual.Hash))
+	}
+	return diffs
+}
+
+func diffBalances(expected, actual map[types.TokenTypeId]*big.Int) []*Diff {
+	var diffs []*Diff
+	for tokenId, balance := range expected {
+		if actualBalance := actual[tokenId]; actualBalance == nil || actualBalance.Cmp(balance) != 0 {
+			diffs = append(diffs, newDiff("Balance "+tokenId.String(), balance, formatBigInt(actualBalance)))
+		}
+	}
+	for tokenId, balance := range actual {
+		if _, ok := expected[tokenId]; !ok {
+			diffs = append(diffs, newDiff("Balance "+tokenId.String(), 0, balance))
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Field < diffs[j].Field
+	})
+	return diffs
+}
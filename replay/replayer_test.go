@@ -0,0 +1,160 @@
+package replay
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/config"
+	"github.com/vitelabs/go-vite/generator"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/vm"
+)
+
+func newTestChain(t *testing.T, dataDir string) chain.Chain {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	c := chain.NewChain(&config.Config{DataDir: dataDir, Chain: &config.Chain{LedgerInMemory: true}})
+	c.Init()
+	c.Start()
+	return c
+}
+
+func insertSnapshotBlock(t *testing.T, c chain.Chain) {
+	latestBlock := c.GetLatestSnapshotBlock()
+	now := time.Now()
+	snapshotBlock := &ledger.SnapshotBlock{
+		Height:          latestBlock.Height + 1,
+		PrevHash:        latestBlock.Hash,
+		Timestamp:       &now,
+		SnapshotContent: c.GetNeedSnapshotContent(),
+	}
+
+	stateTrie, err := c.GenStateTrie(latestBlock.StateHash, snapshotBlock.SnapshotContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshotBlock.StateTrie = stateTrie
+	snapshotBlock.StateHash = *stateTrie.Hash()
+	snapshotBlock.Hash = snapshotBlock.ComputeHash()
+
+	if err := c.InsertSnapshotBlock(snapshotBlock); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func insertGenResult(t *testing.T, c chain.Chain, genResult *generator.GenResult, err error) {
+	if err != nil {
+		t.Fatal(err)
+	}
+	if genResult.Err != nil || len(genResult.BlockGenList) <= 0 {
+		t.Fatalf("generate block failed, %v", genResult.Err)
+	}
+	if err := c.InsertAccountBlocks(genResult.BlockGenList); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tamperedSource changes the state hash of the block of hash.
+type tamperedSource struct {
+	Source
+	hash types.Hash
+}
+
+func (s *tamperedSource) Next() (*ledger.SnapshotBlock, map[types.Address][]*ledger.AccountBlock, error) {
+	snapshotBlock, subLedger, err := s.Source.Next()
+	for _, blocks := range subLedger {
+		for i, block := range blocks {
+			if block.Hash == s.hash {
+				blocks[i] = block.Copy()
+				blocks[i].StateHash = types.Hash{}
+			}
+		}
+	}
+	return snapshotBlock, subLedger, err
+}
+
+func TestReplay(t *testing.T) {
+	vm.InitVmConfig(true, false)
+
+	dataDir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+
+	source := newTestChain(t, filepath.Join(dataDir, "source"))
+	defer func() {
+		source.Stop()
+		source.Destroy()
+	}()
+
+	// receive the vite of the genesis, then send some to another account
+	genesisAddr := chain.GenesisMintageSendBlock.ToAddress
+	gen, err := generator.NewGenerator(source, nil, nil, &genesisAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genResult, err := gen.GenerateWithOnroad(chain.GenesisMintageSendBlock, nil, nil, nil)
+	insertGenResult(t, source, genResult, err)
+	insertSnapshotBlock(t, source)
+
+	addr, _, _ := types.CreateAddress()
+	gen, err = generator.NewGenerator(source, nil, nil, &genesisAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genResult, err = gen.GenerateWithMessage(&generator.IncomingMessage{
+		BlockType:      ledger.BlockTypeSendCall,
+		AccountAddress: genesisAddr,
+		ToAddress:      &addr,
+		TokenId:        &ledger.ViteTokenId,
+		Amount:         big.NewInt(100),
+	}, nil)
+	insertGenResult(t, source, genResult, err)
+	sendHash := genResult.BlockGenList[0].AccountBlock.Hash
+	insertSnapshotBlock(t, source)
+
+	chainSource, err := NewChainSource(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := newTestChain(t, filepath.Join(dataDir, "target"))
+	result, err := Replay(chainSource, target, 0)
+	target.Stop()
+	target.Destroy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Consistent() {
+		result.Write(os.Stderr)
+		t.Fatal("replayed ledger should match the source")
+	}
+	if result.FromSnapshotHeight != 3 || result.ToSnapshotHeight != 4 || result.AccountBlocks != 2 || result.CheckedBalances != 2 {
+		t.Fatalf("result is %+v", result)
+	}
+
+	chainSource, err = NewChainSource(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target = newTestChain(t, filepath.Join(dataDir, "tampered"))
+	result, err = Replay(&tamperedSource{Source: chainSource, hash: sendHash}, target, 0)
+	target.Stop()
+	target.Destroy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mismatch := result.Mismatch
+	if mismatch == nil || mismatch.SnapshotHeight != 4 || mismatch.Hash != sendHash ||
+		len(mismatch.Diffs) != 1 || mismatch.Diffs[0].Field != "StateHash" {
+		result.Write(os.Stderr)
+		t.Fatal("state hash of the send block should mismatch")
+	}
+}
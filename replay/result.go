@@ -0,0 +1,57 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/vitelabs/go-vite/common/types"
+)
+
+// Diff is a field of which the replayed value differs from the recorded one.
+type Diff struct {
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// Mismatch is the first block of which the replayed result differs from the source.
+type Mismatch struct {
+	SnapshotHeight uint64 `json:"snapshotHeight"`
+
+	// Address is nil if the mismatched block is the snapshot block
+	Address *types.Address `json:"address,omitempty"`
+	Height  uint64         `json:"height"`
+	Hash    types.Hash     `json:"hash"`
+
+	Message string  `json:"message"`
+	Diffs   []*Diff `json:"diffs,omitempty"`
+}
+
+type Result struct {
+	FromSnapshotHeight uint64 `json:"fromSnapshotHeight"`
+	ToSnapshotHeight   uint64 `json:"toSnapshotHeight"`
+
+	SnapshotBlocks uint64 `json:"snapshotBlocks"`
+	AccountBlocks  uint64 `json:"accountBlocks"`
+
+	// The balances can't be compared if the source has no state, or the state has been pruned
+	CheckedBalances uint64 `json:"checkedBalances"`
+	SkippedBalances uint64 `json:"skippedBalances"`
+
+	Mismatch *Mismatch `json:"mismatch,omitempty"`
+}
+
+// Consistent reports whether all the replayed blocks match the source.
+func (r *Result) Consistent() bool {
+	return r.Mismatch == nil
+}
+
+func (r *Result) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
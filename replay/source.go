@@ -0,0 +1,266 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/compress"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/trie"
+	"github.com/vitelabs/go-vite/vm_context"
+)
+
+// Source provides the blocks of the ledger to replay, snapshot block by snapshot block from the genesis.
+type Source interface {
+	// Next returns the next snapshot block and the account blocks confirmed by it in the order of height, io.EOF is
+	// returned after the last snapshot block.
+	Next() (*ledger.SnapshotBlock, map[types.Address][]*ledger.AccountBlock, error)
+}
+
+// StateSource is a Source which has the states of the account blocks, the replayed balances are compared with them.
+type StateSource interface {
+	Source
+
+	// Balances returns the balances of the account of block after block, false if the state isn't available.
+	Balances(block *ledger.AccountBlock) (map[types.TokenTypeId]*big.Int, bool)
+}
+
+// balances reads the non zero balances of the account state t.
+func balances(t *trie.Trie) map[types.TokenTypeId]*big.Int {
+	result := make(map[types.TokenTypeId]*big.Int)
+
+	prefix := vm_context.STORAGE_KEY_BALANCE
+	iter := t.NewIterator(prefix)
+	for {
+		key, value, ok := iter.Next()
+		if !ok {
+			break
+		}
+
+		tokenId, err := types.BytesToTokenTypeId(key[len(prefix):])
+		if err != nil {
+			continue
+		}
+		if balance := new(big.Int).SetBytes(value); balance.Sign() > 0 {
+			result[tokenId] = balance
+		}
+	}
+	return result
+}
+
+type chainSource struct {
+	chain chain.Chain
+
+	height       uint64
+	latestHeight uint64
+
+	// the height of the latest confirmed account block of each account
+	confirmedHeights map[types.Address]uint64
+}
+
+// NewChainSource returns the source of the blocks in the db of c, the blocks of the snapshot blocks which exist when
+// it's created are provided. c doesn't need to be started, it's only read.
+func NewChainSource(c chain.Chain) (StateSource, error) {
+	latestBlock, err := c.ChainDb().Sc.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+	if latestBlock == nil {
+		return nil, errors.New("ledger is empty")
+	}
+
+	return &chainSource{
+		chain:            c,
+		latestHeight:     latestBlock.Height,
+		confirmedHeights: make(map[types.Address]uint64),
+	}, nil
+}
+
+func (s *chainSource) Next() (*ledger.SnapshotBlock, map[types.Address][]*ledger.AccountBlock, error) {
+	if s.height >= s.latestHeight {
+		return nil, nil, io.EOF
+	}
+	s.height++
+
+	snapshotBlock, err := s.chain.GetSnapshotBlockByHeight(s.height)
+	if err != nil {
+		return nil, nil, err
+	}
+	if snapshotBlock == nil {
+		return nil, nil, fmt.Errorf("snapshot block %d doesn't exist, the ledger imported from a ledger snapshot can't be replayed", s.height)
+	}
+
+	subLedger := make(map[types.Address][]*ledger.AccountBlock)
+	for addr, hashHeight := range snapshotBlock.SnapshotContent {
+		startHeight := s.confirmedHeights[addr] + 1
+		if hashHeight.Height < startHeight {
+			continue
+		}
+
+		count := hashHeight.Height - startHeight + 1
+		blocks, err := s.chain.GetAccountBlocksByHeight(addr, startHeight, count, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		if uint64(len(blocks)) != count {
+			return nil, nil, fmt.Errorf("account blocks of %s from height %d to %d confirmed by snapshot block %d are missing",
+				addr, startHeight, hashHeight.Height, s.height)
+		}
+
+		subLedger[addr] = blocks
+		s.confirmedHeights[addr] = hashHeight.Height
+	}
+	return snapshotBlock, subLedger, nil
+}
+
+func (s *chainSource) Balances(block *ledger.AccountBlock) (map[types.TokenTypeId]*big.Int, bool) {
+	// the state may be pruned
+	key, _ := database.EncodeKey(database.DBKP_TRIE_NODE, block.StateHash.Bytes())
+	if ok, err := s.chain.ChainDb().Db().Has(key); err != nil || !ok {
+		return nil, false
+	}
+	return balances(s.chain.GetStateTrie(&block.StateHash)), true
+}
+
+type fileSource struct {
+	dir   string
+	metas []*ledger.CompressedFileMeta
+
+	height         uint64
+	snapshotBlocks []*ledger.SnapshotBlock
+
+	// the account blocks read from the files which aren't confirmed yet, in the order of height
+	pending          map[types.Address][]*ledger.AccountBlock
+	confirmedHeights map[types.Address]uint64
+}
+
+// NewFileSource returns the source of the blocks in the ledger files of the compressor in dir, the files are
+// verified by the hashes in the index when they are read.
+func NewFileSource(dir string) (Source, error) {
+	if _, err := os.Stat(filepath.Join(dir, "index")); err != nil {
+		return nil, fmt.Errorf("%s doesn't have the index of the ledger files: %v", dir, err)
+	}
+
+	indexer := compress.NewIndexer(dir)
+	metas := indexer.List()
+	indexer.Clear()
+
+	if len(metas) <= 0 {
+		return nil, fmt.Errorf("%s doesn't have any ledger file", dir)
+	}
+
+	return &fileSource{
+		dir:              dir,
+		metas:            metas,
+		pending:          make(map[types.Address][]*ledger.AccountBlock),
+		confirmedHeights: make(map[types.Address]uint64),
+	}, nil
+}
+
+func (s *fileSource) readFile(meta *ledger.CompressedFileMeta) error {
+	file, err := os.Open(filepath.Join(s.dir, meta.Filename))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var blockErr error
+	if err := compress.ParseFile(file, meta, s.dir, func(block ledger.Block, err error) {
+		if err != nil {
+			if blockErr == nil {
+				blockErr = err
+			}
+			return
+		}
+
+		switch block := block.(type) {
+		case *ledger.SnapshotBlock:
+			s.snapshotBlocks = append(s.snapshotBlocks, block)
+		case *ledger.AccountBlock:
+			s.addPending(block)
+		}
+	}); err != nil {
+		return fmt.Errorf("parse %s failed: %v", meta.Filename, err)
+	}
+	if blockErr != nil {
+		return fmt.Errorf("parse block of %s failed: %v", meta.Filename, blockErr)
+	}
+
+	sort.Slice(s.snapshotBlocks, func(i, j int) bool {
+		return s.snapshotBlocks[i].Height < s.snapshotBlocks[j].Height
+	})
+	return nil
+}
+
+// addPending keeps the account block until it's confirmed, the blocks written into several files are kept once.
+func (s *fileSource) addPending(block *ledger.AccountBlock) {
+	if block.Height <= s.confirmedHeights[block.AccountAddress] {
+		return
+	}
+
+	blocks := s.pending[block.AccountAddress]
+	i := sort.Search(len(blocks), func(i int) bool {
+		return blocks[i].Height >= block.Height
+	})
+	if i < len(blocks) && blocks[i].Height == block.Height {
+		return
+	}
+
+	blocks = append(blocks, nil)
+	copy(blocks[i+1:], blocks[i:])
+	blocks[i] = block
+	s.pending[block.AccountAddress] = blocks
+}
+
+func (s *fileSource) Next() (*ledger.SnapshotBlock, map[types.Address][]*ledger.AccountBlock, error) {
+	for len(s.snapshotBlocks) <= 0 {
+		if len(s.metas) <= 0 {
+			return nil, nil, io.EOF
+		}
+		if err := s.readFile(s.metas[0]); err != nil {
+			return nil, nil, err
+		}
+		s.metas = s.metas[1:]
+	}
+
+	snapshotBlock := s.snapshotBlocks[0]
+	s.snapshotBlocks = s.snapshotBlocks[1:]
+	if snapshotBlock.Height != s.height+1 {
+		return nil, nil, fmt.Errorf("snapshot block %d is missing in the ledger files", s.height+1)
+	}
+	s.height = snapshotBlock.Height
+
+	subLedger := make(map[types.Address][]*ledger.AccountBlock)
+	for addr, hashHeight := range snapshotBlock.SnapshotContent {
+		startHeight := s.confirmedHeights[addr] + 1
+		if hashHeight.Height < startHeight {
+			continue
+		}
+
+		blocks := s.pending[addr]
+		count := 0
+		for count < len(blocks) && blocks[count].Height <= hashHeight.Height {
+			if blocks[count].Height != startHeight+uint64(count) {
+				break
+			}
+			count++
+		}
+		if count <= 0 || blocks[count-1].Height != hashHeight.Height || blocks[count-1].Hash != hashHeight.Hash {
+			return nil, nil, fmt.Errorf("account blocks of %s from height %d to %d confirmed by snapshot block %d are missing in the ledger files",
+				addr, startHeight, hashHeight.Height, s.height)
+		}
+
+		subLedger[addr] = blocks[:count]
+		s.pending[addr] = blocks[count:]
+		s.confirmedHeights[addr] = hashHeight.Height
+	}
+	return snapshotBlock, subLedger, nil
+}
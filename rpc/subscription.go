@@ -37,7 +37,8 @@ type ID string
 type Subscription struct {
 	ID        ID
 	namespace string
-	err       chan error // closed on unsubscribe
+	err       chan error    // closed on unsubscribe
+	activated chan struct{} // closed on activate
 }
 
 // Err returns a channel that is closed when the client send an unsubscribe request.
@@ -45,6 +46,12 @@ func (s *Subscription) Err() <-chan error {
 	return s.err
 }
 
+// Activated returns a channel that is closed when the subscription is activated, the notifications sent before
+// are dropped.
+func (s *Subscription) Activated() <-chan struct{} {
+	return s.activated
+}
+
 // notifierKey is used to store a notifier within the connection context.
 type notifierKey struct{}
 
@@ -78,7 +85,7 @@ func NotifierFromContext(ctx context.Context) (*Notifier, bool) {
 // are dropped until the subscription is marked as active. This is done
 // by the RPC server after the subscription ID is send to the client.
 func (n *Notifier) CreateSubscription() *Subscription {
-	s := &Subscription{ID: NewID(), err: make(chan error)}
+	s := &Subscription{ID: NewID(), err: make(chan error), activated: make(chan struct{})}
 	n.subMu.Lock()
 	n.inactive[s.ID] = s
 	n.subMu.Unlock()
@@ -131,5 +138,6 @@ func (n *Notifier) activate(id ID, namespace string) {
 		sub.namespace = namespace
 		n.active[id] = sub
 		delete(n.inactive, id)
+		close(sub.activated)
 	}
 }
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/p2p"
+	"github.com/vitelabs/go-vite/p2p/discovery"
+	"github.com/vitelabs/go-vite/vite"
+	"github.com/vitelabs/go-vite/vite/net"
+	"time"
+)
+
+// AdminApi manages the p2p server at runtime, it should only be exposed by IPC
+type AdminApi struct {
+	p2p *p2p.Server
+	net net.Net
+	log log15.Logger
+}
+
+func NewAdminApi(vite *vite.Vite) *AdminApi {
+	return &AdminApi{
+		p2p: vite.P2P(),
+		net: vite.Net(),
+		log: log15.New("module", "rpc_api/admin_api"),
+	}
+}
+
+// AddTrustedPeer trusts the node of url "vnode://id@ip:port", it can be connected even if peers too many,
+// and will be redialed forever
+func (a *AdminApi) AddTrustedPeer(url string) error {
+	return a.p2p.AddTrustedNode(url)
+}
+
+// RemoveTrustedPeer untrusts the node of id, return false if it isn`t trusted
+func (a *AdminApi) RemoveTrustedPeer(id string) (bool, error) {
+	nodeId, err := discovery.HexStr2NodeID(id)
+	if err != nil {
+		return false, err
+	}
+
+	return a.p2p.RemoveTrustedNode(nodeId), nil
+}
+
+func (a *AdminApi) TrustedPeers() []string {
+	return a.p2p.TrustedNodes()
+}
+
+// BanPeer disconnects the peer and refuses its connections for seconds
+func (a *AdminApi) BanPeer(id string, seconds int64) error {
+	return a.net.Ban(id, time.Duration(seconds)*time.Second)
+}
+
+// UnbanPeer removes the ban of the peer, return false if the peer isn`t banned
+func (a *AdminApi) UnbanPeer(id string) (bool, error) {
+	return a.net.Unban(id)
+}
+
+func (a *AdminApi) ListBans() []*p2p.BanInfo {
+	return a.net.Bans()
+}
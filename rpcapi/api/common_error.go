@@ -4,4 +4,14 @@ import "errors"
 
 var (
 	ErrStrToBigInt = errors.New("convert to big.Int failed")
+
+	ErrTransferDirection = errors.New("direction should be in or out")
+	ErrTransferCursor    = errors.New("invalid cursor")
+
+	ErrEmptyContractCode  = errors.New("contract code is empty")
+	ErrAbiRequired        = errors.New("abi is required to pack constructor params")
+	ErrNotContractAddress = errors.New("address is not a contract address")
+
+	ErrStateNotFound = errors.New("state of the snapshot block not found, it may have been pruned")
+	ErrStorageCount  = errors.New("count should be greater than 0")
 )
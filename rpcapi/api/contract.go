@@ -1,10 +1,18 @@
 package api
 
 import (
+	"encoding/hex"
+	"math/big"
+	"strconv"
+	"strings"
+
 	"github.com/vitelabs/go-vite/chain"
 	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/generator"
+	"github.com/vitelabs/go-vite/ledger"
 	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/vite"
+	"github.com/vitelabs/go-vite/vm/abi"
 	"github.com/vitelabs/go-vite/vm/contracts"
 )
 
@@ -27,3 +35,105 @@ func (c ContractApi) String() string {
 func (c *ContractApi) GetCreateContractToAddress(selfAddr types.Address, height uint64, prevHash types.Hash, snapshotHash types.Hash) types.Address {
 	return contracts.NewContractAddress(selfAddr, height, prevHash, snapshotHash)
 }
+
+// GetCreateContractData returns the data of a create block, the gid followed by the bytecode and the constructor
+// params packed according to abiStr.
+func (c *ContractApi) GetCreateContractData(gid types.Gid, hexCode string, abiStr string, params []string) ([]byte, error) {
+	return getCreateContractData(gid, hexCode, abiStr, params)
+}
+
+func getCreateContractData(gid types.Gid, hexCode string, abiStr string, params []string) ([]byte, error) {
+	code, err := hex.DecodeString(strings.TrimPrefix(hexCode, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	if len(code) == 0 {
+		return nil, ErrEmptyContractCode
+	}
+	if len(abiStr) > 0 {
+		abiContract, err := abi.JSONToABIContract(strings.NewReader(abiStr))
+		if err != nil {
+			return nil, err
+		}
+		args, err := abiContract.Constructor.Inputs.ConvertStringParams(params)
+		if err != nil {
+			return nil, err
+		}
+		constructorData, err := abiContract.PackMethod("", args...)
+		if err != nil {
+			return nil, err
+		}
+		code = append(code, constructorData...)
+	} else if len(params) > 0 {
+		return nil, ErrAbiRequired
+	}
+	return contracts.GetCreateContractData(code, gid), nil
+}
+
+type CallParam struct {
+	SelfAddr    types.Address     `json:"selfAddr"`
+	ToAddr      types.Address     `json:"toAddr"`
+	TokenTypeId types.TokenTypeId `json:"tokenTypeId"`
+	Amount      *string           `json:"amount"`
+	Data        []byte            `json:"data"` //base64
+	Difficulty  *string           `json:"difficulty,omitempty"`
+}
+
+type CallResult struct {
+	SendQuota    string           `json:"sendQuota"`
+	ReceiveQuota string           `json:"receiveQuota"`
+	ReturnData   []byte           `json:"returnData"`
+	Logs         ledger.VmLogList `json:"logs"`
+	Error        string           `json:"error,omitempty"`
+}
+
+// Call simulates a send call block and, if the to address is a contract, its receive block on the latest
+// snapshot block. Nothing is written to the pool or chain.
+func (c *ContractApi) Call(param CallParam) (*CallResult, error) {
+	result, err := simulateCall(c.chain, param)
+	if err != nil {
+		return nil, err
+	}
+	return &CallResult{
+		SendQuota:    strconv.FormatUint(result.SendQuota(), 10),
+		ReceiveQuota: strconv.FormatUint(result.ReceiveQuota(), 10),
+		ReturnData:   result.ReturnData,
+		Logs:         result.Logs,
+		Error:        simulateErrorString(result.Err),
+	}, nil
+}
+
+func simulateCall(chain chain.Chain, param CallParam) (*generator.SimulateResult, error) {
+	amount := big.NewInt(0)
+	if param.Amount != nil {
+		var ok bool
+		if amount, ok = new(big.Int).SetString(*param.Amount, 10); !ok {
+			return nil, ErrStrToBigInt
+		}
+	}
+	var difficulty *big.Int
+	if param.Difficulty != nil {
+		var ok bool
+		if difficulty, ok = new(big.Int).SetString(*param.Difficulty, 10); !ok {
+			return nil, ErrStrToBigInt
+		}
+	}
+
+	return generator.Simulate(chain, &generator.IncomingMessage{
+		BlockType:      ledger.BlockTypeSendCall,
+		AccountAddress: param.SelfAddr,
+		ToAddress:      &param.ToAddr,
+		TokenId:        &param.TokenTypeId,
+		Amount:         amount,
+		Data:           param.Data,
+		Difficulty:     difficulty,
+	})
+}
+
+func simulateErrorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	newerr, _ := TryMakeConcernedError(err)
+	return newerr.Error()
+}
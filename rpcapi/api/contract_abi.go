@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"strconv"
+
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/vite"
+	"github.com/vitelabs/go-vite/vm/contracts/abi"
+)
+
+type RpcDecodedArg struct {
+	Name    string      `json:"name"`
+	Type    string      `json:"type"`
+	Indexed bool        `json:"indexed,omitempty"`
+	Value   interface{} `json:"value"`
+}
+
+type RpcDecodedMethod struct {
+	Name string           `json:"name"`
+	Args []*RpcDecodedArg `json:"args"`
+}
+
+// RpcDecodedEvent is a decoded vm log of a block, Error is set and Name is empty if the log can't be decoded.
+type RpcDecodedEvent struct {
+	Name  string           `json:"name,omitempty"`
+	Args  []*RpcDecodedArg `json:"args,omitempty"`
+	Error string           `json:"error,omitempty"`
+}
+
+// PrivateContractApi is served on IPC only. Abis aren't verified against the code of contracts, so a
+// registered abi is trusted by the node operator only.
+type PrivateContractApi struct {
+	chain chain.Chain
+	log   log15.Logger
+}
+
+func NewPrivateContractApi(vite *vite.Vite) *PrivateContractApi {
+	return &PrivateContractApi{
+		chain: vite.Chain(),
+		log:   log15.New("module", "rpc_api/private_contract_api"),
+	}
+}
+
+func (c PrivateContractApi) String() string {
+	return "PrivateContractApi"
+}
+
+// RegisterAbi saves the json abi of a user contract, it is used to decode the blocks and logs of the contract.
+func (c *PrivateContractApi) RegisterAbi(addr types.Address, abiStr string) error {
+	accountType, err := c.chain.AccountType(&addr)
+	if err != nil {
+		return err
+	}
+	if accountType != ledger.AccountTypeContract {
+		return ErrNotContractAddress
+	}
+	if err := abi.NewRegistry(c.chain).Register(addr, abiStr); err != nil {
+		c.log.Error("Register failed, error is "+err.Error(), "method", "RegisterAbi")
+		return err
+	}
+	return nil
+}
+
+// GetAbi returns the json abi registered for a user contract, or an empty string if none is registered.
+func (c *ContractApi) GetAbi(addr types.Address) (string, error) {
+	abiJson, err := c.chain.GetContractAbi(&addr)
+	if err != nil {
+		return "", err
+	}
+	return string(abiJson), nil
+}
+
+// decodeRpcBlock decodes the data of a send call block with the abi of its to address and the vm logs of a
+// block with the abi of its account address. Blocks without a known abi are left undecoded.
+func decodeRpcBlock(rpcBlock *AccountBlock, c chain.Chain) error {
+	block := rpcBlock.AccountBlock
+	registry := abi.NewRegistry(c)
+
+	if block.BlockType == ledger.BlockTypeSendCall && len(block.Data) > 0 {
+		abiContract, err := registry.Get(block.ToAddress)
+		if err != nil {
+			return err
+		}
+		if abiContract != nil {
+			if method, err := abi.DecodeMethod(abiContract, block.Data); err == nil {
+				rpcBlock.DecodedData = &RpcDecodedMethod{Name: method.Name, Args: toRpcDecodedArgs(method.Args)}
+			}
+		}
+	}
+
+	if block.LogHash == nil {
+		return nil
+	}
+	logList, err := c.GetVmLogList(block.LogHash)
+	if err != nil || len(logList) == 0 {
+		return err
+	}
+	abiContract, err := registry.Get(block.AccountAddress)
+	if err != nil || abiContract == nil {
+		return err
+	}
+	rpcBlock.DecodedLogs = make([]*RpcDecodedEvent, len(logList))
+	for i, log := range logList {
+		event, err := abi.DecodeLog(abiContract, log)
+		if err != nil {
+			rpcBlock.DecodedLogs[i] = &RpcDecodedEvent{Error: err.Error()}
+			continue
+		}
+		rpcBlock.DecodedLogs[i] = &RpcDecodedEvent{Name: event.Name, Args: toRpcDecodedArgs(event.Args)}
+	}
+	return nil
+}
+
+func toRpcDecodedArgs(args []*abi.DecodedArg) []*RpcDecodedArg {
+	rpcArgs := make([]*RpcDecodedArg, len(args))
+	for i, arg := range args {
+		rpcArgs[i] = &RpcDecodedArg{
+			Name:    arg.Name,
+			Type:    arg.Type,
+			Indexed: arg.Indexed,
+			Value:   toRpcAbiValue(arg.Value),
+		}
+	}
+	return rpcArgs
+}
+
+// toRpcAbiValue formats numbers as decimal strings and bytes as hex strings, addresses, gids, token ids and
+// hashes keep their own text forms.
+func toRpcAbiValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *big.Int:
+		return v.String()
+	case []byte:
+		return hex.EncodeToString(v)
+	case types.Address, types.Gid, types.TokenTypeId, types.Hash, string, bool:
+		return v
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return hex.EncodeToString(b)
+		}
+		fallthrough
+	case reflect.Slice:
+		list := make([]interface{}, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			list[i] = toRpcAbiValue(rv.Index(i).Interface())
+		}
+		return list
+	}
+	return value
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/config"
+	"github.com/vitelabs/go-vite/crypto/ed25519"
+	"github.com/vitelabs/go-vite/generator"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/vm"
+	"github.com/vitelabs/go-vite/vm/contracts/abi"
+)
+
+// newCallChain returns a chain in memory, on which the genesis account has received the genesis vite
+func newCallChain(t *testing.T) (chain.Chain, func()) {
+	dir, err := ioutil.TempDir("", "call")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := chain.NewChain(&config.Config{DataDir: dir, Chain: &config.Chain{LedgerInMemory: true}})
+	c.Init()
+	c.Start()
+	closeChain := func() {
+		c.Stop()
+		c.Destroy()
+		os.RemoveAll(dir)
+	}
+
+	gen, err := generator.NewGenerator(c, nil, nil, &ledger.GenesisAccountAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey, privKey, _ := ed25519.GenerateKey(nil)
+	genResult, err := gen.GenerateWithMessage(&generator.IncomingMessage{
+		BlockType:      ledger.BlockTypeReceive,
+		AccountAddress: ledger.GenesisAccountAddress,
+		FromBlockHash:  &chain.GenesisMintageSendBlock.Hash,
+	}, func(addr types.Address, data []byte) (signedData, pubkey []byte, err error) {
+		return ed25519.Sign(privKey, data), pubKey, nil
+	})
+	if err != nil || genResult.Err != nil {
+		t.Fatal(err, genResult.Err)
+	}
+	if err := c.InsertAccountBlocks(genResult.BlockGenList); err != nil {
+		t.Fatal(err)
+	}
+
+	insertSnapshotBlock(t, c)
+	return c, closeChain
+}
+
+func TestContractApi_Call(t *testing.T) {
+	// the accounts get quota without pledge or PoW
+	vm.InitVmConfig(true, false)
+	defer vm.InitVmConfig(false, false)
+
+	c, closeChain := newCallChain(t)
+	defer closeChain()
+
+	contract := &ContractApi{chain: c}
+	toAddr, _, _ := types.CreateAddress()
+	amount := "10"
+
+	result, err := contract.Call(CallParam{
+		SelfAddr:    ledger.GenesisAccountAddress,
+		ToAddr:      toAddr,
+		TokenTypeId: ledger.ViteTokenId,
+		Amount:      &amount,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" || result.SendQuota != "21000" || result.ReceiveQuota != "0" {
+		t.Fatalf("unexpected result of transfer %+v", result)
+	}
+
+	// pledge to nobody fails on the send side
+	result, err = contract.Call(CallParam{
+		SelfAddr:    ledger.GenesisAccountAddress,
+		ToAddr:      abi.AddressPledge,
+		TokenTypeId: ledger.ViteTokenId,
+		Amount:      &amount,
+		Data:        []byte{1, 2, 3, 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error == "" {
+		t.Fatalf("call with invalid data should fail, got %+v", result)
+	}
+
+	invalid := "abc"
+	if _, err := contract.Call(CallParam{SelfAddr: ledger.GenesisAccountAddress, ToAddr: toAddr, Amount: &invalid}); err != ErrStrToBigInt {
+		t.Fatalf("invalid amount should be rejected, got %v", err)
+	}
+}
+
+func TestTx_EstimateQuota(t *testing.T) {
+	vm.InitVmConfig(true, false)
+	defer vm.InitVmConfig(false, false)
+
+	c, closeChain := newCallChain(t)
+	defer closeChain()
+
+	toAddr, _, _ := types.CreateAddress()
+	amount := "10"
+
+	estimate, err := estimateQuota(c, CallParam{
+		SelfAddr:    ledger.GenesisAccountAddress,
+		ToAddr:      toAddr,
+		TokenTypeId: ledger.ViteTokenId,
+		Amount:      &amount,
+		Data:        []byte{1, 2, 3, 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 21000 plus 68 for each non-zero byte of data
+	if estimate.Error != "" || estimate.SendQuota != "21272" || estimate.ReceiveQuota != "0" {
+		t.Fatalf("unexpected estimate %+v", estimate)
+	}
+
+	// the account has no blocks
+	if _, err := estimateQuota(c, CallParam{SelfAddr: toAddr, ToAddr: toAddr, TokenTypeId: ledger.ViteTokenId}); err == nil {
+		t.Fatal("estimate for the account without blocks should fail")
+	}
+}
@@ -1,7 +1,11 @@
 package api
 
 import (
+	"context"
+	"encoding/hex"
+	"io"
 	"math/big"
+	"strconv"
 	"time"
 
 	"runtime/debug"
@@ -11,9 +15,13 @@ import (
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/consensus"
 	"github.com/vitelabs/go-vite/consensus/core"
+	"github.com/vitelabs/go-vite/generator"
 	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/rpc"
+	"github.com/vitelabs/go-vite/statedump"
 	"github.com/vitelabs/go-vite/vite"
 	"github.com/vitelabs/go-vite/vite/net"
+	"github.com/vitelabs/go-vite/vm"
 )
 
 type DebugApi struct {
@@ -289,6 +297,193 @@ func (api DebugApi) MachineInfo() map[string]interface{} {
 	return result
 }
 
+// PrivateDebugApi holds the debug methods which may take much time and memory, it is served on IPC only.
+type PrivateDebugApi struct {
+	v *vite.Vite
+
+	// stateDumps holds a token for each running state dump
+	stateDumps chan struct{}
+}
+
+// maxStateDumps is the max number of the state dumps running at the same time, a dump walks the whole state.
+const maxStateDumps = 1
+
+var ErrStateDumpBusy = errors.New("too many state dumps are running, retry later")
+
+func NewPrivateDebugApi(v *vite.Vite) *PrivateDebugApi {
+	return &PrivateDebugApi{
+		v:          v,
+		stateDumps: make(chan struct{}, maxStateDumps),
+	}
+}
+
+func (api PrivateDebugApi) String() string {
+	return "PrivateDebugApi"
+}
+
+const (
+	StructLoggerTracer = "structLogger"
+	CallTracer         = "callTracer"
+)
+
+type TraceConfig struct {
+	Tracer        string `json:"tracer"`
+	DisableMemory bool   `json:"disableMemory"`
+	Limit         int    `json:"limit"`
+}
+
+type AccountBlockTrace struct {
+	Hash      types.Hash `json:"hash"`
+	BlockType byte       `json:"blockType"`
+	Quota     string     `json:"quota"`
+	Output    string     `json:"output,omitempty"`
+	Err       string     `json:"error,omitempty"`
+
+	StructLogs []*vm.StructLog `json:"structLogs,omitempty"`
+	Calls      *vm.CallFrame   `json:"calls,omitempty"`
+}
+
+// TraceAccountBlock re-executes an account block on the state it was generated on and returns the trace of its
+// contract code execution. The block list generated by the vm is discarded.
+func (api PrivateDebugApi) TraceAccountBlock(hash types.Hash, config *TraceConfig) (*AccountBlockTrace, error) {
+	if config == nil {
+		config = &TraceConfig{}
+	}
+
+	block, err := api.v.Chain().GetAccountBlockByHash(&hash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, errors.New("account block is not exist")
+	}
+
+	var prevHash *types.Hash
+	if block.Height > 1 {
+		prevHash = &block.PrevHash
+	}
+	gen, err := generator.NewGenerator(api.v.Chain(), &block.SnapshotHash, prevHash, &block.AccountAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	var structLogger *vm.StructLogger
+	var callTracer *vm.CallTracer
+	switch config.Tracer {
+	case "", StructLoggerTracer:
+		structLogger = vm.NewStructLogger(config.DisableMemory, config.Limit)
+		gen.SetTracer(structLogger)
+	case CallTracer:
+		callTracer = vm.NewCallTracer()
+		gen.SetTracer(callTracer)
+	default:
+		return nil, errors.New("unknown tracer " + config.Tracer)
+	}
+
+	genResult, err := gen.GenerateWithBlock(block, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	trace := &AccountBlockTrace{
+		Hash:      block.Hash,
+		BlockType: block.BlockType,
+		Quota:     "0",
+	}
+	if len(genResult.BlockGenList) > 0 {
+		genBlock := genResult.BlockGenList[0].AccountBlock
+		trace.BlockType = genBlock.BlockType
+		trace.Quota = strconv.FormatUint(genBlock.Quota, 10)
+	}
+	if genResult.Err != nil {
+		trace.Err = genResult.Err.Error()
+	}
+	if structLogger != nil {
+		trace.StructLogs = structLogger.StructLogs()
+		trace.Output = hex.EncodeToString(structLogger.Output())
+	}
+	if callTracer != nil {
+		trace.Calls = callTracer.Result()
+		if trace.Calls != nil {
+			trace.Output = trace.Calls.Output
+		}
+	}
+	return trace, nil
+}
+
+// StateDumpMsg is a message of the state dump stream, the accounts are followed by a message with Done set, which
+// has the number of the accounts, or the error which stopped the dump.
+type StateDumpMsg struct {
+	Account *statedump.AccountState `json:"account,omitempty"`
+
+	Done  bool   `json:"done"`
+	Count string `json:"count,omitempty"`
+	Err   string `json:"err,omitempty"`
+}
+
+// DumpState streams the balances of all the accounts at the snapshot block of snapshotHeight, 0 means the latest.
+// Only the balances of tokenId are streamed if it isn't nil, the accounts without balance are skipped. At most
+// maxStateDumps dumps run at the same time, ErrStateDumpBusy is returned if there are more.
+func (api PrivateDebugApi) DumpState(ctx context.Context, snapshotHeight uint64, tokenId *types.TokenTypeId) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+
+	select {
+	case api.stateDumps <- struct{}{}:
+	default:
+		return nil, ErrStateDumpBusy
+	}
+
+	d, err := statedump.NewDumper(api.v.Chain(), snapshotHeight, tokenId)
+	if err != nil {
+		<-api.stateDumps
+		return nil, err
+	}
+
+	rpcSub := notifier.CreateSubscription()
+	go func() {
+		defer func() {
+			<-api.stateDumps
+		}()
+
+		select {
+		case <-rpcSub.Activated():
+		case <-rpcSub.Err():
+			return
+		case <-notifier.Closed():
+			return
+		}
+
+		count := uint64(0)
+		for {
+			select {
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			default:
+			}
+
+			state, err := d.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				notifier.Notify(rpcSub.ID, &StateDumpMsg{Done: true, Count: strconv.FormatUint(count, 10), Err: err.Error()})
+				return
+			}
+			if err := notifier.Notify(rpcSub.ID, &StateDumpMsg{Account: state}); err != nil {
+				return
+			}
+			count++
+		}
+		notifier.Notify(rpcSub.ID, &StateDumpMsg{Done: true, Count: strconv.FormatUint(count, 10)})
+	}()
+	return rpcSub, nil
+}
+
 func NewDebugApi(v *vite.Vite) *DebugApi {
 	return &DebugApi{
 		v: v,
@@ -0,0 +1,553 @@
+package api
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/rpc"
+	"github.com/vitelabs/go-vite/vite"
+)
+
+const (
+	maxLogQueryResults = 10000
+	logFilterTimeout   = 5 * time.Minute
+)
+
+var (
+	ErrLogFilterEmpty       = errors.New("both addrList and topics of log filter are empty")
+	ErrLogFilterHeightRange = errors.New("fromHeight is greater than toHeight")
+	ErrTooManyLogs          = errors.New("too many logs, narrow the height range")
+	ErrLogFilterNotFound    = errors.New("log filter not found")
+)
+
+// LogFilterParam selects the vm logs emitted by the contracts in AddrList, within the blocks confirmed by the
+// snapshot blocks from FromHeight to ToHeight. ToHeight 0 means up to the latest blocks, including the unconfirmed
+// ones. Topics[i] lists the accepted values of the i-th topic, an empty list accepts any value. If AddrList is
+// empty, the logs of all contracts are selected by the topic index, then at least one list of Topics must be
+// non-empty.
+type LogFilterParam struct {
+	AddrList   []types.Address `json:"addrList"`
+	Topics     [][]types.Hash  `json:"topics"`
+	FromHeight uint64          `json:"fromHeight"`
+	ToHeight   uint64          `json:"toHeight"`
+}
+
+// RpcVmLog is a matched vm log, Removed is set if the block of the log was rolled back after the log had been
+// returned by GetFilterChanges.
+type RpcVmLog struct {
+	Topics []types.Hash `json:"topics"`
+	Data   []byte       `json:"data"`
+
+	AccountAddress types.Address `json:"accountAddress"`
+	AccountHeight  string        `json:"accountHeight"`
+	BlockHash      types.Hash    `json:"blockHash"`
+	LogIndex       string        `json:"logIndex"`
+	Removed        bool          `json:"removed"`
+}
+
+func toRpcVmLogs(items []*chain.VmLogItem, removed bool) []*RpcVmLog {
+	logs := make([]*RpcVmLog, len(items))
+	for i, item := range items {
+		logs[i] = &RpcVmLog{
+			Topics:         item.Log.Topics,
+			Data:           item.Log.Data,
+			AccountAddress: item.AccountAddress,
+			AccountHeight:  strconv.FormatUint(item.AccountHeight, 10),
+			BlockHash:      item.BlockHash,
+			LogIndex:       strconv.FormatUint(item.LogIndex, 10),
+			Removed:        removed,
+		}
+	}
+	return logs
+}
+
+// logFilter is an installed filter. A filter with addresses polls by account height, nextHeights keeps the
+// account height of each address from which the next poll starts. A filter with topics only polls the confirmed
+// blocks by snapshot height, from nextSnapshotHeight. The logs of the blocks polled and then rolled back are kept
+// in removed until the next poll. For the filters without addresses, the blocks left unconfirmed by a snapshot
+// rollback are rolled back too, their logs are returned again when they are confirmed again.
+type logFilter struct {
+	param              *LogFilterParam
+	nextHeights        map[types.Address]uint64
+	nextSnapshotHeight uint64
+	removed            []*RpcVmLog
+	lastPoll           time.Time
+
+	// held while the filter is polled, the chain is queried without FilterApi.lock
+	pollLock sync.Mutex
+	// increased by every rollback, the logs polled across a rollback are dropped and polled again
+	rollbacks uint64
+}
+
+// FilterApi queries the log index, which has to be opened by the OpenLogIndex config.
+type FilterApi struct {
+	chain chain.Chain
+	log   log15.Logger
+
+	lock    sync.Mutex
+	filters map[rpc.ID]*logFilter
+
+	// the rollback listeners are registered only while there are filters
+	listenerIds []uint64
+}
+
+func NewFilterApi(vite *vite.Vite) *FilterApi {
+	return newFilterApi(vite.Chain())
+}
+
+func newFilterApi(c chain.Chain) *FilterApi {
+	return &FilterApi{
+		chain:   c,
+		log:     log15.New("module", "rpc_api/filter_api"),
+		filters: make(map[rpc.ID]*logFilter),
+	}
+}
+
+func (f *FilterApi) String() string {
+	return "FilterApi"
+}
+
+// GetLogs returns the vm logs matched by param, ordered by address and account height.
+func (f *FilterApi) GetLogs(param LogFilterParam) ([]*RpcVmLog, error) {
+	if err := checkLogFilterParam(&param); err != nil {
+		return nil, err
+	}
+
+	if len(param.AddrList) == 0 {
+		items, err := f.chain.GetLogsByTopics(param.Topics, param.FromHeight, param.ToHeight, maxLogQueryResults)
+		if err != nil {
+			return nil, toLogQueryError(err)
+		}
+		return toRpcVmLogs(items, false), nil
+	}
+
+	var items []*chain.VmLogItem
+	for _, addr := range param.AddrList {
+		startHeight, endHeight, ok, err := f.accountHeightRange(&addr, param.FromHeight, param.ToHeight)
+		if err != nil {
+			f.log.Error("accountHeightRange failed, error is "+err.Error(), "method", "GetLogs")
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		addrItems, err := f.chain.GetLogs(&addr, startHeight, endHeight, param.Topics, maxLogQueryResults-len(items))
+		if err != nil {
+			return nil, toLogQueryError(err)
+		}
+		items = append(items, addrItems...)
+	}
+	return toRpcVmLogs(items, false), nil
+}
+
+// NewLogFilter installs a filter of which GetFilterChanges returns the logs emitted after the last poll, the
+// height range of param is ignored. A filter without addresses returns the logs when their blocks are confirmed.
+// A filter is uninstalled if it is not polled for 5 minutes.
+func (f *FilterApi) NewLogFilter(param LogFilterParam) (rpc.ID, error) {
+	if err := checkLogFilterParam(&param); err != nil {
+		return "", err
+	}
+
+	filter := &logFilter{
+		param:       &param,
+		nextHeights: make(map[types.Address]uint64, len(param.AddrList)),
+		lastPoll:    time.Now(),
+	}
+	if len(param.AddrList) == 0 {
+		filter.nextSnapshotHeight = f.chain.GetLatestSnapshotBlock().Height + 1
+	}
+	for _, addr := range param.AddrList {
+		latestBlock, err := f.chain.GetLatestAccountBlock(&addr)
+		if err != nil {
+			f.log.Error("GetLatestAccountBlock failed, error is "+err.Error(), "method", "NewLogFilter")
+			return "", err
+		}
+		filter.nextHeights[addr] = 1
+		if latestBlock != nil {
+			filter.nextHeights[addr] = latestBlock.Height + 1
+		}
+	}
+
+	id := rpc.NewID()
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.removeExpiredFilters()
+	f.filters[id] = filter
+	if f.listenerIds == nil {
+		f.listenerIds = []uint64{
+			f.chain.RegisterDeleteAccountBlocks(f.onDeleteAccountBlocks),
+			f.chain.RegisterDeleteSnapshotBlocksSuccess(f.onDeleteSnapshotBlocks),
+		}
+	}
+	return id, nil
+}
+
+// GetFilterChanges returns the logs matched by the filter which were emitted since the last poll, following the
+// logs removed by rollbacks since the last poll. At most 10000 logs are returned at a time, the rest are returned
+// by the next poll.
+func (f *FilterApi) GetFilterChanges(id rpc.ID) ([]*RpcVmLog, error) {
+	f.lock.Lock()
+	f.removeExpiredFilters()
+	filter, ok := f.filters[id]
+	if ok {
+		filter.lastPoll = time.Now()
+	}
+	f.lock.Unlock()
+	if !ok {
+		return nil, ErrLogFilterNotFound
+	}
+
+	// the rollback listeners take f.lock, so it isn't held while the chain is queried
+	filter.pollLock.Lock()
+	defer filter.pollLock.Unlock()
+
+	f.lock.Lock()
+	rollbacks := filter.rollbacks
+	nextSnapshotHeight := filter.nextSnapshotHeight
+	nextHeights := make(map[types.Address]uint64, len(filter.nextHeights))
+	for addr, height := range filter.nextHeights {
+		nextHeights[addr] = height
+	}
+	f.lock.Unlock()
+
+	items, nextSnapshotHeight, err := f.pollFilter(filter.param, nextSnapshotHeight, nextHeights)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if err != nil {
+		return nil, toLogQueryError(err)
+	}
+	logs := filter.removed
+	filter.removed = nil
+	if filter.rollbacks != rollbacks {
+		// the polled blocks may have been rolled back, the rollback has lowered the heights to poll again from
+		return logs, nil
+	}
+	filter.nextSnapshotHeight = nextSnapshotHeight
+	filter.nextHeights = nextHeights
+	return append(logs, toRpcVmLogs(items, false)...), nil
+}
+
+// pollFilter queries the logs matched by param from the next heights of a filter. nextHeights is updated and
+// the next snapshot height is returned.
+func (f *FilterApi) pollFilter(param *LogFilterParam, nextSnapshotHeight uint64, nextHeights map[types.Address]uint64) ([]*chain.VmLogItem, uint64, error) {
+	if len(param.AddrList) == 0 {
+		latestHeight := f.chain.GetLatestSnapshotBlock().Height
+		if latestHeight < nextSnapshotHeight {
+			return nil, nextSnapshotHeight, nil
+		}
+		items, toHeight, err := pollLogs(nextSnapshotHeight, latestHeight, maxLogQueryResults, func(fromHeight, toHeight uint64, maxCount int) ([]*chain.VmLogItem, error) {
+			return f.chain.GetLogsByTopics(param.Topics, fromHeight, toHeight, maxCount)
+		}, func(item *chain.VmLogItem) uint64 {
+			return item.ConfirmHeight
+		})
+		if err != nil {
+			return nil, nextSnapshotHeight, err
+		}
+		return items, toHeight + 1, nil
+	}
+
+	var items []*chain.VmLogItem
+	for _, addr := range param.AddrList {
+		maxCount := maxLogQueryResults - len(items)
+		if maxCount <= 0 {
+			break
+		}
+
+		latestBlock, err := f.chain.GetLatestAccountBlock(&addr)
+		if err != nil {
+			f.log.Error("GetLatestAccountBlock failed, error is "+err.Error(), "method", "pollFilter")
+			return nil, nextSnapshotHeight, err
+		}
+		if latestBlock == nil || latestBlock.Height < nextHeights[addr] {
+			continue
+		}
+
+		addrItems, toHeight, err := pollLogs(nextHeights[addr], latestBlock.Height, maxCount, func(startHeight, endHeight uint64, maxCount int) ([]*chain.VmLogItem, error) {
+			return f.chain.GetLogs(&addr, startHeight, endHeight, param.Topics, maxCount)
+		}, func(item *chain.VmLogItem) uint64 {
+			return item.AccountHeight
+		})
+		if err != nil {
+			if err == chain.ErrTooManyLogs && len(items) > 0 {
+				// the logs of this address are returned by the next poll
+				break
+			}
+			return nil, nextSnapshotHeight, err
+		}
+		items = append(items, addrItems...)
+		nextHeights[addr] = toHeight + 1
+	}
+	return items, nextSnapshotHeight, nil
+}
+
+// pollLogs queries the logs from fromHeight to toHeight. If more than maxCount logs match, the logs before the
+// height exceeding the limit are taken, which are returned by the query together with chain.ErrTooManyLogs, the
+// range is halved only if there aren't any. height returns the height of a log, the height up to which the logs
+// are returned is returned.
+func pollLogs(fromHeight uint64, toHeight uint64, maxCount int, query func(uint64, uint64, int) ([]*chain.VmLogItem, error), height func(*chain.VmLogItem) uint64) ([]*chain.VmLogItem, uint64, error) {
+	for {
+		items, err := query(fromHeight, toHeight, maxCount)
+		if err != chain.ErrTooManyLogs {
+			return items, toHeight, err
+		}
+		if len(items) > 0 {
+			return items, height(items[len(items)-1]), nil
+		}
+		if toHeight == fromHeight {
+			return nil, toHeight, err
+		}
+		toHeight = fromHeight + (toHeight-fromHeight)/2
+	}
+}
+
+// GetFilterLogs returns all logs matched by the param of the filter, like GetLogs.
+func (f *FilterApi) GetFilterLogs(id rpc.ID) ([]*RpcVmLog, error) {
+	f.lock.Lock()
+	filter, ok := f.filters[id]
+	f.lock.Unlock()
+	if !ok {
+		return nil, ErrLogFilterNotFound
+	}
+	return f.GetLogs(*filter.param)
+}
+
+func (f *FilterApi) UninstallFilter(id rpc.ID) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	_, ok := f.filters[id]
+	delete(f.filters, id)
+	f.unlisten()
+	return ok
+}
+
+func (f *FilterApi) removeExpiredFilters() {
+	for id, filter := range f.filters {
+		if time.Since(filter.lastPoll) > logFilterTimeout {
+			delete(f.filters, id)
+		}
+	}
+	f.unlisten()
+}
+
+// unlisten removes the rollback listeners when the last filter is uninstalled
+func (f *FilterApi) unlisten() {
+	if len(f.filters) > 0 || f.listenerIds == nil {
+		return
+	}
+	for _, id := range f.listenerIds {
+		f.chain.UnRegister(id)
+	}
+	f.listenerIds = nil
+}
+
+// onDeleteAccountBlocks runs before the blocks are deleted, so their logs can still be read. The logs which have
+// been polled are kept as removed logs of the filter, and the filter polls again from the deleted blocks.
+func (f *FilterApi) onDeleteAccountBlocks(batch database.Batch, subLedger map[types.Address][]*ledger.AccountBlock) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	// the snapshot heights confirming the deleted blocks with logs, 0 if unconfirmed
+	confirmHeights := make(map[types.Hash]uint64)
+	for _, blocks := range subLedger {
+		for _, block := range blocks {
+			if block.LogHash == nil {
+				continue
+			}
+			snapshotBlock, err := f.chain.GetConfirmBlock(&block.Hash)
+			if err != nil {
+				f.log.Error("GetConfirmBlock failed, error is "+err.Error(), "method", "onDeleteAccountBlocks")
+				continue
+			}
+			if snapshotBlock != nil {
+				confirmHeights[block.Hash] = snapshotBlock.Height
+			}
+		}
+	}
+
+	for _, filter := range f.filters {
+		filter.rollbacks++
+
+		// decide by the heights polled before this rollback
+		nextHeights := make(map[types.Address]uint64, len(filter.nextHeights))
+		for addr, height := range filter.nextHeights {
+			nextHeights[addr] = height
+		}
+
+		for addr, blocks := range subLedger {
+			for _, block := range blocks {
+				if len(filter.param.AddrList) > 0 {
+					if nextHeight, ok := nextHeights[addr]; !ok || block.Height >= nextHeight {
+						continue
+					}
+					if block.Height < filter.nextHeights[addr] {
+						filter.nextHeights[addr] = block.Height
+					}
+				} else {
+					// onDeleteSnapshotBlocks resets the snapshot height
+					confirmHeight := confirmHeights[block.Hash]
+					if confirmHeight == 0 || confirmHeight >= filter.nextSnapshotHeight {
+						continue
+					}
+				}
+
+				filter.removed = append(filter.removed, f.removedLogs(filter, block)...)
+			}
+		}
+	}
+	return nil
+}
+
+// onDeleteSnapshotBlocks lets the filters without addresses poll again from the deleted snapshot blocks. The
+// blocks confirmed by the deleted snapshot blocks are unconfirmed now, their polled logs are removed.
+func (f *FilterApi) onDeleteSnapshotBlocks(snapshotBlocks []*ledger.SnapshotBlock) {
+	if len(snapshotBlocks) <= 0 {
+		return
+	}
+	snapshotBlocks = append([]*ledger.SnapshotBlock(nil), snapshotBlocks...)
+	sort.Slice(snapshotBlocks, func(i, j int) bool {
+		return snapshotBlocks[i].Height < snapshotBlocks[j].Height
+	})
+	height := snapshotBlocks[0].Height
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var filters []*logFilter
+	for _, filter := range f.filters {
+		filter.rollbacks++
+		if len(filter.param.AddrList) == 0 && height < filter.nextSnapshotHeight {
+			filters = append(filters, filter)
+		}
+	}
+	if len(filters) == 0 {
+		return
+	}
+
+	// the blocks deleted by the rollback are handled by onDeleteAccountBlocks, the others are left unconfirmed
+	fromHeights := make(map[types.Address]uint64)
+	for _, snapshotBlock := range snapshotBlocks {
+		for addr, hashHeight := range snapshotBlock.SnapshotContent {
+			fromHeight, ok := fromHeights[addr]
+			if !ok {
+				fromHeight = 1
+				if block, err := f.chain.GetConfirmAccountBlock(height-1, &addr); err != nil {
+					f.log.Error("GetConfirmAccountBlock failed, error is "+err.Error(), "method", "onDeleteSnapshotBlocks")
+					continue
+				} else if block != nil {
+					fromHeight = block.Height + 1
+				}
+			}
+			if hashHeight.Height < fromHeight {
+				continue
+			}
+
+			blocks, err := f.chain.GetAccountBlocksByHeight(addr, fromHeight, hashHeight.Height-fromHeight+1, true)
+			if err != nil {
+				f.log.Error("GetAccountBlocksByHeight failed, error is "+err.Error(), "method", "onDeleteSnapshotBlocks")
+				continue
+			}
+			for _, block := range blocks {
+				for _, filter := range filters {
+					// polled if confirmed below the next height of the filter
+					if snapshotBlock.Height < filter.nextSnapshotHeight {
+						filter.removed = append(filter.removed, f.removedLogs(filter, block)...)
+					}
+				}
+			}
+			fromHeights[addr] = hashHeight.Height + 1
+		}
+	}
+
+	for _, filter := range filters {
+		filter.nextSnapshotHeight = height
+	}
+}
+
+// removedLogs returns the logs of block matched by filter as removed logs
+func (f *FilterApi) removedLogs(filter *logFilter, block *ledger.AccountBlock) []*RpcVmLog {
+	if block.LogHash == nil {
+		return nil
+	}
+	logList, err := f.chain.GetVmLogList(block.LogHash)
+	if err != nil {
+		f.log.Error("GetVmLogList failed, error is "+err.Error(), "method", "removedLogs")
+		return nil
+	}
+
+	var items []*chain.VmLogItem
+	for index, log := range logList {
+		if chain.MatchTopics(log, filter.param.Topics) {
+			items = append(items, &chain.VmLogItem{
+				Log:            log,
+				AccountAddress: block.AccountAddress,
+				AccountHeight:  block.Height,
+				BlockHash:      block.Hash,
+				LogIndex:       uint64(index),
+			})
+		}
+	}
+	return toRpcVmLogs(items, true)
+}
+
+// accountHeightRange converts a snapshot height range to the account height range of addr. The returned ok is
+// false if no block of addr is confirmed up to toHeight.
+func (f *FilterApi) accountHeightRange(addr *types.Address, fromHeight uint64, toHeight uint64) (uint64, uint64, bool, error) {
+	startHeight, endHeight := uint64(1), uint64(0)
+	if fromHeight > 1 {
+		block, err := f.chain.GetConfirmAccountBlock(fromHeight-1, addr)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		if block != nil {
+			startHeight = block.Height + 1
+		}
+	}
+	if toHeight > 0 {
+		block, err := f.chain.GetConfirmAccountBlock(toHeight, addr)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		if block == nil || block.Height < startHeight {
+			return 0, 0, false, nil
+		}
+		endHeight = block.Height
+	}
+	return startHeight, endHeight, true, nil
+}
+
+func checkLogFilterParam(param *LogFilterParam) error {
+	if len(param.AddrList) == 0 {
+		empty := true
+		for _, topics := range param.Topics {
+			if len(topics) > 0 {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			return ErrLogFilterEmpty
+		}
+	}
+	if param.ToHeight > 0 && param.FromHeight > param.ToHeight {
+		return ErrLogFilterHeightRange
+	}
+	return nil
+}
+
+func toLogQueryError(err error) error {
+	if err == chain.ErrTooManyLogs {
+		return ErrTooManyLogs
+	}
+	return err
+}
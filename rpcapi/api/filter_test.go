@@ -0,0 +1,245 @@
+package api
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/config"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/vm_context"
+)
+
+func insertSnapshotBlock(t *testing.T, c chain.Chain) *ledger.SnapshotBlock {
+	latestBlock := c.GetLatestSnapshotBlock()
+	now := time.Now()
+	snapshotBlock := &ledger.SnapshotBlock{
+		Height:          latestBlock.Height + 1,
+		PrevHash:        latestBlock.Hash,
+		Timestamp:       &now,
+		SnapshotContent: c.GetNeedSnapshotContent(),
+	}
+
+	stateTrie, err := c.GenStateTrie(latestBlock.StateHash, snapshotBlock.SnapshotContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshotBlock.StateTrie = stateTrie
+	snapshotBlock.StateHash = *stateTrie.Hash()
+	snapshotBlock.Hash = snapshotBlock.ComputeHash()
+
+	if err := c.InsertSnapshotBlock(snapshotBlock); err != nil {
+		t.Fatal(err)
+	}
+	return snapshotBlock
+}
+
+// insertLogBlock inserts a send block of addr which emits a log of topic, data tells the logs apart
+func insertLogBlock(t *testing.T, c chain.Chain, addr types.Address, topic types.Hash, data byte) *ledger.AccountBlock {
+	vmContext, err := vm_context.NewVmContext(c, nil, nil, &addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeSendCall,
+		AccountAddress: addr,
+		ToAddress:      addr,
+		TokenId:        ledger.ViteTokenId,
+		Amount:         big.NewInt(0),
+		Fee:            big.NewInt(0),
+		Height:         1,
+		SnapshotHash:   c.GetLatestSnapshotBlock().Hash,
+		Timestamp:      &time.Time{},
+	}
+	if latestBlock, _ := c.GetLatestAccountBlock(&addr); latestBlock != nil {
+		block.Height = latestBlock.Height + 1
+		block.PrevHash = latestBlock.Hash
+	}
+
+	vmContext.AddLog(&ledger.VmLog{Topics: []types.Hash{topic}, Data: []byte{data}})
+	block.LogHash = vmContext.GetLogListHash()
+	if stateHash := vmContext.GetStorageHash(); stateHash != nil {
+		block.StateHash = *stateHash
+	}
+	block.Hash = block.ComputeHash()
+
+	if err := c.InsertAccountBlocks([]*vm_context.VmAccountBlock{{AccountBlock: block, VmContext: vmContext}}); err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+func newLogIndexChain(t *testing.T) (chain.Chain, func()) {
+	dir, err := ioutil.TempDir("", "filter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := chain.NewChain(&config.Config{DataDir: dir, Chain: &config.Chain{LedgerInMemory: true, OpenLogIndex: true}})
+	c.Init()
+	c.Start()
+	return c, func() {
+		c.Stop()
+		c.Destroy()
+		os.RemoveAll(dir)
+	}
+}
+
+func checkRpcVmLogs(t *testing.T, logs []*RpcVmLog, err error, removed []bool, blocks ...*ledger.AccountBlock) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != len(blocks) {
+		t.Fatalf("should get %d logs, got %d", len(blocks), len(logs))
+	}
+	for i, log := range logs {
+		if log.BlockHash != blocks[i].Hash || log.Removed != removed[i] {
+			t.Fatalf("log %d mismatch, removed %v", i, log.Removed)
+		}
+	}
+}
+
+func TestFilterApi_GetLogs(t *testing.T) {
+	c, closeChain := newLogIndexChain(t)
+	defer closeChain()
+
+	f := newFilterApi(c)
+	topic := types.DataHash([]byte("topic"))
+	addr1, _, _ := types.CreateAddress()
+	addr2, _, _ := types.CreateAddress()
+
+	block1 := insertLogBlock(t, c, addr1, topic, 1)
+	block2 := insertLogBlock(t, c, addr2, topic, 2)
+	insertSnapshotBlock(t, c)
+	block3 := insertLogBlock(t, c, addr1, types.DataHash([]byte("other")), 3)
+
+	logs, err := f.GetLogs(LogFilterParam{AddrList: []types.Address{addr1}})
+	checkRpcVmLogs(t, logs, err, []bool{false, false}, block1, block3)
+
+	// topics only
+	logs, err = f.GetLogs(LogFilterParam{Topics: [][]types.Hash{{topic}}})
+	expected := []*ledger.AccountBlock{block1, block2}
+	if string(addr2.Bytes()) < string(addr1.Bytes()) {
+		expected = []*ledger.AccountBlock{block2, block1}
+	}
+	checkRpcVmLogs(t, logs, err, []bool{false, false}, expected...)
+
+	if _, err := f.GetLogs(LogFilterParam{Topics: [][]types.Hash{{}}}); err != ErrLogFilterEmpty {
+		t.Fatalf("should get ErrLogFilterEmpty, got %v", err)
+	}
+}
+
+func TestFilterApi_GetFilterChanges(t *testing.T) {
+	c, closeChain := newLogIndexChain(t)
+	defer closeChain()
+
+	f := newFilterApi(c)
+	topic := types.DataHash([]byte("topic"))
+	addr, _, _ := types.CreateAddress()
+
+	addrFilter, err := f.NewLogFilter(LogFilterParam{AddrList: []types.Address{addr}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	topicFilter, err := f.NewLogFilter(LogFilterParam{Topics: [][]types.Hash{{topic}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.listenerIds) != 2 {
+		t.Fatal("rollback listeners should be registered")
+	}
+
+	block1 := insertLogBlock(t, c, addr, topic, 1)
+	snapshotBlock := insertSnapshotBlock(t, c)
+	block2 := insertLogBlock(t, c, addr, topic, 2)
+
+	logs, err := f.GetFilterChanges(addrFilter)
+	checkRpcVmLogs(t, logs, err, []bool{false, false}, block1, block2)
+	// the filter without addresses returns the confirmed logs
+	logs, err = f.GetFilterChanges(topicFilter)
+	checkRpcVmLogs(t, logs, err, []bool{false}, block1)
+
+	logs, err = f.GetFilterChanges(addrFilter)
+	checkRpcVmLogs(t, logs, err, nil)
+
+	// roll back both blocks, then insert another block at the height of the first one
+	if _, _, err := c.DeleteSnapshotBlocksToHeight(snapshotBlock.Height); err != nil {
+		t.Fatal(err)
+	}
+	// the snapshot rollback leaves the blocks unconfirmed
+	if block, _ := c.GetLatestAccountBlock(&addr); block != nil {
+		if _, err := c.DeleteAccountBlocks(&addr, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	block3 := insertLogBlock(t, c, addr, topic, 3)
+	insertSnapshotBlock(t, c)
+
+	logs, err = f.GetFilterChanges(addrFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 3 || !logs[0].Removed || !logs[1].Removed || logs[2].Removed || logs[2].BlockHash != block3.Hash {
+		t.Fatalf("should get 2 removed logs and the new log, got %d logs", len(logs))
+	}
+	logs, err = f.GetFilterChanges(topicFilter)
+	checkRpcVmLogs(t, logs, err, []bool{true, false}, block1, block3)
+
+	if !f.UninstallFilter(addrFilter) || !f.UninstallFilter(topicFilter) {
+		t.Fatal("filters should be uninstalled")
+	}
+	if f.listenerIds != nil {
+		t.Fatal("rollback listeners should be removed with the last filter")
+	}
+}
+
+func TestPollLogs(t *testing.T) {
+	height := func(item *chain.VmLogItem) uint64 {
+		return item.AccountHeight
+	}
+	// a log at every height, too many logs are only reported
+	query := func(fromHeight, toHeight uint64, maxCount int) ([]*chain.VmLogItem, error) {
+		if toHeight-fromHeight+1 > uint64(maxCount) {
+			return nil, chain.ErrTooManyLogs
+		}
+		return make([]*chain.VmLogItem, toHeight-fromHeight+1), nil
+	}
+
+	items, toHeight, err := pollLogs(1, 100, 30, query, height)
+	if err != nil || toHeight != 25 || len(items) != 25 {
+		t.Fatalf("the range should be halved to 1-25, got %d logs to %d, %v", len(items), toHeight, err)
+	}
+
+	items, toHeight, err = pollLogs(5, 10, 30, query, height)
+	if err != nil || toHeight != 10 || len(items) != 6 {
+		t.Fatalf("the range should not be halved, got %d logs to %d, %v", len(items), toHeight, err)
+	}
+
+	if _, _, err := pollLogs(1, 100, 0, query, height); err != chain.ErrTooManyLogs {
+		t.Fatalf("should get ErrTooManyLogs, got %v", err)
+	}
+
+	// the logs before the height exceeding the limit are returned with too many logs
+	queries := 0
+	query = func(fromHeight, toHeight uint64, maxCount int) ([]*chain.VmLogItem, error) {
+		queries++
+		var items []*chain.VmLogItem
+		for h := fromHeight; h <= toHeight; h++ {
+			if len(items) >= maxCount {
+				return items, chain.ErrTooManyLogs
+			}
+			items = append(items, &chain.VmLogItem{AccountHeight: h})
+		}
+		return items, nil
+	}
+	items, toHeight, err = pollLogs(1, 100, 30, query, height)
+	if err != nil || toHeight != 30 || len(items) != 30 || queries != 1 {
+		t.Fatalf("the logs to 30 should be taken by a query, got %d logs to %d by %d queries, %v", len(items), toHeight, queries, err)
+	}
+}
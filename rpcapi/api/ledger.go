@@ -1,7 +1,9 @@
 package api
 
 import (
+	"encoding/hex"
 	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/chain_db/access"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/generator"
 	"github.com/vitelabs/go-vite/ledger"
@@ -77,6 +79,34 @@ func (l *LedgerApi) GetBlocksByHash(addr types.Address, originBlockHash *types.H
 
 }
 
+// GetDecodedBlockByHash is GetBlockByHash with the data and vm logs of the block decoded by the registered abis.
+func (l *LedgerApi) GetDecodedBlockByHash(blockHash *types.Hash) (*AccountBlock, error) {
+	block, err := l.GetBlockByHash(blockHash)
+	if err != nil || block == nil {
+		return block, err
+	}
+	if err := decodeRpcBlock(block, l.chain); err != nil {
+		l.log.Error("decodeRpcBlock failed, error is "+err.Error(), "method", "GetDecodedBlockByHash")
+		return nil, err
+	}
+	return block, nil
+}
+
+// GetDecodedBlocksByHash is GetBlocksByHash with the data and vm logs of the blocks decoded by the registered abis.
+func (l *LedgerApi) GetDecodedBlocksByHash(addr types.Address, originBlockHash *types.Hash, count uint64) ([]*AccountBlock, error) {
+	blocks, err := l.GetBlocksByHash(addr, originBlockHash, count)
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range blocks {
+		if err := decodeRpcBlock(block, l.chain); err != nil {
+			l.log.Error("decodeRpcBlock failed, error is "+err.Error(), "method", "GetDecodedBlocksByHash")
+			return nil, err
+		}
+	}
+	return blocks, nil
+}
+
 type Statistics struct {
 	SnapshotBlockCount uint64 `json:"snapshotBlockCount"`
 	AccountBlockCount  uint64 `json:"accountBlockCount"`
@@ -217,32 +247,128 @@ func (l *LedgerApi) GetTokenMintage(tti types.TokenTypeId) (*RpcTokenInfo, error
 	}
 }
 
-func (l *LedgerApi) GetSenderInfo() (*KafkaSendInfo, error) {
+func (l *LedgerApi) GetSenderInfo() (*SenderInfo, error) {
 	l.log.Info("GetSenderInfo")
-	if l.chain.KafkaSender() == nil {
+	if l.chain.Sender() == nil {
 		return nil, nil
 	}
-	senderInfo := &KafkaSendInfo{}
+	senderInfo := &SenderInfo{}
 
 	var totalErr error
 	senderInfo.TotalEvent, totalErr = l.chain.GetLatestBlockEventId()
 	if totalErr != nil {
-		l.log.Error("GetLatestBlockEventId failed, error is "+totalErr.Error(), "method", "GetKafkaSenderInfo")
+		l.log.Error("GetLatestBlockEventId failed, error is "+totalErr.Error(), "method", "GetSenderInfo")
 
 		return nil, totalErr
 	}
 
-	for _, producer := range l.chain.KafkaSender().Producers() {
-		senderInfo.Producers = append(senderInfo.Producers, createKafkaProducerInfo(producer))
+	for _, producer := range l.chain.Sender().Producers() {
+		senderInfo.Producers = append(senderInfo.Producers, createProducerInfo(producer))
 	}
 
-	for _, producer := range l.chain.KafkaSender().RunProducers() {
-		senderInfo.RunProducers = append(senderInfo.RunProducers, createKafkaProducerInfo(producer))
+	for _, producer := range l.chain.Sender().RunProducers() {
+		senderInfo.RunProducers = append(senderInfo.RunProducers, createProducerInfo(producer))
 	}
 
 	return senderInfo, nil
 }
 
+func (l *LedgerApi) GetLatestEventId() (string, error) {
+	l.log.Info("GetLatestEventId")
+	eventId, err := l.chain.GetLatestBlockEventId()
+	if err != nil {
+		l.log.Error("GetLatestBlockEventId failed, error is "+err.Error(), "method", "GetLatestEventId")
+		return "", err
+	}
+	return strconv.FormatUint(eventId, 10), nil
+}
+
+// GetEvents pages through the block event log, returning at most count events whose id is greater than or
+// equal to fromEventId. Consumers resume from the last returned event id plus one. count is clamped to
+// chain.MaxEventsCount, zero means the max.
+func (l *LedgerApi) GetEvents(fromEventId uint64, count uint64) ([]*RpcBlockEvent, error) {
+	l.log.Info("GetEvents")
+	if count == 0 || count > chain.MaxEventsCount {
+		count = chain.MaxEventsCount
+	}
+	events, err := l.chain.GetEvents(fromEventId, count)
+	if err != nil {
+		l.log.Error("GetEvents failed, error is "+err.Error(), "method", "GetEvents")
+		return nil, err
+	}
+
+	rpcEvents := make([]*RpcBlockEvent, 0, len(events))
+	for _, event := range events {
+		rpcEvent, err := createRpcBlockEvent(event, l.chain)
+		if err != nil {
+			l.log.Error("createRpcBlockEvent failed, error is "+err.Error(), "method", "GetEvents")
+			return nil, err
+		}
+		rpcEvents = append(rpcEvents, rpcEvent)
+	}
+	return rpcEvents, nil
+}
+
+// GetTransfers queries the transfer index, which has to be opened by the OpenTxIndex config. Incoming transfers
+// are the send blocks to addr, outgoing transfers are the send blocks of addr. A page may hold fewer blocks than
+// query.Count while nextCursor isn't nil, keep paging until nextCursor is nil. The blocks inserted while the index
+// was closed are missing until the index is rebuilt in the background.
+func (l *LedgerApi) GetTransfers(addr types.Address, query TransferQuery) (*TransferList, error) {
+	l.log.Info("GetTransfers")
+
+	var direction byte
+	switch query.Direction {
+	case "in":
+		direction = access.TxDirectionIn
+	case "out":
+		direction = access.TxDirectionOut
+	default:
+		return nil, ErrTransferDirection
+	}
+
+	count := query.Count
+	if count == 0 || count > maxTransferCount {
+		count = maxTransferCount
+	}
+
+	var cursor []byte
+	if query.Cursor != nil {
+		var err error
+		if cursor, err = hex.DecodeString(*query.Cursor); err != nil || len(cursor) != access.TxIndexCursorSize {
+			return nil, ErrTransferCursor
+		}
+	}
+
+	filter := &access.TxIndexFilter{
+		TokenId:      query.TokenId,
+		BlockType:    query.BlockType,
+		Counterparty: query.Counterparty,
+		StartTime:    query.StartTime,
+		EndTime:      query.EndTime,
+	}
+
+	blocks, nextCursor, err := l.chain.GetTransfers(&addr, direction, filter, cursor, count)
+	if err != nil {
+		l.log.Error("GetTransfers failed, error is "+err.Error(), "method", "GetTransfers")
+		return nil, err
+	}
+
+	rpcBlocks, err := l.ledgerBlocksToRpcBlocks(blocks)
+	if err != nil {
+		l.log.Error("ledgerBlocksToRpcBlocks failed, error is "+err.Error(), "method", "GetTransfers")
+		return nil, err
+	}
+
+	transferList := &TransferList{
+		Blocks: rpcBlocks,
+	}
+	if nextCursor != nil {
+		c := hex.EncodeToString(nextCursor)
+		transferList.NextCursor = &c
+	}
+	return transferList, nil
+}
+
 func (l *LedgerApi) GetBlockMeta(hash *types.Hash) (*ledger.AccountBlockMeta, error) {
 	return l.chain.GetAccountBlockMetaByHash(hash)
 }
@@ -275,17 +401,17 @@ func (l *LedgerApi) GetNeedSnapshotContent() map[types.Address]*ledger.HashHeigh
 func (l *LedgerApi) SetSenderHasSend(producerId uint8, hasSend uint64) {
 	l.log.Info("SetSenderHasSend")
 
-	if l.chain.KafkaSender() == nil {
+	if l.chain.Sender() == nil {
 		return
 	}
-	l.chain.KafkaSender().SetHasSend(producerId, hasSend)
+	l.chain.Sender().SetHasSend(producerId, hasSend)
 }
 
 func (l *LedgerApi) StopSender(producerId uint8) {
 	l.log.Info("StopSender")
 
-	if l.chain.KafkaSender() == nil {
+	if l.chain.Sender() == nil {
 		return
 	}
-	l.chain.KafkaSender().StopById(producerId)
+	l.chain.Sender().StopById(producerId)
 }
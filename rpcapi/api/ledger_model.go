@@ -4,6 +4,7 @@ import (
 	"errors"
 	"github.com/vitelabs/go-vite/chain"
 	"github.com/vitelabs/go-vite/chain/sender"
+	"github.com/vitelabs/go-vite/chain_db/access"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/ledger"
 	"math/big"
@@ -27,6 +28,9 @@ type AccountBlock struct {
 
 	ConfirmedTimes *string       `json:"confirmedTimes"`
 	TokenInfo      *RpcTokenInfo `json:"tokenInfo"`
+
+	DecodedData *RpcDecodedMethod  `json:"decodedData,omitempty"`
+	DecodedLogs []*RpcDecodedEvent `json:"decodedLogs,omitempty"`
 }
 
 func (ab *AccountBlock) LedgerAccountBlock() (*ledger.AccountBlock, error) {
@@ -158,21 +162,22 @@ func RawTokenInfoToRpc(tinfo *types.TokenInfo, tti types.TokenTypeId) *RpcTokenI
 	return rt
 }
 
-type KafkaSendInfo struct {
-	Producers    []*KafkaProducerInfo `json:"producers"`
-	RunProducers []*KafkaProducerInfo `json:"runProducers"`
-	TotalEvent   uint64               `json:"totalEvent"`
+type SenderInfo struct {
+	Producers    []*ProducerInfo `json:"producers"`
+	RunProducers []*ProducerInfo `json:"runProducers"`
+	TotalEvent   uint64          `json:"totalEvent"`
 }
 
-type KafkaProducerInfo struct {
+type ProducerInfo struct {
 	ProducerId uint8    `json:"producerId"`
-	BrokerList []string `json:"brokerList"`
-	Topic      string   `json:"topic"`
+	SinkId     string   `json:"sinkId"`
+	BrokerList []string `json:"brokerList,omitempty"`
+	Topic      string   `json:"topic,omitempty"`
 	HasSend    uint64   `json:"hasSend"`
 	Status     string   `json:"status"`
 }
 
-func createKafkaProducerInfo(producer *sender.Producer) *KafkaProducerInfo {
+func createProducerInfo(producer *sender.Producer) *ProducerInfo {
 	status := "unknown"
 	switch producer.Status() {
 	case sender.STOPPED:
@@ -181,14 +186,18 @@ func createKafkaProducerInfo(producer *sender.Producer) *KafkaProducerInfo {
 		status = "running"
 	}
 
-	producerInfo := &KafkaProducerInfo{
+	producerInfo := &ProducerInfo{
 		ProducerId: producer.ProducerId(),
-		BrokerList: producer.BrokerList(),
-		Topic:      producer.Topic(),
+		SinkId:     producer.SinkId(),
 		HasSend:    producer.HasSend(),
 		Status:     status,
 	}
 
+	if kafkaSink, ok := producer.Sink().(*sender.KafkaSink); ok {
+		producerInfo.BrokerList = kafkaSink.BrokerList()
+		producerInfo.Topic = kafkaSink.Topic()
+	}
+
 	return producerInfo
 }
 
@@ -223,3 +232,61 @@ func ledgerToRpcBlock(block *ledger.AccountBlock, chain chain.Chain) (*AccountBl
 	rpcAccountBlock.ToAddress = toAddress
 	return rpcAccountBlock, nil
 }
+
+type RpcBlockEvent struct {
+	EventId        string                  `json:"eventId"` // uint64
+	Type           string                  `json:"type"`
+	BlockHashList  []types.Hash            `json:"blockHashList"`
+	AccountBlocks  []*AccountBlock         `json:"accountBlocks,omitempty"`
+	SnapshotBlocks []*ledger.SnapshotBlock `json:"snapshotBlocks,omitempty"`
+}
+
+func blockEventTypeToString(eventType byte) string {
+	switch eventType {
+	case access.AddAccountBlocksEvent:
+		return "InsertAccountBlocks"
+	case access.DeleteAccountBlocksEvent:
+		return "DeleteAccountBlocks"
+	case access.AddSnapshotBlocksEvent:
+		return "InsertSnapshotBlocks"
+	case access.DeleteSnapshotBlocksEvent:
+		return "DeleteSnapshotBlocks"
+	}
+	return "unknown"
+}
+
+func createRpcBlockEvent(event *chain.BlockEvent, c chain.Chain) (*RpcBlockEvent, error) {
+	rpcEvent := &RpcBlockEvent{
+		EventId:        strconv.FormatUint(event.EventId, 10),
+		Type:           blockEventTypeToString(event.EventType),
+		BlockHashList:  event.BlockHashList,
+		SnapshotBlocks: event.SnapshotBlocks,
+	}
+
+	for _, block := range event.AccountBlocks {
+		rpcBlock, err := ledgerToRpcBlock(block, c)
+		if err != nil {
+			return nil, err
+		}
+		rpcEvent.AccountBlocks = append(rpcEvent.AccountBlocks, rpcBlock)
+	}
+	return rpcEvent, nil
+}
+
+const maxTransferCount = 1000
+
+type TransferQuery struct {
+	Direction    string             `json:"direction"` // in or out
+	TokenId      *types.TokenTypeId `json:"tokenId"`
+	BlockType    *byte              `json:"blockType"`
+	Counterparty *types.Address     `json:"counterparty"`
+	StartTime    uint64             `json:"startTime"` // unix seconds
+	EndTime      uint64             `json:"endTime"`   // unix seconds
+	Cursor       *string            `json:"cursor"`
+	Count        uint64             `json:"count"`
+}
+
+type TransferList struct {
+	Blocks     []*AccountBlock `json:"blocks"`
+	NextCursor *string         `json:"nextCursor"`
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/trie"
+	"github.com/vitelabs/go-vite/vm_context"
+)
+
+var ErrSnapshotBlockNotFound = errors.New("snapshot block not found")
+
+// StateProof proves a storage value of an account at a snapshot block in two steps. AccountProof proves the
+// account state hash, the value of the address in the state trie with the root StateHash of the snapshot block,
+// StorageProof proves the value of the key in the storage trie with the root AccountStateHash. AccountStateHash
+// is nil if the account has no state at the snapshot block, Value is empty if the key has no value.
+type StateProof struct {
+	SnapshotHash   types.Hash `json:"snapshotHash"`
+	SnapshotHeight string     `json:"snapshotHeight"`
+	StateHash      types.Hash `json:"stateHash"`
+
+	AccountStateHash *types.Hash `json:"accountStateHash"`
+	AccountProof     []string    `json:"accountProof"`
+
+	Key          string   `json:"key"`
+	Value        string   `json:"value"`
+	StorageProof []string `json:"storageProof"`
+}
+
+type BalanceProof struct {
+	*StateProof
+	Balance string `json:"balance"`
+}
+
+// GetStorageProof returns the proof of the storage value of the hex key of addr at the snapshot block, nil
+// snapshotHash means the latest snapshot block.
+func (l *LedgerApi) GetStorageProof(addr types.Address, key string, snapshotHash *types.Hash) (*StateProof, error) {
+	keyBytes, err := hex.DecodeString(strings.TrimPrefix(key, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	return l.getStateProof(addr, keyBytes, snapshotHash)
+}
+
+// GetBalanceProof returns the proof of the balance of tokenId of addr at the snapshot block, nil snapshotHash
+// means the latest snapshot block.
+func (l *LedgerApi) GetBalanceProof(addr types.Address, tokenId types.TokenTypeId, snapshotHash *types.Hash) (*BalanceProof, error) {
+	proof, err := l.getStateProof(addr, vm_context.BalanceKey(&tokenId), snapshotHash)
+	if err != nil {
+		return nil, err
+	}
+	balance := big.NewInt(0)
+	if len(proof.Value) > 0 {
+		value, _ := hex.DecodeString(proof.Value)
+		balance.SetBytes(value)
+	}
+	return &BalanceProof{StateProof: proof, Balance: balance.String()}, nil
+}
+
+func (l *LedgerApi) getStateProof(addr types.Address, key []byte, snapshotHash *types.Hash) (*StateProof, error) {
+	snapshotBlock, err := l.getSnapshotBlock(0, snapshotHash)
+	if err != nil {
+		return nil, err
+	}
+
+	proof := &StateProof{
+		SnapshotHash:   snapshotBlock.Hash,
+		SnapshotHeight: strconv.FormatUint(snapshotBlock.Height, 10),
+		StateHash:      snapshotBlock.StateHash,
+		Key:            hex.EncodeToString(key),
+	}
+
+	// only the nodes on the paths are read, the state of a pruned snapshot block isn't found
+	stateTrie := l.chain.GetLazyStateTrie(&snapshotBlock.StateHash)
+	if stateHash := stateTrie.Hash(); stateHash == nil || *stateHash != snapshotBlock.StateHash {
+		return nil, ErrStateNotFound
+	}
+	accountProof, err := stateTrie.Prove(addr.Bytes())
+	if err != nil {
+		l.log.Error("Prove account failed, error is "+err.Error(), "method", "getStateProof")
+		return nil, err
+	}
+	proof.AccountProof = encodeProof(accountProof)
+
+	accountStateHash, err := types.BytesToHash(stateTrie.GetValue(addr.Bytes()))
+	if err != nil {
+		return proof, nil
+	}
+	proof.AccountStateHash = &accountStateHash
+
+	storageTrie := l.chain.GetLazyStateTrie(&accountStateHash)
+	if storageHash := storageTrie.Hash(); storageHash == nil || *storageHash != accountStateHash {
+		return nil, ErrStateNotFound
+	}
+	storageProof, err := storageTrie.Prove(key)
+	if err != nil {
+		l.log.Error("Prove storage failed, error is "+err.Error(), "method", "getStateProof")
+		return nil, err
+	}
+	proof.StorageProof = encodeProof(storageProof)
+	proof.Value = hex.EncodeToString(storageTrie.GetValue(key))
+	return proof, nil
+}
+
+func encodeProof(proof trie.Proof) []string {
+	list := make([]string, len(proof))
+	for i, item := range proof {
+		list[i] = hex.EncodeToString(item)
+	}
+	return list
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/trie"
+	"github.com/vitelabs/go-vite/vm_context"
+)
+
+// The state queries take the snapshot block by snapshotHash, or by snapshotHeight if snapshotHash is nil, the
+// latest snapshot block if both are empty. The states are read from the state trie of the snapshot block, so they
+// are the states of the account blocks confirmed by it.
+
+type SnapshotBalances struct {
+	SnapshotHash        types.Hash                                 `json:"snapshotHash"`
+	SnapshotHeight      string                                     `json:"snapshotHeight"`
+	TokenBalanceInfoMap map[types.TokenTypeId]*RpcTokenBalanceInfo `json:"tokenBalanceInfoMap"`
+}
+
+type SnapshotStorage struct {
+	SnapshotHash   types.Hash `json:"snapshotHash"`
+	SnapshotHeight string     `json:"snapshotHeight"`
+	Key            string     `json:"key"`
+	Value          string     `json:"value"`
+}
+
+type StorageItem struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type SnapshotStorageList struct {
+	SnapshotHash   types.Hash     `json:"snapshotHash"`
+	SnapshotHeight string         `json:"snapshotHeight"`
+	List           []*StorageItem `json:"list"`
+	NextKey        *string        `json:"nextKey"`
+}
+
+// maxStorageCount is the max count of the storage values in a page of GetStoragesBySnapshot.
+const maxStorageCount = 1000
+
+// GetBalancesBySnapshot returns the balances of all the tokens of addr at the snapshot block.
+func (l *LedgerApi) GetBalancesBySnapshot(addr types.Address, snapshotHeight uint64, snapshotHash *types.Hash) (*SnapshotBalances, error) {
+	snapshotBlock, storageTrie, err := l.getStorageTrie(addr, snapshotHeight, snapshotHash)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenBalanceInfoMap := make(map[types.TokenTypeId]*RpcTokenBalanceInfo)
+	if storageTrie != nil {
+		keys, values, _ := storageTrie.Range(vm_context.STORAGE_KEY_BALANCE, nil, 0)
+		for i, key := range keys {
+			value := values[i]
+			tokenId, err := types.BytesToTokenTypeId(key[len(vm_context.STORAGE_KEY_BALANCE):])
+			if err != nil {
+				continue
+			}
+			token, _ := l.chain.GetTokenInfoById(&tokenId)
+			tokenBalanceInfoMap[tokenId] = &RpcTokenBalanceInfo{
+				TokenInfo:   RawTokenInfoToRpc(token, tokenId),
+				TotalAmount: new(big.Int).SetBytes(value).String(),
+			}
+		}
+	}
+
+	return &SnapshotBalances{
+		SnapshotHash:        snapshotBlock.Hash,
+		SnapshotHeight:      strconv.FormatUint(snapshotBlock.Height, 10),
+		TokenBalanceInfoMap: tokenBalanceInfoMap,
+	}, nil
+}
+
+// GetStorageBySnapshot returns the storage value of the hex key of addr at the snapshot block, Value is empty if the
+// key has no value.
+func (l *LedgerApi) GetStorageBySnapshot(addr types.Address, key string, snapshotHeight uint64, snapshotHash *types.Hash) (*SnapshotStorage, error) {
+	keyBytes, err := hex.DecodeString(strings.TrimPrefix(key, "0x"))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotBlock, storageTrie, err := l.getStorageTrie(addr, snapshotHeight, snapshotHash)
+	if err != nil {
+		return nil, err
+	}
+
+	storage := &SnapshotStorage{
+		SnapshotHash:   snapshotBlock.Hash,
+		SnapshotHeight: strconv.FormatUint(snapshotBlock.Height, 10),
+		Key:            hex.EncodeToString(keyBytes),
+	}
+	if storageTrie != nil {
+		storage.Value = hex.EncodeToString(storageTrie.GetValue(keyBytes))
+	}
+	return storage, nil
+}
+
+// GetStoragesBySnapshot pages through the storage values of addr of which the keys start with the hex prefix at the
+// snapshot block, in the order of key. A page holds at most count values of which the keys aren't less than the hex
+// start key, count is required and clamped to maxStorageCount. Pass nextKey as start to get the next page, it's nil
+// on the last page.
+func (l *LedgerApi) GetStoragesBySnapshot(addr types.Address, prefix string, start string, count uint64, snapshotHeight uint64, snapshotHash *types.Hash) (*SnapshotStorageList, error) {
+	if count == 0 {
+		return nil, ErrStorageCount
+	}
+	if count > maxStorageCount {
+		count = maxStorageCount
+	}
+
+	prefixBytes, err := hex.DecodeString(strings.TrimPrefix(prefix, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	startBytes, err := hex.DecodeString(strings.TrimPrefix(start, "0x"))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotBlock, storageTrie, err := l.getStorageTrie(addr, snapshotHeight, snapshotHash)
+	if err != nil {
+		return nil, err
+	}
+
+	storageList := &SnapshotStorageList{
+		SnapshotHash:   snapshotBlock.Hash,
+		SnapshotHeight: strconv.FormatUint(snapshotBlock.Height, 10),
+		List:           make([]*StorageItem, 0),
+	}
+	if storageTrie == nil {
+		return storageList, nil
+	}
+
+	keys, values, nextKey := storageTrie.Range(prefixBytes, startBytes, int(count))
+	for i, key := range keys {
+		storageList.List = append(storageList.List, &StorageItem{
+			Key:   hex.EncodeToString(key),
+			Value: hex.EncodeToString(values[i]),
+		})
+	}
+	if nextKey != nil {
+		next := hex.EncodeToString(nextKey)
+		storageList.NextKey = &next
+	}
+	return storageList, nil
+}
+
+func (l *LedgerApi) getSnapshotBlock(snapshotHeight uint64, snapshotHash *types.Hash) (*ledger.SnapshotBlock, error) {
+	var snapshotBlock *ledger.SnapshotBlock
+	var err error
+	if snapshotHash != nil {
+		if snapshotBlock, err = l.chain.GetSnapshotBlockHeadByHash(snapshotHash); err != nil {
+			l.log.Error("GetSnapshotBlockHeadByHash failed, error is "+err.Error(), "method", "getSnapshotBlock")
+			return nil, err
+		}
+	} else if snapshotHeight > 0 {
+		if snapshotBlock, err = l.chain.GetSnapshotBlockHeadByHeight(snapshotHeight); err != nil {
+			l.log.Error("GetSnapshotBlockHeadByHeight failed, error is "+err.Error(), "method", "getSnapshotBlock")
+			return nil, err
+		}
+	} else {
+		snapshotBlock = l.chain.GetLatestSnapshotBlock()
+	}
+	if snapshotBlock == nil {
+		return nil, ErrSnapshotBlockNotFound
+	}
+	return snapshotBlock, nil
+}
+
+// getStorageTrie returns the storage trie of addr at the snapshot block, nil if the account has no state at it. The
+// tries are read lazily, only the visited nodes are loaded.
+func (l *LedgerApi) getStorageTrie(addr types.Address, snapshotHeight uint64, snapshotHash *types.Hash) (*ledger.SnapshotBlock, *trie.LazyTrie, error) {
+	snapshotBlock, err := l.getSnapshotBlock(snapshotHeight, snapshotHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stateTrie := l.chain.GetLazyStateTrie(&snapshotBlock.StateHash)
+	if stateHash := stateTrie.Hash(); stateHash == nil || *stateHash != snapshotBlock.StateHash {
+		return nil, nil, ErrStateNotFound
+	}
+
+	accountStateHash, err := types.BytesToHash(stateTrie.GetValue(addr.Bytes()))
+	if err != nil {
+		return snapshotBlock, nil, nil
+	}
+	storageTrie := l.chain.GetLazyStateTrie(&accountStateHash)
+	if storageHash := storageTrie.Hash(); storageHash == nil || *storageHash != accountStateHash {
+		return nil, nil, ErrStateNotFound
+	}
+	return snapshotBlock, storageTrie, nil
+}
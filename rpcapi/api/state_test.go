@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/vm_context"
+)
+
+// insertStorageBlock inserts a send block of addr which sets the storage values
+func insertStorageBlock(t *testing.T, c chain.Chain, addr types.Address, storage map[string]string) *ledger.AccountBlock {
+	vmContext, err := vm_context.NewVmContext(c, nil, nil, &addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	block := &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeSendCall,
+		AccountAddress: addr,
+		ToAddress:      addr,
+		TokenId:        ledger.ViteTokenId,
+		Amount:         big.NewInt(0),
+		Fee:            big.NewInt(0),
+		Height:         1,
+		SnapshotHash:   c.GetLatestSnapshotBlock().Hash,
+		Timestamp:      &now,
+	}
+	if latestBlock, _ := c.GetLatestAccountBlock(&addr); latestBlock != nil {
+		block.Height = latestBlock.Height + 1
+		block.PrevHash = latestBlock.Hash
+	}
+
+	for key, value := range storage {
+		vmContext.SetStorage([]byte(key), []byte(value))
+	}
+	if stateHash := vmContext.GetStorageHash(); stateHash != nil {
+		block.StateHash = *stateHash
+	}
+	block.Hash = block.ComputeHash()
+
+	if err := c.InsertAccountBlocks([]*vm_context.VmAccountBlock{{AccountBlock: block, VmContext: vmContext}}); err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+func TestLedgerApi_GetStoragesBySnapshot(t *testing.T) {
+	c, closeChain := newLogIndexChain(t)
+	defer closeChain()
+	ledgerApi := &LedgerApi{chain: c, log: log15.New("module", "rpc_api/ledger_api")}
+
+	addr, _, _ := types.CreateAddress()
+	storage := make(map[string]string)
+	for i := 0; i < 25; i++ {
+		storage[fmt.Sprintf("key%02d", i)] = fmt.Sprintf("value%d", i)
+	}
+	insertStorageBlock(t, c, addr, storage)
+	oldSnapshotBlock := insertSnapshotBlock(t, c)
+	insertStorageBlock(t, c, addr, map[string]string{"key00": "new value"})
+	insertSnapshotBlock(t, c)
+
+	if _, err := ledgerApi.GetStoragesBySnapshot(addr, "", "", 0, 0, nil); err != ErrStorageCount {
+		t.Fatalf("should get ErrStorageCount without a count, got %v", err)
+	}
+
+	// pages in the order of key at the old snapshot block
+	start := ""
+	var pages int
+	for i := 0; ; pages++ {
+		storageList, err := ledgerApi.GetStoragesBySnapshot(addr, "", start, 10, oldSnapshotBlock.Height, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if storageList.SnapshotHash != oldSnapshotBlock.Hash {
+			t.Fatalf("snapshot block should be %s, got %s", oldSnapshotBlock.Hash, storageList.SnapshotHash)
+		}
+		for _, item := range storageList.List {
+			key := fmt.Sprintf("key%02d", i)
+			if item.Key != hex.EncodeToString([]byte(key)) || item.Value != hex.EncodeToString([]byte(storage[key])) {
+				t.Fatalf("item %d should be %s, got %+v", i, key, item)
+			}
+			i++
+		}
+		if storageList.NextKey == nil {
+			if i != len(storage) {
+				t.Fatalf("should get %d items, got %d", len(storage), i)
+			}
+			break
+		}
+		if len(storageList.List) != 10 {
+			t.Fatalf("a page before the last one should have 10 items, got %d", len(storageList.List))
+		}
+		start = *storageList.NextKey
+	}
+	if pages != 2 {
+		t.Fatalf("should get 3 pages, got %d", pages+1)
+	}
+
+	// the latest storage with a prefix
+	storageList, err := ledgerApi.GetStoragesBySnapshot(addr, hex.EncodeToString([]byte("key0")), "", maxStorageCount+1, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(storageList.List) != 10 || storageList.NextKey != nil {
+		t.Fatalf("should get the 10 items of key0x, got %d", len(storageList.List))
+	}
+	if storageList.List[0].Value != hex.EncodeToString([]byte("new value")) {
+		t.Fatalf("key00 should have the new value, got %s", storageList.List[0].Value)
+	}
+
+	// an account without state
+	otherAddr, _, _ := types.CreateAddress()
+	if storageList, err := ledgerApi.GetStoragesBySnapshot(otherAddr, "", "", 10, 0, nil); err != nil || len(storageList.List) != 0 {
+		t.Fatalf("an account without state should have no storage, got %v", err)
+	}
+}
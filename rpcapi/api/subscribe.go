@@ -0,0 +1,330 @@
+package api
+
+import (
+	"context"
+	"sync"
+
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/rpc"
+	"github.com/vitelabs/go-vite/vite"
+	"github.com/vitelabs/go-vite/vm_context"
+)
+
+const subscriptionBufferSize = 1024
+
+type AccountBlockFilter struct {
+	AddrList []types.Address    `json:"addrList"`
+	TokenId  *types.TokenTypeId `json:"tokenId"`
+}
+
+type AccountBlockMsg struct {
+	Block   *AccountBlock `json:"block"`
+	Removed bool          `json:"removed"`
+}
+
+type SnapshotBlockMsg struct {
+	Block   *ledger.SnapshotBlock `json:"block"`
+	Removed bool                  `json:"removed"`
+}
+
+// OnroadMsg describes a change of an address's onroad set. A new send block to the address opens an onroad block,
+// the receive block of the address closes it. Removed is set when the change was rolled back.
+type OnroadMsg struct {
+	Address       types.Address `json:"address"`
+	SendBlockHash types.Hash    `json:"sendBlockHash"`
+	Block         *AccountBlock `json:"block,omitempty"`
+	Closed        bool          `json:"closed"`
+	Removed       bool          `json:"removed"`
+}
+
+type subscription struct {
+	id       rpc.ID
+	rpcSub   *rpc.Subscription
+	notifier *rpc.Notifier
+	msgCh    chan interface{}
+
+	accountFilter *AccountBlockFilter
+	onroadAddrs   map[types.Address]struct{}
+}
+
+func (s *subscription) matchAccountBlock(block *ledger.AccountBlock) bool {
+	filter := s.accountFilter
+	if filter == nil {
+		return true
+	}
+	if filter.TokenId != nil && block.TokenId != *filter.TokenId {
+		return false
+	}
+	if len(filter.AddrList) == 0 {
+		return true
+	}
+	for _, addr := range filter.AddrList {
+		if addr == block.AccountAddress || (block.IsSendBlock() && addr == block.ToAddress) {
+			return true
+		}
+	}
+	return false
+}
+
+// subscribeChain is the part of chain.Chain used by SubscribeApi
+type subscribeChain interface {
+	RegisterInsertAccountBlocksSuccess(processor chain.InsertProcessorFuncSuccess) uint64
+	RegisterDeleteAccountBlocksSuccess(processor chain.DeleteProcessorFuncSuccess) uint64
+	RegisterInsertSnapshotBlocksSuccess(processor chain.InsertSnapshotBlocksSuccess) uint64
+	RegisterDeleteSnapshotBlocksSuccess(processor chain.DeleteSnapshotBlocksSuccess) uint64
+	UnRegister(listenerId uint64)
+	GetTokenInfoById(tokenId *types.TokenTypeId) (*types.TokenInfo, error)
+}
+
+type SubscribeApi struct {
+	chain subscribeChain
+	log   log15.Logger
+
+	lock              sync.RWMutex
+	accountBlockSubs  map[rpc.ID]*subscription
+	snapshotBlockSubs map[rpc.ID]*subscription
+	onroadSubs        map[rpc.ID]*subscription
+
+	// the chain listeners are registered only while there are subscriptions, so the api created for every
+	// rpc endpoint won`t leak listeners. listenLock is never held with lock, chain callbacks take lock.
+	listenLock  sync.Mutex
+	listening   int
+	listenerIds []uint64
+}
+
+func NewSubscribeApi(vite *vite.Vite) *SubscribeApi {
+	return newSubscribeApi(vite.Chain())
+}
+
+func newSubscribeApi(c subscribeChain) *SubscribeApi {
+	return &SubscribeApi{
+		chain:             c,
+		log:               log15.New("module", "rpc_api/subscribe_api"),
+		accountBlockSubs:  make(map[rpc.ID]*subscription),
+		snapshotBlockSubs: make(map[rpc.ID]*subscription),
+		onroadSubs:        make(map[rpc.ID]*subscription),
+	}
+}
+
+func (s *SubscribeApi) String() string {
+	return "SubscribeApi"
+}
+
+// listen registers the chain listeners when the first subscription starts
+func (s *SubscribeApi) listen() {
+	s.listenLock.Lock()
+	defer s.listenLock.Unlock()
+
+	s.listening++
+	if s.listening > 1 {
+		return
+	}
+
+	s.listenerIds = []uint64{
+		s.chain.RegisterInsertAccountBlocksSuccess(s.onInsertAccountBlocks),
+		s.chain.RegisterDeleteAccountBlocksSuccess(s.onDeleteAccountBlocks),
+		s.chain.RegisterInsertSnapshotBlocksSuccess(s.onInsertSnapshotBlocks),
+		s.chain.RegisterDeleteSnapshotBlocksSuccess(s.onDeleteSnapshotBlocks),
+	}
+}
+
+// unlisten removes the chain listeners when the last subscription ends
+func (s *SubscribeApi) unlisten() {
+	s.listenLock.Lock()
+	defer s.listenLock.Unlock()
+
+	s.listening--
+	if s.listening > 0 {
+		return
+	}
+
+	for _, id := range s.listenerIds {
+		s.chain.UnRegister(id)
+	}
+	s.listenerIds = nil
+}
+
+// NewAccountBlocks streams inserted and rolled back account blocks. A block matches the filter if its
+// account address or, for send blocks, its to address is in AddrList and its token equals TokenId.
+func (s *SubscribeApi) NewAccountBlocks(ctx context.Context, filter *AccountBlockFilter) (*rpc.Subscription, error) {
+	sub, rpcSub, err := s.newSubscription(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sub.accountFilter = filter
+
+	s.serve(sub, s.accountBlockSubs)
+	return rpcSub, nil
+}
+
+// NewSnapshotBlocks streams inserted and rolled back snapshot blocks.
+func (s *SubscribeApi) NewSnapshotBlocks(ctx context.Context) (*rpc.Subscription, error) {
+	sub, rpcSub, err := s.newSubscription(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.serve(sub, s.snapshotBlockSubs)
+	return rpcSub, nil
+}
+
+// NewOnroadBlocks streams the opening and closing of onroad blocks of the given addresses.
+func (s *SubscribeApi) NewOnroadBlocks(ctx context.Context, addrList []types.Address) (*rpc.Subscription, error) {
+	sub, rpcSub, err := s.newSubscription(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sub.onroadAddrs = make(map[types.Address]struct{}, len(addrList))
+	for _, addr := range addrList {
+		sub.onroadAddrs[addr] = struct{}{}
+	}
+
+	s.serve(sub, s.onroadSubs)
+	return rpcSub, nil
+}
+
+func (s *SubscribeApi) newSubscription(ctx context.Context) (*subscription, *rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, nil, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+	return &subscription{
+		id:       rpcSub.ID,
+		rpcSub:   rpcSub,
+		notifier: notifier,
+		msgCh:    make(chan interface{}, subscriptionBufferSize),
+	}, rpcSub, nil
+}
+
+func (s *SubscribeApi) serve(sub *subscription, subs map[rpc.ID]*subscription) {
+	s.listen()
+
+	s.lock.Lock()
+	subs[sub.id] = sub
+	s.lock.Unlock()
+
+	go func() {
+		defer func() {
+			s.lock.Lock()
+			delete(subs, sub.id)
+			s.lock.Unlock()
+
+			s.unlisten()
+		}()
+
+		// notifications sent before the subscription is activated are dropped, so keep them in msgCh
+		select {
+		case <-sub.rpcSub.Activated():
+		case <-sub.rpcSub.Err():
+			return
+		case <-sub.notifier.Closed():
+			return
+		}
+
+		for {
+			select {
+			case msg := <-sub.msgCh:
+				if err := sub.notifier.Notify(sub.id, msg); err != nil {
+					s.log.Info("notify failed, error is "+err.Error(), "method", "serve", "id", sub.id)
+					return
+				}
+			case <-sub.rpcSub.Err():
+				return
+			case <-sub.notifier.Closed():
+				return
+			}
+		}
+	}()
+}
+
+func (s *SubscribeApi) send(sub *subscription, msg interface{}) {
+	select {
+	case sub.msgCh <- msg:
+	default:
+		s.log.Warn("subscription buffer is full, message dropped", "method", "send", "id", sub.id)
+	}
+}
+
+func (s *SubscribeApi) onInsertAccountBlocks(blocks []*vm_context.VmAccountBlock) {
+	accountBlocks := make([]*ledger.AccountBlock, 0, len(blocks))
+	for _, block := range blocks {
+		accountBlocks = append(accountBlocks, block.AccountBlock)
+	}
+	s.notifyAccountBlocks(accountBlocks, false)
+}
+
+func (s *SubscribeApi) onDeleteAccountBlocks(subLedger map[types.Address][]*ledger.AccountBlock) {
+	for _, blocks := range subLedger {
+		s.notifyAccountBlocks(blocks, true)
+	}
+}
+
+func (s *SubscribeApi) onInsertSnapshotBlocks(blocks []*ledger.SnapshotBlock) {
+	s.notifySnapshotBlocks(blocks, false)
+}
+
+func (s *SubscribeApi) onDeleteSnapshotBlocks(blocks []*ledger.SnapshotBlock) {
+	s.notifySnapshotBlocks(blocks, true)
+}
+
+func (s *SubscribeApi) notifyAccountBlocks(blocks []*ledger.AccountBlock, removed bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if len(s.accountBlockSubs) <= 0 && len(s.onroadSubs) <= 0 {
+		return
+	}
+
+	for _, block := range blocks {
+		var rpcBlock *AccountBlock
+		toRpcBlock := func() *AccountBlock {
+			if rpcBlock == nil {
+				token, _ := s.chain.GetTokenInfoById(&block.TokenId)
+				rpcBlock = createAccountBlock(block, token, 0)
+			}
+			return rpcBlock
+		}
+
+		for _, sub := range s.accountBlockSubs {
+			if sub.matchAccountBlock(block) {
+				s.send(sub, &AccountBlockMsg{Block: toRpcBlock(), Removed: removed})
+			}
+		}
+
+		for _, sub := range s.onroadSubs {
+			if block.IsSendBlock() {
+				if _, ok := sub.onroadAddrs[block.ToAddress]; ok {
+					s.send(sub, &OnroadMsg{
+						Address:       block.ToAddress,
+						SendBlockHash: block.Hash,
+						Block:         toRpcBlock(),
+						Removed:       removed,
+					})
+				}
+			} else if _, ok := sub.onroadAddrs[block.AccountAddress]; ok {
+				s.send(sub, &OnroadMsg{
+					Address:       block.AccountAddress,
+					SendBlockHash: block.FromBlockHash,
+					Closed:        true,
+					Removed:       removed,
+				})
+			}
+		}
+	}
+}
+
+func (s *SubscribeApi) notifySnapshotBlocks(blocks []*ledger.SnapshotBlock, removed bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for _, block := range blocks {
+		for _, sub := range s.snapshotBlockSubs {
+			s.send(sub, &SnapshotBlockMsg{Block: block, Removed: removed})
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/rpc"
+	"github.com/vitelabs/go-vite/vm_context"
+)
+
+type mockSubscribeChain struct {
+	lock      sync.Mutex
+	id        uint64
+	listeners map[uint64]interface{}
+}
+
+func newMockSubscribeChain() *mockSubscribeChain {
+	return &mockSubscribeChain{
+		listeners: make(map[uint64]interface{}),
+	}
+}
+
+func (c *mockSubscribeChain) register(processor interface{}) uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.id++
+	c.listeners[c.id] = processor
+	return c.id
+}
+
+func (c *mockSubscribeChain) count() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return len(c.listeners)
+}
+
+func (c *mockSubscribeChain) RegisterInsertAccountBlocksSuccess(processor chain.InsertProcessorFuncSuccess) uint64 {
+	return c.register(processor)
+}
+
+func (c *mockSubscribeChain) RegisterDeleteAccountBlocksSuccess(processor chain.DeleteProcessorFuncSuccess) uint64 {
+	return c.register(processor)
+}
+
+func (c *mockSubscribeChain) RegisterInsertSnapshotBlocksSuccess(processor chain.InsertSnapshotBlocksSuccess) uint64 {
+	return c.register(processor)
+}
+
+func (c *mockSubscribeChain) RegisterDeleteSnapshotBlocksSuccess(processor chain.DeleteSnapshotBlocksSuccess) uint64 {
+	return c.register(processor)
+}
+
+func (c *mockSubscribeChain) UnRegister(listenerId uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.listeners, listenerId)
+}
+
+func (c *mockSubscribeChain) GetTokenInfoById(tokenId *types.TokenTypeId) (*types.TokenInfo, error) {
+	return nil, nil
+}
+
+func newSubscribeClient(t *testing.T) (*SubscribeApi, *mockSubscribeChain, *rpc.Client) {
+	c := newMockSubscribeChain()
+	api := newSubscribeApi(c)
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("subscribe", api); err != nil {
+		t.Fatal(err)
+	}
+
+	return api, c, rpc.DialInProc(server)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timeout")
+}
+
+func TestSubscribeApi_Listeners(t *testing.T) {
+	_, c, client := newSubscribeClient(t)
+	defer client.Close()
+
+	if c.count() != 0 {
+		t.Fatal("listeners should not be registered before any subscription")
+	}
+
+	ch1 := make(chan *SnapshotBlockMsg)
+	sub1, err := client.Subscribe(context.Background(), "subscribe", ch1, "newSnapshotBlocks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch2 := make(chan *SnapshotBlockMsg)
+	sub2, err := client.Subscribe(context.Background(), "subscribe", ch2, "newSnapshotBlocks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.count() != 4 {
+		t.Fatalf("listeners should be registered once, got %d", c.count())
+	}
+
+	sub1.Unsubscribe()
+	time.Sleep(50 * time.Millisecond)
+	if c.count() != 4 {
+		t.Fatal("listeners should be kept while there are subscriptions")
+	}
+
+	sub2.Unsubscribe()
+	waitFor(t, func() bool {
+		return c.count() == 0
+	})
+}
+
+func TestSubscribeApi_NewSnapshotBlocks(t *testing.T) {
+	api, _, client := newSubscribeClient(t)
+	defer client.Close()
+
+	ch := make(chan *SnapshotBlockMsg, 10)
+	sub, err := client.Subscribe(context.Background(), "subscribe", ch, "newSnapshotBlocks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	block := &ledger.SnapshotBlock{Height: 10}
+	api.onInsertSnapshotBlocks([]*ledger.SnapshotBlock{block})
+	api.onDeleteSnapshotBlocks([]*ledger.SnapshotBlock{block})
+
+	for _, removed := range []bool{false, true} {
+		select {
+		case msg := <-ch:
+			if msg.Block.Height != block.Height || msg.Removed != removed {
+				t.Fatalf("wrong message: height %d, removed %v", msg.Block.Height, msg.Removed)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+}
+
+func TestSubscribeApi_NewOnroadBlocks(t *testing.T) {
+	api, _, client := newSubscribeClient(t)
+	defer client.Close()
+
+	var addr, other types.Address
+	addr[0], other[0] = 1, 2
+
+	ch := make(chan *OnroadMsg, 10)
+	sub, err := client.Subscribe(context.Background(), "subscribe", ch, "newOnroadBlocks", []types.Address{addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	send := &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeSendCall,
+		AccountAddress: other,
+		ToAddress:      addr,
+		Hash:           types.DataHash([]byte("send")),
+	}
+	unrelated := &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeSendCall,
+		AccountAddress: addr,
+		ToAddress:      other,
+		Hash:           types.DataHash([]byte("unrelated")),
+	}
+	receive := &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeReceive,
+		AccountAddress: addr,
+		FromBlockHash:  send.Hash,
+		Hash:           types.DataHash([]byte("receive")),
+	}
+
+	api.onInsertAccountBlocks([]*vm_context.VmAccountBlock{{AccountBlock: send}, {AccountBlock: unrelated}, {AccountBlock: receive}})
+	api.onDeleteAccountBlocks(map[types.Address][]*ledger.AccountBlock{addr: {receive}})
+
+	expected := []struct {
+		closed, removed bool
+	}{{false, false}, {true, false}, {true, true}}
+
+	for _, e := range expected {
+		select {
+		case msg := <-ch:
+			if msg.Address != addr || msg.SendBlockHash != send.Hash || msg.Closed != e.closed || msg.Removed != e.removed {
+				t.Fatalf("wrong message: %+v", msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+
+	select {
+	case msg := <-ch:
+		t.Fatalf("unexpected message: %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
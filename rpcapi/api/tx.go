@@ -2,6 +2,7 @@ package api
 
 import (
 	"errors"
+	"github.com/vitelabs/go-vite/chain"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/crypto/ed25519"
 	"github.com/vitelabs/go-vite/generator"
@@ -10,6 +11,7 @@ import (
 	"github.com/vitelabs/go-vite/vite"
 	"github.com/vitelabs/go-vite/vm/contracts/abi"
 	"math/big"
+	"strconv"
 )
 
 var preCompiledContracts = []types.Address{
@@ -143,6 +145,30 @@ func (t Tx) SendTxWithPrivateKey(param SendTxWithPrivateKeyParam) (*AccountBlock
 
 }
 
+type QuotaEstimate struct {
+	SendQuota    string `json:"sendQuota"`
+	ReceiveQuota string `json:"receiveQuota"`
+	Error        string `json:"error,omitempty"`
+}
+
+// EstimateQuota simulates a send call block and, for contract to addresses, its receive block and returns
+// the quota used by both sides.
+func (t Tx) EstimateQuota(param CallParam) (*QuotaEstimate, error) {
+	return estimateQuota(t.vite.Chain(), param)
+}
+
+func estimateQuota(chain chain.Chain, param CallParam) (*QuotaEstimate, error) {
+	result, err := simulateCall(chain, param)
+	if err != nil {
+		return nil, err
+	}
+	return &QuotaEstimate{
+		SendQuota:    strconv.FormatUint(result.SendQuota(), 10),
+		ReceiveQuota: strconv.FormatUint(result.ReceiveQuota(), 10),
+		Error:        simulateErrorString(result.Err),
+	}, nil
+}
+
 func isPreCompiledContracts(address types.Address) bool {
 	for _, v := range preCompiledContracts {
 		if v == address {
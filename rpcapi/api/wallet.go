@@ -48,6 +48,19 @@ type CreateTransferTxParms struct {
 	Difficulty       *string           `json:"difficulty,omitempty"`
 }
 
+type CreateContractParms struct {
+	EntropystoreFile *string           `json:"entropystoreFile,omitempty"`
+	SelfAddr         types.Address     `json:"selfAddr"`
+	Gid              types.Gid         `json:"gid"`
+	TokenTypeId      types.TokenTypeId `json:"tokenTypeId"`
+	Passphrase       string            `json:"passphrase"`
+	Amount           string            `json:"amount"`
+	HexCode          string            `json:"hexCode"`
+	Abi              string            `json:"abi,omitempty"`
+	Params           []string          `json:"params,omitempty"`
+	Difficulty       *string           `json:"difficulty,omitempty"`
+}
+
 type IsMayValidKeystoreFileResponse struct {
 	Maybe      bool
 	MayAddress types.Address
@@ -352,6 +365,77 @@ func (m WalletApi) CreateTxWithPassphrase(params CreateTransferTxParms) error {
 
 }
 
+// CreateContractWithPassphrase deploys bytecode with the constructor params packed by abi and returns the address of
+// the new contract. The contract is created when the send block is received by the producers of gid.
+func (m WalletApi) CreateContractWithPassphrase(params CreateContractParms) (*types.Address, error) {
+	amount, ok := new(big.Int).SetString(params.Amount, 10)
+	if !ok {
+		return nil, ErrStrToBigInt
+	}
+	var difficulty *big.Int = nil
+	if params.Difficulty != nil {
+		difficulty, ok = new(big.Int).SetString(*params.Difficulty, 10)
+		if !ok {
+			return nil, ErrStrToBigInt
+		}
+	}
+	data, err := getCreateContractData(params.Gid, params.HexCode, params.Abi, params.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &generator.IncomingMessage{
+		BlockType:      ledger.BlockTypeSendCreate,
+		AccountAddress: params.SelfAddr,
+		TokenId:        &params.TokenTypeId,
+		Amount:         amount,
+		Fee:            nil,
+		Difficulty:     difficulty,
+		Data:           data,
+	}
+
+	fitestSnapshotBlockHash, err := generator.GetFitestGeneratorSnapshotHash(m.chain, nil)
+	if err != nil {
+		return nil, err
+	}
+	g, e := generator.NewGenerator(m.chain, fitestSnapshotBlockHash, nil, &params.SelfAddr)
+	if e != nil {
+		return nil, e
+	}
+
+	result, e := g.GenerateWithMessage(msg, func(addr types.Address, data []byte) (signedData, pubkey []byte, err error) {
+		if params.EntropystoreFile != nil {
+			manager, e := m.wallet.GetEntropyStoreManager(*params.EntropystoreFile)
+			if e != nil {
+				return nil, nil, e
+			}
+			return manager.SignDataWithPassphrase(addr, params.Passphrase, data)
+		}
+
+		_, key, _, e := m.wallet.GlobalFindAddrWithPassphrase(addr, params.Passphrase)
+		if e != nil {
+			return nil, nil, e
+		}
+		return key.SignData(data)
+	})
+
+	if e != nil {
+		newerr, _ := TryMakeConcernedError(e)
+		return nil, newerr
+	}
+	if result.Err != nil {
+		newerr, _ := TryMakeConcernedError(result.Err)
+		return nil, newerr
+	}
+	if len(result.BlockGenList) == 0 || result.BlockGenList[0] == nil {
+		return nil, errors.New("generator gen an empty block")
+	}
+	if err := m.pool.AddDirectAccountBlock(params.SelfAddr, result.BlockGenList[0]); err != nil {
+		return nil, err
+	}
+	return &result.BlockGenList[0].AccountBlock.ToAddress, nil
+}
+
 func (m WalletApi) SignDataWithPassphrase(addr types.Address, hexMsg string, passphrase string) (*HexSignedTuple, error) {
 
 	msgbytes, err := hex.DecodeString(hexMsg)
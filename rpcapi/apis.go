@@ -28,6 +28,20 @@ func GetApi(vite *vite.Vite, apiModule string) rpc.API {
 			Service:   api.NewPrivateOnroadApi(vite),
 			Public:    false,
 		}
+	case "private_debug":
+		return rpc.API{
+			Namespace: "debug",
+			Version:   "1.0",
+			Service:   api.NewPrivateDebugApi(vite),
+			Public:    false,
+		}
+	case "private_contract":
+		return rpc.API{
+			Namespace: "contract",
+			Version:   "1.0",
+			Service:   api.NewPrivateContractApi(vite),
+			Public:    false,
+		}
 		// public  WS HTTP IPC
 	case "pow":
 		return rpc.API{
@@ -115,6 +129,27 @@ func GetApi(vite *vite.Vite, apiModule string) rpc.API {
 			Service:   api.NewTestApi(api.NewWalletApi(vite)),
 			Public:    true,
 		}
+	case "subscribe":
+		return rpc.API{
+			Namespace: "subscribe",
+			Version:   "1.0",
+			Service:   api.NewSubscribeApi(vite),
+			Public:    true,
+		}
+	case "filter":
+		return rpc.API{
+			Namespace: "filter",
+			Version:   "1.0",
+			Service:   api.NewFilterApi(vite),
+			Public:    true,
+		}
+	case "admin":
+		return rpc.API{
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   api.NewAdminApi(vite),
+			Public:    false,
+		}
 	case "debug":
 		return rpc.API{
 			Namespace: "debug",
@@ -136,9 +171,9 @@ func GetApis(vite *vite.Vite, apiModule ...string) []rpc.API {
 }
 
 func GetPublicApis(vite *vite.Vite) []rpc.API {
-	return GetApis(vite, "ledger", "public_onroad", "net", "contract", "pledge", "register", "vote", "mintage", "consensusGroup", "testapi", "pow", "tx", "debug")
+	return GetApis(vite, "ledger", "public_onroad", "net", "contract", "pledge", "register", "vote", "mintage", "consensusGroup", "testapi", "pow", "tx", "debug", "subscribe", "filter")
 }
 
 func GetAllApis(vite *vite.Vite) []rpc.API {
-	return GetApis(vite, "ledger", "wallet", "private_onroad", "net", "contract", "pledge", "register", "vote", "mintage", "consensusGroup", "testapi", "pow", "tx", "debug")
+	return GetApis(vite, "ledger", "wallet", "private_onroad", "net", "admin", "contract", "private_contract", "pledge", "register", "vote", "mintage", "consensusGroup", "testapi", "pow", "tx", "debug", "private_debug", "subscribe", "filter")
 }
@@ -0,0 +1,158 @@
+package statedump
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/trie"
+	"github.com/vitelabs/go-vite/vm_context"
+)
+
+var ErrSnapshotBlockNotFound = errors.New("snapshot block not found")
+
+// AccountState is the state of an account at a snapshot block, the state of the latest account block confirmed by
+// the snapshot block.
+type AccountState struct {
+	Address  types.Address
+	Height   uint64
+	Hash     types.Hash
+	Balances map[types.TokenTypeId]*big.Int
+}
+
+// Dumper reads the states of all the accounts at a snapshot block, in the order of account id.
+type Dumper struct {
+	chain         chain.Chain
+	snapshotBlock *ledger.SnapshotBlock
+	tokenId       *types.TokenTypeId
+
+	accountId     uint64
+	lastAccountId uint64
+}
+
+// NewDumper returns the dumper of the account states at the snapshot block of snapshotHeight, 0 means the latest.
+// Only the balances of tokenId are read if it isn't nil. c doesn't need to be started, it's only read.
+func NewDumper(c chain.Chain, snapshotHeight uint64, tokenId *types.TokenTypeId) (*Dumper, error) {
+	var snapshotBlock *ledger.SnapshotBlock
+	var err error
+	if snapshotHeight > 0 {
+		snapshotBlock, err = c.GetSnapshotBlockHeadByHeight(snapshotHeight)
+	} else {
+		snapshotBlock, err = c.ChainDb().Sc.GetLatestBlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if snapshotBlock == nil {
+		return nil, ErrSnapshotBlockNotFound
+	}
+
+	// the accounts created after the snapshot block have no state at it, they are skipped
+	lastAccountId, err := c.ChainDb().Account.GetLastAccountId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Dumper{
+		chain:         c,
+		snapshotBlock: snapshotBlock,
+		tokenId:       tokenId,
+		lastAccountId: lastAccountId,
+	}, nil
+}
+
+func (d *Dumper) SnapshotBlock() *ledger.SnapshotBlock {
+	return d.snapshotBlock
+}
+
+// Next returns the state of the next account which has balances at the snapshot block, io.EOF is returned after the
+// last account.
+func (d *Dumper) Next() (*AccountState, error) {
+	for d.accountId < d.lastAccountId {
+		d.accountId++
+
+		addr, err := d.chain.ChainDb().Account.GetAddressById(d.accountId)
+		if err != nil {
+			return nil, fmt.Errorf("GetAddressById %d failed: %v", d.accountId, err)
+		}
+
+		block, err := d.chain.GetConfirmAccountBlock(d.snapshotBlock.Height, addr)
+		if err != nil {
+			return nil, fmt.Errorf("GetConfirmAccountBlock of %s failed: %v", addr, err)
+		}
+		if block == nil {
+			continue
+		}
+
+		stateTrie := d.chain.GetStateTrie(&block.StateHash)
+		if stateHash := stateTrie.Hash(); stateHash == nil || *stateHash != block.StateHash {
+			return nil, fmt.Errorf("state of account block %s of %s not found, it may have been pruned", block.Hash, addr)
+		}
+
+		balances := d.balances(stateTrie)
+		if len(balances) <= 0 {
+			continue
+		}
+		return &AccountState{
+			Address:  *addr,
+			Height:   block.Height,
+			Hash:     block.Hash,
+			Balances: balances,
+		}, nil
+	}
+	return nil, io.EOF
+}
+
+func (d *Dumper) balances(stateTrie *trie.Trie) map[types.TokenTypeId]*big.Int {
+	balances := make(map[types.TokenTypeId]*big.Int)
+	if d.tokenId != nil {
+		if value := stateTrie.GetValue(vm_context.BalanceKey(d.tokenId)); len(value) > 0 {
+			if balance := new(big.Int).SetBytes(value); balance.Sign() > 0 {
+				balances[*d.tokenId] = balance
+			}
+		}
+		return balances
+	}
+
+	prefix := vm_context.STORAGE_KEY_BALANCE
+	iter := stateTrie.NewIterator(prefix)
+	for {
+		key, value, ok := iter.Next()
+		if !ok {
+			break
+		}
+
+		tokenId, err := types.BytesToTokenTypeId(key[len(prefix):])
+		if err != nil {
+			continue
+		}
+		if balance := new(big.Int).SetBytes(value); balance.Sign() > 0 {
+			balances[tokenId] = balance
+		}
+	}
+	return balances
+}
+
+// Dump writes the states of all the accounts of d into w, it returns the number of the accounts.
+func Dump(d *Dumper, w Writer) (uint64, error) {
+	count := uint64(0)
+	for {
+		state, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+
+		if err := w.Write(state); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, w.Flush()
+}
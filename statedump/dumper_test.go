@@ -0,0 +1,140 @@
+package statedump
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vitelabs/go-vite/chain"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/config"
+	"github.com/vitelabs/go-vite/generator"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/vm"
+)
+
+func insertSnapshotBlock(t *testing.T, c chain.Chain) {
+	latestBlock := c.GetLatestSnapshotBlock()
+	now := time.Now()
+	snapshotBlock := &ledger.SnapshotBlock{
+		Height:          latestBlock.Height + 1,
+		PrevHash:        latestBlock.Hash,
+		Timestamp:       &now,
+		SnapshotContent: c.GetNeedSnapshotContent(),
+	}
+
+	stateTrie, err := c.GenStateTrie(latestBlock.StateHash, snapshotBlock.SnapshotContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshotBlock.StateTrie = stateTrie
+	snapshotBlock.StateHash = *stateTrie.Hash()
+	snapshotBlock.Hash = snapshotBlock.ComputeHash()
+
+	if err := c.InsertSnapshotBlock(snapshotBlock); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type dumpedState struct {
+	Address  types.Address     `json:"address"`
+	Height   string            `json:"height"`
+	Hash     types.Hash        `json:"hash"`
+	Balances map[string]string `json:"balances"`
+}
+
+func dump(t *testing.T, c chain.Chain, snapshotHeight uint64, tokenId *types.TokenTypeId) map[types.Address]*dumpedState {
+	d, err := NewDumper(c, snapshotHeight, tokenId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	count, err := Dump(d, NewJSONWriter(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	states := make(map[types.Address]*dumpedState)
+	decoder := json.NewDecoder(buf)
+	for decoder.More() {
+		state := &dumpedState{}
+		if err := decoder.Decode(state); err != nil {
+			t.Fatal(err)
+		}
+		states[state.Address] = state
+	}
+	if uint64(len(states)) != count {
+		t.Fatalf("%d accounts are dumped, %d lines are written", count, len(states))
+	}
+	return states
+}
+
+func TestDump(t *testing.T) {
+	vm.InitVmConfig(true, false)
+
+	dataDir, err := ioutil.TempDir("", "statedump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+
+	c := chain.NewChain(&config.Config{DataDir: dataDir, Chain: &config.Chain{LedgerInMemory: true}})
+	c.Init()
+	c.Start()
+	defer func() {
+		c.Stop()
+		c.Destroy()
+	}()
+
+	genesisAddr := chain.GenesisMintageSendBlock.ToAddress
+	beforeHeight := c.GetLatestSnapshotBlock().Height
+
+	gen, err := generator.NewGenerator(c, nil, nil, &genesisAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genResult, err := gen.GenerateWithOnroad(chain.GenesisMintageSendBlock, nil, nil, nil)
+	if err != nil || genResult.Err != nil || len(genResult.BlockGenList) <= 0 {
+		t.Fatalf("generate block failed, %v %v", err, genResult.Err)
+	}
+	if err := c.InsertAccountBlocks(genResult.BlockGenList); err != nil {
+		t.Fatal(err)
+	}
+	insertSnapshotBlock(t, c)
+
+	if _, ok := dump(t, c, beforeHeight, nil)[genesisAddr]; ok {
+		t.Fatal("genesis account has no balance before receiving the mintage")
+	}
+
+	states := dump(t, c, 0, nil)
+	state, ok := states[genesisAddr]
+	if !ok || state.Hash != genResult.BlockGenList[0].AccountBlock.Hash {
+		t.Fatal("genesis account should be dumped with its receive block")
+	}
+	if balance := state.Balances[ledger.ViteTokenId.String()]; balance != chain.GenesisMintageSendBlock.Amount.String() {
+		t.Fatalf("balance of genesis account is %v, should be %v", balance, chain.GenesisMintageSendBlock.Amount)
+	}
+
+	otherTokenId := types.CreateTokenTypeId([]byte("other"))
+	if states := dump(t, c, 0, &otherTokenId); len(states) != 0 {
+		t.Fatal("no account has the other token")
+	}
+
+	d, err := NewDumper(c, 0, &ledger.ViteTokenId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if _, err := Dump(d, NewCSVWriter(buf)); err != nil {
+		t.Fatal(err)
+	}
+	expected := genesisAddr.String() + "," + ledger.ViteTokenId.String() + "," + chain.GenesisMintageSendBlock.Amount.String()
+	if !strings.Contains(buf.String(), expected) || !strings.HasPrefix(buf.String(), "address,tokenId,balance,height,hash\n") {
+		t.Fatalf("csv is\n%s", buf.String())
+	}
+}
@@ -0,0 +1,126 @@
+package statedump
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/vitelabs/go-vite/common/types"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Writer writes the account states in a format.
+type Writer interface {
+	Write(state *AccountState) error
+	Flush() error
+}
+
+// NewWriter returns the writer of format into w.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w), nil
+	case FormatJSON:
+		return NewJSONWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q, it should be %s or %s", format, FormatCSV, FormatJSON)
+	}
+}
+
+type jsonAccountState struct {
+	Address  types.Address                `json:"address"`
+	Height   string                       `json:"height"`
+	Hash     types.Hash                   `json:"hash"`
+	Balances map[types.TokenTypeId]string `json:"balances"`
+}
+
+func (s *AccountState) MarshalJSON() ([]byte, error) {
+	balances := make(map[types.TokenTypeId]string, len(s.Balances))
+	for tokenId, balance := range s.Balances {
+		balances[tokenId] = balance.String()
+	}
+	return json.Marshal(&jsonAccountState{
+		Address:  s.Address,
+		Height:   strconv.FormatUint(s.Height, 10),
+		Hash:     s.Hash,
+		Balances: balances,
+	})
+}
+
+func (s *AccountState) sortedTokenIds() []types.TokenTypeId {
+	tokenIds := make([]types.TokenTypeId, 0, len(s.Balances))
+	for tokenId := range s.Balances {
+		tokenIds = append(tokenIds, tokenId)
+	}
+	sort.Slice(tokenIds, func(i, j int) bool {
+		return bytes.Compare(tokenIds[i].Bytes(), tokenIds[j].Bytes()) < 0
+	})
+	return tokenIds
+}
+
+type csvWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+// NewCSVWriter returns the writer of a row for each balance of an account, with the header
+// address,tokenId,balance,height,hash.
+func NewCSVWriter(w io.Writer) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (w *csvWriter) Write(state *AccountState) error {
+	if !w.wroteHeader {
+		if err := w.w.Write([]string{"address", "tokenId", "balance", "height", "hash"}); err != nil {
+			return err
+		}
+		w.wroteHeader = true
+	}
+
+	height := strconv.FormatUint(state.Height, 10)
+	for _, tokenId := range state.sortedTokenIds() {
+		record := []string{state.Address.String(), tokenId.String(), state.Balances[tokenId].String(), height, state.Hash.String()}
+		if err := w.w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type jsonWriter struct {
+	w *bufio.Writer
+}
+
+// NewJSONWriter returns the writer of a JSON line for each account.
+func NewJSONWriter(w io.Writer) Writer {
+	return &jsonWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *jsonWriter) Write(state *AccountState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Write(data); err != nil {
+		return err
+	}
+	return w.w.WriteByte('\n')
+}
+
+func (w *jsonWriter) Flush() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,204 @@
+package trie
+
+import (
+	"errors"
+
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/crypto"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+var ErrMissingNode = errors.New("trie node is missing")
+
+// NodeExporter reads the nodes and ref values of tries from db, the nodes shared by the exported tries are exported
+// once.
+type NodeExporter struct {
+	trie *Trie
+
+	exported map[types.Hash]struct{}
+}
+
+func NewNodeExporter(db database.KeyValueStore) *NodeExporter {
+	return &NodeExporter{
+		trie: &Trie{
+			db:  db,
+			log: log15.New("module", "trie_exporter"),
+		},
+		exported: make(map[types.Hash]struct{}),
+	}
+}
+
+// Export calls nodeFunc with the serialized nodes of the trie with rootHash which haven't been exported before and
+// refValueFunc with the ref values of its hash nodes. leafFunc is called with the values of the exported value nodes.
+func (e *NodeExporter) Export(rootHash *types.Hash, nodeFunc func(data []byte) error, refValueFunc func(value []byte) error, leafFunc func(value []byte) error) error {
+	if rootHash == nil {
+		return nil
+	}
+	return e.export(rootHash, nodeFunc, refValueFunc, leafFunc)
+}
+
+func (e *NodeExporter) export(hash *types.Hash, nodeFunc func(data []byte) error, refValueFunc func(value []byte) error, leafFunc func(value []byte) error) error {
+	if _, ok := e.exported[*hash]; ok {
+		return nil
+	}
+	node := e.trie.getNodeFromDb(hash)
+	if node == nil {
+		return ErrMissingNode
+	}
+
+	data, err := node.DbSerialize()
+	if err != nil {
+		return err
+	}
+	if err := nodeFunc(data); err != nil {
+		return err
+	}
+	e.exported[*hash] = struct{}{}
+
+	switch node.NodeType() {
+	case TRIE_FULL_NODE:
+		if node.child != nil {
+			if err := e.export(node.child.Hash(), nodeFunc, refValueFunc, leafFunc); err != nil {
+				return err
+			}
+		}
+		for _, child := range node.children {
+			if err := e.export(child.Hash(), nodeFunc, refValueFunc, leafFunc); err != nil {
+				return err
+			}
+		}
+	case TRIE_SHORT_NODE:
+		return e.export(node.child.Hash(), nodeFunc, refValueFunc, leafFunc)
+	case TRIE_HASH_NODE:
+		value, err := e.trie.getRefValue(node.value)
+		if err != nil {
+			return err
+		}
+		return refValueFunc(value)
+	case TRIE_VALUE_NODE:
+		if leafFunc != nil {
+			return leafFunc(node.value)
+		}
+	}
+	return nil
+}
+
+// NodeSet collects the untrusted nodes and ref values of tries, only the nodes of the tries which have been verified
+// by their root hashes are saved.
+type NodeSet struct {
+	nodes     map[types.Hash][]byte
+	refValues map[types.Hash][]byte
+
+	verifiedNodes     map[types.Hash]*TrieNode
+	verifiedRefValues map[types.Hash][]byte
+}
+
+func NewNodeSet() *NodeSet {
+	return &NodeSet{
+		nodes:     make(map[types.Hash][]byte),
+		refValues: make(map[types.Hash][]byte),
+
+		verifiedNodes:     make(map[types.Hash]*TrieNode),
+		verifiedRefValues: make(map[types.Hash][]byte),
+	}
+}
+
+// AddNode adds a serialized node, the node is indexed by the hash computed from its content.
+func (s *NodeSet) AddNode(data []byte) error {
+	node := &TrieNode{}
+	if err := node.DbDeserialize(data); err != nil {
+		return err
+	}
+	s.nodes[*node.Hash()] = data
+	return nil
+}
+
+func (s *NodeSet) AddRefValue(value []byte) {
+	valueHash, _ := types.BytesToHash(crypto.Hash256(value))
+	s.refValues[valueHash] = value
+}
+
+// Trie verifies that all the nodes of the trie with rootHash are in the set and returns the trie, which can be read
+// only.
+func (s *NodeSet) Trie(rootHash *types.Hash) (*Trie, error) {
+	trie := &Trie{
+		log: log15.New("module", "trie_node_set"),
+
+		unSavedRefValueMap: make(map[types.Hash][]byte),
+	}
+	if rootHash == nil {
+		return trie, nil
+	}
+
+	root, err := s.load(trie, rootHash)
+	if err != nil {
+		return nil, err
+	}
+	trie.Root = root
+	return trie, nil
+}
+
+func (s *NodeSet) load(trie *Trie, hash *types.Hash) (*TrieNode, error) {
+	data, ok := s.nodes[*hash]
+	if !ok {
+		return nil, ErrMissingNode
+	}
+	node := &TrieNode{}
+	if err := node.DbDeserialize(data); err != nil {
+		return nil, err
+	}
+
+	switch node.NodeType() {
+	case TRIE_FULL_NODE:
+		for key, child := range node.children {
+			childNode, err := s.load(trie, child.Hash())
+			if err != nil {
+				return nil, err
+			}
+			node.children[key] = childNode
+		}
+		if node.child != nil {
+			childNode, err := s.load(trie, node.child.Hash())
+			if err != nil {
+				return nil, err
+			}
+			node.child = childNode
+		}
+	case TRIE_SHORT_NODE:
+		childNode, err := s.load(trie, node.child.Hash())
+		if err != nil {
+			return nil, err
+		}
+		node.child = childNode
+	case TRIE_HASH_NODE:
+		valueHash, err := types.BytesToHash(node.value)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := s.refValues[valueHash]
+		if !ok {
+			return nil, ErrMissingNode
+		}
+		trie.unSavedRefValueMap[valueHash] = value
+		s.verifiedRefValues[valueHash] = value
+	}
+
+	s.verifiedNodes[*hash] = node
+	return node, nil
+}
+
+// Save writes the nodes and ref values of the verified tries into batch.
+func (s *NodeSet) Save(batch database.Batch) error {
+	trie := &Trie{}
+	for _, node := range s.verifiedNodes {
+		if err := trie.saveNodeInDb(batch, node); err != nil {
+			return err
+		}
+	}
+	for valueHash, value := range s.verifiedRefValues {
+		dbKey, _ := database.EncodeKey(database.DBKP_TRIE_REF_VALUE, valueHash.Bytes())
+		batch.Put(dbKey, value)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package trie
+
+import (
+	"bytes"
+
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+// LazyTrie reads a saved trie, the nodes are read when they are visited instead of being loaded at once like
+// NewTrie does, so a few keys of a large trie are read without loading the whole trie.
+type LazyTrie struct {
+	trie *Trie
+	root *TrieNode
+}
+
+func NewLazyTrie(db database.KeyValueStore, rootHash *types.Hash, pool *TrieNodePool) *LazyTrie {
+	lazyTrie := &LazyTrie{
+		trie: &Trie{
+			db:        db,
+			cachePool: pool,
+			log:       log15.New("module", "trie"),
+		},
+	}
+	if rootHash != nil {
+		lazyTrie.root = lazyTrie.trie.getNode(rootHash)
+	}
+	return lazyTrie
+}
+
+// Hash returns nil if the root node isn't found.
+func (lazyTrie *LazyTrie) Hash() *types.Hash {
+	if lazyTrie.root == nil {
+		return nil
+	}
+	return lazyTrie.root.Hash()
+}
+
+func (lazyTrie *LazyTrie) GetValue(key []byte) []byte {
+	node := lazyTrie.root
+	for node != nil {
+		switch node.NodeType() {
+		case TRIE_FULL_NODE:
+			node.childrenSetLock.RLock()
+			var child *TrieNode
+			if len(key) == 0 {
+				child = node.child
+			} else {
+				child = node.children[key[0]]
+				key = key[1:]
+			}
+			node.childrenSetLock.RUnlock()
+			node = lazyTrie.load(child)
+		case TRIE_SHORT_NODE:
+			if !bytes.HasPrefix(key, node.key) {
+				return nil
+			}
+			key = key[len(node.key):]
+			node = lazyTrie.load(node.child)
+		default:
+			if len(key) > 0 {
+				return nil
+			}
+			return lazyTrie.trie.LeafNodeValue(node)
+		}
+	}
+	return nil
+}
+
+// Range returns at most limit values of which the keys start with prefix and aren't less than start, in the
+// order of key. nextKey is the key following the returned ones, nil if there are no more keys. limit <= 0 means
+// no limit.
+func (lazyTrie *LazyTrie) Range(prefix []byte, start []byte, limit int) (keys [][]byte, values [][]byte, nextKey []byte) {
+	lazyTrie.walk(lazyTrie.root, []byte{}, prefix, start, func(key []byte, leafNode *TrieNode) bool {
+		if limit > 0 && len(keys) >= limit {
+			nextKey = key
+			return false
+		}
+		keys = append(keys, key)
+		values = append(values, lazyTrie.trie.LeafNodeValue(leafNode))
+		return true
+	})
+	return keys, values, nextKey
+}
+
+// load returns the node itself if it's loaded, or reads it by the hash.
+func (lazyTrie *LazyTrie) load(node *TrieNode) *TrieNode {
+	if node == nil || node.NodeType() != TRIE_UNKNOW_NODE {
+		return node
+	}
+	return lazyTrie.trie.getNode(node.Hash())
+}
+
+// walk visits the leaf nodes under node in the order of key, the subtrees out of the range aren't read. It
+// returns false if visit stops the walk.
+func (lazyTrie *LazyTrie) walk(node *TrieNode, key []byte, prefix []byte, start []byte, visit func([]byte, *TrieNode) bool) bool {
+	if node == nil {
+		return true
+	}
+
+	switch node.NodeType() {
+	case TRIE_FULL_NODE, TRIE_SHORT_NODE:
+		if !bytes.HasPrefix(key, prefix) && !bytes.HasPrefix(prefix, key) {
+			return true
+		}
+		// all the keys under node are less than start
+		if !bytes.HasPrefix(start, key) && bytes.Compare(key, start) < 0 {
+			return true
+		}
+	default:
+		if !bytes.HasPrefix(key, prefix) || bytes.Compare(key, start) < 0 {
+			return true
+		}
+		return visit(key, node)
+	}
+
+	if node.NodeType() == TRIE_SHORT_NODE {
+		return lazyTrie.walk(lazyTrie.load(node.child), join(key, node.key), prefix, start, visit)
+	}
+
+	// the child of a full node has the key of the full node, it's the first one
+	node.childrenSetLock.RLock()
+	child := node.child
+	children := newSortedChildren(node.children)
+	node.childrenSetLock.RUnlock()
+
+	if !lazyTrie.walk(lazyTrie.load(child), key, prefix, start, visit) {
+		return false
+	}
+	for _, c := range children {
+		if !lazyTrie.walk(lazyTrie.load(c.Value), join(key, []byte{c.Key}), prefix, start, visit) {
+			return false
+		}
+	}
+	return true
+}
+
+func join(key []byte, suffix []byte) []byte {
+	joined := make([]byte, len(key)+len(suffix))
+	copy(joined, key)
+	copy(joined[len(key):], suffix)
+	return joined
+}
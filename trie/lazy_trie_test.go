@@ -0,0 +1,101 @@
+package trie
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestLazyTrie(t *testing.T) {
+	trie, db, close := getTrieOfNewContext()
+	defer close()
+
+	values := make(map[string][]byte)
+	setValue := func(key []byte, value []byte) {
+		trie.SetValue(key, value)
+		values[string(key)] = value
+	}
+	for i := 0; i < 100; i++ {
+		setValue([]byte("key"+strconv.Itoa(i)), []byte("value"+strconv.Itoa(i)))
+	}
+	setValue(nil, []byte("empty key"))
+	setValue([]byte("ke"), []byte("prefix of the keys"))
+	setValue([]byte("long"), bytes.Repeat([]byte("long value"), 10))
+	saveTrie(t, db, trie)
+
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lazyTrie := NewLazyTrie(db, trie.Hash(), nil)
+	if hash := lazyTrie.Hash(); hash == nil || *hash != *trie.Hash() {
+		t.Fatalf("hash should be %s, got %v", trie.Hash(), hash)
+	}
+	for key, value := range values {
+		if getValue := lazyTrie.GetValue([]byte(key)); !bytes.Equal(getValue, value) {
+			t.Fatalf("value of %q should be %q, got %q", key, value, getValue)
+		}
+	}
+	if value := lazyTrie.GetValue([]byte("key100")); value != nil {
+		t.Fatalf("key100 shouldn't have a value, got %q", value)
+	}
+
+	// pages in the order of key
+	var start []byte
+	var rangeKeys []string
+	for pages := 0; ; pages++ {
+		if pages > len(keys) {
+			t.Fatal("paging doesn't end")
+		}
+		pageKeys, pageValues, nextKey := lazyTrie.Range(nil, start, 7)
+		if len(pageKeys) > 7 {
+			t.Fatalf("a page should have at most 7 keys, got %d", len(pageKeys))
+		}
+		for i, key := range pageKeys {
+			if !bytes.Equal(pageValues[i], values[string(key)]) {
+				t.Fatalf("value of %q should be %q, got %q", key, values[string(key)], pageValues[i])
+			}
+			rangeKeys = append(rangeKeys, string(key))
+		}
+		if nextKey == nil {
+			break
+		}
+		start = nextKey
+	}
+	if len(rangeKeys) != len(keys) {
+		t.Fatalf("should range %d keys, got %d", len(keys), len(rangeKeys))
+	}
+	for i, key := range keys {
+		if rangeKeys[i] != key {
+			t.Fatalf("key %d should be %q, got %q", i, key, rangeKeys[i])
+		}
+	}
+
+	// prefix and start
+	pageKeys, _, nextKey := lazyTrie.Range([]byte("key1"), []byte("key15"), 0)
+	if nextKey != nil {
+		t.Fatalf("nextKey should be nil without a limit, got %q", nextKey)
+	}
+	var expected []string
+	for _, key := range keys {
+		if len(key) >= 4 && key[:4] == "key1" && key >= "key15" {
+			expected = append(expected, key)
+		}
+	}
+	if len(pageKeys) != len(expected) {
+		t.Fatalf("should range %v, got %q", expected, pageKeys)
+	}
+	for i, key := range expected {
+		if string(pageKeys[i]) != key {
+			t.Fatalf("key %d should be %q, got %q", i, key, pageKeys[i])
+		}
+	}
+
+	// missing root
+	if missing := NewLazyTrie(db, nil, nil); missing.Hash() != nil || missing.GetValue([]byte("key1")) != nil {
+		t.Fatal("the trie without a root should be empty")
+	}
+}
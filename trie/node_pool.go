@@ -49,3 +49,10 @@ func (pool *TrieNodePool) clear() {
 		}
 	}
 }
+
+func (pool *TrieNodePool) Clear() {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.nodes = make(map[types.Hash]*TrieNode)
+}
@@ -0,0 +1,133 @@
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/crypto"
+)
+
+// Proof is the list of serialized nodes on the path from the root to the key. If the value of the key is longer
+// than 32 bytes and kept out of the trie by a hash node, the value itself is the last item.
+type Proof [][]byte
+
+var (
+	ErrProofMissingNode = errors.New("proof doesn't contain the node of the path")
+	ErrProofMissingRoot = errors.New("proof doesn't contain the root node")
+	ErrNodeNotFound     = errors.New("trie node not found")
+)
+
+// Prove returns the proof of key. The proof of a key which isn't in the trie proves its absence.
+func (trie *Trie) Prove(key []byte) (Proof, error) {
+	return trie.prove(trie.Root, key, func(node *TrieNode) *TrieNode {
+		return node
+	})
+}
+
+// Prove returns the proof of key like Trie.Prove, only the nodes on the path of key are read. ErrNodeNotFound is
+// returned if a node on the path isn't saved.
+func (lazyTrie *LazyTrie) Prove(key []byte) (Proof, error) {
+	return lazyTrie.trie.prove(lazyTrie.root, key, lazyTrie.load)
+}
+
+// prove walks the path of key from root, load returns the node to walk into for a child node.
+func (trie *Trie) prove(root *TrieNode, key []byte, load func(*TrieNode) *TrieNode) (Proof, error) {
+	var proof Proof
+	node := root
+	for node != nil {
+		data, err := node.DbSerialize()
+		if err != nil {
+			return nil, err
+		}
+		proof = append(proof, data)
+
+		var next *TrieNode
+		next, key = nextProofNode(node, key)
+		if next == nil && node.NodeType() == TRIE_HASH_NODE && key == nil {
+			value, err := trie.getRefValue(node.value)
+			if err != nil {
+				return nil, err
+			}
+			proof = append(proof, value)
+		}
+		if node = load(next); next != nil && node == nil {
+			return nil, ErrNodeNotFound
+		}
+	}
+	return proof, nil
+}
+
+// nextProofNode returns the next node on the path of key and the rest of key, the same way as getLeafNode walks.
+// The returned key is nil if node is the leaf node of key.
+func nextProofNode(node *TrieNode, key []byte) (*TrieNode, []byte) {
+	if len(key) == 0 {
+		switch node.NodeType() {
+		case TRIE_HASH_NODE, TRIE_VALUE_NODE:
+			return nil, nil
+		case TRIE_FULL_NODE:
+			if node.child == nil {
+				return nil, []byte{}
+			}
+			return node.child, []byte{}
+		default:
+			return nil, []byte{}
+		}
+	}
+
+	switch node.NodeType() {
+	case TRIE_FULL_NODE:
+		return node.children[key[0]], key[1:]
+	case TRIE_SHORT_NODE:
+		if !bytes.HasPrefix(key, node.key) {
+			return nil, key
+		}
+		return node.child, key[len(node.key):]
+	default:
+		return nil, key
+	}
+}
+
+// VerifyProof checks proof against rootHash and returns the value of key, which is nil if proof proves the absence
+// of key.
+func VerifyProof(rootHash types.Hash, key []byte, proof Proof) ([]byte, error) {
+	nodes := make(map[types.Hash]*TrieNode, len(proof))
+	for _, data := range proof {
+		node := &TrieNode{}
+		if err := node.DbDeserialize(data); err != nil {
+			// the ref value of a hash node
+			continue
+		}
+		nodes[*node.Hash()] = node
+	}
+
+	node, ok := nodes[rootHash]
+	if !ok {
+		return nil, ErrProofMissingRoot
+	}
+	for {
+		next, restKey := nextProofNode(node, key)
+		if restKey == nil {
+			return proofLeafValue(node, proof)
+		}
+		if next == nil {
+			return nil, nil
+		}
+
+		if node, ok = nodes[*next.Hash()]; !ok {
+			return nil, ErrProofMissingNode
+		}
+		key = restKey
+	}
+}
+
+func proofLeafValue(leafNode *TrieNode, proof Proof) ([]byte, error) {
+	if leafNode.NodeType() == TRIE_VALUE_NODE {
+		return leafNode.value, nil
+	}
+	for _, data := range proof {
+		if bytes.Equal(crypto.Hash256(data), leafNode.value) {
+			return data, nil
+		}
+	}
+	return nil, ErrProofMissingNode
+}
@@ -0,0 +1,103 @@
+package trie
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+)
+
+func TestTrieProve(t *testing.T) {
+	trie, db, close := getTrieOfNewContext()
+	defer close()
+
+	kvs := make(map[string][]byte)
+	for i := 0; i < 200; i++ {
+		key := "key" + strconv.Itoa(i)
+		value := []byte("value" + strconv.Itoa(i))
+		if i%3 == 0 {
+			// kept out of the trie by a hash node
+			value = bytes.Repeat(value, 10)
+		}
+		kvs[key] = value
+		trie.SetValue([]byte(key), value)
+	}
+	kvs["key"] = []byte("prefix of other keys")
+	trie.SetValue([]byte("key"), kvs["key"])
+
+	batch := db.NewBatch()
+	callback, err := trie.Save(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Write(batch); err != nil {
+		t.Fatal(err)
+	}
+	callback()
+	loadedTrie := NewTrie(db, trie.Hash(), nil)
+
+	for key, value := range kvs {
+		proof, err := loadedTrie.Prove([]byte(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		provedValue, err := VerifyProof(*trie.Hash(), []byte(key), proof)
+		if err != nil {
+			t.Fatalf("verify proof of %s failed, %v", key, err)
+		}
+		if !bytes.Equal(provedValue, value) {
+			t.Fatalf("proved value of %s is %s, expected %s", key, provedValue, value)
+		}
+	}
+
+	for _, key := range []string{"ke", "key1000", "other"} {
+		proof, err := loadedTrie.Prove([]byte(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		provedValue, err := VerifyProof(*trie.Hash(), []byte(key), proof)
+		if err != nil || provedValue != nil {
+			t.Fatalf("proof of %s should prove absence, got %s, %v", key, provedValue, err)
+		}
+	}
+
+	// the lazy trie reads the nodes on the path only
+	lazyTrie := NewLazyTrie(db, trie.Hash(), nil)
+	for _, key := range []string{"key10", "key12", "ke", "other"} {
+		proof, err := lazyTrie.Prove([]byte(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		provedValue, err := VerifyProof(*trie.Hash(), []byte(key), proof)
+		if err != nil {
+			t.Fatalf("verify proof of %s by the lazy trie failed, %v", key, err)
+		}
+		if !bytes.Equal(provedValue, kvs[key]) {
+			t.Fatalf("proved value of %s by the lazy trie is %s, expected %s", key, provedValue, kvs[key])
+		}
+	}
+
+	proof, _ := loadedTrie.Prove([]byte("key10"))
+	if _, err := VerifyProof(types.DataHash([]byte("other root")), []byte("key10"), proof); err != ErrProofMissingRoot {
+		t.Fatalf("verify with other root should fail with %v, got %v", ErrProofMissingRoot, err)
+	}
+	if _, err := VerifyProof(*trie.Hash(), []byte("key10"), proof[:len(proof)-1]); err != ErrProofMissingNode {
+		t.Fatalf("verify truncated proof should fail with %v, got %v", ErrProofMissingNode, err)
+	}
+
+	// the nodes under the root are pruned
+	iter := db.NewIterator(util.BytesPrefix([]byte{database.DBKP_TRIE_NODE}))
+	rootKey, _ := database.EncodeKey(database.DBKP_TRIE_NODE, trie.Hash().Bytes())
+	for iter.Next() {
+		if !bytes.Equal(iter.Key(), rootKey) {
+			db.Delete(append([]byte(nil), iter.Key()...))
+		}
+	}
+	iter.Release()
+	if _, err := NewLazyTrie(db, trie.Hash(), nil).Prove([]byte("key10")); err != ErrNodeNotFound {
+		t.Fatalf("prove with pruned nodes should fail with %v, got %v", ErrNodeNotFound, err)
+	}
+}
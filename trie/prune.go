@@ -0,0 +1,203 @@
+package trie
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+// count of keys scanned by a batch of the sweep
+const pruneBatchSize = 10000
+
+// prefix of the marks of the nodes visited with a leafFunc, it differs from the prefixes of the swept keys
+const leafVisitedPrefix = byte(0)
+
+// Pruner deletes the trie nodes and ref values which aren't reachable from the marked roots by mark and sweep.
+// The marks are kept in markDb, so the memory isn't bounded by the size of the tries. The nodes written while a
+// Pruner is used must be marked after they are written. Sweep deletes a batch of keys at a time while holding the
+// lock of the writers, a writer holds it from saving a trie to marking it, since a saved trie may skip the
+// nodes existing in db.
+type Pruner struct {
+	trie   *Trie
+	markDb database.KeyValueStore
+
+	// accessed atomically
+	markedNodes     uint64
+	markedRefValues uint64
+
+	errLock sync.Mutex
+	markErr error
+}
+
+func NewPruner(db database.KeyValueStore, markDb database.KeyValueStore) *Pruner {
+	return &Pruner{
+		trie: &Trie{
+			db:  db,
+			log: log15.New("module", "trie_pruner"),
+		},
+		markDb: markDb,
+	}
+}
+
+// Mark marks the trie with rootHash as reachable. leafFunc is called with the value of every value node of the
+// trie, except for the subtries which have been marked with a leafFunc before. Mark may be called concurrently.
+func (p *Pruner) Mark(rootHash *types.Hash, leafFunc func(value []byte)) {
+	if rootHash == nil {
+		return
+	}
+	p.mark(rootHash, leafFunc)
+}
+
+func (p *Pruner) markKey(prefix byte, hash *types.Hash) []byte {
+	return append([]byte{prefix}, hash.Bytes()...)
+}
+
+func (p *Pruner) isMarked(key []byte) bool {
+	ok, err := p.markDb.Has(key)
+	// marking again is harmless
+	return err == nil && ok
+}
+
+func (p *Pruner) setMarked(key []byte) bool {
+	if err := p.markDb.Put(key, nil); err != nil {
+		p.errLock.Lock()
+		if p.markErr == nil {
+			p.markErr = err
+		}
+		p.errLock.Unlock()
+		return false
+	}
+	return true
+}
+
+func (p *Pruner) mark(hash *types.Hash, leafFunc func(value []byte)) {
+	key := p.markKey(database.DBKP_TRIE_NODE, hash)
+	marked := p.isMarked(key)
+	// the nodes shared with a trie marked without leafFunc are visited again to call leafFunc on their values
+	var visitedKey []byte
+	if leafFunc != nil {
+		visitedKey = p.markKey(leafVisitedPrefix, hash)
+		if p.isMarked(visitedKey) {
+			return
+		}
+	} else if marked {
+		return
+	}
+
+	node := p.trie.getNodeFromDb(hash)
+	if node == nil {
+		return
+	}
+	if !marked {
+		if !p.setMarked(key) {
+			return
+		}
+		atomic.AddUint64(&p.markedNodes, 1)
+	}
+	if visitedKey != nil && !p.setMarked(visitedKey) {
+		return
+	}
+
+	switch node.NodeType() {
+	case TRIE_FULL_NODE:
+		if node.child != nil {
+			p.mark(node.child.Hash(), leafFunc)
+		}
+		for _, child := range node.children {
+			p.mark(child.Hash(), leafFunc)
+		}
+	case TRIE_SHORT_NODE:
+		p.mark(node.child.Hash(), leafFunc)
+	case TRIE_HASH_NODE:
+		if valueHash, err := types.BytesToHash(node.value); err == nil {
+			refKey := p.markKey(database.DBKP_TRIE_REF_VALUE, &valueHash)
+			if !p.isMarked(refKey) && p.setMarked(refKey) {
+				atomic.AddUint64(&p.markedRefValues, 1)
+			}
+		}
+	case TRIE_VALUE_NODE:
+		if leafFunc != nil {
+			leafFunc(node.value)
+		}
+	}
+}
+
+// MarkedCount returns the number of marked nodes and ref values.
+func (p *Pruner) MarkedCount() (uint64, uint64) {
+	return atomic.LoadUint64(&p.markedNodes), atomic.LoadUint64(&p.markedRefValues)
+}
+
+// Sweep deletes the nodes and ref values which haven't been marked. lock is held while a batch is deleted,
+// afterBatch is called with lock held after each batch. Nothing is deleted if a mark failed.
+func (p *Pruner) Sweep(lock sync.Locker, afterBatch func()) (deletedNodes uint64, deletedRefValues uint64, err error) {
+	p.errLock.Lock()
+	err = p.markErr
+	p.errLock.Unlock()
+	if err != nil {
+		return
+	}
+
+	if deletedNodes, err = p.sweep(database.DBKP_TRIE_NODE, lock, afterBatch); err != nil {
+		return
+	}
+	deletedRefValues, err = p.sweep(database.DBKP_TRIE_REF_VALUE, lock, afterBatch)
+	return
+}
+
+func (p *Pruner) sweep(prefix byte, lock sync.Locker, afterBatch func()) (uint64, error) {
+	keyRange := util.BytesPrefix([]byte{prefix})
+	deleted := uint64(0)
+	for {
+		lock.Lock()
+		next, count, err := p.sweepBatch(keyRange)
+		if afterBatch != nil {
+			afterBatch()
+		}
+		lock.Unlock()
+
+		deleted += count
+		if err != nil || next == nil {
+			return deleted, err
+		}
+		keyRange.Start = next
+	}
+}
+
+// sweepBatch scans at most pruneBatchSize keys in keyRange, it returns the key the next batch starts from, or nil
+// if all keys have been scanned.
+func (p *Pruner) sweepBatch(keyRange *util.Range) ([]byte, uint64, error) {
+	db := p.trie.db
+	iter := db.NewIterator(keyRange)
+	defer iter.Release()
+
+	var next []byte
+	deleted := uint64(0)
+	batch := db.NewBatch()
+	for scanned := 0; iter.Next(); scanned++ {
+		key := iter.Key()
+		if scanned >= pruneBatchSize {
+			next = append([]byte{}, key...)
+			break
+		}
+		// the keys are marked with the same encoding
+		if len(key) != 1+types.HashSize || p.isMarked(key) {
+			continue
+		}
+
+		batch.Delete(append([]byte{}, key...))
+		deleted++
+	}
+	if err := iter.Error(); err != nil && err != database.ErrNotFound {
+		return nil, 0, err
+	}
+	if batch.Len() > 0 {
+		if err := db.Write(batch); err != nil {
+			return nil, 0, err
+		}
+	}
+	return next, deleted, nil
+}
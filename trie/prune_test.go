@@ -0,0 +1,146 @@
+package trie
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/vitelabs/go-vite/chain_db/database"
+	"github.com/vitelabs/go-vite/common/types"
+)
+
+func saveTrie(t *testing.T, db database.KeyValueStore, trie *Trie) {
+	batch := db.NewBatch()
+	callback, err := trie.Save(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Write(batch); err != nil {
+		t.Fatal(err)
+	}
+	callback()
+}
+
+func countKeys(db database.KeyValueStore, prefix byte) int {
+	iter := db.NewIterator(util.BytesPrefix([]byte{prefix}))
+	defer iter.Release()
+
+	count := 0
+	for iter.Next() {
+		count++
+	}
+	return count
+}
+
+func TestPruner(t *testing.T) {
+	oldTrie, db, close := getTrieOfNewContext()
+	defer close()
+
+	longValue := bytes.Repeat([]byte("long value"), 10)
+	for i := 0; i < 100; i++ {
+		oldTrie.SetValue([]byte("key"+strconv.Itoa(i)), []byte("value"+strconv.Itoa(i)))
+	}
+	oldTrie.SetValue([]byte("long"), longValue)
+	saveTrie(t, db, oldTrie)
+
+	newTrie := oldTrie.Copy()
+	for i := 0; i < 10; i++ {
+		newTrie.SetValue([]byte("key"+strconv.Itoa(i)), []byte("new value"+strconv.Itoa(i)))
+	}
+	newTrie.SetValue([]byte("long"), []byte("short value"))
+	saveTrie(t, db, newTrie)
+
+	nodeCount := countKeys(db, database.DBKP_TRIE_NODE)
+
+	pruner := NewPruner(db, database.NewMemStore())
+	leafCount := 0
+	pruner.Mark(newTrie.Hash(), func(value []byte) {
+		leafCount++
+	})
+	if leafCount != 101 {
+		t.Fatalf("leafFunc should be called 101 times, got %d", leafCount)
+	}
+
+	// a batch is deleted at a time
+	batches := 0
+	var lock sync.Mutex
+	deletedNodes, deletedRefValues, err := pruner.Sweep(&lock, func() {
+		batches++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deletedNodes == 0 || deletedRefValues != 1 {
+		t.Fatalf("should delete the nodes and the ref value of the old trie, got %d nodes and %d ref values", deletedNodes, deletedRefValues)
+	}
+	if batches != 2 {
+		t.Fatalf("the nodes and the ref values should be swept in 2 batches, got %d", batches)
+	}
+	markedNodes, _ := pruner.MarkedCount()
+	if left := countKeys(db, database.DBKP_TRIE_NODE); uint64(left) != markedNodes || left+int(deletedNodes) != nodeCount {
+		t.Fatalf("%d nodes are left, %d nodes are marked, %d nodes are deleted of %d nodes", left, markedNodes, deletedNodes, nodeCount)
+	}
+
+	loadedTrie := NewTrie(db, newTrie.Hash(), nil)
+	for i := 0; i < 100; i++ {
+		expected := "value" + strconv.Itoa(i)
+		if i < 10 {
+			expected = "new " + expected
+		}
+		if value := loadedTrie.GetValue([]byte("key" + strconv.Itoa(i))); string(value) != expected {
+			t.Fatalf("value of key%d should be %s, got %s", i, expected, value)
+		}
+	}
+	if value := loadedTrie.GetValue([]byte("long")); string(value) != "short value" {
+		t.Fatalf("value of long should be short value, got %s", value)
+	}
+}
+
+func TestPruner_SharedValueNode(t *testing.T) {
+	accountTrie, db, close := getTrieOfNewContext()
+	defer close()
+
+	accountTrie.SetValue([]byte("storage key"), []byte("storage value"))
+	saveTrie(t, db, accountTrie)
+
+	// the value of the storage trie equals the value of the snapshot trie referring the account trie
+	storageTrie := NewTrie(db, nil, nil)
+	storageTrie.SetValue([]byte("key"), accountTrie.Hash().Bytes())
+	saveTrie(t, db, storageTrie)
+
+	snapshotTrie := NewTrie(db, nil, nil)
+	snapshotTrie.SetValue([]byte("address"), accountTrie.Hash().Bytes())
+	saveTrie(t, db, snapshotTrie)
+
+	pruner := NewPruner(db, database.NewMemStore())
+	pruner.Mark(storageTrie.Hash(), nil)
+	leafCount := 0
+	pruner.Mark(snapshotTrie.Hash(), func(value []byte) {
+		leafCount++
+		stateHash, err := types.BytesToHash(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pruner.Mark(&stateHash, nil)
+	})
+	if leafCount != 1 {
+		t.Fatalf("leafFunc should be called on the shared value node, got %d calls", leafCount)
+	}
+	// marked with leafFunc again
+	pruner.Mark(snapshotTrie.Hash(), func(value []byte) {
+		leafCount++
+	})
+	if leafCount != 1 {
+		t.Fatalf("leafFunc shouldn't be called on the visited nodes, got %d calls", leafCount)
+	}
+
+	var lock sync.Mutex
+	if deletedNodes, _, err := pruner.Sweep(&lock, nil); err != nil || deletedNodes != 0 {
+		t.Fatalf("should delete no node, got %d nodes, error %v", deletedNodes, err)
+	}
+	if value := NewTrie(db, accountTrie.Hash(), nil).GetValue([]byte("storage key")); string(value) != "storage value" {
+		t.Fatalf("value of the account trie should be storage value, got %s", value)
+	}
+}
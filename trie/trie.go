@@ -3,7 +3,6 @@ package trie
 import (
 	"bytes"
 	"github.com/pkg/errors"
-	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/vitelabs/go-vite/chain_db/database"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/crypto"
@@ -11,7 +10,7 @@ import (
 )
 
 type Trie struct {
-	db        *leveldb.DB
+	db        database.KeyValueStore
 	cachePool *TrieNodePool
 	log       log15.Logger
 
@@ -20,7 +19,7 @@ type Trie struct {
 	unSavedRefValueMap map[types.Hash][]byte
 }
 
-func NewTrie(db *leveldb.DB, rootHash *types.Hash, pool *TrieNodePool) *Trie {
+func NewTrie(db database.KeyValueStore, rootHash *types.Hash, pool *TrieNodePool) *Trie {
 	trie := &Trie{
 		db:        db,
 		cachePool: pool,
@@ -38,9 +37,9 @@ func (trie *Trie) getNodeFromDb(key *types.Hash) *TrieNode {
 		return nil
 	}
 	dbKey, _ := database.EncodeKey(database.DBKP_TRIE_NODE, key.Bytes())
-	value, err := trie.db.Get(dbKey, nil)
+	value, err := trie.db.Get(dbKey)
 	if err != nil {
-		if err != leveldb.ErrNotFound {
+		if err != database.ErrNotFound {
 			trie.log.Error("Query trie node failed from the database, error is "+err.Error(), "method", "getNodeFromDb")
 		}
 
@@ -56,7 +55,7 @@ func (trie *Trie) getNodeFromDb(key *types.Hash) *TrieNode {
 	return trieNode
 }
 
-func (trie *Trie) saveNodeInDb(batch *leveldb.Batch, node *TrieNode) error {
+func (trie *Trie) saveNodeInDb(batch database.Batch, node *TrieNode) error {
 	dbKey, _ := database.EncodeKey(database.DBKP_TRIE_NODE, node.Hash().Bytes())
 	data, err := node.DbSerialize()
 
@@ -84,7 +83,7 @@ func (trie *Trie) deleteUnSavedRefValueMap(node *TrieNode) {
 
 }
 
-func (trie *Trie) saveRefValueMap(batch *leveldb.Batch) {
+func (trie *Trie) saveRefValueMap(batch database.Batch) {
 	for key, value := range trie.unSavedRefValueMap {
 		dbKey, _ := database.EncodeKey(database.DBKP_TRIE_REF_VALUE, key.Bytes())
 		batch.Put(dbKey, value)
@@ -106,7 +105,7 @@ func (trie *Trie) getRefValue(key []byte) ([]byte, error) {
 	}
 
 	dbKey, _ := database.EncodeKey(database.DBKP_TRIE_REF_VALUE, key)
-	return trie.db.Get(dbKey, nil)
+	return trie.db.Get(dbKey)
 }
 
 func (trie *Trie) getNode(key *types.Hash) *TrieNode {
@@ -179,7 +178,7 @@ func (trie *Trie) Copy() *Trie {
 	return newTrie
 }
 
-func (trie *Trie) Save(batch *leveldb.Batch) (successCallback func(), returnErr error) {
+func (trie *Trie) Save(batch database.Batch) (successCallback func(), returnErr error) {
 	err := trie.traverseSave(batch, trie.Root)
 	if err != nil {
 		return nil, err
@@ -192,7 +191,7 @@ func (trie *Trie) Save(batch *leveldb.Batch) (successCallback func(), returnErr
 	}, nil
 }
 
-func (trie *Trie) traverseSave(batch *leveldb.Batch, node *TrieNode) error {
+func (trie *Trie) traverseSave(batch database.Batch, node *TrieNode) error {
 	if node == nil {
 		return nil
 	}
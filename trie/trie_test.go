@@ -3,23 +3,16 @@ package trie
 import (
 	"bytes"
 	"fmt"
-	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/vitelabs/go-vite/chain_db/database"
-	"github.com/vitelabs/go-vite/common"
 	"github.com/vitelabs/go-vite/common/types"
-	"os"
-	"path/filepath"
 	"strconv"
 	"sync"
 	"testing"
 	"time"
 )
 
-func getTrieOfNewContext() (*Trie, *leveldb.DB, func()) {
-	trieDbFile := filepath.Join(common.GoViteTestDataDir(), "trie")
-	os.RemoveAll(trieDbFile)
-
-	db, _ := database.NewLevelDb(trieDbFile)
+func getTrieOfNewContext() (*Trie, database.KeyValueStore, func()) {
+	db := database.NewMemStore()
 
 	pool := NewTrieNodePool()
 
@@ -83,8 +76,7 @@ func TestSetGetCase2(t *testing.T) {
 }
 
 func TestNewTrie(t *testing.T) {
-	db, _ := database.NewLevelDb(filepath.Join(common.GoViteTestDataDir(), "trie"))
-	defer db.Close()
+	db := database.NewMemStore()
 
 	pool := NewTrieNodePool()
 
@@ -383,8 +375,7 @@ func TestNewTrie(t *testing.T) {
 }
 
 func TestTrieHash(t *testing.T) {
-	db, _ := database.NewLevelDb(filepath.Join(common.GoViteTestDataDir(), "trie"))
-	defer db.Close()
+	db := database.NewMemStore()
 
 	pool := NewTrieNodePool()
 	trie := NewTrie(db, nil, pool)
@@ -448,11 +439,7 @@ func TestTrieHash(t *testing.T) {
 }
 
 func TestTrieSaveAndLoadCase1(t *testing.T) {
-	trieDbFile := filepath.Join(common.GoViteTestDataDir(), "trie")
-	os.RemoveAll(trieDbFile)
-
-	db, _ := database.NewLevelDb(trieDbFile)
-	defer db.Close()
+	db := database.NewMemStore()
 
 	pool := NewTrieNodePool()
 
@@ -485,9 +472,9 @@ func TestTrieSaveAndLoadCase1(t *testing.T) {
 	}
 
 	// save db
-	batch := new(leveldb.Batch)
+	batch := db.NewBatch()
 	callback, _ := trie.Save(batch)
-	db.Write(batch, nil)
+	db.Write(batch)
 	callback()
 
 	rootHash := trie.Hash()
@@ -510,11 +497,7 @@ func TestTrieSaveAndLoadCase1(t *testing.T) {
 }
 
 func TestTrieSaveAndLoad(t *testing.T) {
-	trieDbFile := filepath.Join(common.GoViteTestDataDir(), "trie")
-	os.RemoveAll(trieDbFile)
-
-	db, _ := database.NewLevelDb(filepath.Join(common.GoViteTestDataDir(), "trie"))
-	defer db.Close()
+	db := database.NewMemStore()
 
 	pool := NewTrieNodePool()
 
@@ -533,9 +516,9 @@ func TestTrieSaveAndLoad(t *testing.T) {
 	fmt.Println(trie.Hash())
 	fmt.Println()
 
-	batch := new(leveldb.Batch)
+	batch := db.NewBatch()
 	callback, _ := trie.Save(batch)
-	db.Write(batch, nil)
+	db.Write(batch)
 	callback()
 
 	rootHash := trie.Hash()
@@ -588,9 +571,9 @@ func TestTrieSaveAndLoad(t *testing.T) {
 	fmt.Println(newTri2.Hash())
 	fmt.Println()
 
-	batch2 := new(leveldb.Batch)
+	batch2 := db.NewBatch()
 	callback2, _ := newTri2.Save(batch2)
-	if err := db.Write(batch2, nil); err != nil {
+	if err := db.Write(batch2); err != nil {
 		t.Fatal(err)
 	}
 	callback2()
@@ -619,8 +602,7 @@ func TestTrieSaveAndLoad(t *testing.T) {
 }
 
 func TestTrieConcurrence(t *testing.T) {
-	db, _ := database.NewLevelDb(filepath.Join(common.GoViteTestDataDir(), "trie"))
-	defer db.Close()
+	db := database.NewMemStore()
 
 	pool := NewTrieNodePool()
 
@@ -644,9 +626,9 @@ func TestTrieConcurrence(t *testing.T) {
 		sw.Add(1)
 		go func() {
 			defer sw.Done()
-			batch := new(leveldb.Batch)
+			batch := db.NewBatch()
 			trie.Save(batch)
-			db.Write(batch, nil)
+			db.Write(batch)
 		}()
 	}
 	sw.Wait()
@@ -14,6 +14,7 @@ import (
 	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/monitor"
 	"github.com/vitelabs/go-vite/pow"
+	"github.com/vitelabs/go-vite/vm/contracts"
 	"github.com/vitelabs/go-vite/vm_context"
 )
 
@@ -298,6 +299,16 @@ func (verifier *AccountVerifier) verifyProducerLegality(block *ledger.AccountBlo
 	if err != nil || code == ledger.AccountTypeError {
 		return FAIL, err
 	}
+	if code == ledger.AccountTypeNotExist && block.IsReceiveBlock() {
+		// the first receive block of a new contract must be produced by its consensus group
+		fromBlock, err := verifier.chain.GetAccountBlockByHash(&block.FromBlockHash)
+		if err != nil {
+			return FAIL, err
+		}
+		if fromBlock != nil && fromBlock.BlockType == ledger.BlockTypeSendCreate {
+			code = ledger.AccountTypeContract
+		}
+	}
 	if code == ledger.AccountTypeContract {
 		if block.IsReceiveBlock() {
 			if result, err := verifier.consensus.VerifyAccountProducer(block); !result {
@@ -343,6 +354,12 @@ func (verifier *AccountVerifier) VerifyDataValidity(block *ledger.AccountBlock)
 		return errors.New("block timestamp can't be nil")
 	}
 
+	if block.BlockType == ledger.BlockTypeSendCreate {
+		if err := verifier.VerifyCreateContract(block); err != nil {
+			return err
+		}
+	}
+
 	if err := verifier.VerifyHash(block); err != nil {
 		return err
 	}
@@ -392,6 +409,27 @@ func (verifier *AccountVerifier) VerifyP2PDataValidity(block *ledger.AccountBloc
 	return nil
 }
 
+// VerifyCreateContract checks that a create block refers to a snapshot block after the contract creation fork,
+// carries a gid and code and is sent to the derived contract address. The fork is checked by vm as well if the
+// snapshot block isn't known yet.
+func (verifier *AccountVerifier) VerifyCreateContract(block *ledger.AccountBlock) error {
+	snapshotBlock, err := verifier.chain.GetSnapshotBlockByHash(&block.SnapshotHash)
+	if err != nil {
+		return err
+	}
+	if snapshotBlock != nil && !contracts.IsCreateContractForked(snapshotBlock.Height) {
+		return ErrVerifyCreateContractNotForked
+	}
+	if len(block.Data) <= types.GidSize {
+		return ErrVerifyCreateDataFailed
+	}
+	contractAddr := contracts.NewContractAddress(block.AccountAddress, block.Height, block.PrevHash, block.SnapshotHash)
+	if block.ToAddress != contractAddr {
+		return ErrVerifyContractAddrFailed
+	}
+	return nil
+}
+
 func (verifier *AccountVerifier) VerifyIsReceivedSucceed(block *ledger.AccountBlock) bool {
 	return verifier.chain.IsSuccessReceived(&block.AccountAddress, &block.FromBlockHash)
 }
@@ -12,4 +12,7 @@ var (
 	ErrVerifySnapshotOfReferredBlockFailed = errors.New("verify snapshotBlock of the referredBlock failed")
 	ErrVerifyForVmGeneratorFailed          = errors.New("generator in verifier failed")
 	ErrVerifyWithVmResultFailed            = errors.New("verify with vm result failed")
+	ErrVerifyCreateDataFailed              = errors.New("create block data should contain gid and contract code")
+	ErrVerifyContractAddrFailed            = errors.New("create block toAddress is not the derived contract address")
+	ErrVerifyCreateContractNotForked       = errors.New("create block refers to a snapshot block before contract creation is activated")
 )
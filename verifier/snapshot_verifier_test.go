@@ -22,7 +22,8 @@ func getChainInstance(path string) chain.Chain {
 		innerChainInstance = chain.NewChain(&config.Config{
 			DataDir: filepath.Join(home, path),
 			//Chain: &config.Chain{
-			//	KafkaProducers: []*config.KafkaProducer{{
+			//	EventSinks: []*config.EventSink{{
+			//		Type:       "kafka",
 			//		Topic:      "test",
 			//		BrokerList: []string{"abc", "def"},
 			//	}},
@@ -5,6 +5,7 @@ import (
 	"github.com/vitelabs/go-vite/ledger"
 	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/monitor"
+	"math"
 	"time"
 )
 
@@ -41,11 +42,25 @@ func (b *broadcaster) BroadcastAccountBlock(block *ledger.AccountBlock) {
 	defer monitor.LogTime("net/broadcast", "AccountBlock", time.Now())
 
 	peers := b.peers.UnknownBlock(block.Hash)
+
+	// send the full block to sqrt(n) peers and only the hash to the rest, they will fetch the block if need.
+	// peers of old version can`t handle the hash, so they always receive the full block.
+	full := int(math.Sqrt(float64(len(peers))))
+
+	var sent, announced int
 	for _, peer := range peers {
-		peer.SendNewAccountBlock(block)
+		if sent < full || peer.version < hashAnnounceVersion {
+			peer.SendNewAccountBlock(block)
+			sent++
+		} else {
+			peer.SendNewAccountBlockHash(block.Hash)
+			announced++
+		}
 	}
 
-	b.log.Debug(fmt.Sprintf("broadcast AccountBlock %s to %d peers", block.Hash, len(peers)))
+	monitor.LogEventNum("net/broadcast", "AccountBlock_Announce", announced)
+
+	b.log.Debug(fmt.Sprintf("broadcast AccountBlock %s to %d peers, announce to %d peers", block.Hash, sent, announced))
 }
 
 func (b *broadcaster) BroadcastAccountBlocks(blocks []*ledger.AccountBlock) {
@@ -4,12 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/ledger"
 	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/monitor"
+	"github.com/vitelabs/go-vite/p2p"
 	"github.com/vitelabs/go-vite/vite/net/message"
 )
 
@@ -91,20 +94,44 @@ type fPolicy interface {
 	pickSnap() Peer
 }
 
+const maxAnnounced = 10000
+const announceExpire = time.Minute
+const announceTimeout = 5 * time.Second // fetch from the next announcer if the block isn`t replied in time
+const maxAnnouncers = 5                 // announcers of a block are remembered at most
+
+// announcement is an accountblock announced by hash and being fetched
+type announcement struct {
+	id         uint64    // id of the request fetching the block
+	requested  time.Time // when the request was sent
+	announcers []Peer    // the other announcers, the block will be fetched from them in turn
+}
+
+// fetchRequest is a GetAccountBlocksByHash request sent to peer
+type fetchRequest struct {
+	peer   Peer
+	hashes []types.Hash
+}
+
 type fetcher struct {
 	filter Filter
 	policy fPolicy
 	pool   MsgIder
 	ready  int32 // atomic
 	log    log15.Logger
+
+	alock     sync.Mutex
+	announced map[types.Hash]*announcement
+	requests  map[uint64]*fetchRequest // the GetAccountBlocksByHash requests, keyed by message id
 }
 
 func newFetcher(filter Filter, peers *peerSet, pool MsgIder) *fetcher {
 	return &fetcher{
-		filter: filter,
-		policy: &fetchPolicy{peers},
-		pool:   pool,
-		log:    log15.New("module", "net/fetcher"),
+		filter:    filter,
+		policy:    &fetchPolicy{peers},
+		pool:      pool,
+		log:       log15.New("module", "net/fetcher"),
+		announced: make(map[types.Hash]*announcement),
+		requests:  make(map[uint64]*fetchRequest),
 	}
 }
 
@@ -228,6 +255,169 @@ func (f *fetcher) FetchAccountBlocksWithHeight(start types.Hash, count uint64, a
 	}
 }
 
+// fetch the accountblocks announced by sender. the blocks being fetched from other peers will be skipped, and
+// sender is remembered to fetch them again if the other peers don`t reply.
+func (f *fetcher) fetchAnnounced(hashes []types.Hash, sender Peer) {
+	monitor.LogEvent("net/fetch", "GetAccountBlocksByHash")
+
+	if atomic.LoadInt32(&f.ready) == 0 {
+		f.log.Debug("not ready")
+		return
+	}
+
+	now := time.Now()
+	missing := make([]types.Hash, 0, len(hashes))
+
+	f.alock.Lock()
+	for _, hash := range hashes {
+		if a, ok := f.announced[hash]; ok && now.Sub(a.requested) < announceExpire {
+			if len(a.announcers) < maxAnnouncers && !hasPeer(a.announcers, sender) {
+				a.announcers = append(a.announcers, sender)
+			}
+			continue
+		}
+
+		if len(f.announced) >= maxAnnounced {
+			for h, a := range f.announced {
+				if now.Sub(a.requested) >= announceExpire {
+					delete(f.announced, h)
+				}
+			}
+
+			// still too many, discard the announcement
+			if len(f.announced) >= maxAnnounced {
+				break
+			}
+		}
+
+		f.announced[hash] = &announcement{
+			requested: now,
+		}
+		missing = append(missing, hash)
+	}
+	f.alock.Unlock()
+
+	if len(missing) > 0 {
+		f.request(missing, sender)
+	}
+}
+
+// request the blocks of hashes from p, they will be fetched from the next announcers if p doesn`t reply in
+// announceTimeout or replies an exception
+func (f *fetcher) request(hashes []types.Hash, p Peer) {
+	id := f.pool.MsgID()
+	now := time.Now()
+
+	f.alock.Lock()
+	for _, hash := range hashes {
+		if a, ok := f.announced[hash]; ok {
+			a.id = id
+			a.requested = now
+		}
+	}
+	f.requests[id] = &fetchRequest{p, hashes}
+	f.alock.Unlock()
+
+	m := &message.BlockHashes{
+		Hashes: hashes,
+	}
+
+	monitor.LogEvent("net/fetch", "GetAccountBlocksByHash_Send")
+
+	if err := p.Send(GetAccountBlocksByHashCode, id, m); err != nil {
+		f.log.Error(fmt.Sprintf("send GetAccountBlocksByHash %s to %s error: %v", m, p.RemoteAddr(), err))
+		f.retry(id)
+		return
+	}
+
+	f.log.Info(fmt.Sprintf("send GetAccountBlocksByHash %s to %s done", m, p.RemoteAddr()))
+
+	time.AfterFunc(announceTimeout, func() {
+		f.retry(id)
+	})
+}
+
+// retry fetches the blocks of request id haven`t been received from their next announcers, the blocks without
+// announcers are forgotten, so they can be fetched when announced again
+func (f *fetcher) retry(id uint64) {
+	f.alock.Lock()
+
+	var hashes []types.Hash
+	if req, ok := f.requests[id]; ok {
+		hashes = req.hashes
+		delete(f.requests, id)
+	}
+
+	next := make(map[Peer][]types.Hash)
+	for _, hash := range hashes {
+		a, ok := f.announced[hash]
+		// fetched or requested again
+		if !ok || a.id != id {
+			continue
+		}
+
+		if len(a.announcers) == 0 {
+			delete(f.announced, hash)
+			continue
+		}
+
+		p := a.announcers[0]
+		a.announcers = a.announcers[1:]
+		next[p] = append(next[p], hash)
+	}
+
+	f.alock.Unlock()
+
+	for p, hashes := range next {
+		monitor.LogEvent("net/fetch", "GetAccountBlocksByHash_Retry")
+		f.request(hashes, p)
+	}
+}
+
+// fetched returns true if the block is fetched by announcement, then the block should be handled as a new block
+func (f *fetcher) fetched(hash types.Hash) bool {
+	f.alock.Lock()
+	defer f.alock.Unlock()
+
+	_, ok := f.announced[hash]
+	delete(f.announced, hash)
+
+	return ok
+}
+
+func (f *fetcher) ID() string {
+	return "fetcher"
+}
+
+func (f *fetcher) Cmds() []ViteCmd {
+	return []ViteCmd{ExceptionCode}
+}
+
+// Handle the exception replied to GetAccountBlocksByHash, eg. Missing, the blocks are fetched from the next announcers.
+// The exceptions from the peers the request isn`t sent to are ignored, the ids of messages are predictable.
+func (f *fetcher) Handle(msg *p2p.Msg, sender Peer) error {
+	f.alock.Lock()
+	req, ok := f.requests[msg.Id]
+	f.alock.Unlock()
+
+	if !ok || req.peer != sender {
+		return nil
+	}
+
+	f.retry(msg.Id)
+	return nil
+}
+
+func hasPeer(peers []Peer, p Peer) bool {
+	for _, peer := range peers {
+		if peer == p {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (f *fetcher) listen(st SyncState) {
 	if st == Syncdone || st == SyncDownloaded {
 		f.log.Info(fmt.Sprintf("ready: %s", st))
@@ -0,0 +1,118 @@
+package net
+
+import (
+	net2 "net"
+	"testing"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/p2p"
+	"github.com/vitelabs/go-vite/vite/net/message"
+)
+
+type sendPeer struct {
+	Peer
+	sent []p2p.Serializable
+	ids  []uint64
+}
+
+func (p *sendPeer) RemoteAddr() *net2.TCPAddr {
+	return &net2.TCPAddr{}
+}
+
+func (p *sendPeer) Send(code ViteCmd, msgId uint64, payload p2p.Serializable) error {
+	p.sent = append(p.sent, payload)
+	p.ids = append(p.ids, msgId)
+	return nil
+}
+
+func TestFetcher_FetchAnnounced(t *testing.T) {
+	f := newFetcher(newFilter(), newPeerSet(), new(gid))
+	p := &sendPeer{}
+
+	h1 := types.DataHash([]byte("block1"))
+	h2 := types.DataHash([]byte("block2"))
+
+	// not ready
+	f.fetchAnnounced([]types.Hash{h1}, p)
+	if len(p.sent) != 0 {
+		t.Fatal("should not fetch before ready")
+	}
+
+	f.listen(Syncdone)
+
+	f.fetchAnnounced([]types.Hash{h1}, p)
+	f.fetchAnnounced([]types.Hash{h1, h2}, p)
+	if len(p.sent) != 2 {
+		t.Fatalf("should send 2 requests, got %d", len(p.sent))
+	}
+
+	// h1 is being fetched
+	if bh := p.sent[1].(*message.BlockHashes); len(bh.Hashes) != 1 || bh.Hashes[0] != h2 {
+		t.Fatal("only h2 should be requested")
+	}
+
+	if !f.fetched(h1) {
+		t.Fatal("h1 should be fetched by announcement")
+	}
+	if f.fetched(h1) {
+		t.Fatal("h1 should be fetched only once")
+	}
+}
+
+func TestFetcher_Retry(t *testing.T) {
+	f := newFetcher(newFilter(), newPeerSet(), new(gid))
+	f.listen(Syncdone)
+	p1, p2 := &sendPeer{}, &sendPeer{}
+
+	h1 := types.DataHash([]byte("block1"))
+	h2 := types.DataHash([]byte("block2"))
+
+	f.fetchAnnounced([]types.Hash{h1, h2}, p1)
+	f.fetchAnnounced([]types.Hash{h1, h2}, p2)
+	f.fetchAnnounced([]types.Hash{h1}, p2)
+	if len(p1.sent) != 1 || len(p2.sent) != 0 {
+		t.Fatal("the blocks should be fetched only from the first announcer")
+	}
+
+	// h2 is received, the exception of p1's request from another peer is ignored
+	f.fetched(h2)
+	msg := p2p.NewMsg()
+	msg.Cmd = p2p.Cmd(ExceptionCode)
+	msg.Id = p1.ids[0]
+	if err := f.Handle(msg, &sendPeer{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Handle(msg, p2); err != nil {
+		t.Fatal(err)
+	}
+	if len(p2.sent) != 0 {
+		t.Fatal("the exception from the peer not requested should be ignored")
+	}
+
+	// p1 replies Missing for h1
+	if err := f.Handle(msg, p1); err != nil {
+		t.Fatal(err)
+	}
+	if len(p2.sent) != 1 {
+		t.Fatalf("h1 should be fetched from the next announcer once, got %d requests", len(p2.sent))
+	}
+	if bh := p2.sent[0].(*message.BlockHashes); len(bh.Hashes) != 1 || bh.Hashes[0] != h1 {
+		t.Fatal("only h1 should be fetched again")
+	}
+
+	// the reply of the old request is ignored
+	f.retry(p1.ids[0])
+	if len(p1.sent) != 1 || len(p2.sent) != 1 {
+		t.Fatal("the old request should not be retried again")
+	}
+
+	// p2 times out, no more announcers, h1 is forgotten
+	f.retry(p2.ids[0])
+	if f.fetched(h1) {
+		t.Fatal("h1 without announcers should be forgotten")
+	}
+	f.fetchAnnounced([]types.Hash{h1}, p1)
+	if len(p1.sent) != 2 {
+		t.Fatal("h1 should be fetched when announced again")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/vitelabs/go-vite/common"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/compress"
 	"github.com/vitelabs/go-vite/ledger"
 	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/p2p"
@@ -128,7 +130,20 @@ func (s *fileServer) handleConn(conn net2.Conn) {
 			// send files
 			var n int64
 			for _, filename := range req.Names {
-				n, err = io.Copy(conn, s.chain.Compressor().FileReader(filename))
+				// don't send the corrupted file
+				if err = s.chain.Compressor().VerifyFile(filename); err != nil {
+					s.log.Error(fmt.Sprintf("verify file<%s> error: %v", filename, err))
+					return
+				}
+
+				reader := s.chain.Compressor().FileReader(filename)
+				if reader == nil {
+					s.log.Error(fmt.Sprintf("open file<%s> error", filename))
+					return
+				}
+
+				n, err = io.Copy(conn, reader)
+				reader.Close()
 
 				if err != nil {
 					s.log.Error(fmt.Sprintf("send file<%s> to %s error: %v", filename, conn.RemoteAddr(), err))
@@ -151,6 +166,7 @@ type conn struct {
 	idle   bool
 	idleT  time.Time
 	done   bool // file request done
+	bad    bool // file of the peer is corrupted
 }
 
 type filesEvent struct {
@@ -407,10 +423,24 @@ loop:
 			files, sender := e.files, e.sender
 			pFiles[sender.ID()] = files
 			for _, file := range files {
-				if _, ok := record[file.Filename]; !ok {
-					record[file.Filename] = &fileState{file: file}
+				r, ok := record[file.Filename]
+				if !ok {
+					r = &fileState{file: file}
+					record[file.Filename] = r
+				}
+
+				// the file which can't be verified isn't requested, the blocks of it will be got by chunks if no
+				// peer has a verifiable one. peers may compress the file with different codecs, only those of the
+				// same hash can be requested with the same meta.
+				if compress.CheckFileMeta(file) != nil {
+					continue
+				}
+				if r.file.Hash == (types.Hash{}) {
+					r.file = file
+				}
+				if r.file.Hash == file.Hash {
+					r.peers = append(r.peers, sender)
 				}
-				record[file.Filename].peers = append(record[file.Filename].peers, sender)
 			}
 
 			fileList = fileList[:0]
@@ -428,6 +458,11 @@ loop:
 				} else {
 					r.state = reqError
 				}
+
+				// request the file from other peers
+				if ctx.bad {
+					r.peers = r.peers.delete(ctx.peer.ID())
+				}
 			}
 
 			ctx.idle = true
@@ -524,6 +559,7 @@ func (fc *fileClient) exec(ctx *conn) {
 	}
 
 	ctx.idle = false
+	ctx.bad = false
 
 	getFiles := &message.GetFiles{
 		Names: []string{ctx.file.Filename},
@@ -546,7 +582,13 @@ func (fc *fileClient) exec(ctx *conn) {
 
 	fc.log.Info(fmt.Sprintf("send %s to %s done", getFiles, ctx.RemoteAddr()))
 
-	if err = fc.receiveFile(ctx); err != nil {
+	if err = fc.receiveFile(ctx); err == compress.ErrFileHashMismatch || err == compress.ErrFileInvalid {
+		// the whole file has been read, the connection can be used again
+		fc.log.Error(fmt.Sprintf("receive corrupted file %s from %s: %v", ctx.file.Filename, ctx.RemoteAddr(), err))
+		ctx.done = false
+		ctx.bad = true
+		fc.idle(ctx)
+	} else if err != nil {
 		fc.log.Error(fmt.Sprintf("receive file from %s error: %v", ctx.RemoteAddr(), err))
 		fc.delete(ctx)
 	} else {
@@ -567,7 +609,7 @@ func (fc *fileClient) receiveFile(ctx *conn) error {
 
 		file := ctx.file
 
-		fc.chain.Compressor().BlockParser(ctx, file.BlockNumbers, func(block ledger.Block, err error) {
+		err := fc.chain.Compressor().BlockParser(ctx, file, func(block ledger.Block, err error) {
 			if err != nil {
 				return
 			}
@@ -580,7 +622,7 @@ func (fc *fileClient) receiveFile(ctx *conn) error {
 				}
 
 				sCount++
-				fc.handler.receiveSnapshotBlock(block)
+				fc.handler.receiveSnapshotBlock(block, ctx.peer)
 				ctx.height = block.Height
 
 			case *ledger.AccountBlock:
@@ -590,9 +632,12 @@ func (fc *fileClient) receiveFile(ctx *conn) error {
 				}
 
 				aCount++
-				fc.handler.receiveAccountBlock(block)
+				fc.handler.receiveAccountBlock(block, ctx.peer)
 			}
 		})
+		if err != nil {
+			return err
+		}
 
 		sTotal := file.EndHeight - file.StartHeight + 1
 		if sCount < sTotal {
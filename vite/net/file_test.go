@@ -25,6 +25,8 @@ func Test_wait(t *testing.T) {
 					copy(wait[i:], wait[i+1:])
 				}
 				wait = wait[:len(wait)-1]
+				// wait is shortened, the range is over
+				break
 			}
 		}
 	}
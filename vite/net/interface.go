@@ -1,6 +1,8 @@
 package net
 
 import (
+	"time"
+
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/compress"
 	"github.com/vitelabs/go-vite/ledger"
@@ -125,6 +127,9 @@ type Net interface {
 	Fetcher
 	Broadcaster
 	Receiver
+	Ban(id string, duration time.Duration) error
+	Unban(id string) (bool, error)
+	Bans() []*p2p.BanInfo
 	Protocols() []*p2p.Protocol
 	Start(svr *p2p.Server) error
 	Stop()
@@ -19,6 +19,13 @@ const Vite = "vite"
 
 const CmdSet = 2
 
+// protocol version, exchanged by handshake, peers of old version will not receive the messages they can`t handle
+const (
+	hashAnnounceVersion   uint32 = 1 // NewAccountBlockHashCode and GetAccountBlocksByHashCode
+	compressedFileVersion uint32 = 2 // ledger files with codec and hash
+	Version                      = compressedFileVersion
+)
+
 type ViteCmd p2p.Cmd
 
 const (
@@ -45,6 +52,7 @@ const (
 	AccountBlocksCode
 	NewSnapshotBlockCode
 	NewAccountBlockCode
+	NewAccountBlockHashCode // announce hashes of new accountblocks, need protocol version >= hashAnnounceVersion
 
 	ExceptionCode = 127
 )
@@ -73,6 +81,7 @@ var msgNames = [...]string{
 	AccountBlocksCode:                  "AccountBlocksMsg",
 	NewSnapshotBlockCode:               "NewSnapshotBlockMsg",
 	NewAccountBlockCode:                "NewAccountBlockMsg",
+	NewAccountBlockHashCode:            "NewAccountBlockHashMsg",
 }
 
 func (t ViteCmd) String() string {
@@ -80,13 +89,24 @@ func (t ViteCmd) String() string {
 		return "ExceptionMsg"
 	}
 
-	if t > NewAccountBlockCode {
+	if t > NewAccountBlockHashCode {
 		return "UnkownMsg"
 	}
 
 	return msgNames[t]
 }
 
+// parse the message name to ViteCmd, eg. GetSubLedgerMsg
+func parseViteCmd(name string) (ViteCmd, bool) {
+	for code, str := range msgNames {
+		if str == name {
+			return ViteCmd(code), true
+		}
+	}
+
+	return 0, false
+}
+
 type MsgHandler interface {
 	ID() string
 	Cmds() []ViteCmd
@@ -139,6 +159,7 @@ func newQueryHandler(chain Chain) *queryHandler {
 	q.addHandler(&getSubLedgerHandler{chain})
 	q.addHandler(&getSnapshotBlocksHandler{chain})
 	q.addHandler(&getAccountBlocksHandler{chain})
+	q.addHandler(&getAccountBlocksByHashHandler{chain})
 	q.addHandler(&getChunkHandler{chain})
 
 	return q
@@ -175,7 +196,7 @@ func (q *queryHandler) ID() string {
 }
 
 func (q *queryHandler) Cmds() []ViteCmd {
-	return []ViteCmd{GetSubLedgerCode, GetSnapshotBlocksCode, GetAccountBlocksCode, GetChunkCode}
+	return []ViteCmd{GetSubLedgerCode, GetSnapshotBlocksCode, GetAccountBlocksCode, GetAccountBlocksByHashCode, GetChunkCode}
 }
 
 type queryTask struct {
@@ -258,7 +279,34 @@ func (q *queryHandler) loop() {
 	}
 }
 
+// @section exceptionHandler
+// exceptionHandler passes ExceptionCode to all the handlers, each of them tells its requests by message id
+type exceptionHandler struct {
+	handlers []MsgHandler
+}
+
+func (e *exceptionHandler) ID() string {
+	return "exception handler"
+}
+
+func (e *exceptionHandler) Cmds() []ViteCmd {
+	return []ViteCmd{ExceptionCode}
+}
+
+func (e *exceptionHandler) Handle(msg *p2p.Msg, sender Peer) error {
+	for _, handler := range e.handlers {
+		if err := handler.Handle(msg, sender); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // @section getSubLedgerHandler
+// heights of a GetSubLedger request at most, the files of the heights beyond it are not listed
+const maxSubLedgerCount = 10 * subLedgerUnit
+
 type getSubLedgerHandler struct {
 	chain Chain
 }
@@ -282,6 +330,10 @@ func (s *getSubLedgerHandler) Handle(msg *p2p.Msg, sender Peer) (err error) {
 
 	netLog.Info(fmt.Sprintf("receive %s from %s", req, sender.RemoteAddr()))
 
+	if req.Count > maxSubLedgerCount {
+		req.Count = maxSubLedgerCount
+	}
+
 	var files []*ledger.CompressedFileMeta
 	var chunks [][2]uint64
 	if req.From.Hash != types.ZERO_HASH {
@@ -295,6 +347,11 @@ func (s *getSubLedgerHandler) Handle(msg *p2p.Msg, sender Peer) (err error) {
 		return sender.Send(ExceptionCode, msg.Id, message.Missing)
 	}
 
+	// peers of old version can't verify and decode the files, they get the blocks by chunks
+	if sender.Version() < compressedFileVersion {
+		files, chunks = filesToChunks(files, chunks)
+	}
+
 	fileList := &message.FileList{
 		Files:  files,
 		Chunks: chunks,
@@ -312,6 +369,19 @@ func (s *getSubLedgerHandler) Handle(msg *p2p.Msg, sender Peer) (err error) {
 	return
 }
 
+// filesToChunks returns chunks with the heights of files prepended, the files are lower than the chunks.
+func filesToChunks(files []*ledger.CompressedFileMeta, chunks [][2]uint64) ([]*ledger.CompressedFileMeta, [][2]uint64) {
+	if len(files) == 0 {
+		return files, chunks
+	}
+
+	merged := make([][2]uint64, 0, len(files)+len(chunks))
+	for _, file := range files {
+		merged = append(merged, [2]uint64{file.StartHeight, file.EndHeight})
+	}
+	return nil, append(merged, chunks...)
+}
+
 type getSnapshotBlocksHandler struct {
 	chain Chain
 }
@@ -468,7 +538,66 @@ func (a *getAccountBlocksHandler) Handle(msg *p2p.Msg, sender Peer) (err error)
 	return
 }
 
+// @section get account blocks by hash
+const maxHashesOfRequest = 1000
+
+type getAccountBlocksByHashHandler struct {
+	chain Chain
+}
+
+func (a *getAccountBlocksByHashHandler) ID() string {
+	return "GetAccountBlocksByHash Handler"
+}
+
+func (a *getAccountBlocksByHashHandler) Cmds() []ViteCmd {
+	return []ViteCmd{GetAccountBlocksByHashCode}
+}
+
+var errTooManyHashes = errors.New("too many hashes to GetAccountBlocksByHash")
+
+func (a *getAccountBlocksByHashHandler) Handle(msg *p2p.Msg, sender Peer) (err error) {
+	defer monitor.LogTime("net", "handle_GetAccountBlocksByHashMsg", time.Now())
+
+	req := new(message.BlockHashes)
+
+	if err = req.Deserialize(msg.Payload); err != nil {
+		return
+	}
+
+	if len(req.Hashes) > maxHashesOfRequest {
+		return errTooManyHashes
+	}
+
+	netLog.Info(fmt.Sprintf("receive GetAccountBlocksByHash %s from %s", req, sender.RemoteAddr()))
+
+	blocks := make([]*ledger.AccountBlock, 0, len(req.Hashes))
+	for i := range req.Hashes {
+		block, err := a.chain.GetAccountBlockByHash(&req.Hashes[i])
+		if err == nil && block != nil {
+			blocks = append(blocks, block)
+		}
+	}
+
+	if len(blocks) == 0 {
+		monitor.LogEvent("net/handle", "GetAccountBlocksByHash_Fail")
+		return sender.Send(ExceptionCode, msg.Id, message.Missing)
+	}
+
+	monitor.LogEvent("net/handle", "GetAccountBlocksByHash_Success")
+
+	if err = sender.SendAccountBlocks(blocks, msg.Id); err != nil {
+		netLog.Error(fmt.Sprintf("send %d AccountBlocks to %s error: %v", len(blocks), sender.RemoteAddr(), err))
+	} else {
+		netLog.Info(fmt.Sprintf("send %d AccountBlocks to %s done", len(blocks), sender.RemoteAddr()))
+	}
+
+	return
+}
+
 // @section getChunkHandler
+// heights of a GetChunk request at most, chunks are requested by chunk heights
+const maxChunkCount = 10 * chunk
+
 type getChunkHandler struct {
 	chain Chain
 }
@@ -481,6 +610,8 @@ func (c *getChunkHandler) Cmds() []ViteCmd {
 	return []ViteCmd{GetChunkCode}
 }
 
+var errTooLargeChunk = errors.New("too many heights to GetChunk")
+
 func (c *getChunkHandler) Handle(msg *p2p.Msg, sender Peer) (err error) {
 	defer monitor.LogTime("net", "handle_GetChunkMsg", time.Now())
 
@@ -497,6 +628,10 @@ func (c *getChunkHandler) Handle(msg *p2p.Msg, sender Peer) (err error) {
 		start, end = end, start
 	}
 
+	if end-start >= maxChunkCount {
+		return errTooLargeChunk
+	}
+
 	// split chunk
 	chunks := splitChunk(start, end)
 
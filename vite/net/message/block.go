@@ -257,3 +257,43 @@ func (a *AccountBlocks) Deserialize(buf []byte) error {
 
 	return nil
 }
+
+// @section BlockHashes
+
+// BlockHashes is used to announce new blocks, or request blocks by hash
+type BlockHashes struct {
+	Hashes []types.Hash
+}
+
+func (b *BlockHashes) String() string {
+	return "BlockHashes<" + strconv.FormatInt(int64(len(b.Hashes)), 10) + ">"
+}
+
+func (b *BlockHashes) Serialize() ([]byte, error) {
+	pb := new(vitepb.BlockHashes)
+
+	pb.Hashes = make([][]byte, len(b.Hashes))
+	for i := range b.Hashes {
+		pb.Hashes[i] = b.Hashes[i][:]
+	}
+
+	return proto.Marshal(pb)
+}
+
+func (b *BlockHashes) Deserialize(buf []byte) error {
+	pb := new(vitepb.BlockHashes)
+
+	err := proto.Unmarshal(buf, pb)
+	if err != nil {
+		return err
+	}
+
+	b.Hashes = make([]types.Hash, len(pb.Hashes))
+	for i, hash := range pb.Hashes {
+		if b.Hashes[i], err = types.BytesToHash(hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -195,3 +195,32 @@ func TestSubLedger_Serialize(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestBlockHashes_Deserialize(t *testing.T) {
+	bh := &BlockHashes{
+		Hashes: make([]types.Hash, 10),
+	}
+	for i := range bh.Hashes {
+		crand.Read(bh.Hashes[i][:])
+	}
+
+	buf, err := bh.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bh2 := new(BlockHashes)
+	if err = bh2.Deserialize(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bh2.Hashes) != len(bh.Hashes) {
+		t.Fatalf("should have %d hashes, got %d", len(bh.Hashes), len(bh2.Hashes))
+	}
+
+	for i := range bh.Hashes {
+		if bh.Hashes[i] != bh2.Hashes[i] {
+			t.Fatalf("hash %d not equal", i)
+		}
+	}
+}
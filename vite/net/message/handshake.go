@@ -7,6 +7,7 @@ import (
 )
 
 type HandShake struct {
+	Version uint32 // protocol version, decide which messages can be sent to the peer
 	Height  uint64
 	Port    uint16
 	Current types.Hash
@@ -20,6 +21,7 @@ func (h *HandShake) Serialize() ([]byte, error) {
 	pb.Port = uint32(h.Port)
 	pb.Current = h.Current[:]
 	pb.Genesis = h.Genesis[:]
+	pb.Version = h.Version
 
 	return proto.Marshal(pb)
 }
@@ -36,6 +38,7 @@ func (h *HandShake) Deserialize(data []byte) error {
 	h.Port = uint16(pb.Port)
 	copy(h.Current[:], pb.Current)
 	copy(h.Genesis[:], pb.Genesis)
+	h.Version = pb.Version
 
 	return nil
 }
@@ -4,17 +4,16 @@ import (
 	crand "crypto/rand"
 	"fmt"
 	"github.com/vitelabs/go-vite/chain"
-	"github.com/vitelabs/go-vite/common"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/config"
 	"github.com/vitelabs/go-vite/ledger"
 	"github.com/vitelabs/go-vite/p2p"
 	"github.com/vitelabs/go-vite/vite/net/message"
 	"github.com/vitelabs/go-vite/vm_context"
+	"io/ioutil"
 	"math/big"
 	"math/rand"
 	net2 "net"
-	"path/filepath"
 	"testing"
 	"time"
 )
@@ -37,15 +36,19 @@ func getAccountList() []types.Address {
 
 func getChain() chain.Chain {
 	if chn == nil {
+		dataDir, err := ioutil.TempDir("", "net")
+		if err != nil {
+			panic(err)
+		}
 		chn = chain.NewChain(&config.Config{
-			DataDir: filepath.Join(common.HomeDir(), "Library/GVite/test"),
+			DataDir: dataDir,
+			Chain:   &config.Chain{LedgerInMemory: true},
 		})
 
 		chn.Init()
 		chn.Start()
 
-		to := rand.Intn(1000)
-		mockBlocks(chn, uint64(to))
+		mockBlocks(chn, 10)
 	}
 
 	return chn
@@ -166,6 +169,10 @@ func (m *mock_Peer) Height() uint64 {
 	panic("implement me")
 }
 
+func (m *mock_Peer) Version() uint32 {
+	panic("implement me")
+}
+
 func (m *mock_Peer) Report(err error) {
 	panic("implement me")
 }
@@ -250,11 +257,10 @@ func mockGetAccountBlocksMsg() *p2p.Msg {
 	}
 }
 
-var gaHandler = getAccountBlocksHandler{
-	chain: getChain(),
-}
-
 func TestGetAccountBlocksHandler_Handle(t *testing.T) {
+	gaHandler := getAccountBlocksHandler{
+		chain: getChain(),
+	}
 	gaHandler.Handle(mockGetAccountBlocksMsg(), &mock_Peer{})
 }
 
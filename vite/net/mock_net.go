@@ -1,6 +1,8 @@
 package net
 
 import (
+	"time"
+
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/p2p"
@@ -12,6 +14,7 @@ type mockNet struct {
 	*fetcher
 	*broadcaster
 	*receiver
+	*reputation
 }
 
 func (n *mockNet) Info() *NodeInfo {
@@ -40,12 +43,23 @@ func mock() Net {
 	filter := &filter{
 		records: make(map[types.Hash]*record),
 	}
+	rep := newReputation(DefaultBanThreshold, DefaultBanDuration*time.Second, false)
+	fetcher := &fetcher{
+		filter:    filter,
+		policy:    &fetchPolicy{peers},
+		pool:      pool,
+		ready:     1,
+		announced: make(map[types.Hash]*announcement),
+		requests:  make(map[uint64]*fetchRequest),
+	}
 	receiver := &receiver{
 		ready:       0,
 		sFeed:       newSnapshotBlockFeed(),
 		aFeed:       newAccountBlockFeed(),
 		broadcaster: broadcaster,
 		filter:      filter,
+		rep:         rep,
+		fetcher:     fetcher,
 	}
 
 	return &mockNet{
@@ -59,13 +73,9 @@ func mock() Net {
 			pool:    &chunkPool{},
 			running: 1,
 		},
-		fetcher: &fetcher{
-			filter: filter,
-			policy: &fetchPolicy{peers},
-			pool:   pool,
-			ready:  1,
-		},
+		fetcher:     fetcher,
 		broadcaster: broadcaster,
 		receiver:    receiver,
+		reputation:  rep,
 	}
 }
@@ -29,6 +29,16 @@ type Config struct {
 	Topic        string
 	Interval     int64 // second
 	TopoDisabled bool
+
+	// peers whose reputation score drops to BanThreshold will be banned for BanDuration seconds,
+	// the offences are only logged if ReputationDisabled is true
+	BanThreshold       int
+	BanDuration        int64
+	ReputationDisabled bool
+
+	// limit the requests of each peer, key is the message name, eg. GetSubLedgerMsg.
+	// merged into DefaultRateLimits
+	RateLimits map[string]RateLimit
 }
 
 const DefaultPort uint16 = 8484
@@ -41,6 +51,7 @@ type net struct {
 	*broadcaster
 	*receiver
 	*filter
+	*reputation
 	term      chan struct{}
 	log       log15.Logger
 	protocols []*p2p.Protocol // mount to p2p.Server
@@ -49,6 +60,7 @@ type net struct {
 	handlers  map[ViteCmd]MsgHandler
 	topo      *topo.Topology
 	query     *queryHandler // handle query message (eg. getAccountBlocks, getSnapshotblocks, getChunk, getSubLedger)
+	limits    map[ViteCmd]RateLimit
 }
 
 // auto from
@@ -62,14 +74,25 @@ func New(cfg *Config) Net {
 		cfg.Port = DefaultPort
 	}
 
+	if cfg.BanDuration == 0 {
+		cfg.BanDuration = DefaultBanDuration
+	}
+
+	limits, err := makeRateLimits(cfg.RateLimits)
+	if err != nil {
+		netLog.Error(fmt.Sprintf("use default rate limits, config error: %v", err))
+		limits = DefaultRateLimits
+	}
+
 	g := new(gid)
 	peers := newPeerSet()
 
 	broadcaster := newBroadcaster(peers)
 	filter := newFilter()
-	receiver := newReceiver(cfg.Verifier, broadcaster, filter)
-	syncer := newSyncer(cfg.Chain, peers, g, receiver)
+	rep := newReputation(cfg.BanThreshold, time.Duration(cfg.BanDuration)*time.Second, cfg.ReputationDisabled)
 	fetcher := newFetcher(filter, peers, g)
+	receiver := newReceiver(cfg.Verifier, broadcaster, filter, rep, fetcher)
+	syncer := newSyncer(cfg.Chain, peers, g, receiver, rep)
 
 	syncer.feed.Sub(receiver.listen) // subscribe sync status
 	syncer.feed.Sub(fetcher.listen)  // subscribe sync status
@@ -82,9 +105,11 @@ func New(cfg *Config) Net {
 		broadcaster: broadcaster,
 		receiver:    receiver,
 		filter:      filter,
+		reputation:  rep,
 		fs:          newFileServer(cfg.Port, cfg.Chain),
 		handlers:    make(map[ViteCmd]MsgHandler),
 		log:         netLog,
+		limits:      limits,
 	}
 
 	n.addHandler(_statusHandler(statusHandler))
@@ -92,13 +117,16 @@ func New(cfg *Config) Net {
 	n.addHandler(n.query)
 	n.addHandler(syncer)   // FileListCode, SubLedgerCode, ExceptionCode
 	n.addHandler(receiver) // NewSnapshotBlockCode, NewAccountBlockCode, SnapshotBlocksCode, AccountBlocksCode
+	n.addHandler(&exceptionHandler{
+		handlers: []MsgHandler{syncer, fetcher},
+	})
 
 	n.protocols = append(n.protocols, &p2p.Protocol{
 		Name: Vite,
 		ID:   CmdSet,
 		Handle: func(p *p2p.Peer, rw *p2p.ProtoFrame) error {
 			// will be called by p2p.Peer.runProtocols use goroutine
-			peer := newPeer(p, rw, CmdSet)
+			peer := newPeer(p, rw, CmdSet, n.limits)
 			return n.handlePeer(peer)
 		},
 	})
@@ -146,6 +174,8 @@ func (n *net) Start(svr *p2p.Server) (err error) {
 
 	n.filter.start()
 
+	n.reputation.start(svr)
+
 	return
 }
 
@@ -171,6 +201,8 @@ func (n *net) Stop() {
 
 		n.filter.stop()
 
+		n.reputation.stop()
+
 		n.wg.Wait()
 	}
 }
@@ -182,6 +214,7 @@ func (n *net) handlePeer(p *peer) error {
 
 	n.log.Debug(fmt.Sprintf("handshake with %s", p))
 	err := p.Handshake(&message.HandShake{
+		Version: Version,
 		Height:  current.Height,
 		Port:    n.Port,
 		Current: current.Hash,
@@ -190,6 +223,9 @@ func (n *net) handlePeer(p *peer) error {
 
 	if err != nil {
 		n.log.Error(fmt.Sprintf("handshake with %s error: %v", p, err))
+		if err == errDiffGesis {
+			n.reputation.report(p, OffenceDiffGenesis)
+		}
 		return err
 	}
 
@@ -279,6 +315,21 @@ func (n *net) handleMsg(p *peer) (err error) {
 	}
 
 	code := ViteCmd(msg.Cmd)
+	p.traffic.recordIn(code, len(msg.Payload))
+
+	// throttle the peer, the next message won`t be read until the request is handled
+	if delay := p.limiter.reserve(code, msg.Payload); delay > 0 {
+		n.log.Debug(fmt.Sprintf("message %s from %s exceed the rate limit, delay %s", code, p, delay))
+		p.traffic.recordLimited(code)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-n.term:
+			timer.Stop()
+			return p2p.DiscQuitting
+		case <-timer.C:
+		}
+	}
 
 	// before syncDone, ignore GetAccountBlocksCode
 	if n.syncer.SyncState() != Syncdone {
@@ -310,12 +361,14 @@ func (n *net) handleMsg(p *peer) (err error) {
 func (n *net) Info() *NodeInfo {
 	peersInfo := n.peers.Info()
 
-	var send, received, handled, discarded uint64
+	var send, received, handled, discarded, bytesIn, bytesOut uint64
 	for _, pi := range peersInfo {
 		send += pi.MsgSend
 		received += pi.MsgReceived
 		handled += pi.MsgHandled
 		discarded += pi.MsgDiscarded
+		bytesIn += pi.Traffic.In.Bytes
+		bytesOut += pi.Traffic.Out.Bytes
 	}
 
 	return &NodeInfo{
@@ -324,6 +377,8 @@ func (n *net) Info() *NodeInfo {
 		MsgReceived:  received,
 		MsgHandled:   handled,
 		MsgDiscarded: discarded,
+		BytesIn:      bytesIn,
+		BytesOut:     bytesOut,
 	}
 }
 
@@ -333,6 +388,8 @@ type NodeInfo struct {
 	MsgReceived  uint64      `json:"msgReceived"`
 	MsgHandled   uint64      `json:"msgHandled"`
 	MsgDiscarded uint64      `json:"msgDiscarded"`
+	BytesIn      uint64      `json:"bytesIn"`
+	BytesOut     uint64      `json:"bytesOut"`
 }
 
 // for debug
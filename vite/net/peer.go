@@ -36,6 +36,7 @@ type Peer interface {
 	Report(err error)
 	ID() string
 	Height() uint64
+	Version() uint32
 }
 
 const peerMsgConcurrency = 10
@@ -47,12 +48,15 @@ type peer struct {
 	head        types.Hash // hash of the top snapshotblock in snapshotchain
 	height      uint64     // height of the snapshotchain
 	filePort    uint16     // fileServer port, for request file
+	version     uint32     // protocol version
 	CmdSet      p2p.CmdSet // which cmdSet it belongs
 	KnownBlocks *cuckoofilter.CuckooFilter
 	log         log15.Logger
 	errChan     chan error
 	term        chan struct{}
 	msgHandled  map[ViteCmd]uint64 // message statistic
+	traffic     *traffic
+	limiter     *limiter
 	wg          sync.WaitGroup
 }
 
@@ -64,7 +68,11 @@ func (p *peer) ID() string {
 	return p.id
 }
 
-func newPeer(p *p2p.Peer, mrw *p2p.ProtoFrame, cmdSet p2p.CmdSet) *peer {
+func (p *peer) Version() uint32 {
+	return p.version
+}
+
+func newPeer(p *p2p.Peer, mrw *p2p.ProtoFrame, cmdSet p2p.CmdSet, limits map[ViteCmd]RateLimit) *peer {
 	return &peer{
 		Peer:        p,
 		mrw:         mrw,
@@ -75,6 +83,8 @@ func newPeer(p *p2p.Peer, mrw *p2p.ProtoFrame, cmdSet p2p.CmdSet) *peer {
 		errChan:     make(chan error, 1),
 		term:        make(chan struct{}),
 		msgHandled:  make(map[ViteCmd]uint64),
+		traffic:     newTraffic(),
+		limiter:     newLimiter(limits),
 	}
 }
 
@@ -113,6 +123,7 @@ func (p *peer) Handshake(our *message.HandShake) error {
 	}
 
 	p.SetHead(their.Current, their.Height)
+	p.version = their.Version
 	p.filePort = their.Port
 	if p.filePort == 0 {
 		p.filePort = DefaultPort
@@ -185,6 +196,21 @@ func (p *peer) SendNewAccountBlock(b *ledger.AccountBlock) (err error) {
 	return
 }
 
+// announce the hash of new accountblock, the peer will request the block if it hasn`t seen the block
+func (p *peer) SendNewAccountBlockHash(hash types.Hash) (err error) {
+	err = p.Send(NewAccountBlockHashCode, 0, &message.BlockHashes{
+		Hashes: []types.Hash{hash},
+	})
+
+	if err != nil {
+		return
+	}
+
+	p.SeeBlock(hash)
+
+	return
+}
+
 func (p *peer) Send(code ViteCmd, msgId uint64, payload p2p.Serializable) (err error) {
 	var msg *p2p.Msg
 
@@ -196,6 +222,8 @@ func (p *peer) Send(code ViteCmd, msgId uint64, payload p2p.Serializable) (err e
 		return err
 	}
 
+	p.traffic.recordOut(code, len(msg.Payload))
+
 	p.log.Info(fmt.Sprintf("send message %s to %s", code, p.RemoteAddr()))
 
 	return nil
@@ -206,6 +234,7 @@ type PeerInfo struct {
 	Addr               string            `json:"addr"`
 	Head               string            `json:"head"`
 	Height             uint64            `json:"height"`
+	Version            uint32            `json:"version"`
 	MsgReceived        uint64            `json:"msgReceived"`
 	MsgHandled         uint64            `json:"msgHandled"`
 	MsgSend            uint64            `json:"msgSend"`
@@ -214,6 +243,7 @@ type PeerInfo struct {
 	MsgDiscardedDetail map[string]uint64 `json:"msgDiscarded"`
 	MsgHandledDetail   map[string]uint64 `json:"msgHandledDetail"`
 	MsgSendDetail      map[string]uint64 `json:"msgSendDetail"`
+	Traffic            *TrafficInfo      `json:"traffic"`
 	Uptime             time.Duration     `json:"uptime"`
 }
 
@@ -252,6 +282,7 @@ func (p *peer) Info() *PeerInfo {
 		Addr:               p.RemoteAddr().String(),
 		Head:               p.head.String(),
 		Height:             p.height,
+		Version:            p.version,
 		MsgReceived:        received,
 		MsgHandled:         handled,
 		MsgSend:            send,
@@ -260,6 +291,7 @@ func (p *peer) Info() *PeerInfo {
 		MsgDiscardedDetail: discMap,
 		MsgHandledDetail:   handMap,
 		MsgSendDetail:      sendMap,
+		Traffic:            p.traffic.info(),
 		Uptime:             time.Now().Sub(p.Created),
 	}
 }
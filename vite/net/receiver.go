@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/crypto"
 	"github.com/vitelabs/go-vite/ledger"
 	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/monitor"
@@ -26,11 +27,13 @@ type receiver struct {
 	verifier    Verifier
 	broadcaster Broadcaster
 	filter      Filter
+	rep         *reputation
+	fetcher     *fetcher // fetch the accountblocks announced by hash
 	log         log15.Logger
 	batchSource types.BlockSource // report to pool
 }
 
-func newReceiver(verifier Verifier, broadcaster Broadcaster, filter Filter) *receiver {
+func newReceiver(verifier Verifier, broadcaster Broadcaster, filter Filter, rep *reputation, fetcher *fetcher) *receiver {
 	return &receiver{
 		newSBlocks:  make([]*ledger.SnapshotBlock, 0, cacheSBlockTotal),
 		newABlocks:  make([]*ledger.AccountBlock, 0, cacheABlockTotal),
@@ -39,6 +42,8 @@ func newReceiver(verifier Verifier, broadcaster Broadcaster, filter Filter) *rec
 		verifier:    verifier,
 		broadcaster: broadcaster,
 		filter:      filter,
+		rep:         rep,
+		fetcher:     fetcher,
 		log:         log15.New("module", "net/receiver"),
 		batchSource: types.RemoteSync,
 	}
@@ -50,7 +55,7 @@ func (s *receiver) ID() string {
 }
 
 func (s *receiver) Cmds() []ViteCmd {
-	return []ViteCmd{NewSnapshotBlockCode, NewAccountBlockCode, SnapshotBlocksCode, AccountBlocksCode}
+	return []ViteCmd{NewSnapshotBlockCode, NewAccountBlockCode, NewAccountBlockHashCode, SnapshotBlocksCode, AccountBlocksCode}
 }
 
 func (s *receiver) Handle(msg *p2p.Msg, sender Peer) error {
@@ -59,12 +64,13 @@ func (s *receiver) Handle(msg *p2p.Msg, sender Peer) error {
 		block := new(ledger.SnapshotBlock)
 		err := block.Deserialize(msg.Payload)
 		if err != nil {
+			s.rep.report(sender, OffenceBadMessage)
 			return err
 		}
 
 		sender.SeeBlock(block.Hash)
 
-		s.ReceiveNewSnapshotBlock(block)
+		s.receiveNewSnapshotBlock(block, sender)
 
 		s.log.Info(fmt.Sprintf("receive new snapshotblock %s/%d from %s", block.Hash, block.Height, sender.RemoteAddr()))
 
@@ -72,32 +78,67 @@ func (s *receiver) Handle(msg *p2p.Msg, sender Peer) error {
 		block := new(ledger.AccountBlock)
 		err := block.Deserialize(msg.Payload)
 		if err != nil {
+			s.rep.report(sender, OffenceBadMessage)
 			return err
 		}
 
 		sender.SeeBlock(block.Hash)
 
-		s.ReceiveNewAccountBlock(block)
+		s.receiveNewAccountBlock(block, sender)
 
 		s.log.Info(fmt.Sprintf("receive new accountblock %s from %s", block.Hash, sender.RemoteAddr()))
 
+	case NewAccountBlockHashCode:
+		bh := new(message.BlockHashes)
+		err := bh.Deserialize(msg.Payload)
+		if err != nil {
+			s.rep.report(sender, OffenceBadMessage)
+			return err
+		}
+
+		hashes := bh.Hashes[:0]
+		for _, hash := range bh.Hashes {
+			sender.SeeBlock(hash)
+
+			if !s.filter.has(hash) {
+				hashes = append(hashes, hash)
+			}
+		}
+
+		s.log.Info(fmt.Sprintf("receive %s from %s, %d unknown", bh, sender.RemoteAddr(), len(hashes)))
+
+		if len(hashes) > 0 && s.fetcher != nil {
+			s.fetcher.fetchAnnounced(hashes, sender)
+		}
+
 	case SnapshotBlocksCode:
 		bs := new(message.SnapshotBlocks)
 		err := bs.Deserialize(msg.Payload)
 		if err != nil {
+			s.rep.report(sender, OffenceBadMessage)
 			return err
 		}
 
-		s.ReceiveSnapshotBlocks(bs.Blocks)
+		for _, block := range bs.Blocks {
+			s.receiveSnapshotBlock(block, sender)
+		}
 
 	case AccountBlocksCode:
 		bs := new(message.AccountBlocks)
 		err := bs.Deserialize(msg.Payload)
 		if err != nil {
+			s.rep.report(sender, OffenceBadMessage)
 			return err
 		}
 
-		s.ReceiveAccountBlocks(bs.Blocks)
+		for _, block := range bs.Blocks {
+			// the block announced by hash is new, it should be broadcast too
+			if s.fetcher != nil && s.fetcher.fetched(block.Hash) {
+				s.receiveNewAccountBlock(block, sender)
+			} else {
+				s.receiveAccountBlock(block, sender)
+			}
+		}
 	}
 
 	return nil
@@ -109,6 +150,11 @@ func (s *receiver) mark(hash types.Hash) {
 
 // implementation Receiver
 func (s *receiver) ReceiveNewSnapshotBlock(block *ledger.SnapshotBlock) {
+	s.receiveNewSnapshotBlock(block, nil)
+}
+
+// sender is nil if the block isn`t from a peer
+func (s *receiver) receiveNewSnapshotBlock(block *ledger.SnapshotBlock, sender Peer) {
 	if block == nil {
 		return
 	}
@@ -126,6 +172,9 @@ func (s *receiver) ReceiveNewSnapshotBlock(block *ledger.SnapshotBlock) {
 		if err := s.verifier.VerifyNetSb(block); err != nil {
 			monitor.LogDuration("net/verifier", "SnapshotBlock", time.Now().Sub(verify_b).Nanoseconds())
 			s.log.Error(fmt.Sprintf("verify NewSnapshotBlock %s/%d fail: %v", block.Hash, block.Height, err))
+			if invalidSnapshotBlock(block) {
+				s.rep.report(sender, OffenceInvalidBlock)
+			}
 			return
 		}
 		monitor.LogDuration("net/verifier", "SnapshotBlock", time.Now().Sub(verify_b).Nanoseconds())
@@ -156,6 +205,11 @@ func (s *receiver) ReceiveNewSnapshotBlock(block *ledger.SnapshotBlock) {
 }
 
 func (s *receiver) ReceiveNewAccountBlock(block *ledger.AccountBlock) {
+	s.receiveNewAccountBlock(block, nil)
+}
+
+// sender is nil if the block isn`t from a peer
+func (s *receiver) receiveNewAccountBlock(block *ledger.AccountBlock, sender Peer) {
 	if block == nil {
 		return
 	}
@@ -173,6 +227,9 @@ func (s *receiver) ReceiveNewAccountBlock(block *ledger.AccountBlock) {
 		if err := s.verifier.VerifyNetAb(block); err != nil {
 			monitor.LogDuration("net/verifier", "AccountBlock", time.Now().Sub(verify_b).Nanoseconds())
 			s.log.Error(fmt.Sprintf("verify NewAccountBlock %s fail: %v", block.Hash, err))
+			if invalidAccountBlock(block) {
+				s.rep.report(sender, OffenceInvalidBlock)
+			}
 			return
 		}
 		monitor.LogDuration("net/verifier", "AccountBlock", time.Now().Sub(verify_b).Nanoseconds())
@@ -201,6 +258,11 @@ func (s *receiver) ReceiveNewAccountBlock(block *ledger.AccountBlock) {
 }
 
 func (s *receiver) ReceiveSnapshotBlock(block *ledger.SnapshotBlock) {
+	s.receiveSnapshotBlock(block, nil)
+}
+
+// sender is nil if the block isn`t from a peer
+func (s *receiver) receiveSnapshotBlock(block *ledger.SnapshotBlock, sender Peer) {
 	if block == nil {
 		return
 	}
@@ -218,6 +280,9 @@ func (s *receiver) ReceiveSnapshotBlock(block *ledger.SnapshotBlock) {
 		if err := s.verifier.VerifyNetSb(block); err != nil {
 			monitor.LogDuration("net/verifier", "SnapshotBlock", time.Now().Sub(verify_b).Nanoseconds())
 			s.log.Error(fmt.Sprintf("verify SnapshotBlock %s/%d fail: %v", block.Hash, block.Height, err))
+			if invalidSnapshotBlock(block) {
+				s.rep.report(sender, OffenceInvalidBlock)
+			}
 			return
 		}
 		monitor.LogDuration("net/verifier", "SnapshotBlock", time.Now().Sub(verify_b).Nanoseconds())
@@ -232,6 +297,11 @@ func (s *receiver) ReceiveSnapshotBlock(block *ledger.SnapshotBlock) {
 }
 
 func (s *receiver) ReceiveAccountBlock(block *ledger.AccountBlock) {
+	s.receiveAccountBlock(block, nil)
+}
+
+// sender is nil if the block isn`t from a peer
+func (s *receiver) receiveAccountBlock(block *ledger.AccountBlock, sender Peer) {
 	if block == nil {
 		return
 	}
@@ -249,6 +319,9 @@ func (s *receiver) ReceiveAccountBlock(block *ledger.AccountBlock) {
 		if err := s.verifier.VerifyNetAb(block); err != nil {
 			monitor.LogDuration("net/verifier", "AccountBlock", time.Now().Sub(verify_b).Nanoseconds())
 			s.log.Error(fmt.Sprintf("verify AccountBlock %s fail: %v", block.Hash, err))
+			if invalidAccountBlock(block) {
+				s.rep.report(sender, OffenceInvalidBlock)
+			}
 			return
 		}
 		monitor.LogDuration("net/verifier", "AccountBlock", time.Now().Sub(verify_b).Nanoseconds())
@@ -321,3 +394,28 @@ func (s *receiver) SubscribeSnapshotBlock(fn SnapshotBlockCallback) (subId int)
 func (s *receiver) UnsubscribeSnapshotBlock(subId int) {
 	s.sFeed.Unsub(subId)
 }
+
+// invalidSnapshotBlock returns true if the hash or the signature of the block is wrong. The checks don`t depend on
+// the ledger or the clock, and the honest peers do them before relaying the block, so the sender is punished.
+func invalidSnapshotBlock(block *ledger.SnapshotBlock) bool {
+	if block.Timestamp == nil || block.Hash != block.ComputeHash() {
+		return true
+	}
+
+	verified, _ := crypto.VerifySig(block.PublicKey, block.Hash.Bytes(), block.Signature)
+	return !verified
+}
+
+// invalidAccountBlock returns true if the hash or the signature of the block is wrong, like invalidSnapshotBlock.
+// The send blocks of contracts have no signature.
+func invalidAccountBlock(block *ledger.AccountBlock) bool {
+	if block.Timestamp == nil || (block.IsSendBlock() && block.Amount == nil) || block.Hash != block.ComputeHash() {
+		return true
+	}
+
+	if block.IsReceiveBlock() || len(block.Signature) > 0 || len(block.PublicKey) > 0 {
+		verified, _ := crypto.VerifySig(block.PublicKey, block.Hash.Bytes(), block.Signature)
+		return !verified
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package net
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vitelabs/go-vite/common"
+	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/monitor"
+	"github.com/vitelabs/go-vite/p2p"
+	"github.com/vitelabs/go-vite/p2p/discovery"
+)
+
+// Offence is the misbehavior of a peer, the value is the score deducted from the peer. Only the misbehaviors can
+// be checked without the ledger are offences, a block fails in pool may be relayed by honest peers.
+type Offence int
+
+const (
+	OffenceBadMessage   Offence = 20  // send message can`t be deserialized
+	OffenceInvalidBlock Offence = 50  // send block of wrong hash or signature
+	OffenceDiffGenesis  Offence = 100 // on the chain of a different genesis block
+)
+
+const (
+	maxScore             = 100
+	DefaultBanThreshold  = 0
+	DefaultBanDuration   = 3600        // second
+	scoreRecoverInterval = time.Minute // recover 1 score at intervals
+)
+
+var errNoServer = errors.New("p2p server is not started")
+
+type score struct {
+	value   int
+	updated time.Time
+}
+
+// recover the score as time goes by, so peers won`t be banned by occasional offences
+func (s *score) recover(now time.Time) {
+	if n := int(now.Sub(s.updated) / scoreRecoverInterval); n > 0 {
+		s.value += n
+		if s.value > maxScore {
+			s.value = maxScore
+		}
+		s.updated = s.updated.Add(time.Duration(n) * scoreRecoverInterval)
+	}
+}
+
+// reputation scores peers by their offences, the peer whose score drops to threshold will be disconnected
+// and banned for a while. If it`s disabled, the offences are only logged, the peers can still be banned manually.
+type reputation struct {
+	threshold int
+	duration  time.Duration
+	disabled  bool
+	svr       *p2p.Server
+
+	lock   sync.Mutex
+	scores map[string]*score
+
+	term chan struct{}
+	wg   sync.WaitGroup
+	log  log15.Logger
+}
+
+func newReputation(threshold int, duration time.Duration, disabled bool) *reputation {
+	return &reputation{
+		threshold: threshold,
+		duration:  duration,
+		disabled:  disabled,
+		scores:    make(map[string]*score),
+		log:       log15.New("module", "net/reputation"),
+	}
+}
+
+func (r *reputation) start(svr *p2p.Server) {
+	r.svr = svr
+	r.term = make(chan struct{})
+
+	r.wg.Add(1)
+	common.Go(r.loop)
+}
+
+func (r *reputation) stop() {
+	if r.term == nil {
+		return
+	}
+
+	select {
+	case <-r.term:
+	default:
+		close(r.term)
+		r.wg.Wait()
+	}
+}
+
+func (r *reputation) loop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.term:
+			return
+		case now := <-ticker.C:
+			r.lock.Lock()
+
+			for id, s := range r.scores {
+				if s.recover(now); s.value >= maxScore {
+					delete(r.scores, id)
+				}
+			}
+
+			r.lock.Unlock()
+		}
+	}
+}
+
+func (r *reputation) report(sender Peer, offence Offence) {
+	if sender == nil {
+		return
+	}
+
+	r.punish(sender.ID(), offence)
+}
+
+func (r *reputation) punish(id string, offence Offence) {
+	if r.disabled {
+		r.log.Warn(fmt.Sprintf("offence %d of %s", offence, id))
+		return
+	}
+
+	now := time.Now()
+
+	r.lock.Lock()
+	s, ok := r.scores[id]
+	if !ok {
+		s = &score{maxScore, now}
+		r.scores[id] = s
+	}
+	s.recover(now)
+	s.value -= int(offence)
+	value := s.value
+
+	if value <= r.threshold {
+		// start again after the ban expired
+		delete(r.scores, id)
+	}
+	r.lock.Unlock()
+
+	monitor.LogEvent("net/reputation", "punish")
+	r.log.Warn(fmt.Sprintf("punish %s by %d, score %d", id, offence, value))
+
+	if value <= r.threshold {
+		if err := r.Ban(id, r.duration); err != nil {
+			r.log.Error(fmt.Sprintf("ban %s error: %v", id, err))
+		}
+	}
+}
+
+// Ban disconnects the peer of id and refuses it in duration
+func (r *reputation) Ban(id string, duration time.Duration) error {
+	if r.svr == nil {
+		return errNoServer
+	}
+
+	nodeId, err := discovery.HexStr2NodeID(id)
+	if err != nil {
+		return err
+	}
+
+	return r.svr.Ban(nodeId, duration)
+}
+
+// Unban removes the ban of id and resets its score, return false if id isn`t banned
+func (r *reputation) Unban(id string) (bool, error) {
+	if r.svr == nil {
+		return false, errNoServer
+	}
+
+	nodeId, err := discovery.HexStr2NodeID(id)
+	if err != nil {
+		return false, err
+	}
+
+	r.lock.Lock()
+	delete(r.scores, id)
+	r.lock.Unlock()
+
+	return r.svr.Unban(nodeId), nil
+}
+
+func (r *reputation) Bans() []*p2p.BanInfo {
+	if r.svr == nil {
+		return nil
+	}
+
+	return r.svr.Bans()
+}
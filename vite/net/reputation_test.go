@@ -0,0 +1,146 @@
+package net
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/vitelabs/go-vite/crypto/ed25519"
+	"github.com/vitelabs/go-vite/ledger"
+)
+
+type idPeer struct {
+	Peer
+	id string
+}
+
+func (p *idPeer) ID() string {
+	return p.id
+}
+
+func TestReputation_Punish(t *testing.T) {
+	r := newReputation(DefaultBanThreshold, time.Hour, false)
+	p := &idPeer{id: "peer"}
+
+	r.report(p, OffenceInvalidBlock)
+	if s := r.scores[p.id]; s == nil || s.value != maxScore-int(OffenceInvalidBlock) {
+		t.Fatalf("score should be %d", maxScore-int(OffenceInvalidBlock))
+	}
+
+	// nil sender is ignored
+	r.report(nil, OffenceInvalidBlock)
+	if len(r.scores) != 1 {
+		t.Fatal("only the peer should be scored")
+	}
+
+	// recover as time goes by
+	s := r.scores[p.id]
+	s.updated = s.updated.Add(-10 * scoreRecoverInterval)
+	s.recover(time.Now())
+	if s.value != maxScore-int(OffenceInvalidBlock)+10 {
+		t.Fatalf("score should recover to %d, got %d", maxScore-int(OffenceInvalidBlock)+10, s.value)
+	}
+
+	// below threshold, the score is deleted when the peer is banned
+	r.report(p, OffenceDiffGenesis)
+	if _, ok := r.scores[p.id]; ok {
+		t.Fatal("score of the banned peer should be deleted")
+	}
+}
+
+func TestReputation_FirstOffence(t *testing.T) {
+	r := newReputation(DefaultBanThreshold, time.Hour, false)
+
+	// a fresh peer on the chain of a different genesis block is banned at once
+	p := &idPeer{id: "genesis"}
+	r.report(p, OffenceDiffGenesis)
+	if _, ok := r.scores[p.id]; ok {
+		t.Fatal("the peer of a different genesis block should be banned by the first offence")
+	}
+
+	// the other offences are tolerated once
+	p = &idPeer{id: "block"}
+	r.report(p, OffenceInvalidBlock)
+	if _, ok := r.scores[p.id]; !ok {
+		t.Fatal("the peer should not be banned by the first invalid block")
+	}
+	r.report(p, OffenceInvalidBlock)
+	if _, ok := r.scores[p.id]; ok {
+		t.Fatal("the peer should be banned by the second invalid block")
+	}
+}
+
+func TestReputation_Disabled(t *testing.T) {
+	r := newReputation(DefaultBanThreshold, time.Hour, true)
+	p := &idPeer{id: "peer"}
+
+	r.report(p, OffenceDiffGenesis)
+	if len(r.scores) != 0 {
+		t.Fatal("the peer should not be scored")
+	}
+}
+
+func TestInvalidBlock(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	now := time.Now()
+
+	snapshotBlock := &ledger.SnapshotBlock{
+		Height:    2,
+		Timestamp: &now,
+		PublicKey: pub,
+	}
+	snapshotBlock.Hash = snapshotBlock.ComputeHash()
+	snapshotBlock.Signature = ed25519.Sign(priv, snapshotBlock.Hash.Bytes())
+	if invalidSnapshotBlock(snapshotBlock) {
+		t.Fatal("the signed snapshot block should be valid")
+	}
+	// a block of a future timestamp fails the verifier, but it`s not an offence
+	future := now.Add(2 * time.Hour)
+	snapshotBlock.Timestamp = &future
+	snapshotBlock.Hash = snapshotBlock.ComputeHash()
+	snapshotBlock.Signature = ed25519.Sign(priv, snapshotBlock.Hash.Bytes())
+	if invalidSnapshotBlock(snapshotBlock) {
+		t.Fatal("the snapshot block of a future timestamp should be valid")
+	}
+	snapshotBlock.Height++
+	if !invalidSnapshotBlock(snapshotBlock) {
+		t.Fatal("the snapshot block of a wrong hash should be invalid")
+	}
+	snapshotBlock.Hash = snapshotBlock.ComputeHash()
+	if !invalidSnapshotBlock(snapshotBlock) {
+		t.Fatal("the snapshot block of a wrong signature should be invalid")
+	}
+
+	accountBlock := &ledger.AccountBlock{
+		BlockType: ledger.BlockTypeReceive,
+		Height:    1,
+		Fee:       big.NewInt(0),
+		Timestamp: &now,
+		PublicKey: pub,
+	}
+	accountBlock.Hash = accountBlock.ComputeHash()
+	if !invalidAccountBlock(accountBlock) {
+		t.Fatal("the receive block without a signature should be invalid")
+	}
+	accountBlock.Signature = ed25519.Sign(priv, accountBlock.Hash.Bytes())
+	if invalidAccountBlock(accountBlock) {
+		t.Fatal("the signed receive block should be valid")
+	}
+
+	// the send blocks of contracts aren`t signed
+	sendBlock := &ledger.AccountBlock{
+		BlockType: ledger.BlockTypeSendCall,
+		Height:    1,
+		Amount:    big.NewInt(1),
+		Fee:       big.NewInt(0),
+		Timestamp: &now,
+	}
+	sendBlock.Hash = sendBlock.ComputeHash()
+	if invalidAccountBlock(sendBlock) {
+		t.Fatal("the unsigned send block should be valid")
+	}
+	sendBlock.Amount = nil
+	if !invalidAccountBlock(sendBlock) {
+		t.Fatal("the send block without amount should be invalid")
+	}
+}
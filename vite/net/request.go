@@ -70,8 +70,8 @@ type piece interface {
 }
 
 type blockReceiver interface {
-	receiveSnapshotBlock(block *ledger.SnapshotBlock)
-	receiveAccountBlock(block *ledger.AccountBlock)
+	receiveSnapshotBlock(block *ledger.SnapshotBlock, sender Peer)
+	receiveAccountBlock(block *ledger.AccountBlock, sender Peer)
 	catch(piece)
 }
 
@@ -180,11 +180,11 @@ func (p *chunkPool) Handle(msg *p2p.Msg, sender Peer) error {
 
 		// receive account blocks first
 		for _, block := range res.ABlocks {
-			p.handler.receiveAccountBlock(block)
+			p.handler.receiveAccountBlock(block, sender)
 		}
 
 		for _, block := range res.SBlocks {
-			p.handler.receiveSnapshotBlock(block)
+			p.handler.receiveSnapshotBlock(block, sender)
 		}
 
 		c := p.chunk(msg.Id)
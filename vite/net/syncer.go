@@ -2,6 +2,7 @@ package net
 
 import (
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -98,16 +99,18 @@ type syncer struct {
 	feed       *SyncStateFeed
 	chain      Chain // query latest block
 	pEvent     chan *peerEvent
-	receiver   Receiver
+	receiver   *receiver
+	rep        *reputation
 	fc         *fileClient
 	pool       *chunkPool
-	chunked    int32
+	chunkLock  sync.Mutex
+	chunked    map[uint64]struct{} // id of the GetSubLedger requests of which the chunks have been added
 	running    int32
 	term       chan struct{}
 	log        log15.Logger
 }
 
-func newSyncer(chain Chain, peers *peerSet, gid MsgIder, receiver Receiver) *syncer {
+func newSyncer(chain Chain, peers *peerSet, gid MsgIder, receiver *receiver, rep *reputation) *syncer {
 	s := &syncer{
 		state:      SyncNotStart,
 		term:       make(chan struct{}),
@@ -116,8 +119,10 @@ func newSyncer(chain Chain, peers *peerSet, gid MsgIder, receiver Receiver) *syn
 		chain:      chain,
 		peers:      peers,
 		pEvent:     make(chan *peerEvent, 1),
+		chunked:    make(map[uint64]struct{}),
 		log:        log15.New("module", "net/syncer"),
 		receiver:   receiver,
+		rep:        rep,
 	}
 
 	// subscribe peer add/del event
@@ -149,7 +154,6 @@ func (s *syncer) Start() {
 	}
 
 	defer atomic.StoreInt32(&s.running, 0)
-	defer atomic.StoreInt32(&s.chunked, 0)
 
 	// prepare to request file
 	s.fc.start()
@@ -303,13 +307,19 @@ func (s *syncer) inc() {
 	}
 }
 
+// sync requests the sub ledger by segments of maxSubLedgerCount heights, the id of request is the index of
+// segment counted from 1, so the chunks of a segment are added once though all peers reply it.
 func (s *syncer) sync() {
 	peerList := s.peers.Pick(s.from + 1)
 
+	s.chunkLock.Lock()
+	s.chunked = make(map[uint64]struct{})
+	s.chunkLock.Unlock()
+
 	var msg *message.GetSubLedger
 
 	from, to := s.from, s.to
-	var pTo, pHeight uint64
+	var pTo, pHeight, count uint64
 	for _, peer := range peerList {
 		pHeight = peer.Height()
 		if pHeight > to {
@@ -318,18 +328,37 @@ func (s *syncer) sync() {
 			pTo = pHeight
 		}
 
-		msg = &message.GetSubLedger{
-			From:    ledger.HashHeight{Height: from},
-			Count:   pTo - from + 1,
-			Forward: true,
-		}
+		for id, sFrom := uint64(1), from; sFrom <= pTo; id, sFrom = id+1, sFrom+maxSubLedgerCount {
+			if count = pTo - sFrom + 1; count > maxSubLedgerCount {
+				count = maxSubLedgerCount
+			}
 
-		peer.Send(GetSubLedgerCode, 0, msg)
+			msg = &message.GetSubLedger{
+				From:    ledger.HashHeight{Height: sFrom},
+				Count:   count,
+				Forward: true,
+			}
+
+			peer.Send(GetSubLedgerCode, id, msg)
+		}
 
 		s.log.Info(fmt.Sprintf("sync from %d to %d to %s at %d", from, pTo, peer.RemoteAddr(), peer.Height()))
 	}
 }
 
+// chunk returns false if the chunks of request id have been added
+func (s *syncer) chunk(id uint64) bool {
+	s.chunkLock.Lock()
+	defer s.chunkLock.Unlock()
+
+	if _, ok := s.chunked[id]; ok {
+		return false
+	}
+
+	s.chunked[id] = struct{}{}
+	return true
+}
+
 func (s *syncer) ID() string {
 	return "syncer"
 }
@@ -345,17 +374,23 @@ func (s *syncer) Handle(msg *p2p.Msg, sender Peer) error {
 
 		if err := res.Deserialize(msg.Payload); err != nil {
 			s.log.Error(fmt.Sprintf("descerialize %s from %s error: %v", res, sender.RemoteAddr(), err))
+			s.rep.report(sender, OffenceBadMessage)
 			return err
 		}
 
 		s.log.Info(fmt.Sprintf("receive %s from %s", res, sender.RemoteAddr()))
 
+		// the files of old version have no hash to be verified by
+		if sender.Version() < compressedFileVersion {
+			res.Files, res.Chunks = filesToChunks(res.Files, res.Chunks)
+		}
+
 		if len(res.Files) > 0 {
 			s.fc.gotFiles(res.Files, sender)
 		}
 
 		if sender.Height() >= s.to && len(res.Chunks) > 0 {
-			if atomic.CompareAndSwapInt32(&s.chunked, 0, 1) {
+			if s.chunk(msg.Id) {
 				for _, c := range res.Chunks {
 					if len(c) == 2 && c[1] > 0 && c[1] >= c[0] {
 						s.pool.add(c[0], c[1])
@@ -365,6 +400,9 @@ func (s *syncer) Handle(msg *p2p.Msg, sender Peer) error {
 		}
 	} else if cmd == SubLedgerCode {
 		s.pool.Handle(msg, sender)
+	} else if cmd == ExceptionCode {
+		// the peer can`t answer our request, it may be pruned or throttle us
+		s.log.Warn(fmt.Sprintf("receive exception from %s", sender.RemoteAddr()))
 	} else {
 		s.log.Warn(fmt.Sprintf("syncer: got %d need %d", msg.Cmd, SubLedgerCode))
 	}
@@ -421,15 +459,15 @@ func (s *syncer) UnsubscribeSyncStatus(subId int) {
 //	return block.Height - s.from
 //}
 
-func (s *syncer) receiveSnapshotBlock(block *ledger.SnapshotBlock) {
+func (s *syncer) receiveSnapshotBlock(block *ledger.SnapshotBlock, sender Peer) {
 	s.log.Debug(fmt.Sprintf("syncer: receive SnapshotBlock %s/%d", block.Hash, block.Height))
-	s.receiver.ReceiveSnapshotBlock(block)
+	s.receiver.receiveSnapshotBlock(block, sender)
 	s.inc()
 }
 
-func (s *syncer) receiveAccountBlock(block *ledger.AccountBlock) {
+func (s *syncer) receiveAccountBlock(block *ledger.AccountBlock, sender Peer) {
 	s.log.Debug(fmt.Sprintf("syncer: receive AccountBlock %s/%d", block.Hash, block.Height))
-	s.receiver.ReceiveAccountBlock(block)
+	s.receiver.receiveAccountBlock(block, sender)
 }
 
 type SyncStatus struct {
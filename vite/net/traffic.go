@@ -0,0 +1,264 @@
+package net
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vitelabs/go-vite/monitor"
+	"github.com/vitelabs/go-vite/vite/net/message"
+)
+
+// @section meter
+// Meter counts the messages and bytes of payload
+type Meter struct {
+	Msgs  uint64 `json:"msgs"`
+	Bytes uint64 `json:"bytes"`
+}
+
+func (m *Meter) add(size int) {
+	m.Msgs++
+	m.Bytes += uint64(size)
+}
+
+// traffic meters the messages in and out of a peer by ViteCmd
+type traffic struct {
+	lock    sync.Mutex
+	in      map[ViteCmd]*Meter
+	out     map[ViteCmd]*Meter
+	limited map[ViteCmd]uint64 // requests delayed by rate limit
+}
+
+func newTraffic() *traffic {
+	return &traffic{
+		in:      make(map[ViteCmd]*Meter),
+		out:     make(map[ViteCmd]*Meter),
+		limited: make(map[ViteCmd]uint64),
+	}
+}
+
+func (t *traffic) record(dir map[ViteCmd]*Meter, code ViteCmd, size int) {
+	t.lock.Lock()
+	m, ok := dir[code]
+	if !ok {
+		m = new(Meter)
+		dir[code] = m
+	}
+	m.add(size)
+	t.lock.Unlock()
+}
+
+func (t *traffic) recordIn(code ViteCmd, size int) {
+	t.record(t.in, code, size)
+	monitor.LogEventNum("net/traffic", "in_"+code.String(), size)
+}
+
+func (t *traffic) recordOut(code ViteCmd, size int) {
+	t.record(t.out, code, size)
+	monitor.LogEventNum("net/traffic", "out_"+code.String(), size)
+}
+
+func (t *traffic) recordLimited(code ViteCmd) {
+	t.lock.Lock()
+	t.limited[code]++
+	t.lock.Unlock()
+
+	monitor.LogEvent("net/limit", code.String())
+}
+
+// TrafficInfo is the snapshot of traffic, key of the maps is message name
+type TrafficInfo struct {
+	In        Meter             `json:"in"`
+	Out       Meter             `json:"out"`
+	InDetail  map[string]Meter  `json:"inDetail"`
+	OutDetail map[string]Meter  `json:"outDetail"`
+	Limited   map[string]uint64 `json:"limited"`
+}
+
+func (t *traffic) info() *TrafficInfo {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	info := &TrafficInfo{
+		InDetail:  make(map[string]Meter, len(t.in)),
+		OutDetail: make(map[string]Meter, len(t.out)),
+		Limited:   make(map[string]uint64, len(t.limited)),
+	}
+
+	for code, m := range t.in {
+		info.InDetail[code.String()] = *m
+		info.In.Msgs += m.Msgs
+		info.In.Bytes += m.Bytes
+	}
+
+	for code, m := range t.out {
+		info.OutDetail[code.String()] = *m
+		info.Out.Msgs += m.Msgs
+		info.Out.Bytes += m.Bytes
+	}
+
+	for code, n := range t.limited {
+		info.Limited[code.String()] = n
+	}
+
+	return info
+}
+
+// @section rate limit
+// RateLimit allows Rate tokens per second and Burst tokens at most in a moment, a request costs tokens by the
+// range it asks for, see requestCost. Rate is zero means unlimited.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// DefaultRateLimits limit the requests will make us read much history from disk
+var DefaultRateLimits = map[ViteCmd]RateLimit{
+	GetSubLedgerCode:           {Rate: 10, Burst: 100},
+	GetSnapshotBlocksCode:      {Rate: 10, Burst: 50},
+	GetAccountBlocksCode:       {Rate: 20, Burst: 100},
+	GetAccountBlocksByHashCode: {Rate: 200, Burst: 1000}, // blocks announced by hash are fetched one by one
+	GetChunkCode:               {Rate: 5, Burst: 20},
+}
+
+// a token of GetSubLedger is for subLedgerUnit heights, the files of them are only listed but not read
+const subLedgerUnit = 100000
+
+// requestCost returns the tokens of the request, a token for chunk blocks or hashes it asks for, except
+// GetSubLedger. the request can`t be deserialized costs a token, the handler will reject it.
+func requestCost(code ViteCmd, payload []byte) float64 {
+	var count, unit uint64 = 1, chunk
+
+	switch code {
+	case GetSubLedgerCode:
+		unit = subLedgerUnit
+		req := new(message.GetSubLedger)
+		if req.Deserialize(payload) == nil {
+			count = req.Count
+		}
+	case GetSnapshotBlocksCode:
+		req := new(message.GetSnapshotBlocks)
+		if req.Deserialize(payload) == nil {
+			count = req.Count
+		}
+	case GetAccountBlocksCode:
+		req := new(message.GetAccountBlocks)
+		if req.Deserialize(payload) == nil {
+			count = req.Count
+		}
+	case GetAccountBlocksByHashCode:
+		req := new(message.BlockHashes)
+		if req.Deserialize(payload) == nil {
+			count = uint64(len(req.Hashes))
+		}
+	case GetChunkCode:
+		req := new(message.GetChunk)
+		if req.Deserialize(payload) == nil {
+			if req.Start > req.End {
+				req.Start, req.End = req.End, req.Start
+			}
+			count = req.End - req.Start + 1
+		}
+	}
+
+	cost := count / unit
+	if count%unit != 0 || cost == 0 {
+		cost++
+	}
+
+	return float64(cost)
+}
+
+// parse the limits keyed by message name, and merge into the default limits
+func makeRateLimits(limits map[string]RateLimit) (map[ViteCmd]RateLimit, error) {
+	merged := make(map[ViteCmd]RateLimit, len(DefaultRateLimits)+len(limits))
+	for code, limit := range DefaultRateLimits {
+		merged[code] = limit
+	}
+
+	for name, limit := range limits {
+		code, ok := parseViteCmd(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown message %s of rate limit", name)
+		}
+
+		if limit.Rate < 0 || limit.Burst < 0 {
+			return nil, fmt.Errorf("rate limit of %s should not be negative", name)
+		}
+
+		merged[code] = limit
+	}
+
+	return merged, nil
+}
+
+// token bucket
+type bucket struct {
+	rate    float64
+	burst   float64
+	tokens  float64
+	updated time.Time
+}
+
+func newBucket(limit RateLimit, now time.Time) *bucket {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &bucket{
+		rate:    limit.Rate,
+		burst:   burst,
+		tokens:  burst,
+		updated: now,
+	}
+}
+
+// reserve takes cost tokens, and returns how long to wait until the tokens are refilled. the tokens owed are
+// refilled before the next request. cost is no more than burst, so a request waits burst / rate at most.
+func (b *bucket) reserve(cost float64, now time.Time) time.Duration {
+	b.tokens += now.Sub(b.updated).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.updated = now
+
+	if cost > b.burst {
+		cost = b.burst
+	}
+
+	if b.tokens -= cost; b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// limiter holds token buckets of a peer, it`s used only in the goroutine handle messages of the peer
+type limiter struct {
+	buckets map[ViteCmd]*bucket
+}
+
+func newLimiter(limits map[ViteCmd]RateLimit) *limiter {
+	l := &limiter{
+		buckets: make(map[ViteCmd]*bucket, len(limits)),
+	}
+
+	now := time.Now()
+	for code, limit := range limits {
+		if limit.Rate > 0 {
+			l.buckets[code] = newBucket(limit, now)
+		}
+	}
+
+	return l
+}
+
+// reserve returns how long the request should wait before handled
+func (l *limiter) reserve(code ViteCmd, payload []byte) time.Duration {
+	if b, ok := l.buckets[code]; ok {
+		return b.reserve(requestCost(code, payload), time.Now())
+	}
+
+	return 0
+}
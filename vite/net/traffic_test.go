@@ -0,0 +1,128 @@
+package net
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/p2p"
+	"github.com/vitelabs/go-vite/vite/net/message"
+)
+
+func TestBucket_Reserve(t *testing.T) {
+	now := time.Now()
+	b := newBucket(RateLimit{Rate: 2, Burst: 3}, now)
+
+	for i := 0; i < 3; i++ {
+		if delay := b.reserve(1, now); delay != 0 {
+			t.Fatalf("request %d should not be delayed in burst, got %s", i, delay)
+		}
+	}
+
+	if delay := b.reserve(1, now); delay != 500*time.Millisecond {
+		t.Fatalf("request should wait a token when burst is exhausted, got %s", delay)
+	}
+
+	// the owed token is refilled first, 2 tokens per second
+	now = now.Add(time.Second)
+	if delay := b.reserve(1, now); delay != 0 {
+		t.Fatalf("tokens should be refilled, got %s", delay)
+	}
+	if delay := b.reserve(1, now); delay != 500*time.Millisecond {
+		t.Fatalf("only 2 tokens should be refilled, got %s", delay)
+	}
+
+	// refill no more than burst, and cost no more than burst
+	now = now.Add(time.Hour)
+	if delay := b.reserve(3, now); delay != 0 {
+		t.Fatalf("tokens should be refilled to burst, got %s", delay)
+	}
+	if delay := b.reserve(100, now); delay != 1500*time.Millisecond {
+		t.Fatalf("request should wait the burst at most, got %s", delay)
+	}
+}
+
+func TestRequestCost(t *testing.T) {
+	payload := func(msg p2p.Serializable) []byte {
+		data, err := msg.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	cases := []struct {
+		code    ViteCmd
+		payload []byte
+		cost    float64
+	}{
+		{GetChunkCode, payload(&message.GetChunk{Start: 1, End: chunk}), 1},
+		{GetChunkCode, payload(&message.GetChunk{Start: chunk + 1, End: 1}), 2},
+		{GetSnapshotBlocksCode, payload(&message.GetSnapshotBlocks{Count: 0}), 1},
+		{GetAccountBlocksCode, payload(&message.GetAccountBlocks{Count: 10 * chunk}), 10},
+		{GetAccountBlocksByHashCode, payload(&message.BlockHashes{Hashes: make([]types.Hash, chunk+1)}), 2},
+		{GetSubLedgerCode, payload(&message.GetSubLedger{Count: subLedgerUnit}), 1},
+		{GetSubLedgerCode, payload(&message.GetSubLedger{Count: math.MaxUint64}), math.MaxUint64/subLedgerUnit + 1},
+		{GetChunkCode, []byte("bad message"), 1},
+	}
+
+	for i, c := range cases {
+		if cost := requestCost(c.code, c.payload); cost != c.cost {
+			t.Errorf("cost of request %d should be %f, got %f", i, c.cost, cost)
+		}
+	}
+}
+
+func TestMakeRateLimits(t *testing.T) {
+	limits, err := makeRateLimits(map[string]RateLimit{
+		"GetChunkMsg": {Rate: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if limits[GetSubLedgerCode] != DefaultRateLimits[GetSubLedgerCode] {
+		t.Fatal("default limit should be kept")
+	}
+
+	l := newLimiter(limits)
+	if _, ok := l.buckets[GetChunkCode]; ok {
+		t.Fatal("GetChunkMsg should be unlimited")
+	}
+
+	if _, err = makeRateLimits(map[string]RateLimit{"UnknownMsg": {Rate: 1}}); err == nil {
+		t.Fatal("unknown message should be rejected")
+	}
+}
+
+func TestTraffic_Info(t *testing.T) {
+	tr := newTraffic()
+	tr.recordIn(GetChunkCode, 10)
+	tr.recordIn(GetChunkCode, 20)
+	tr.recordOut(FileListCode, 100)
+	tr.recordLimited(GetChunkCode)
+
+	info := tr.info()
+	if info.In.Msgs != 2 || info.In.Bytes != 30 {
+		t.Fatalf("wrong in traffic: %+v", info.In)
+	}
+	if m := info.OutDetail[FileListCode.String()]; m.Msgs != 1 || m.Bytes != 100 {
+		t.Fatalf("wrong out traffic: %+v", m)
+	}
+	if info.Limited[GetChunkCode.String()] != 1 {
+		t.Fatal("wrong limited count")
+	}
+}
+
+func TestGetChunkHandler_MaxCount(t *testing.T) {
+	h := &getChunkHandler{}
+
+	msg := p2p.NewMsg()
+	msg.Cmd = p2p.Cmd(GetChunkCode)
+	msg.Payload, _ = (&message.GetChunk{Start: maxChunkCount + 1, End: 1}).Serialize()
+
+	if err := h.Handle(msg, &sendPeer{}); err != errTooLargeChunk {
+		t.Fatalf("should get errTooLargeChunk, got %v", err)
+	}
+}
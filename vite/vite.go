@@ -58,6 +58,10 @@ func New(cfg *config.Config, walletManager *wallet.Manager) (vite *Vite, err err
 
 	// net
 	netVerifier := verifier.NewNetVerifier(sbVerifier, aVerifier)
+	rateLimits := make(map[string]net.RateLimit, len(cfg.RateLimits))
+	for name, limit := range cfg.RateLimits {
+		rateLimits[name] = net.RateLimit{Rate: limit.Rate, Burst: limit.Burst}
+	}
 	net := net.New(&net.Config{
 		Single:       cfg.Single,
 		Port:         uint16(cfg.FilePort),
@@ -67,6 +71,11 @@ func New(cfg *config.Config, walletManager *wallet.Manager) (vite *Vite, err err
 		Topic:        cfg.Topic,
 		Interval:     cfg.Interval,
 		TopoDisabled: cfg.TopoDisabled,
+		BanThreshold: cfg.BanThreshold,
+		BanDuration:  cfg.BanDuration,
+		RateLimits:   rateLimits,
+
+		ReputationDisabled: cfg.ReputationDisabled,
 	})
 
 	// vite
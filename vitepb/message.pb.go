@@ -26,6 +26,7 @@ type Handshake struct {
 	Port                 uint32   `protobuf:"varint,3,opt,name=Port,proto3" json:"Port,omitempty"`
 	Current              []byte   `protobuf:"bytes,4,opt,name=Current,proto3" json:"Current,omitempty"`
 	Genesis              []byte   `protobuf:"bytes,5,opt,name=Genesis,proto3" json:"Genesis,omitempty"`
+	Version              uint32   `protobuf:"varint,6,opt,name=Version,proto3" json:"Version,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -91,6 +92,13 @@ func (m *Handshake) GetGenesis() []byte {
 	return nil
 }
 
+func (m *Handshake) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
 type BlockID struct {
 	Hash                 []byte   `protobuf:"bytes,1,opt,name=Hash,proto3" json:"Hash,omitempty"`
 	Height               uint64   `protobuf:"varint,2,opt,name=Height,proto3" json:"Height,omitempty"`
@@ -144,6 +152,8 @@ type CompressedFileMeta struct {
 	Filename             string   `protobuf:"bytes,3,opt,name=Filename,proto3" json:"Filename,omitempty"`
 	FileSize             int64    `protobuf:"varint,4,opt,name=FileSize,proto3" json:"FileSize,omitempty"`
 	BlockNumbers         uint64   `protobuf:"varint,5,opt,name=BlockNumbers,proto3" json:"BlockNumbers,omitempty"`
+	Codec                uint32   `protobuf:"varint,6,opt,name=Codec,proto3" json:"Codec,omitempty"`
+	Hash                 []byte   `protobuf:"bytes,7,opt,name=Hash,proto3" json:"Hash,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -209,6 +219,20 @@ func (m *CompressedFileMeta) GetBlockNumbers() uint64 {
 	return 0
 }
 
+func (m *CompressedFileMeta) GetCodec() uint32 {
+	if m != nil {
+		return m.Codec
+	}
+	return 0
+}
+
+func (m *CompressedFileMeta) GetHash() []byte {
+	if m != nil {
+		return m.Hash
+	}
+	return nil
+}
+
 type FileList struct {
 	Files                []*CompressedFileMeta `protobuf:"bytes,1,rep,name=Files,proto3" json:"Files,omitempty"`
 	Chunks               []uint64              `protobuf:"varint,2,rep,packed,name=Chunks,proto3" json:"Chunks,omitempty"`
@@ -609,6 +633,45 @@ func (m *AccountBlocks) GetBlocks() []*AccountBlock {
 	return nil
 }
 
+type BlockHashes struct {
+	Hashes               [][]byte `protobuf:"bytes,1,rep,name=Hashes,proto3" json:"Hashes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BlockHashes) Reset()         { *m = BlockHashes{} }
+func (m *BlockHashes) String() string { return proto.CompactTextString(m) }
+func (*BlockHashes) ProtoMessage()    {}
+func (*BlockHashes) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2a6a8486deb9ab39, []int{11}
+}
+
+func (m *BlockHashes) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BlockHashes.Unmarshal(m, b)
+}
+func (m *BlockHashes) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BlockHashes.Marshal(b, m, deterministic)
+}
+func (m *BlockHashes) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BlockHashes.Merge(m, src)
+}
+func (m *BlockHashes) XXX_Size() int {
+	return xxx_messageInfo_BlockHashes.Size(m)
+}
+func (m *BlockHashes) XXX_DiscardUnknown() {
+	xxx_messageInfo_BlockHashes.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BlockHashes proto.InternalMessageInfo
+
+func (m *BlockHashes) GetHashes() [][]byte {
+	if m != nil {
+		return m.Hashes
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Handshake)(nil), "vitepb.Handshake")
 	proto.RegisterType((*BlockID)(nil), "vitepb.BlockID")
@@ -621,45 +684,48 @@ func init() {
 	proto.RegisterType((*SnapshotBlocks)(nil), "vitepb.SnapshotBlocks")
 	proto.RegisterType((*GetAccountBlocks)(nil), "vitepb.GetAccountBlocks")
 	proto.RegisterType((*AccountBlocks)(nil), "vitepb.AccountBlocks")
+	proto.RegisterType((*BlockHashes)(nil), "vitepb.BlockHashes")
 }
 
 func init() { proto.RegisterFile("vitepb/message.proto", fileDescriptor_2a6a8486deb9ab39) }
 
 var fileDescriptor_2a6a8486deb9ab39 = []byte{
-	// 548 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x94, 0x4d, 0x6f, 0xd3, 0x4c,
-	0x10, 0xc7, 0xe5, 0xd8, 0x79, 0x9b, 0xa4, 0xcf, 0x53, 0x56, 0x01, 0x59, 0x81, 0x83, 0x65, 0x2e,
-	0x39, 0x40, 0x8a, 0x82, 0xe0, 0x86, 0x50, 0x08, 0x4d, 0x82, 0x54, 0x2a, 0xb4, 0xfe, 0x00, 0x68,
-	0x1d, 0x8f, 0x62, 0x93, 0xda, 0x8e, 0x76, 0xd7, 0x20, 0x71, 0x43, 0xdc, 0xf8, 0x34, 0x7c, 0x44,
-	0xb4, 0x2f, 0x4e, 0xe2, 0x42, 0xa5, 0xde, 0xf6, 0x3f, 0x33, 0xff, 0x1d, 0xff, 0x76, 0x26, 0x81,
-	0xd1, 0xd7, 0x4c, 0xe2, 0x3e, 0xbe, 0xc8, 0x51, 0x08, 0xb6, 0xc5, 0xe9, 0x9e, 0x97, 0xb2, 0x24,
-	0x1d, 0x13, 0x1d, 0x8f, 0x6d, 0x96, 0x6d, 0x36, 0x65, 0x55, 0xc8, 0xcf, 0xf1, 0x4d, 0xb9, 0xd9,
-	0x99, 0x9a, 0xf1, 0x63, 0x9b, 0x13, 0x05, 0xdb, 0x8b, 0xb4, 0x6c, 0x24, 0xc3, 0x9f, 0x0e, 0xf4,
-	0xd7, 0xac, 0x48, 0x44, 0xca, 0x76, 0x48, 0x1e, 0x41, 0x67, 0x91, 0x27, 0x11, 0x4a, 0xdf, 0x09,
-	0x9c, 0x89, 0x47, 0xad, 0x52, 0xf1, 0x35, 0x66, 0xdb, 0x54, 0xfa, 0x2d, 0x13, 0x37, 0x8a, 0x10,
-	0xf0, 0x3e, 0x95, 0x5c, 0xfa, 0x6e, 0xe0, 0x4c, 0xce, 0xa8, 0x3e, 0x13, 0x1f, 0xba, 0x8b, 0x8a,
-	0x73, 0x2c, 0xa4, 0xef, 0x05, 0xce, 0x64, 0x48, 0x6b, 0xa9, 0x32, 0x2b, 0x2c, 0x50, 0x64, 0xc2,
-	0x6f, 0x9b, 0x8c, 0x95, 0xe1, 0x2b, 0xe8, 0xbe, 0x53, 0x1f, 0xf5, 0xe1, 0xbd, 0xba, 0x72, 0xcd,
-	0x44, 0xaa, 0x3f, 0x60, 0x48, 0xf5, 0xf9, 0xae, 0xf6, 0xe1, 0x6f, 0x07, 0xc8, 0xa2, 0xcc, 0xf7,
-	0x1c, 0x85, 0xc0, 0x64, 0x99, 0xdd, 0xe0, 0x47, 0x94, 0x8c, 0x04, 0x30, 0x88, 0x24, 0xe3, 0xd2,
-	0x7a, 0x0c, 0xca, 0x69, 0x88, 0x3c, 0x81, 0xfe, 0x65, 0x91, 0x34, 0xee, 0x3c, 0x06, 0xc8, 0x18,
-	0x7a, 0xea, 0xae, 0x82, 0xe5, 0xa8, 0xc9, 0xfa, 0xf4, 0xa0, 0xeb, 0x5c, 0x94, 0x7d, 0x47, 0x8d,
-	0xe7, 0xd2, 0x83, 0x26, 0x21, 0x0c, 0x35, 0xc5, 0x75, 0x95, 0xc7, 0xc8, 0x0d, 0xa4, 0x47, 0x1b,
-	0xb1, 0xf0, 0x8b, 0xf1, 0x5f, 0x65, 0x42, 0x92, 0x17, 0xd0, 0x56, 0x67, 0xe1, 0x3b, 0x81, 0x3b,
-	0x19, 0xcc, 0xc6, 0x53, 0x33, 0xa8, 0xe9, 0xdf, 0x48, 0xd4, 0x14, 0xea, 0xf9, 0xa4, 0x55, 0xb1,
-	0x13, 0x7e, 0x2b, 0x70, 0xf5, 0x7c, 0xb4, 0x22, 0x23, 0x68, 0x5f, 0x97, 0xc5, 0xc6, 0x7c, 0xae,
-	0x47, 0x8d, 0x08, 0x5f, 0x43, 0x6f, 0x85, 0xd2, 0x38, 0x55, 0x05, 0xcb, 0x6d, 0xaf, 0x3e, 0x35,
-	0xe2, 0xe8, 0x6b, 0x9d, 0xfa, 0x66, 0xda, 0xa7, 0xaf, 0x56, 0x15, 0xfa, 0xe1, 0xec, 0x2b, 0x1a,
-	0x41, 0xce, 0xc1, 0xbd, 0x2c, 0x12, 0xeb, 0x52, 0xc7, 0xf0, 0x97, 0x03, 0xfd, 0xa8, 0x8a, 0xaf,
-	0x30, 0xd9, 0x22, 0x27, 0x17, 0xd0, 0x8d, 0x34, 0x76, 0xcd, 0xf6, 0xb0, 0x66, 0x8b, 0xec, 0x12,
-	0xea, 0x2c, 0xad, 0xab, 0xc8, 0x14, 0xba, 0x73, 0x6b, 0x68, 0x69, 0xc3, 0xa8, 0x36, 0xcc, 0xcd,
-	0x46, 0xdb, 0x7a, 0x5b, 0xa4, 0x06, 0x38, 0x8f, 0xed, 0xbb, 0x5a, 0xe8, 0x63, 0x20, 0x4c, 0xe1,
-	0xc1, 0x0a, 0x65, 0xa3, 0x95, 0x20, 0x4f, 0xc1, 0x5b, 0xf2, 0x32, 0xd7, 0x20, 0x83, 0xd9, 0xff,
-	0xf5, 0xfd, 0x76, 0xef, 0xa8, 0x4e, 0x2a, 0xdc, 0x85, 0x6a, 0x57, 0x3f, 0x88, 0x16, 0x6a, 0x71,
-	0x97, 0x25, 0xff, 0xc6, 0x78, 0xa2, 0x7b, 0xf5, 0x68, 0x2d, 0xc3, 0xb7, 0xf0, 0xdf, 0xad, 0x36,
-	0xcf, 0xa1, 0x73, 0x1f, 0x72, 0x5b, 0x14, 0xfe, 0x70, 0xe0, 0x7c, 0x85, 0xf2, 0x94, 0x52, 0xa8,
-	0x7e, 0xf3, 0x24, 0x51, 0x2b, 0x60, 0x7f, 0x06, 0xb5, 0x3c, 0x40, 0xb4, 0xee, 0x05, 0xe1, 0xde,
-	0x01, 0xe1, 0x35, 0x21, 0xde, 0xc0, 0x59, 0xb3, 0xff, 0xb3, 0x5b, 0x0c, 0xff, 0x1e, 0x86, 0xad,
-	0x89, 0x3b, 0xfa, 0x9f, 0xe4, 0xe5, 0x9f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x82, 0x97, 0xa9, 0x9d,
-	0xa2, 0x04, 0x00, 0x00,
+	// 585 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x94, 0x5d, 0x8b, 0xd3, 0x4c,
+	0x14, 0xc7, 0x49, 0x93, 0xed, 0xcb, 0x69, 0xf7, 0x79, 0xd6, 0xa1, 0x4a, 0xa8, 0x5e, 0x84, 0x88,
+	0xd0, 0x0b, 0xed, 0x4a, 0x45, 0xef, 0x44, 0x6a, 0xdd, 0xb6, 0xc2, 0xba, 0xc8, 0x04, 0xbc, 0x95,
+	0xa4, 0x19, 0x9a, 0xd8, 0xcd, 0x4c, 0x99, 0x99, 0x28, 0x78, 0xe7, 0xad, 0xdf, 0xc1, 0x2f, 0xe6,
+	0xa7, 0x91, 0x79, 0x6b, 0x9b, 0xd5, 0x85, 0xbd, 0x3b, 0xff, 0xf3, 0x32, 0x27, 0xbf, 0x39, 0x67,
+	0x02, 0xc3, 0xaf, 0xa5, 0x24, 0xbb, 0xec, 0xbc, 0x22, 0x42, 0xa4, 0x1b, 0x32, 0xd9, 0x71, 0x26,
+	0x19, 0x6a, 0x1b, 0xef, 0x68, 0x64, 0xa3, 0xe9, 0x7a, 0xcd, 0x6a, 0x2a, 0x3f, 0x67, 0xd7, 0x6c,
+	0xbd, 0x35, 0x39, 0xa3, 0x87, 0x36, 0x26, 0x68, 0xba, 0x13, 0x05, 0x6b, 0x04, 0xe3, 0x5f, 0x1e,
+	0xf4, 0x56, 0x29, 0xcd, 0x45, 0x91, 0x6e, 0x09, 0x7a, 0x00, 0xed, 0x79, 0x95, 0x27, 0x44, 0x86,
+	0x5e, 0xe4, 0x8d, 0x03, 0x6c, 0x95, 0xf2, 0xaf, 0x48, 0xb9, 0x29, 0x64, 0xd8, 0x32, 0x7e, 0xa3,
+	0x10, 0x82, 0xe0, 0x23, 0xe3, 0x32, 0xf4, 0x23, 0x6f, 0x7c, 0x8a, 0xb5, 0x8d, 0x42, 0xe8, 0xcc,
+	0x6b, 0xce, 0x09, 0x95, 0x61, 0x10, 0x79, 0xe3, 0x01, 0x76, 0x52, 0x45, 0x96, 0x84, 0x12, 0x51,
+	0x8a, 0xf0, 0xc4, 0x44, 0xac, 0x54, 0x91, 0x4f, 0x84, 0x8b, 0x92, 0xd1, 0xb0, 0xad, 0x8f, 0x72,
+	0x32, 0x7e, 0x09, 0x9d, 0xb7, 0xea, 0x73, 0xdf, 0xbf, 0x53, 0xcd, 0x56, 0xa9, 0x28, 0xf4, 0xa7,
+	0x0d, 0xb0, 0xb6, 0x6f, 0xfb, 0xb0, 0xf8, 0xb7, 0x07, 0x68, 0xce, 0xaa, 0x1d, 0x27, 0x42, 0x90,
+	0x7c, 0x51, 0x5e, 0x93, 0x0f, 0x44, 0xa6, 0x28, 0x82, 0x7e, 0x22, 0x53, 0x2e, 0x6d, 0x8d, 0x81,
+	0x3c, 0x76, 0xa1, 0x47, 0xd0, 0xbb, 0xa0, 0x79, 0xe3, 0xcc, 0x83, 0x03, 0x8d, 0xa0, 0xab, 0xce,
+	0xa2, 0x69, 0x45, 0x34, 0x73, 0x0f, 0xef, 0xb5, 0x8b, 0x25, 0xe5, 0x77, 0xa2, 0xc1, 0x7d, 0xbc,
+	0xd7, 0x28, 0x86, 0x81, 0xa6, 0xb8, 0xaa, 0xab, 0x8c, 0x70, 0x83, 0x1f, 0xe0, 0x86, 0x0f, 0x0d,
+	0xe1, 0x64, 0xce, 0x72, 0xb2, 0xb6, 0x37, 0x60, 0xc4, 0x1e, 0xba, 0x73, 0x80, 0x8e, 0xbf, 0x98,
+	0x4e, 0x97, 0xa5, 0x90, 0xe8, 0x39, 0x9c, 0x28, 0x5b, 0x84, 0x5e, 0xe4, 0x8f, 0xfb, 0xd3, 0xd1,
+	0xc4, 0x0c, 0x7b, 0xf2, 0x37, 0x3c, 0x36, 0x89, 0x7a, 0xc6, 0x45, 0x4d, 0xb7, 0x22, 0x6c, 0x45,
+	0xbe, 0x9e, 0xb1, 0x56, 0xaa, 0xff, 0x15, 0xa3, 0x6b, 0x03, 0x16, 0x60, 0x23, 0xe2, 0x57, 0xd0,
+	0x5d, 0x12, 0x69, 0x2a, 0x55, 0x46, 0x5a, 0xd9, 0x5e, 0x3d, 0x6c, 0xc4, 0xa1, 0xae, 0x75, 0x5c,
+	0x37, 0xd5, 0x75, 0xfa, 0x68, 0x95, 0xa1, 0xaf, 0xd8, 0xde, 0xb7, 0x11, 0xe8, 0x0c, 0xfc, 0x0b,
+	0x9a, 0xdb, 0x2a, 0x65, 0xc6, 0x3f, 0x3d, 0xe8, 0x25, 0x75, 0x76, 0x49, 0xf2, 0x0d, 0xe1, 0xe8,
+	0x1c, 0x3a, 0x89, 0xbe, 0x20, 0xc7, 0x76, 0xdf, 0xb1, 0x25, 0x76, 0x91, 0x75, 0x14, 0xbb, 0x2c,
+	0x34, 0x81, 0xce, 0xcc, 0x16, 0xb4, 0x74, 0xc1, 0xd0, 0x15, 0xcc, 0xcc, 0xab, 0xb0, 0xf9, 0x36,
+	0x49, 0x8d, 0x7a, 0x96, 0xd9, 0x09, 0x58, 0xe8, 0x83, 0x23, 0x2e, 0xe0, 0xde, 0x92, 0xc8, 0x46,
+	0x2b, 0x81, 0x1e, 0x43, 0xb0, 0xe0, 0xac, 0xd2, 0x20, 0xfd, 0xe9, 0xff, 0xee, 0x7c, 0xbb, 0xa1,
+	0x58, 0x07, 0xcd, 0x20, 0x6b, 0xea, 0xd6, 0xc7, 0x08, 0xb5, 0xe2, 0x0b, 0xc6, 0xbf, 0xa5, 0x3c,
+	0xd7, 0xbd, 0xba, 0xd8, 0xc9, 0xf8, 0x0d, 0xfc, 0x77, 0xa3, 0xcd, 0x33, 0x68, 0xdf, 0x85, 0xdc,
+	0x26, 0xc5, 0x3f, 0x3c, 0x38, 0x5b, 0x12, 0x79, 0x4c, 0xa9, 0x9f, 0xd4, 0x2c, 0xcf, 0xd5, 0x0a,
+	0xd8, 0x07, 0xe3, 0xe4, 0x1e, 0xa2, 0x75, 0x27, 0x08, 0xff, 0x16, 0x88, 0xa0, 0x09, 0xf1, 0x1a,
+	0x4e, 0x9b, 0xfd, 0x9f, 0xde, 0x60, 0xf8, 0xf7, 0x30, 0x1c, 0xc2, 0x13, 0xe8, 0x6b, 0x4b, 0xed,
+	0xb7, 0xd9, 0x51, 0x63, 0xe9, 0xe2, 0x01, 0xb6, 0x2a, 0x6b, 0xeb, 0x9f, 0xd6, 0x8b, 0x3f, 0x03,
+	0x00, 0x56, 0xc9, 0x15, 0x13, 0x0d, 0x05, 0x00, 0x00,
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"github.com/vitelabs/go-vite/common/types"
 	"io"
 )
 
@@ -166,3 +167,14 @@ func (abi *ABIContract) MethodById(sigdata []byte) (*Method, error) {
 	}
 	return nil, fmt.Errorf("no method with id: %#x", sigdata[:4])
 }
+
+// EventById looks up a non-anonymous event by the first topic of a log
+// returns an error if none found
+func (abi *ABIContract) EventById(topic types.Hash) (*Event, error) {
+	for _, event := range abi.Events {
+		if !event.Anonymous && event.Id() == topic {
+			return &event, nil
+		}
+	}
+	return nil, fmt.Errorf("no event with id: %v", topic)
+}
@@ -0,0 +1,78 @@
+package abi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/vitelabs/go-vite/common/types"
+)
+
+// ConvertStringParams converts string params to the values expected by Pack. Numbers are decimal, bytes are hex,
+// address, gid and tokenId use their string forms. Slices and arrays are not supported.
+func (arguments Arguments) ConvertStringParams(params []string) ([]interface{}, error) {
+	if len(params) != len(arguments) {
+		return nil, fmt.Errorf("argument count mismatch: %d for %d", len(params), len(arguments))
+	}
+	values := make([]interface{}, len(params))
+	for i, param := range params {
+		value, err := convertStringParam(arguments[i].Type, param)
+		if err != nil {
+			return nil, fmt.Errorf("abi: cannot convert param %d: %v", i, err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func convertStringParam(t Type, param string) (interface{}, error) {
+	switch t.T {
+	case IntTy, UintTy:
+		n, ok := new(big.Int).SetString(param, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid number %s", param)
+		}
+		if t.Kind == reflect.Ptr {
+			return n, nil
+		}
+		if t.T == UintTy {
+			if n.Sign() < 0 || n.BitLen() > t.Size {
+				return nil, fmt.Errorf("number %s out of range of %s", param, t)
+			}
+			return reflect.ValueOf(n.Uint64()).Convert(t.Type).Interface(), nil
+		}
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+		if n.Cmp(new(big.Int).Neg(limit)) < 0 || n.Cmp(limit) >= 0 {
+			return nil, fmt.Errorf("number %s out of range of %s", param, t)
+		}
+		return reflect.ValueOf(n.Int64()).Convert(t.Type).Interface(), nil
+	case BoolTy:
+		return strconv.ParseBool(param)
+	case StringTy:
+		return param, nil
+	case AddressTy:
+		return types.HexToAddress(param)
+	case GidTy:
+		return types.HexToGid(param)
+	case TokenIdTy:
+		return types.HexToTokenTypeId(param)
+	case BytesTy:
+		return hex.DecodeString(strings.TrimPrefix(param, "0x"))
+	case FixedBytesTy:
+		b, err := hex.DecodeString(strings.TrimPrefix(param, "0x"))
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > t.Size {
+			return nil, fmt.Errorf("bytes %s longer than %s", param, t)
+		}
+		array := reflect.New(t.Type).Elem()
+		reflect.Copy(array, reflect.ValueOf(b))
+		return array.Interface(), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
@@ -0,0 +1,36 @@
+package abi
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vitelabs/go-vite/common/types"
+)
+
+func TestArguments_ConvertStringParams(t *testing.T) {
+	abi, err := JSONToABIContract(strings.NewReader(`[{"type":"constructor","inputs":[{"name":"a","type":"uint8"},{"name":"b","type":"int64"},{"name":"c","type":"uint256"},{"name":"d","type":"bool"},{"name":"e","type":"string"},{"name":"f","type":"bytes"},{"name":"g","type":"bytes2"},{"name":"h","type":"gid"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := abi.Constructor.Inputs.ConvertStringParams([]string{"255", "-3", "100000000000000000000", "true", "vite", "0x0102", "0a0b", "00000000000000000001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := new(big.Int).SetString("100000000000000000000", 10)
+	expected := []interface{}{uint8(255), int64(-3), c, true, "vite", []byte{1, 2}, [2]byte{10, 11}, types.SNAPSHOT_GID}
+	if !reflect.DeepEqual(params, expected) {
+		t.Fatalf("convert params error, expected %v, got %v", expected, params)
+	}
+	if _, err := abi.PackMethod("", params...); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := abi.Constructor.Inputs.ConvertStringParams([]string{"256", "-3", "1", "true", "", "", "", "00000000000000000001"}); err == nil {
+		t.Fatal("expected out of range error")
+	}
+	if _, err := abi.Constructor.Inputs.ConvertStringParams([]string{"1"}); err == nil {
+		t.Fatal("expected argument count error")
+	}
+}
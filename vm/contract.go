@@ -59,5 +59,15 @@ func (c *contract) run(vm *VM) (ret []byte, err error) {
 		c.intPool = nil
 	}()
 
-	return vm.i.Run(vm, c)
+	vm.depth++
+	defer func() { vm.depth-- }()
+
+	if vm.Tracer == nil {
+		return vm.i.Run(vm, c)
+	}
+	quotaStart := c.quotaLeft
+	vm.Tracer.CaptureStart(c.caller, c.address, c.codeAddr, c.sendBlock.Data, quotaStart, vm.depth)
+	ret, err = vm.i.Run(vm, c)
+	vm.Tracer.CaptureEnd(ret, quotaStart-c.quotaLeft, vm.depth, err)
+	return ret, err
 }
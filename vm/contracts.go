@@ -55,6 +55,11 @@ var simpleContracts = map[types.Address]*precompiledContract{
 	},
 }
 
+// IsPrecompiledContractAddress reports whether addr is the address of a built-in contract.
+func IsPrecompiledContractAddress(addr types.Address) bool {
+	return isPrecompiledContractAddress(addr)
+}
+
 func isPrecompiledContractAddress(addr types.Address) bool {
 	_, ok := simpleContracts[addr]
 	return ok
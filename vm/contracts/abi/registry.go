@@ -0,0 +1,184 @@
+package abi
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/vm/abi"
+)
+
+var (
+	ErrAbiNotFound        = errors.New("abi not found")
+	ErrBuiltinAbiReadOnly = errors.New("abi of built-in contract can not be changed")
+)
+
+// parsedAbiCache maps the hash of a json abi to the parsed abi, it's shared by the registries since a registry is
+// created for each request. The json abi is still read from the store, so a registered abi replaces the old one.
+var parsedAbiCache, _ = lru.New(1024)
+
+// AbiStore persists the json abis of user contracts.
+type AbiStore interface {
+	SaveContractAbi(addr *types.Address, abiJson []byte) error
+	GetContractAbi(addr *types.Address) ([]byte, error)
+}
+
+// DecodedArg is a decoded method or event argument, Value is the go value returned by abi unpacking.
+type DecodedArg struct {
+	Name    string
+	Type    string
+	Indexed bool
+	Value   interface{}
+}
+
+type DecodedMethod struct {
+	Name string
+	Args []*DecodedArg
+}
+
+type DecodedEvent struct {
+	Name string
+	Args []*DecodedArg
+}
+
+// Registry resolves the abi of a contract address, the abis of built-in contracts are preloaded, the abis of
+// user contracts are read from the store.
+type Registry struct {
+	store AbiStore
+}
+
+func NewRegistry(store AbiStore) *Registry {
+	return &Registry{store: store}
+}
+
+// Register validates abiJson and saves it as the abi of addr.
+func (r *Registry) Register(addr types.Address, abiJson string) error {
+	if _, ok := precompiledContractsAbiMap[addr]; ok {
+		return ErrBuiltinAbiReadOnly
+	}
+	if _, err := abi.JSONToABIContract(strings.NewReader(abiJson)); err != nil {
+		return err
+	}
+	if r.store == nil {
+		return errors.New("abi store is not set")
+	}
+	return r.store.SaveContractAbi(&addr, []byte(abiJson))
+}
+
+// Get returns the abi of addr, or nil if no abi is known. The returned abi is shared and must not be modified.
+func (r *Registry) Get(addr types.Address) (*abi.ABIContract, error) {
+	if abiContract, ok := precompiledContractsAbiMap[addr]; ok {
+		return &abiContract, nil
+	}
+	if r.store == nil {
+		return nil, nil
+	}
+	abiJson, err := r.store.GetContractAbi(&addr)
+	if err != nil || len(abiJson) == 0 {
+		return nil, err
+	}
+
+	key := types.DataHash(abiJson)
+	if cached, ok := parsedAbiCache.Get(key); ok {
+		return cached.(*abi.ABIContract), nil
+	}
+	abiContract, err := abi.JSONToABIContract(bytes.NewReader(abiJson))
+	if err != nil {
+		return nil, err
+	}
+	parsedAbiCache.Add(key, &abiContract)
+	return &abiContract, nil
+}
+
+// DecodeMethod decodes the data of a send call block to addr.
+func (r *Registry) DecodeMethod(addr types.Address, data []byte) (*DecodedMethod, error) {
+	abiContract, err := r.Get(addr)
+	if err != nil {
+		return nil, err
+	}
+	if abiContract == nil {
+		return nil, ErrAbiNotFound
+	}
+	return DecodeMethod(abiContract, data)
+}
+
+// DecodeLog decodes a vm log emitted by addr.
+func (r *Registry) DecodeLog(addr types.Address, log *ledger.VmLog) (*DecodedEvent, error) {
+	abiContract, err := r.Get(addr)
+	if err != nil {
+		return nil, err
+	}
+	if abiContract == nil {
+		return nil, ErrAbiNotFound
+	}
+	return DecodeLog(abiContract, log)
+}
+
+func DecodeMethod(abiContract *abi.ABIContract, data []byte) (*DecodedMethod, error) {
+	method, err := abiContract.MethodById(data)
+	if err != nil {
+		return nil, err
+	}
+	values, err := method.Inputs.UnpackValues(data[4:])
+	if err != nil {
+		return nil, err
+	}
+	decoded := &DecodedMethod{Name: method.Name, Args: make([]*DecodedArg, len(values))}
+	for i, input := range method.Inputs {
+		decoded.Args[i] = &DecodedArg{Name: input.Name, Type: input.Type.String(), Value: values[i]}
+	}
+	return decoded, nil
+}
+
+// DecodeLog decodes the topics and data of log. The first topic is the event id, indexed arguments are read from
+// the following topics, arguments of dynamic types are only available as the hash kept in the topic.
+func DecodeLog(abiContract *abi.ABIContract, log *ledger.VmLog) (*DecodedEvent, error) {
+	if len(log.Topics) == 0 {
+		return nil, errors.New("anonymous log can not be decoded")
+	}
+	event, err := abiContract.EventById(log.Topics[0])
+	if err != nil {
+		return nil, err
+	}
+	values, err := event.Inputs.UnpackValues(log.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := &DecodedEvent{Name: event.Name, Args: make([]*DecodedArg, 0, len(event.Inputs))}
+	topicIndex, valueIndex := 1, 0
+	for _, input := range event.Inputs {
+		arg := &DecodedArg{Name: input.Name, Type: input.Type.String(), Indexed: input.Indexed}
+		if input.Indexed {
+			if topicIndex >= len(log.Topics) {
+				return nil, errors.New("topic count mismatch")
+			}
+			topic := log.Topics[topicIndex]
+			topicIndex++
+			if arg.Value, err = decodeTopic(input, topic); err != nil {
+				return nil, err
+			}
+		} else {
+			arg.Value = values[valueIndex]
+			valueIndex++
+		}
+		decoded.Args = append(decoded.Args, arg)
+	}
+	return decoded, nil
+}
+
+func decodeTopic(input abi.Argument, topic types.Hash) (interface{}, error) {
+	switch input.Type.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy:
+		return topic, nil
+	}
+	input.Indexed = false
+	values, err := abi.Arguments{input}.UnpackValues(topic.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return values[0], nil
+}
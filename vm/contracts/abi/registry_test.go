@@ -0,0 +1,88 @@
+package abi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/vitelabs/go-vite/common/helper"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+)
+
+type memAbiStore map[types.Address][]byte
+
+func (s memAbiStore) SaveContractAbi(addr *types.Address, abiJson []byte) error {
+	s[*addr] = abiJson
+	return nil
+}
+
+func (s memAbiStore) GetContractAbi(addr *types.Address) ([]byte, error) {
+	return s[*addr], nil
+}
+
+const jsonTransfer = `[
+	{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}]},
+	{"type":"event","name":"Transfer","inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"name":"value","type":"uint256"}]}
+]`
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry(memAbiStore{})
+	contractAddr, _ := types.BytesToAddress(helper.HexToBytes("0000000000000000000000000000000000000010"))
+	from, _ := types.BytesToAddress(helper.HexToBytes("0000000000000000000000000000000000000020"))
+	to, _ := types.BytesToAddress(helper.HexToBytes("0000000000000000000000000000000000000030"))
+
+	if err := registry.Register(AddressPledge, jsonTransfer); err != ErrBuiltinAbiReadOnly {
+		t.Fatalf("register built-in abi, expected %v, got %v", ErrBuiltinAbiReadOnly, err)
+	}
+	if _, err := registry.DecodeMethod(contractAddr, nil); err != ErrAbiNotFound {
+		t.Fatalf("decode without abi, expected %v, got %v", ErrAbiNotFound, err)
+	}
+	if err := registry.Register(contractAddr, jsonTransfer); err != nil {
+		t.Fatalf("register abi failed, %v", err)
+	}
+
+	abiContract, _ := registry.Get(contractAddr)
+	data, _ := abiContract.PackMethod("transfer", to, big.NewInt(100))
+	method, err := registry.DecodeMethod(contractAddr, data)
+	if err != nil {
+		t.Fatalf("decode method failed, %v", err)
+	}
+	if method.Name != "transfer" || len(method.Args) != 2 ||
+		method.Args[0].Value.(types.Address) != to || method.Args[1].Value.(*big.Int).Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("decode method result mismatch, %v", method)
+	}
+
+	logData, _ := abiContract.Events["Transfer"].Inputs.NonIndexed().Pack(big.NewInt(100))
+	var fromTopic, toTopic types.Hash
+	copy(fromTopic[types.HashSize-types.AddressSize:], from.Bytes())
+	copy(toTopic[types.HashSize-types.AddressSize:], to.Bytes())
+	log := &ledger.VmLog{
+		Topics: []types.Hash{abiContract.Events["Transfer"].Id(), fromTopic, toTopic},
+		Data:   logData,
+	}
+	event, err := registry.DecodeLog(contractAddr, log)
+	if err != nil {
+		t.Fatalf("decode log failed, %v", err)
+	}
+	if event.Name != "Transfer" || len(event.Args) != 3 ||
+		event.Args[0].Value.(types.Address) != from || event.Args[1].Value.(types.Address) != to ||
+		event.Args[2].Value.(*big.Int).Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("decode log result mismatch, %v", event)
+	}
+
+	pledgeData, _ := ABIPledge.PackMethod(MethodNameCancelPledge, to, big.NewInt(10))
+	if method, err := registry.DecodeMethod(AddressPledge, pledgeData); err != nil || method.Name != MethodNameCancelPledge {
+		t.Fatalf("decode built-in method failed, %v, %v", method, err)
+	}
+
+	// the parsed abi is cached until another abi is registered
+	if cached, _ := registry.Get(contractAddr); cached != abiContract {
+		t.Fatal("the parsed abi should be cached")
+	}
+	if err := registry.Register(contractAddr, `[{"type":"function","name":"burn","inputs":[]}]`); err != nil {
+		t.Fatalf("register abi failed, %v", err)
+	}
+	if replaced, _ := registry.Get(contractAddr); replaced == abiContract || len(replaced.Methods) != 1 || replaced.Methods["burn"].Name != "burn" {
+		t.Fatal("the registered abi should replace the cached one")
+	}
+}
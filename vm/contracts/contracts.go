@@ -21,6 +21,12 @@ func InitContractsConfig(isTestParam bool) {
 	}
 }
 
+// IsCreateContractForked returns whether user contracts can be created by the blocks referring to the snapshot
+// block of snapshotHeight
+func IsCreateContractForked(snapshotHeight uint64) bool {
+	return snapshotHeight >= nodeConfig.params.CreateContractHeight
+}
+
 type SendBlock struct {
 	Block     *ledger.AccountBlock
 	ToAddress types.Address
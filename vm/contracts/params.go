@@ -2,6 +2,7 @@ package contracts
 
 import (
 	"github.com/vitelabs/go-vite/vm/util"
+	"math"
 	"math/big"
 )
 
@@ -54,6 +55,7 @@ type ContractsParams struct {
 	MintagePledgeHeight              uint64 // Pledge height for mintage if choose to pledge instead of destroy vite token
 	RewardEndTimeLimit               uint64 // Cannot get snapshot block reward of current few blocks, for latest snapshot block could be reverted
 	RewardTimeUnit                   uint64
+	CreateContractHeight             uint64 // Snapshot height from which user contracts can be created
 }
 
 var (
@@ -63,6 +65,7 @@ var (
 		MintagePledgeHeight:              1,
 		RewardEndTimeLimit:               75,
 		RewardTimeUnit:                   75 * 2,
+		CreateContractHeight:             1,
 	}
 	ContractsParamsMainNet = ContractsParams{
 		MinPledgeHeight:                  3600 * 24 * 3,
@@ -70,5 +73,6 @@ var (
 		MintagePledgeHeight:              3600 * 24 * 30 * 3,
 		RewardEndTimeLimit:               3600 * 24,
 		RewardTimeUnit:                   1152 * 75,
+		CreateContractHeight:             math.MaxUint64, // not activated on the main net yet
 	}
 )
@@ -57,6 +57,9 @@ func (i *Interpreter) Run(vm *VM, c *contract) (ret []byte, err error) {
 		}
 		c.quotaLeft, err = util.UseQuota(c.quotaLeft, cost)
 		if err != nil {
+			if vm.Tracer != nil {
+				vm.Tracer.CaptureState(currentPc, opCodeToString[op], c.quotaLeft, cost, st.data, mem.store, vm.depth, err)
+			}
 			return nil, err
 		}
 
@@ -66,6 +69,10 @@ func (i *Interpreter) Run(vm *VM, c *contract) (ret []byte, err error) {
 
 		res, err := operation.execute(&pc, vm, c, mem, st)
 
+		if vm.Tracer != nil {
+			vm.Tracer.CaptureState(currentPc, opCodeToString[op], c.quotaLeft, cost, st.data, mem.store, vm.depth, err)
+		}
+
 		if vm.Debug {
 			logger.Info("current code", "code", hex.EncodeToString(c.code[currentPc:]))
 			fmt.Printf("code: %v \n", hex.EncodeToString(c.code[currentPc:]))
@@ -0,0 +1,148 @@
+package vm
+
+import (
+	"encoding/hex"
+	"math/big"
+
+	"github.com/vitelabs/go-vite/common/types"
+)
+
+// Tracer is notified by the interpreter about contract code execution. CaptureStart and CaptureEnd wrap every
+// code run, including nested delegate calls, CaptureState is called after every executed opcode.
+type Tracer interface {
+	CaptureStart(from types.Address, to types.Address, codeAddr types.Address, input []byte, quota uint64, depth int)
+	CaptureState(pc uint64, op string, quotaLeft uint64, cost uint64, stack []*big.Int, memory []byte, depth int, err error)
+	CaptureEnd(output []byte, quotaUsed uint64, depth int, err error)
+}
+
+// StructLog is a single executed opcode recorded by StructLogger.
+type StructLog struct {
+	Pc        uint64   `json:"pc"`
+	Op        string   `json:"op"`
+	QuotaLeft uint64   `json:"quotaLeft"`
+	QuotaCost uint64   `json:"quotaCost"`
+	Depth     int      `json:"depth"`
+	Stack     []string `json:"stack"`
+	Memory    string   `json:"memory,omitempty"`
+	Err       string   `json:"error,omitempty"`
+}
+
+// StructLogger records every executed opcode with a copy of the stack and optionally the memory.
+type StructLogger struct {
+	DisableMemory bool
+	Limit         int
+
+	logs   []*StructLog
+	output []byte
+	err    error
+}
+
+func NewStructLogger(disableMemory bool, limit int) *StructLogger {
+	return &StructLogger{DisableMemory: disableMemory, Limit: limit}
+}
+
+func (l *StructLogger) CaptureStart(from types.Address, to types.Address, codeAddr types.Address, input []byte, quota uint64, depth int) {
+}
+
+func (l *StructLogger) CaptureState(pc uint64, op string, quotaLeft uint64, cost uint64, stack []*big.Int, memory []byte, depth int, err error) {
+	if l.Limit > 0 && len(l.logs) >= l.Limit {
+		return
+	}
+	log := &StructLog{
+		Pc:        pc,
+		Op:        op,
+		QuotaLeft: quotaLeft,
+		QuotaCost: cost,
+		Depth:     depth,
+		Stack:     make([]string, len(stack)),
+	}
+	for i, item := range stack {
+		log.Stack[i] = item.Text(16)
+	}
+	if !l.DisableMemory && len(memory) > 0 {
+		log.Memory = hex.EncodeToString(memory)
+	}
+	if err != nil {
+		log.Err = err.Error()
+	}
+	l.logs = append(l.logs, log)
+}
+
+func (l *StructLogger) CaptureEnd(output []byte, quotaUsed uint64, depth int, err error) {
+	if depth == 1 {
+		l.output = output
+		l.err = err
+	}
+}
+
+func (l *StructLogger) StructLogs() []*StructLog {
+	return l.logs
+}
+
+func (l *StructLogger) Output() []byte {
+	return l.output
+}
+
+func (l *StructLogger) Error() error {
+	return l.err
+}
+
+// CallFrame is a contract code run recorded by CallTracer, Calls are the delegate calls made by it.
+type CallFrame struct {
+	From      types.Address `json:"from"`
+	To        types.Address `json:"to"`
+	CodeAddr  types.Address `json:"codeAddr"`
+	Input     string        `json:"input"`
+	Output    string        `json:"output"`
+	Quota     uint64        `json:"quota"`
+	QuotaUsed uint64        `json:"quotaUsed"`
+	Err       string        `json:"error,omitempty"`
+	Calls     []*CallFrame  `json:"calls,omitempty"`
+}
+
+// CallTracer records the tree of code runs without per opcode details.
+type CallTracer struct {
+	root  *CallFrame
+	stack []*CallFrame
+}
+
+func NewCallTracer() *CallTracer {
+	return &CallTracer{}
+}
+
+func (t *CallTracer) CaptureStart(from types.Address, to types.Address, codeAddr types.Address, input []byte, quota uint64, depth int) {
+	frame := &CallFrame{
+		From:     from,
+		To:       to,
+		CodeAddr: codeAddr,
+		Input:    hex.EncodeToString(input),
+		Quota:    quota,
+	}
+	if len(t.stack) == 0 {
+		t.root = frame
+	} else {
+		parent := t.stack[len(t.stack)-1]
+		parent.Calls = append(parent.Calls, frame)
+	}
+	t.stack = append(t.stack, frame)
+}
+
+func (t *CallTracer) CaptureState(pc uint64, op string, quotaLeft uint64, cost uint64, stack []*big.Int, memory []byte, depth int, err error) {
+}
+
+func (t *CallTracer) CaptureEnd(output []byte, quotaUsed uint64, depth int, err error) {
+	if len(t.stack) == 0 {
+		return
+	}
+	frame := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+	frame.Output = hex.EncodeToString(output)
+	frame.QuotaUsed = quotaUsed
+	if err != nil {
+		frame.Err = err.Error()
+	}
+}
+
+func (t *CallTracer) Result() *CallFrame {
+	return t.root
+}
@@ -0,0 +1,164 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/vitelabs/go-vite/common/helper"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger"
+	"github.com/vitelabs/go-vite/vm/util"
+	"github.com/vitelabs/go-vite/vm_context"
+)
+
+// code1 returns 1+2, code2 returns the result of delegate calling code1
+func prepareTracerContracts() (*testDatabase, types.Address, types.Address) {
+	db := NewNoDatabase()
+	addr1, _, _ := types.CreateAddress()
+	code1 := []byte{byte(PUSH1), 1, byte(PUSH1), 2, byte(ADD), byte(PUSH1), 32, byte(DUP1), byte(SWAP2), byte(SWAP1), byte(MSTORE), byte(PUSH1), 32, byte(SWAP1), byte(RETURN)}
+	db.codeMap = make(map[types.Address][]byte)
+	db.codeMap[addr1] = code1
+
+	addr2, _, _ := types.CreateAddress()
+	code2 := helper.JoinBytes([]byte{byte(PUSH1), 32, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH20)}, addr1.Bytes(), []byte{byte(DELEGATECALL), byte(PUSH1), 32, byte(PUSH1), 0, byte(RETURN)})
+	db.codeMap[addr2] = code2
+	return db, addr1, addr2
+}
+
+func newTracerContract(db *testDatabase, from, to, codeAddr types.Address, quota uint64) *contract {
+	blockTime := time.Now()
+	sendCallBlock := ledger.AccountBlock{
+		AccountAddress: from,
+		ToAddress:      to,
+		BlockType:      ledger.BlockTypeSendCall,
+		Amount:         big.NewInt(10),
+		Fee:            big.NewInt(0),
+		TokenId:        ledger.ViteTokenId,
+		Data:           []byte{1, 2, 3},
+	}
+	receiveCallBlock := &ledger.AccountBlock{
+		AccountAddress: to,
+		BlockType:      ledger.BlockTypeReceive,
+		Timestamp:      &blockTime,
+	}
+	c := newContract(
+		from,
+		to,
+		&vm_context.VmAccountBlock{AccountBlock: receiveCallBlock, VmContext: db},
+		&sendCallBlock,
+		quota,
+		0)
+	c.setCallCode(codeAddr, db.codeMap[codeAddr])
+	return c
+}
+
+func TestStructLogger(t *testing.T) {
+	db, addr1, _ := prepareTracerContracts()
+	result := helper.LeftPadBytes([]byte{3}, 32)
+
+	vm := NewVM()
+	logger := NewStructLogger(false, 0)
+	vm.Tracer = logger
+	ret, err := newTracerContract(db, addr1, addr1, addr1, 1000000).run(vm)
+	if err != nil || !bytes.Equal(ret, result) {
+		t.Fatalf("run error, %v", err)
+	}
+
+	logs := logger.StructLogs()
+	ops := []string{"PUSH1", "PUSH1", "ADD", "PUSH1", "DUP1", "SWAP2", "SWAP1", "MSTORE", "PUSH1", "SWAP1", "RETURN"}
+	if len(logs) != len(ops) {
+		t.Fatalf("should log %d ops, got %d", len(ops), len(logs))
+	}
+	pc := uint64(0)
+	for i, log := range logs {
+		if log.Op != ops[i] || log.Pc != pc || log.Depth != 1 || log.Err != "" {
+			t.Fatalf("op %d is logged as %+v", i, log)
+		}
+		if i > 0 && log.QuotaLeft != logs[i-1].QuotaLeft-log.QuotaCost {
+			t.Fatalf("quota left of op %d is %d, previous %d, cost %d", i, log.QuotaLeft, logs[i-1].QuotaLeft, log.QuotaCost)
+		}
+		pc++
+		if ops[i] == "PUSH1" {
+			pc++
+		}
+	}
+	// the stack is captured after the op is executed
+	if len(logs[2].Stack) != 1 || logs[2].Stack[0] != "3" {
+		t.Fatalf("stack after ADD should be [3], got %v", logs[2].Stack)
+	}
+	// the result is stored at 32
+	memory := hex.EncodeToString(append(make([]byte, 32), result...))
+	if logs[7].Memory != memory {
+		t.Fatalf("memory after MSTORE should be %s, got %s", memory, logs[7].Memory)
+	}
+	if !bytes.Equal(logger.Output(), result) || logger.Error() != nil {
+		t.Fatalf("output is %x, error is %v", logger.Output(), logger.Error())
+	}
+
+	// limit and disable memory
+	logger = NewStructLogger(true, 8)
+	vm = NewVM()
+	vm.Tracer = logger
+	if _, err := newTracerContract(db, addr1, addr1, addr1, 1000000).run(vm); err != nil {
+		t.Fatal(err)
+	}
+	if len(logger.StructLogs()) != 8 {
+		t.Fatalf("should log 8 ops, got %d", len(logger.StructLogs()))
+	}
+	for _, log := range logger.StructLogs() {
+		if log.Memory != "" {
+			t.Fatalf("memory should not be logged, got %s", log.Memory)
+		}
+	}
+}
+
+func TestStructLogger_OutOfQuota(t *testing.T) {
+	db, addr1, _ := prepareTracerContracts()
+
+	vm := NewVM()
+	logger := NewStructLogger(false, 0)
+	vm.Tracer = logger
+	if _, err := newTracerContract(db, addr1, addr1, addr1, 7).run(vm); err != util.ErrOutOfQuota {
+		t.Fatalf("should run out of quota, got %v", err)
+	}
+
+	logs := logger.StructLogs()
+	if len(logs) == 0 || logs[len(logs)-1].Err != util.ErrOutOfQuota.Error() {
+		t.Fatalf("the failed op should be logged with the error, got %d logs", len(logs))
+	}
+	if logger.Error() != util.ErrOutOfQuota {
+		t.Fatalf("error should be %v, got %v", util.ErrOutOfQuota, logger.Error())
+	}
+}
+
+func TestCallTracer(t *testing.T) {
+	db, addr1, addr2 := prepareTracerContracts()
+	sender, _, _ := types.CreateAddress()
+	output := hex.EncodeToString(helper.LeftPadBytes([]byte{3}, 32))
+
+	vm := NewVM()
+	tracer := NewCallTracer()
+	vm.Tracer = tracer
+	if _, err := newTracerContract(db, sender, addr2, addr2, 1000000).run(vm); err != nil {
+		t.Fatal(err)
+	}
+
+	root := tracer.Result()
+	if root == nil || root.From != sender || root.To != addr2 || root.CodeAddr != addr2 ||
+		root.Input != "010203" || root.Output != output || root.Quota != 1000000 || root.Err != "" {
+		t.Fatalf("root frame is %+v", root)
+	}
+	if len(root.Calls) != 1 {
+		t.Fatalf("should record 1 delegate call, got %d", len(root.Calls))
+	}
+	call := root.Calls[0]
+	if call.To != addr2 || call.CodeAddr != addr1 || call.Output != output || len(call.Calls) != 0 {
+		t.Fatalf("delegate call frame is %+v", call)
+	}
+	if call.QuotaUsed == 0 || call.QuotaUsed >= root.QuotaUsed {
+		t.Fatalf("quota used by the delegate call is %d, by the root is %d", call.QuotaUsed, root.QuotaUsed)
+	}
+}
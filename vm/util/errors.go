@@ -13,4 +13,6 @@ var (
 	ErrReturnDataOutOfBounds       = errors.New("evm: return data out of bounds")
 	ErrCalcPoWTwice                = errors.New("calc PoW twice referring to one snapshot block")
 	ErrAbiMethodNotFound           = errors.New("abi: method not found")
+	ErrInvalidCreateData           = errors.New("invalid contract create data")
+	ErrCreateContractNotForked     = errors.New("contract creation is not activated at the snapshot height")
 )
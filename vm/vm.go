@@ -21,7 +21,8 @@ import (
 )
 
 type VMConfig struct {
-	Debug bool
+	Debug  bool
+	Tracer Tracer
 }
 
 type NodeConfig struct {
@@ -59,7 +60,8 @@ type VM struct {
 	VMConfig
 	abort int32
 	VmContext
-	i *Interpreter
+	i     *Interpreter
+	depth int
 }
 
 func NewVM() *VM {
@@ -73,19 +75,19 @@ func (vm *VM) Run(database vmctxt_interface.VmDatabase, block *ledger.AccountBlo
 	case ledger.BlockTypeReceive, ledger.BlockTypeReceiveError:
 		blockContext.AccountBlock.Data = nil
 		// block data, amount, tokenId, fee is already changed to send block data by generator
-		/* TODO not support create contract this version
 		if sendBlock.BlockType == ledger.BlockTypeSendCreate {
 			return vm.receiveCreate(blockContext, sendBlock, quota.CalcCreateQuota(sendBlock.Fee))
-		} else*/
-		if sendBlock.BlockType == ledger.BlockTypeSendCall || sendBlock.BlockType == ledger.BlockTypeSendReward {
+		} else if sendBlock.BlockType == ledger.BlockTypeSendCall || sendBlock.BlockType == ledger.BlockTypeSendReward {
 			return vm.receiveCall(blockContext, sendBlock)
 		}
-	/* TODO not support create contract this version
 	case ledger.BlockTypeSendCreate:
+		if !contracts.IsCreateContractForked(database.CurrentSnapshotBlock().Height) {
+			return nil, NoRetry, util.ErrCreateContractNotForked
+		}
 		quotaTotal, quotaAddition, err := nodeConfig.calcQuota(
 			database,
 			block.AccountAddress,
-			contracts.GetPledgeBeneficialAmount(database, block.AccountAddress),
+			abi.GetPledgeBeneficialAmount(database, block.AccountAddress),
 			block.Difficulty)
 		if err != nil {
 			return nil, NoRetry, err
@@ -95,7 +97,7 @@ func (vm *VM) Run(database vmctxt_interface.VmDatabase, block *ledger.AccountBlo
 			return nil, NoRetry, err
 		} else {
 			return []*vm_context.VmAccountBlock{blockContext}, NoRetry, nil
-		}*/
+		}
 	case ledger.BlockTypeSendCall:
 		quotaTotal, quotaAddition, err := nodeConfig.calcQuota(
 			database,
@@ -134,6 +136,10 @@ func (vm *VM) sendCreate(block *vm_context.VmAccountBlock, quotaTotal, quotaAddi
 		return nil, err
 	}
 
+	if len(block.AccountBlock.Data) <= types.GidSize {
+		return nil, util.ErrInvalidCreateData
+	}
+
 	contractFee, err := calcContractFee(block.AccountBlock.Data)
 	if err != nil {
 		return nil, err
@@ -144,7 +150,7 @@ func (vm *VM) sendCreate(block *vm_context.VmAccountBlock, quotaTotal, quotaAddi
 		return nil, errors.New("consensus group not exist")
 	}
 
-	if !CanTransfer(block.VmContext, block.AccountBlock.AccountAddress, block.AccountBlock.TokenId, block.AccountBlock.Amount, block.AccountBlock.Fee) {
+	if !CanTransfer(block.VmContext, block.AccountBlock.AccountAddress, block.AccountBlock.TokenId, block.AccountBlock.Amount, contractFee) {
 		return nil, util.ErrInsufficientBalance
 	}
 
@@ -191,11 +197,9 @@ func (vm *VM) receiveCreate(block *vm_context.VmAccountBlock, sendBlock *ledger.
 	// create contract account and add balance
 	block.VmContext.AddBalance(&sendBlock.TokenId, sendBlock.Amount)
 
-	sendBlock.Data = sendBlock.Data[types.GidSize:]
-
 	// init contract state and set contract code
 	c := newContract(sendBlock.AccountAddress, block.AccountBlock.AccountAddress, block, sendBlock, quotaLeft, 0)
-	c.setCallCode(block.AccountBlock.AccountAddress, sendBlock.Data)
+	c.setCallCode(block.AccountBlock.AccountAddress, sendBlock.Data[types.GidSize:])
 	code, err := c.run(vm)
 	if err == nil && len(code) <= MaxCodeSize {
 		codeCost := uint64(len(code)) * contractCodeGas
@@ -6,12 +6,15 @@ import (
 	"github.com/vitelabs/go-vite/common/helper"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/ledger"
+	vmabi "github.com/vitelabs/go-vite/vm/abi"
+	"github.com/vitelabs/go-vite/vm/contracts"
 	"github.com/vitelabs/go-vite/vm/contracts/abi"
 	"github.com/vitelabs/go-vite/vm/quota"
 	"github.com/vitelabs/go-vite/vm/util"
 	"github.com/vitelabs/go-vite/vm_context"
 	"math/big"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -53,9 +56,16 @@ func TestVmRun(t *testing.T) {
 		Data:           data13,
 		Timestamp:      &blockTime,
 	}
+	db.addr = addr1
+	// contract creation isn't activated on the main net
+	if _, _, err := NewVM().Run(db, block13, nil); err != util.ErrCreateContractNotForked {
+		t.Fatalf("send create before the fork should fail, got %v", err)
+	}
+	InitVmConfig(false, true)
+	defer InitVmConfig(false, false)
+
 	vm := NewVM()
 	vm.Debug = true
-	db.addr = addr1
 	sendCreateBlockList, isRetry, err := vm.Run(db, block13, nil)
 	balance1.Sub(balance1, block13.Amount)
 	balance1.Sub(balance1, createContractFee)
@@ -563,3 +573,131 @@ func TestVmForTest(t *testing.T) {
 		t.Fatalf("init test vm config failed")
 	}
 }
+
+func TestVmCreateContract(t *testing.T) {
+	InitVmConfig(false, true)
+	defer InitVmConfig(false, false)
+	viteTotalSupply := new(big.Int).Mul(big.NewInt(1e9), util.AttovPerVite)
+	db, addr1, _, hash12, snapshot, _ := prepareDb(viteTotalSupply)
+	blockTime := time.Now()
+
+	// MyContract of TestVmRun, compiled by solc 0.4.24
+	code, _ := hex.DecodeString("608060405260858060116000396000f300608060405260043610603e5763ffffffff7c0100000000000000000000000000000000000000000000000000000000600035041663f021ab8f81146043575b600080fd5b604c600435604e565b005b6000805490910190555600a165627a7a72305820b8d8d60a46c6ac6569047b17b012aa1ea458271f9bc8078ef0cff9208999d0900029")
+	abiContract, err := vmabi.JSONToABIContract(strings.NewReader(`[{"type":"constructor","inputs":[],"payable":true},{"type":"function","name":"AddV","constant":false,"inputs":[{"name":"addition","type":"uint256"}],"payable":true}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	constructorData, err := abiContract.PackMethod("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// invalid create data
+	vm := NewVM()
+	db.addr = addr1
+	block13 := &ledger.AccountBlock{
+		Height:         3,
+		AccountAddress: addr1,
+		BlockType:      ledger.BlockTypeSendCreate,
+		PrevHash:       hash12,
+		Amount:         big.NewInt(0),
+		Fee:            big.NewInt(0),
+		TokenId:        ledger.ViteTokenId,
+		SnapshotHash:   snapshot.Hash,
+		Data:           types.DELEGATE_GID.Bytes(),
+		Timestamp:      &blockTime,
+	}
+	if _, _, err := vm.Run(db, block13, nil); err != util.ErrInvalidCreateData {
+		t.Fatalf("send create with invalid data error, %v", err)
+	}
+
+	// send create
+	block13.Data = contracts.GetCreateContractData(append(code, constructorData...), types.DELEGATE_GID)
+	vm = NewVM()
+	sendCreateBlockList, _, err := vm.Run(db, block13, nil)
+	if err != nil || len(sendCreateBlockList) != 1 {
+		t.Fatalf("send create transaction error, %v", err)
+	}
+	sendCreateBlock := sendCreateBlockList[0].AccountBlock
+	addr2 := contracts.NewContractAddress(addr1, block13.Height, block13.PrevHash, block13.SnapshotHash)
+	if sendCreateBlock.ToAddress != addr2 || sendCreateBlock.Fee.Cmp(createContractFee) != 0 {
+		t.Fatalf("send create block error")
+	}
+	hash13 := types.DataHash([]byte{1, 3})
+	db.accountBlockMap[addr1][hash13] = sendCreateBlock
+
+	// receive create
+	db.storageMap[abi.AddressPledge][string(abi.GetPledgeBeneficialKey(addr2))], _ = abi.ABIPledge.PackVariable(abi.VariableNamePledgeBeneficial, new(big.Int).Mul(big.NewInt(1e9), big.NewInt(1e18)))
+	block21 := &ledger.AccountBlock{
+		Height:         1,
+		AccountAddress: addr2,
+		FromBlockHash:  hash13,
+		BlockType:      ledger.BlockTypeReceive,
+		SnapshotHash:   snapshot.Hash,
+		Timestamp:      &blockTime,
+	}
+	vm = NewVM()
+	db.addr = addr2
+	receiveCreateBlockList, isRetry, err := vm.Run(db, block21, sendCreateBlock)
+	if err != nil || isRetry || len(receiveCreateBlockList) != 1 ||
+		receiveCreateBlockList[0].AccountBlock.BlockType != ledger.BlockTypeReceive ||
+		len(db.GetContractCode(&addr2)) == 0 ||
+		!bytes.Equal(sendCreateBlock.Data, block13.Data) {
+		t.Fatalf("receive create transaction error, %v", err)
+	}
+	hash21 := types.DataHash([]byte{2, 1})
+	db.accountBlockMap[addr2] = make(map[types.Hash]*ledger.AccountBlock)
+	db.accountBlockMap[addr2][hash21] = receiveCreateBlockList[0].AccountBlock
+
+	// call the deployed contract with abi packed arguments, solc 0.4.24 selects methods by keccak256 ids
+	params, err := abiContract.Methods["AddV"].Inputs.ConvertStringParams([]string{"5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	arguments, err := abiContract.Methods["AddV"].Inputs.Pack(params...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data14 := append(helper.HexToBytes("f021ab8f"), arguments...)
+	hash14 := types.DataHash([]byte{1, 4})
+	block14 := &ledger.AccountBlock{
+		Height:         4,
+		AccountAddress: addr1,
+		ToAddress:      addr2,
+		BlockType:      ledger.BlockTypeSendCall,
+		Fee:            big.NewInt(0),
+		PrevHash:       hash13,
+		Amount:         big.NewInt(0),
+		TokenId:        ledger.ViteTokenId,
+		SnapshotHash:   snapshot.Hash,
+		Data:           data14,
+		Timestamp:      &blockTime,
+	}
+	vm = NewVM()
+	db.addr = addr1
+	sendCallBlockList, _, err := vm.Run(db, block14, nil)
+	if err != nil || len(sendCallBlockList) != 1 {
+		t.Fatalf("send call transaction error, %v", err)
+	}
+	db.accountBlockMap[addr1][hash14] = sendCallBlockList[0].AccountBlock
+
+	block22 := &ledger.AccountBlock{
+		Height:         2,
+		AccountAddress: addr2,
+		FromBlockHash:  hash14,
+		PrevHash:       hash21,
+		BlockType:      ledger.BlockTypeReceive,
+		SnapshotHash:   snapshot.Hash,
+		Timestamp:      &blockTime,
+	}
+	vm = NewVM()
+	db.addr = addr2
+	receiveCallBlockList, _, err := vm.Run(db, block22, sendCallBlockList[0].AccountBlock)
+	if err != nil || len(receiveCallBlockList) != 1 {
+		t.Fatalf("receive call transaction error, %v", err)
+	}
+	v := db.GetStorage(&addr2, helper.LeftPadBytes([]byte{0}, helper.WordSize))
+	if new(big.Int).SetBytes(v).Cmp(big.NewInt(5)) != 0 {
+		t.Fatalf("contract storage error, %v", v)
+	}
+}
@@ -121,10 +121,12 @@ func (context *VmContext) CopyAndFreeze() vmctxt_interface.VmDatabase {
 		chain:                context.chain,
 		address:              context.address,
 		currentSnapshotBlock: context.currentSnapshotBlock,
+		prevAccountBlock:     context.prevAccountBlock,
 
 		trie:         copyTrie,
 		unsavedCache: NewUnsavedCache(copyTrie),
 		frozen:       false,
+		log:          context.log,
 	}
 }
 
@@ -33,7 +33,8 @@ func getChainInstance() chain.Chain {
 		innerChainInstance = chain.NewChain(&config.Config{
 			DataDir: common.DefaultDataDir(),
 			//Chain: &config.Chain{
-			//	KafkaProducers: []*config.KafkaProducer{{
+			//	EventSinks: []*config.EventSink{{
+			//		Type:       "kafka",
 			//		Topic:      "test",
 			//		BrokerList: []string{"abc", "def"},
 			//	}},